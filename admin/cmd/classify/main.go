@@ -0,0 +1,93 @@
+// Command classify backfills inferred categories and tags for every server in the registry
+// using the same rules the sync applies to new and updated servers.
+//
+//	go run ./cmd/classify -rules config/taxonomy-rules.yaml -dry-run
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/pluggedin/registry-admin/internal/classifier"
+	"github.com/pluggedin/registry-admin/internal/db"
+	"github.com/pluggedin/registry-admin/internal/models"
+)
+
+func main() {
+	rulesPath := flag.String("rules", "", "rule file (defaults to TAXONOMY_RULES_FILE or "+classifier.DefaultRulesPath+")")
+	dryRun := flag.Bool("dry-run", false, "print inferred assignments without storing them")
+	flag.Parse()
+
+	path := *rulesPath
+	if path == "" {
+		path = os.Getenv("TAXONOMY_RULES_FILE")
+	}
+	if path == "" {
+		path = classifier.DefaultRulesPath
+	}
+
+	c, err := classifier.LoadFile(path)
+	if err != nil {
+		log.Fatalf("Failed to load taxonomy rules: %v", err)
+	}
+
+	ctx := context.Background()
+
+	postgresDB, err := db.NewPostgresDB(ctx)
+	if err != nil {
+		log.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	defer postgresDB.Close()
+
+	ops := db.NewOperations(postgresDB)
+
+	// Classify first, then write, so the export stream is not held open during updates
+	type assignment struct {
+		name     string
+		taxonomy models.ServerTaxonomy
+	}
+	var assignments []assignment
+	err = ops.ExportServers(ctx, "", "", "", false, func(server *models.ExportedServer) error {
+		if server.Status == models.ServerStatusDeleted {
+			return nil
+		}
+		assignments = append(assignments, assignment{name: server.Name, taxonomy: c.Classify(&server.ServerDetail)})
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Failed to read servers: %v", err)
+	}
+
+	if *dryRun {
+		for _, a := range assignments {
+			fmt.Printf("%s\tcategory=%s\ttags=%v\n", a.name, a.taxonomy.Category, a.taxonomy.Tags)
+		}
+		fmt.Printf("Classified %d servers (dry run, nothing stored)\n", len(assignments))
+		return
+	}
+
+	if err := ops.EnsureTaxonomy(ctx, c.Categories(), c.Tags()); err != nil {
+		log.Fatalf("Failed to create taxonomy from rules: %v", err)
+	}
+
+	changed, failed := 0, 0
+	for _, a := range assignments {
+		updated, err := ops.ApplyInferredTaxonomy(ctx, a.name, a.taxonomy)
+		if err != nil {
+			log.Printf("Failed to classify %s: %v", a.name, err)
+			failed++
+			continue
+		}
+		if updated {
+			changed++
+		}
+	}
+
+	fmt.Printf("Classified %d servers: %d changed, %d failed\n", len(assignments), changed, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,290 @@
+// Package classifier infers categories and tags for servers from a YAML rule file.
+//
+// Each rule lists signals that select it: keywords in the server name or description,
+// glob patterns on package identifiers and environment variable names, and repository
+// topics. A server gets every tag whose rule matches and the single best-scoring category.
+package classifier
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pluggedin/registry-admin/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultRulesPath is the rule file used when TAXONOMY_RULES_FILE is not set
+const DefaultRulesPath = "config/taxonomy-rules.yaml"
+
+// Signal weights. Package and environment variable matches are the strongest evidence of
+// what a server integrates with; description keywords are the weakest.
+const (
+	weightName        = 2
+	weightDescription = 1
+	weightPackage     = 3
+	weightEnv         = 3
+	weightTopic       = 2
+)
+
+var (
+	// validSlug matches lowercase, dash-separated slugs such as "developer-tools"
+	validSlug = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+	// wordSeparators matches runs of characters that separate words in names and descriptions
+	wordSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// Rules is the rule file format
+type Rules struct {
+	Categories []Rule `yaml:"categories"`
+	Tags       []Rule `yaml:"tags"`
+}
+
+// Rule assigns a category or tag to servers matching any of its signals
+type Rule struct {
+	Slug        string `yaml:"slug"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	Match       Match  `yaml:"match"`
+}
+
+// Match lists the signals that select a rule
+type Match struct {
+	Name        []string `yaml:"name,omitempty"`        // Keywords in the server name
+	Description []string `yaml:"description,omitempty"` // Keywords in the server description
+	Packages    []string `yaml:"packages,omitempty"`    // Glob patterns on package identifiers, e.g. "@octokit/*"
+	Env         []string `yaml:"env,omitempty"`         // Glob patterns on environment variable names, e.g. "GITHUB_*"
+	Topics      []string `yaml:"topics,omitempty"`      // Repository topics
+}
+
+// Classifier applies compiled rules to servers
+type Classifier struct {
+	categories []compiledRule
+	tags       []compiledRule
+}
+
+type compiledRule struct {
+	rule        Rule
+	name        []string
+	description []string
+	packages    []*regexp.Regexp
+	env         []*regexp.Regexp
+	topics      map[string]bool
+}
+
+// LoadFromEnv loads the rule file named by TAXONOMY_RULES_FILE, falling back to
+// DefaultRulesPath. It returns nil without error when no rule file is configured or present.
+func LoadFromEnv() (*Classifier, error) {
+	path := os.Getenv("TAXONOMY_RULES_FILE")
+	if path == "" {
+		if _, err := os.Stat(DefaultRulesPath); err != nil {
+			return nil, nil
+		}
+		path = DefaultRulesPath
+	}
+	return LoadFile(path)
+}
+
+// LoadFile loads and compiles a rule file
+func LoadFile(path string) (*Classifier, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse parses and compiles YAML rules
+func Parse(data []byte) (*Classifier, error) {
+	var rules Rules
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse rules: %w", err)
+	}
+	return New(rules)
+}
+
+// New compiles rules into a classifier
+func New(rules Rules) (*Classifier, error) {
+	categories, err := compileRules("category", rules.Categories)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := compileRules("tag", rules.Tags)
+	if err != nil {
+		return nil, err
+	}
+	return &Classifier{categories: categories, tags: tags}, nil
+}
+
+// Categories returns the categories defined by the rules
+func (c *Classifier) Categories() []models.Category {
+	categories := make([]models.Category, 0, len(c.categories))
+	for _, r := range c.categories {
+		categories = append(categories, models.Category{Slug: r.rule.Slug, Name: r.rule.Name, Description: r.rule.Description})
+	}
+	return categories
+}
+
+// Tags returns the tags defined by the rules
+func (c *Classifier) Tags() []models.Tag {
+	tags := make([]models.Tag, 0, len(c.tags))
+	for _, r := range c.tags {
+		tags = append(tags, models.Tag{Slug: r.rule.Slug, Name: r.rule.Name})
+	}
+	return tags
+}
+
+// Classify infers the category and tags of a server. The category is the highest-scoring
+// category rule, with ties going to the rule listed first; tags are every matching tag rule.
+func (c *Classifier) Classify(server *models.ServerDetail) models.ServerTaxonomy {
+	s := extractSignals(server)
+
+	result := models.ServerTaxonomy{Tags: []string{}}
+	best := 0
+	for _, r := range c.categories {
+		if score := r.score(s); score > best {
+			best = score
+			result.Category = r.rule.Slug
+		}
+	}
+	for _, r := range c.tags {
+		if r.score(s) > 0 {
+			result.Tags = append(result.Tags, r.rule.Slug)
+		}
+	}
+	return result
+}
+
+// signals holds the normalized server fields that rules match against
+type signals struct {
+	name        string
+	description string
+	packages    []string
+	env         []string
+	topics      []string
+}
+
+func extractSignals(server *models.ServerDetail) signals {
+	s := signals{
+		name:        normalizeWords(server.Name),
+		description: normalizeWords(server.Description),
+	}
+	for _, pkg := range server.Packages {
+		s.packages = append(s.packages, pkg.Name)
+		for _, env := range pkg.EnvironmentVariables {
+			s.env = append(s.env, env.Name)
+		}
+	}
+	for _, topic := range server.Repository.Topics {
+		s.topics = append(s.topics, strings.ToLower(topic))
+	}
+	return s
+}
+
+// score returns the weighted number of signals that match the rule
+func (r compiledRule) score(s signals) int {
+	score := 0
+	for _, kw := range r.name {
+		if containsWords(s.name, kw) {
+			score += weightName
+		}
+	}
+	for _, kw := range r.description {
+		if containsWords(s.description, kw) {
+			score += weightDescription
+		}
+	}
+	for _, re := range r.packages {
+		if matchesAny(re, s.packages) {
+			score += weightPackage
+		}
+	}
+	for _, re := range r.env {
+		if matchesAny(re, s.env) {
+			score += weightEnv
+		}
+	}
+	for _, topic := range s.topics {
+		if r.topics[topic] {
+			score += weightTopic
+		}
+	}
+	return score
+}
+
+func compileRules(kind string, rules []Rule) ([]compiledRule, error) {
+	compiled := make([]compiledRule, 0, len(rules))
+	seen := make(map[string]bool, len(rules))
+
+	for _, rule := range rules {
+		if !validSlug.MatchString(rule.Slug) || len(rule.Slug) > 64 {
+			return nil, fmt.Errorf("invalid %s slug '%s'", kind, rule.Slug)
+		}
+		if seen[rule.Slug] {
+			return nil, fmt.Errorf("duplicate %s rule '%s'", kind, rule.Slug)
+		}
+		seen[rule.Slug] = true
+		if rule.Name == "" {
+			rule.Name = rule.Slug
+		}
+
+		cr := compiledRule{rule: rule, topics: make(map[string]bool, len(rule.Match.Topics))}
+		for _, kw := range rule.Match.Name {
+			if kw = normalizeWords(kw); strings.TrimSpace(kw) != "" {
+				cr.name = append(cr.name, kw)
+			}
+		}
+		for _, kw := range rule.Match.Description {
+			if kw = normalizeWords(kw); strings.TrimSpace(kw) != "" {
+				cr.description = append(cr.description, kw)
+			}
+		}
+		for _, pattern := range rule.Match.Packages {
+			cr.packages = append(cr.packages, compileGlob(pattern))
+		}
+		for _, pattern := range rule.Match.Env {
+			cr.env = append(cr.env, compileGlob(pattern))
+		}
+		for _, topic := range rule.Match.Topics {
+			cr.topics[strings.ToLower(topic)] = true
+		}
+
+		if len(cr.name)+len(cr.description)+len(cr.packages)+len(cr.env)+len(cr.topics) == 0 {
+			return nil, fmt.Errorf("%s rule '%s' has no match signals", kind, rule.Slug)
+		}
+		compiled = append(compiled, cr)
+	}
+
+	return compiled, nil
+}
+
+// normalizeWords lowercases s and reduces it to space-separated words padded with a space
+// on each side, so keyword phrases only match on word boundaries
+func normalizeWords(s string) string {
+	return " " + strings.TrimSpace(wordSeparators.ReplaceAllString(strings.ToLower(s), " ")) + " "
+}
+
+// containsWords reports whether the normalized text contains the normalized keyword phrase
+func containsWords(text, keyword string) bool {
+	return strings.Contains(text, keyword)
+}
+
+// compileGlob converts a case-insensitive glob pattern, where * matches any run of
+// characters, to an anchored regular expression
+func compileGlob(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("(?i)^" + strings.Join(parts, ".*") + "$")
+}
+
+func matchesAny(re *regexp.Regexp, values []string) bool {
+	for _, v := range values {
+		if re.MatchString(v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package classifier
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pluggedin/registry-admin/internal/models"
+)
+
+const testRules = `
+categories:
+  - slug: devtools
+    name: Developer Tools
+    match:
+      name: [github]
+      env: [GITHUB_*]
+  - slug: databases
+    name: Databases
+    match:
+      description: [database]
+      packages: ["*postgres*"]
+      topics: [postgresql]
+tags:
+  - slug: github
+    match:
+      env: [GITHUB_*]
+  - slug: postgres
+    match:
+      packages: ["*postgres*"]
+  - slug: pull-requests
+    match:
+      description: [pull request]
+`
+
+func server(name, description string, packages []models.Package, topics ...string) *models.ServerDetail {
+	return &models.ServerDetail{
+		Server: models.Server{
+			Name:        name,
+			Description: description,
+			Repository:  models.Repository{Topics: topics},
+		},
+		Packages: packages,
+	}
+}
+
+func TestClassify(t *testing.T) {
+	c, err := Parse([]byte(testRules))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		server *models.ServerDetail
+		want   models.ServerTaxonomy
+	}{
+		{
+			name: "env var selects category and tag",
+			server: server("io.example/assistant", "Works with your code", []models.Package{
+				{Name: "assistant-mcp", EnvironmentVariables: []models.EnvironmentVariable{{Name: "GITHUB_TOKEN"}}},
+			}),
+			want: models.ServerTaxonomy{Category: "devtools", Tags: []string{"github"}},
+		},
+		{
+			name: "strongest signals win the category",
+			server: server("io.github.example/pg", "Review each pull request against the database", []models.Package{
+				{Name: "@example/postgres-mcp"},
+			}, "PostgreSQL"),
+			want: models.ServerTaxonomy{Category: "databases", Tags: []string{"postgres", "pull-requests"}},
+		},
+		{
+			name:   "keywords match whole words only",
+			server: server("io.example/githubby", "Databases galore", nil),
+			want:   models.ServerTaxonomy{Tags: []string{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Classify(tt.server); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Classify() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRejectsInvalidRules(t *testing.T) {
+	tests := map[string]string{
+		"invalid slug":   "categories:\n  - slug: Dev Tools\n    match: {name: [git]}\n",
+		"duplicate slug": "tags:\n  - slug: git\n    match: {name: [git]}\n  - slug: git\n    match: {name: [git]}\n",
+		"no signals":     "tags:\n  - slug: git\n",
+	}
+
+	for name, rules := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse([]byte(rules)); err == nil {
+				t.Error("Parse() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestDefaultRulesLoad(t *testing.T) {
+	c, err := LoadFile("../../" + DefaultRulesPath)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if len(c.Categories()) == 0 || len(c.Tags()) == 0 {
+		t.Errorf("default rules define %d categories and %d tags, want both non-empty", len(c.Categories()), len(c.Tags()))
+	}
+}
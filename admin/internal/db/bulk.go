@@ -0,0 +1,204 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pluggedin/registry-admin/internal/models"
+)
+
+// BulkUpdateServers applies a bulk action to every latest server selected by name or
+// filter in a single transaction. It returns the number of matched servers, the names
+// of the servers the action actually changed, and any requested names that do not exist.
+// In dry-run mode the transaction is rolled back, so the result reports what would change.
+// Category and tag actions write the managed assignments, recorded as made by audit.User.
+// Every changed server gets a copy of the audit entry, written in the same transaction.
+func (o *Operations) BulkUpdateServers(ctx context.Context, req *models.BulkRequest, audit models.AuditLog) (int, []string, []string, error) {
+	where, args, err := buildBulkSelection(req)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	tx, err := o.BeginTx(ctx)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }() // No-op once committed
+
+	// Lock the selected rows so the match count and the update agree
+	rows, err := tx.Query(ctx, "SELECT server_name FROM servers "+where+" FOR UPDATE", args...)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("failed to select servers: %w", err)
+	}
+	matched := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return 0, nil, nil, fmt.Errorf("failed to scan server: %w", err)
+		}
+		matched[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	notFound := []string{}
+	for _, name := range req.Names {
+		if !matched[name] {
+			notFound = append(notFound, name)
+		}
+	}
+
+	query, args, err := bulkActionQuery(req, where, args, audit.User)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	rows, err = tx.Query(ctx, query, args...)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("failed to update servers: %w", err)
+	}
+	// Tag actions return one row per server and tag
+	changed := []string{}
+	seen := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return 0, nil, nil, fmt.Errorf("failed to scan server: %w", err)
+		}
+		if !seen[name] {
+			seen[name] = true
+			changed = append(changed, name)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, nil, fmt.Errorf("failed to update servers: %w", err)
+	}
+
+	if !req.DryRun {
+		for _, name := range changed {
+			entry := audit
+			entry.ServerID = name
+			if err := insertAuditEntry(ctx, tx, &entry); err != nil {
+				return 0, nil, nil, err
+			}
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return 0, nil, nil, fmt.Errorf("failed to commit bulk update: %w", err)
+		}
+	}
+
+	return len(matched), changed, notFound, nil
+}
+
+// buildBulkSelection builds the WHERE clause selecting the servers of a bulk request
+func buildBulkSelection(req *models.BulkRequest) (string, []interface{}, error) {
+	if len(req.Names) > 0 {
+		return "WHERE is_latest = true AND server_name = ANY($1)", []interface{}{req.Names}, nil
+	}
+
+	f := req.Filter
+	if f == nil || (f.Status == "" && f.RegistryName == "" && f.Search == "" && f.NamePrefix == "") {
+		// Refuse to touch the whole catalog by accident
+		return "", nil, fmt.Errorf("either names or a non-empty filter is required")
+	}
+
+	where, args := buildServerListWhere(f.Status, f.RegistryName, f.Search)
+	if f.NamePrefix != "" {
+		where += fmt.Sprintf(" AND server_name LIKE $%d", len(args)+1)
+		args = append(args, escapeLikePattern(f.NamePrefix)+"%")
+	}
+
+	return where, args, nil
+}
+
+// bulkActionQuery builds the statement applying the bulk action to the servers selected by
+// where. Servers the action would not change are skipped, and the statement returns the name
+// of every changed server.
+func bulkActionQuery(req *models.BulkRequest, where string, args []interface{}, user string) (string, []interface{}, error) {
+	next := len(args) + 1
+	selected := "SELECT server_name FROM servers " + where
+
+	switch req.Action {
+	case models.BulkActionSetStatus, models.BulkActionSoftDelete:
+		status := req.Status
+		if req.Action == models.BulkActionSoftDelete {
+			status = models.ServerStatusDeleted
+		}
+		query := fmt.Sprintf(`
+			UPDATE servers
+			SET status = $%d, updated_at = $%d
+			%s AND status IS DISTINCT FROM $%d
+			RETURNING server_name
+		`, next, next+1, where, next)
+		return query, append(args, string(status), time.Now()), nil
+
+	case models.BulkActionPin, models.BulkActionUnpin:
+		set, guard := "value = jsonb_set(value, '{pinned}', 'true'::jsonb)", "value->'pinned' IS DISTINCT FROM 'true'::jsonb"
+		if req.Action == models.BulkActionUnpin {
+			set, guard = "value = value - 'pinned'", "value ? 'pinned'"
+		}
+		query := fmt.Sprintf(`
+			UPDATE servers
+			SET %s, updated_at = $%d
+			%s AND %s
+			RETURNING server_name
+		`, set, next, where, guard)
+		return query, append(args, time.Now()), nil
+
+	case models.BulkActionSetCategory:
+		if req.Category == "" {
+			query := fmt.Sprintf(`
+				DELETE FROM proxy_server_categories
+				WHERE server_id IN (%s)
+				RETURNING server_id
+			`, selected)
+			return query, args, nil
+		}
+		query := fmt.Sprintf(`
+			INSERT INTO proxy_server_categories (server_id, category_slug, assigned_by, assigned_at)
+			SELECT server_name, $%d::text, $%d::text, NOW() FROM servers %s
+			ON CONFLICT (server_id) DO UPDATE
+			SET category_slug = EXCLUDED.category_slug, source = 'manual', assigned_by = EXCLUDED.assigned_by, assigned_at = NOW()
+			WHERE proxy_server_categories.category_slug IS DISTINCT FROM EXCLUDED.category_slug
+				OR proxy_server_categories.source <> 'manual'
+			RETURNING server_id
+		`, next, next+1, where)
+		return query, append(args, req.Category, user), nil
+
+	case models.BulkActionAddTags:
+		query := fmt.Sprintf(`
+			INSERT INTO proxy_server_tags (server_id, tag_slug, assigned_by, assigned_at)
+			SELECT server_name, tag, $%d::text, NOW()
+			FROM servers CROSS JOIN unnest($%d::text[]) AS tag
+			%s
+			ON CONFLICT (server_id, tag_slug) DO UPDATE
+			SET source = 'manual', assigned_by = EXCLUDED.assigned_by, assigned_at = NOW()
+			WHERE proxy_server_tags.source <> 'manual'
+			RETURNING server_id
+		`, next+1, next, where)
+		return query, append(args, req.Tags, user), nil
+
+	case models.BulkActionRemoveTags:
+		query := fmt.Sprintf(`
+			DELETE FROM proxy_server_tags
+			WHERE tag_slug = ANY($%d::text[]) AND server_id IN (%s)
+			RETURNING server_id
+		`, next, selected)
+		return query, append(args, req.Tags), nil
+
+	default:
+		return "", nil, fmt.Errorf("unsupported bulk action '%s'", req.Action)
+	}
+}
+
+// escapeLikePattern escapes the LIKE wildcards in s
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
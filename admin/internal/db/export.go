@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pluggedin/registry-admin/internal/models"
+)
+
+// ExportServers streams every latest server matching the list filters to fn, ordered by name.
+// When includeStats is set, rating and installation stats from proxy_server_stats are attached.
+func (o *Operations) ExportServers(ctx context.Context, status, registryName, search string, includeStats bool, fn func(*models.ExportedServer) error) error {
+	pool := o.db.GetPool()
+
+	whereClause, args := buildServerListWhere(status, registryName, search)
+
+	statsColumns := "0::float8, 0, 0"
+	statsJoin := ""
+	if includeStats {
+		statsColumns = "COALESCE(ss.rating, 0)::float8, COALESCE(ss.rating_count, 0), COALESCE(ss.installation_count, 0)"
+		statsJoin = "LEFT JOIN proxy_server_stats ss ON server_name = ss.server_id"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT server_name, value, status, %s
+		FROM servers
+		%s
+		%s
+		ORDER BY server_name
+	`, statsColumns, statsJoin, whereClause)
+
+	rows, err := pool.Query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query servers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var serverName, status string
+		var valueJSON []byte
+		var stats models.ServerStats
+
+		if err := rows.Scan(&serverName, &valueJSON, &status, &stats.Rating, &stats.RatingCount, &stats.InstallationCount); err != nil {
+			return fmt.Errorf("failed to scan server: %w", err)
+		}
+
+		var exported models.ExportedServer
+		if err := json.Unmarshal(valueJSON, &exported.ServerDetail); err != nil {
+			return fmt.Errorf("failed to unmarshal server JSON: %w", err)
+		}
+
+		exported.ID = serverName
+		exported.Status = models.ServerStatus(status)
+		if includeStats {
+			exported.Stats = &stats
+		}
+
+		if err := fn(&exported); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return nil
+}
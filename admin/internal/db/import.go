@@ -0,0 +1,137 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pluggedin/registry-admin/internal/models"
+)
+
+// Querier is implemented by both the connection pool and transactions,
+// so import operations can run inside or outside a transaction
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// BeginTx starts a new transaction on the connection pool
+func (o *Operations) BeginTx(ctx context.Context) (pgx.Tx, error) {
+	tx, err := o.db.GetPool().Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	return tx, nil
+}
+
+// ImportServer creates a server or, depending on the conflict policy, updates or
+// skips an existing server with the same name
+func (o *Operations) ImportServer(ctx context.Context, q Querier, server *models.ServerDetail, policy models.ConflictPolicy) (models.ImportAction, error) {
+	if server.Name == "" {
+		return "", fmt.Errorf("server name is required")
+	}
+
+	// Lock the existing row (if any) so concurrent imports cannot race the upsert
+	var existingStatus string
+	err := q.QueryRow(ctx, `
+		SELECT status
+		FROM servers
+		WHERE server_name = $1 AND is_latest = true
+		FOR UPDATE
+	`, server.Name).Scan(&existingStatus)
+	if err != nil && err != pgx.ErrNoRows {
+		return "", fmt.Errorf("failed to look up server: %w", err)
+	}
+
+	if err == pgx.ErrNoRows {
+		if err := insertServer(ctx, q, server); err != nil {
+			return "", err
+		}
+		return models.ImportActionCreated, nil
+	}
+
+	switch policy {
+	case models.ConflictSkip:
+		return models.ImportActionSkipped, nil
+	case models.ConflictUpdate:
+		// Keep the current status unless the import sets one explicitly
+		if server.Status == "" {
+			server.Status = models.ServerStatus(existingStatus)
+		}
+		if err := updateServer(ctx, q, server.Name, server); err != nil {
+			return "", err
+		}
+		return models.ImportActionUpdated, nil
+	default:
+		return "", fmt.Errorf("server with name %s already exists", server.Name)
+	}
+}
+
+// insertServer inserts a new latest server row
+func insertServer(ctx context.Context, q Querier, server *models.ServerDetail) error {
+	// Set default status if not provided
+	if server.Status == "" {
+		server.Status = models.ServerStatusActive
+	}
+
+	valueJSON, err := json.Marshal(server)
+	if err != nil {
+		return fmt.Errorf("failed to marshal server: %w", err)
+	}
+
+	// Use the server name as ID if not provided
+	if server.ID == "" {
+		server.ID = server.Name
+	}
+
+	query := `
+		INSERT INTO servers (server_name, version, value, status, published_at, updated_at, is_latest)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	now := time.Now()
+	version := DefaultServerVersion
+	if server.VersionDetail.Version != "" {
+		version = server.VersionDetail.Version
+	}
+
+	if _, err := q.Exec(ctx, query, server.Name, version, valueJSON, server.Status, now, now, true); err != nil {
+		return fmt.Errorf("failed to insert server: %w", err)
+	}
+
+	return nil
+}
+
+// updateServer replaces the value, status and version of the latest row for a server,
+// keeping the stored version when the server does not name one
+func updateServer(ctx context.Context, q Querier, id string, server *models.ServerDetail) error {
+	// Ensure ID matches
+	server.ID = id
+	server.Name = id
+
+	valueJSON, err := json.Marshal(server)
+	if err != nil {
+		return fmt.Errorf("failed to marshal server: %w", err)
+	}
+
+	query := `
+		UPDATE servers
+		SET value = $1, status = $2, updated_at = $3, version = COALESCE(NULLIF($5, ''), version)
+		WHERE server_name = $4 AND is_latest = true
+	`
+
+	result, err := q.Exec(ctx, query, valueJSON, server.Status, time.Now(), id, server.VersionDetail.Version)
+	if err != nil {
+		return fmt.Errorf("failed to update server: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("server not found")
+	}
+
+	return nil
+}
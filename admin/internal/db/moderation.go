@@ -0,0 +1,289 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/pluggedin/registry-admin/internal/models"
+)
+
+// Moderation queue filters
+const (
+	ModerationFilterQueue    = ""         // Pending or reported reviews
+	ModerationFilterPending  = "pending"  // Reviews held for moderation
+	ModerationFilterReported = "reported" // Reviews with unresolved reports
+	ModerationFilterHidden   = "hidden"   // Reviews hidden by a moderator
+)
+
+// openReportsExists matches reviews with unresolved reports; r is the proxy_user_ratings alias
+const openReportsExists = `EXISTS (
+	SELECT 1 FROM proxy_review_reports rr
+	WHERE rr.server_id = r.server_id AND rr.user_id = r.user_id AND rr.resolved_at IS NULL
+)`
+
+// ListModerationQueue returns reviews needing moderation, most reported first,
+// together with their unresolved reports
+func (o *Operations) ListModerationQueue(ctx context.Context, page, limit int, filter string) ([]models.Review, int64, error) {
+	pool := o.db.GetPool()
+
+	var where string
+	switch filter {
+	case ModerationFilterQueue:
+		where = "r.status = 'pending' OR " + openReportsExists
+	case ModerationFilterPending:
+		where = "r.status = 'pending'"
+	case ModerationFilterReported:
+		where = openReportsExists
+	case ModerationFilterHidden:
+		where = "r.status = 'hidden'"
+	default:
+		return nil, 0, fmt.Errorf("invalid moderation filter '%s'", filter)
+	}
+
+	var total int64
+	if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM proxy_user_ratings r WHERE "+where).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count reviews: %w", err)
+	}
+
+	rows, err := pool.Query(ctx, `
+		SELECT r.server_id, r.user_id, r.rating, COALESCE(r.comment, ''), r.status,
+			COALESCE(r.moderation_reason, ''), COALESCE(r.moderated_by, ''), r.moderated_at, r.created_at, r.updated_at,
+			(SELECT COUNT(*) FROM proxy_review_reports rr
+				WHERE rr.server_id = r.server_id AND rr.user_id = r.user_id AND rr.resolved_at IS NULL) AS open_reports
+		FROM proxy_user_ratings r
+		WHERE `+where+`
+		ORDER BY open_reports DESC, r.updated_at DESC
+		LIMIT $1 OFFSET $2
+	`, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query reviews: %w", err)
+	}
+	defer rows.Close()
+
+	reviews := []models.Review{}
+	index := make(map[[2]string]int)
+	var serverIDs, userIDs []string
+	for rows.Next() {
+		var r models.Review
+		var status string
+		if err := rows.Scan(&r.ServerID, &r.UserID, &r.Rating, &r.Comment, &status,
+			&r.FlagReason, &r.ModeratedBy, &r.ModeratedAt, &r.CreatedAt, &r.UpdatedAt, &r.OpenReports); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan review: %w", err)
+		}
+		r.Status = models.ReviewStatus(status)
+		r.Reports = []models.ReviewReport{}
+		index[[2]string{r.ServerID, r.UserID}] = len(reviews)
+		serverIDs = append(serverIDs, r.ServerID)
+		userIDs = append(userIDs, r.UserID)
+		reviews = append(reviews, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating reviews: %w", err)
+	}
+
+	if len(reviews) == 0 {
+		return reviews, total, nil
+	}
+
+	// Attach the unresolved reports of the reviews on this page
+	reportRows, err := pool.Query(ctx, `
+		SELECT rr.server_id, rr.user_id, rr.reporter_id, COALESCE(rr.reason, ''), rr.created_at
+		FROM proxy_review_reports rr
+		JOIN unnest($1::text[], $2::text[]) AS k(server_id, user_id)
+			ON rr.server_id = k.server_id AND rr.user_id = k.user_id
+		WHERE rr.resolved_at IS NULL
+		ORDER BY rr.created_at
+	`, serverIDs, userIDs)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query reports: %w", err)
+	}
+	defer reportRows.Close()
+
+	for reportRows.Next() {
+		var serverID, userID string
+		var report models.ReviewReport
+		if err := reportRows.Scan(&serverID, &userID, &report.ReporterID, &report.Reason, &report.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan report: %w", err)
+		}
+		if i, ok := index[[2]string{serverID, userID}]; ok {
+			reviews[i].Reports = append(reviews[i].Reports, report)
+		}
+	}
+	if err := reportRows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating reports: %w", err)
+	}
+
+	return reviews, total, nil
+}
+
+// SetReviewStatus hides or restores a review, resolves its open reports and recomputes
+// the server's rating stats in the same transaction
+func (o *Operations) SetReviewStatus(ctx context.Context, serverID, userID string, status models.ReviewStatus, user string) error {
+	tx, err := o.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }() // No-op once committed
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE proxy_user_ratings
+		SET status = $3, moderated_by = $4, moderated_at = NOW()
+		WHERE server_id = $1 AND user_id = $2
+	`, serverID, userID, string(status), user)
+	if err != nil {
+		return fmt.Errorf("failed to update review: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("review not found")
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE proxy_review_reports SET resolved_at = NOW()
+		WHERE server_id = $1 AND user_id = $2 AND resolved_at IS NULL
+	`, serverID, userID); err != nil {
+		return fmt.Errorf("failed to resolve reports: %w", err)
+	}
+
+	if err := recalculateRatingStats(ctx, tx, serverID, o.ratingPrior); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit review status: %w", err)
+	}
+
+	return nil
+}
+
+// BanUser bans a user from reviewing, hides all of their reviews and recomputes the
+// stats of every affected server. Returns the number of reviews hidden.
+func (o *Operations) BanUser(ctx context.Context, userID, reason, user string) (int, error) {
+	tx, err := o.BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }() // No-op once committed
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO proxy_banned_users (user_id, reason, banned_by, banned_at)
+		VALUES ($1, NULLIF($2, ''), $3, NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET reason = EXCLUDED.reason, banned_by = EXCLUDED.banned_by, banned_at = NOW()
+	`, userID, reason, user); err != nil {
+		return 0, fmt.Errorf("failed to ban user: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `
+		UPDATE proxy_user_ratings
+		SET status = 'hidden', moderated_by = $2, moderated_at = NOW()
+		WHERE user_id = $1 AND status <> 'hidden'
+		RETURNING server_id
+	`, userID, user)
+	if err != nil {
+		return 0, fmt.Errorf("failed to hide reviews: %w", err)
+	}
+	var serverIDs []string
+	for rows.Next() {
+		var serverID string
+		if err := rows.Scan(&serverID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan hidden review: %w", err)
+		}
+		serverIDs = append(serverIDs, serverID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to hide reviews: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE proxy_review_reports SET resolved_at = NOW()
+		WHERE user_id = $1 AND resolved_at IS NULL
+	`, userID); err != nil {
+		return 0, fmt.Errorf("failed to resolve reports: %w", err)
+	}
+
+	for _, serverID := range serverIDs {
+		if err := recalculateRatingStats(ctx, tx, serverID, o.ratingPrior); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit ban: %w", err)
+	}
+
+	return len(serverIDs), nil
+}
+
+// UnbanUser lifts a ban; the user's hidden reviews stay hidden until restored individually
+func (o *Operations) UnbanUser(ctx context.Context, userID string) error {
+	tag, err := o.db.GetPool().Exec(ctx, "DELETE FROM proxy_banned_users WHERE user_id = $1", userID)
+	if err != nil {
+		return fmt.Errorf("failed to unban user: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("user not banned")
+	}
+	return nil
+}
+
+// ListBannedUsers returns every banned user, most recent first
+func (o *Operations) ListBannedUsers(ctx context.Context) ([]models.BannedUser, error) {
+	rows, err := o.db.GetPool().Query(ctx, `
+		SELECT user_id, COALESCE(reason, ''), COALESCE(banned_by, ''), banned_at
+		FROM proxy_banned_users
+		ORDER BY banned_at DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query banned users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.BannedUser{}
+	for rows.Next() {
+		var u models.BannedUser
+		if err := rows.Scan(&u.UserID, &u.Reason, &u.BannedBy, &u.BannedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan banned user: %w", err)
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating banned users: %w", err)
+	}
+
+	return users, nil
+}
+
+// recalculateRatingStats recomputes a server's rating stats from its visible reviews with
+// proxy_recalculate_rating_stats, the calculation the proxy uses
+func recalculateRatingStats(ctx context.Context, q Querier, serverID string, prior RatingPrior) error {
+	_, err := q.Exec(ctx, `SELECT proxy_recalculate_rating_stats($1, $2, $3)`, serverID, prior.Mean, prior.Weight)
+	if err != nil {
+		return fmt.Errorf("failed to update server stats: %w", err)
+	}
+	return nil
+}
+
+// RatingPrior is the global prior blended into weighted ratings, read from the same
+// environment as the proxy's; nil fields take the defaults of proxy_recalculate_rating_stats
+type RatingPrior struct {
+	Mean   *float64
+	Weight *float64
+}
+
+// RatingPriorFromEnv reads RATING_PRIOR_MEAN and RATING_PRIOR_WEIGHT, leaving the
+// default for unset or malformed values
+func RatingPriorFromEnv() RatingPrior {
+	var prior RatingPrior
+	if v, err := strconv.ParseFloat(os.Getenv("RATING_PRIOR_MEAN"), 64); err == nil {
+		prior.Mean = &v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("RATING_PRIOR_WEIGHT"), 64); err == nil {
+		prior.Weight = &v
+	}
+	return prior
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -15,12 +18,13 @@ const DefaultServerVersion = "1.0.0"
 
 // Operations provides database operations
 type Operations struct {
-	db *PostgresDB
+	db          *PostgresDB
+	ratingPrior RatingPrior
 }
 
 // NewOperations creates a new operations instance
 func NewOperations(db *PostgresDB) *Operations {
-	return &Operations{db: db}
+	return &Operations{db: db, ratingPrior: RatingPriorFromEnv()}
 }
 
 // ListServers retrieves servers with pagination
@@ -29,41 +33,8 @@ func NewOperations(db *PostgresDB) *Operations {
 func (o *Operations) ListServers(ctx context.Context, page, limit int, status, registryName, search string) ([]models.ServerDetail, int64, error) {
 	pool := o.db.GetPool()
 
-	// Build WHERE clause
-	whereConditions := []string{"is_latest = true"}
-	args := []interface{}{}
-	argPos := 1
-
-	if status != "" {
-		whereConditions = append(whereConditions, fmt.Sprintf("status = $%d", argPos))
-		args = append(args, status)
-		argPos++
-	}
-
-	if registryName != "" {
-		// TODO(performance): Add a specific GIN index for packages.registry_name queries:
-		// CREATE INDEX IF NOT EXISTS idx_servers_packages_registry_name
-		// ON servers USING GIN ((value->'packages'));
-		// This will improve performance for registry_name filtering on large datasets
-		whereConditions = append(whereConditions, fmt.Sprintf("value->'packages' @> $%d::jsonb", argPos))
-		args = append(args, fmt.Sprintf(`[{"registry_name":"%s"}]`, registryName))
-		argPos++
-	}
-
-	if search != "" {
-		whereConditions = append(whereConditions, fmt.Sprintf("(server_name ILIKE $%d OR value->>'description' ILIKE $%d)", argPos, argPos))
-		searchPattern := "%" + search + "%"
-		args = append(args, searchPattern)
-		argPos++
-	}
-
-	whereClause := ""
-	if len(whereConditions) > 0 {
-		whereClause = "WHERE " + whereConditions[0]
-		for i := 1; i < len(whereConditions); i++ {
-			whereClause += " AND " + whereConditions[i]
-		}
-	}
+	whereClause, args := buildServerListWhere(status, registryName, search)
+	argPos := len(args) + 1
 
 	// Count total
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM servers %s", whereClause)
@@ -81,7 +52,7 @@ func (o *Operations) ListServers(ctx context.Context, page, limit int, status, r
 		SELECT server_name, version, value, status, published_at
 		FROM servers
 		%s
-		ORDER BY server_name
+		ORDER BY COALESCE(value->>'pinned' = 'true', false) DESC, server_name
 		LIMIT $%d OFFSET $%d
 	`, whereClause, argPos, argPos+1)
 
@@ -122,6 +93,37 @@ func (o *Operations) ListServers(ctx context.Context, page, limit int, status, r
 	return servers, total, nil
 }
 
+// buildServerListWhere builds the WHERE clause and arguments for server list filters
+func buildServerListWhere(status, registryName, search string) (string, []interface{}) {
+	whereConditions := []string{"is_latest = true"}
+	args := []interface{}{}
+	argPos := 1
+
+	if status != "" {
+		whereConditions = append(whereConditions, fmt.Sprintf("status = $%d", argPos))
+		args = append(args, status)
+		argPos++
+	}
+
+	if registryName != "" {
+		// TODO(performance): Add a specific GIN index for packages.registry_name queries:
+		// CREATE INDEX IF NOT EXISTS idx_servers_packages_registry_name
+		// ON servers USING GIN ((value->'packages'));
+		// This will improve performance for registry_name filtering on large datasets
+		whereConditions = append(whereConditions, fmt.Sprintf("value->'packages' @> $%d::jsonb", argPos))
+		args = append(args, fmt.Sprintf(`[{"registry_name":"%s"}]`, registryName))
+		argPos++
+	}
+
+	if search != "" {
+		whereConditions = append(whereConditions, fmt.Sprintf("(server_name ILIKE $%d OR value->>'description' ILIKE $%d)", argPos, argPos))
+		searchPattern := "%" + search + "%"
+		args = append(args, searchPattern)
+	}
+
+	return "WHERE " + strings.Join(whereConditions, " AND "), args
+}
+
 // GetServer retrieves a single server by ID (server_name)
 func (o *Operations) GetServer(ctx context.Context, id string) (*models.ServerDetail, error) {
 	pool := o.db.GetPool()
@@ -171,8 +173,6 @@ func (o *Operations) ServerExists(ctx context.Context, name string) (bool, error
 
 // CreateServer creates a new server
 func (o *Operations) CreateServer(ctx context.Context, server *models.ServerDetail) error {
-	pool := o.db.GetPool()
-
 	// Check if server already exists
 	exists, err := o.ServerExists(ctx, server.Name)
 	if err != nil {
@@ -182,73 +182,12 @@ func (o *Operations) CreateServer(ctx context.Context, server *models.ServerDeta
 		return fmt.Errorf("server with name %s already exists", server.Name)
 	}
 
-	// Set default status if not provided
-	if server.Status == "" {
-		server.Status = models.ServerStatusActive
-	}
-
-	// Marshal server to JSON
-	valueJSON, err := json.Marshal(server)
-	if err != nil {
-		return fmt.Errorf("failed to marshal server: %w", err)
-	}
-
-	// Use the server name as ID if not provided
-	if server.ID == "" {
-		server.ID = server.Name
-	}
-
-	// Insert server
-	query := `
-		INSERT INTO servers (server_name, version, value, status, published_at, updated_at, is_latest)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-	`
-
-	now := time.Now()
-	version := DefaultServerVersion
-	if server.VersionDetail.Version != "" {
-		version = server.VersionDetail.Version
-	}
-
-	_, err = pool.Exec(ctx, query, server.Name, version, valueJSON, server.Status, now, now, true)
-	if err != nil {
-		return fmt.Errorf("failed to insert server: %w", err)
-	}
-
-	return nil
+	return insertServer(ctx, o.db.GetPool(), server)
 }
 
 // UpdateServer updates an existing server
 func (o *Operations) UpdateServer(ctx context.Context, id string, server *models.ServerDetail) error {
-	pool := o.db.GetPool()
-
-	// Ensure ID matches
-	server.ID = id
-	server.Name = id
-
-	// Marshal server to JSON
-	valueJSON, err := json.Marshal(server)
-	if err != nil {
-		return fmt.Errorf("failed to marshal server: %w", err)
-	}
-
-	// Update server
-	query := `
-		UPDATE servers
-		SET value = $1, status = $2, updated_at = $3
-		WHERE server_name = $4 AND is_latest = true
-	`
-
-	result, err := pool.Exec(ctx, query, valueJSON, server.Status, time.Now(), id)
-	if err != nil {
-		return fmt.Errorf("failed to update server: %w", err)
-	}
-
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("server not found")
-	}
-
-	return nil
+	return updateServer(ctx, o.db.GetPool(), id, server)
 }
 
 // DeleteServer deletes a server
@@ -289,17 +228,54 @@ func (o *Operations) UpdateStatus(ctx context.Context, id string, status models.
 	return nil
 }
 
-// LogAuditEntry logs an audit entry
-// Note: This requires an audit_logs table which may not exist yet
+// LogAuditEntry records an admin operation in the audit log
 func (o *Operations) LogAuditEntry(ctx context.Context, entry *models.AuditLog) error {
-	// For now, just log to console since audit table may not exist
-	fmt.Printf("[AUDIT] %s - %s by %s\n", entry.Action, entry.ServerID, entry.User)
+	if err := insertAuditEntry(ctx, o.db.GetPool(), entry); err != nil {
+		// Audit failures must not fail the operation that already happened
+		log.Printf("Failed to write audit entry %s for %q: %v", entry.Action, entry.ServerID, err)
+		return err
+	}
 	return nil
 }
 
-// GetAuditLogs retrieves audit logs
-// Note: This requires an audit_logs table which may not exist yet
+// insertAuditEntry writes an audit entry with q, so it can be part of a transaction
+func insertAuditEntry(ctx context.Context, q Querier, entry *models.AuditLog) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO proxy_admin_audit_log (username, action, server_id, details, ip)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5)
+	`, entry.User, entry.Action, entry.ServerID, entry.Details, entry.IP)
+	if err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	return nil
+}
+
+// GetAuditLogs retrieves the most recent audit entries, newest first
 func (o *Operations) GetAuditLogs(ctx context.Context, limit int) ([]models.AuditLog, error) {
-	// Return empty for now since audit table may not exist
-	return []models.AuditLog{}, nil
+	rows, err := o.db.GetPool().Query(ctx, `
+		SELECT id, created_at, username, action, COALESCE(server_id, ''), COALESCE(details, ''), COALESCE(ip, '')
+		FROM proxy_admin_audit_log
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	logs := []models.AuditLog{}
+	for rows.Next() {
+		var entry models.AuditLog
+		var id int64
+		if err := rows.Scan(&id, &entry.Timestamp, &entry.User, &entry.Action, &entry.ServerID, &entry.Details, &entry.IP); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entry.ID = strconv.FormatInt(id, 10)
+		logs = append(logs, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit logs: %w", err)
+	}
+
+	return logs, nil
 }
@@ -0,0 +1,409 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pluggedin/registry-admin/internal/models"
+)
+
+// pgForeignKeyViolation is the PostgreSQL error code for a foreign key violation
+const pgForeignKeyViolation = "23503"
+
+// ListCategories returns the category taxonomy with the number of visible servers in each
+func (o *Operations) ListCategories(ctx context.Context) ([]models.Category, error) {
+	pool := o.db.GetPool()
+
+	rows, err := pool.Query(ctx, `
+		SELECT c.slug, c.name, COALESCE(c.description, ''), c.created_at, c.updated_at, COUNT(s.server_name)
+		FROM proxy_categories c
+		LEFT JOIN proxy_server_categories sc ON sc.category_slug = c.slug
+		LEFT JOIN servers s ON s.server_name = sc.server_id AND s.is_latest = true AND s.status IS DISTINCT FROM 'deleted'
+		GROUP BY c.slug
+		ORDER BY c.name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query categories: %w", err)
+	}
+	defer rows.Close()
+
+	categories := []models.Category{}
+	for rows.Next() {
+		var c models.Category
+		if err := rows.Scan(&c.Slug, &c.Name, &c.Description, &c.CreatedAt, &c.UpdatedAt, &c.ServerCount); err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		categories = append(categories, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return categories, nil
+}
+
+// CreateCategory adds a category to the taxonomy
+func (o *Operations) CreateCategory(ctx context.Context, category *models.Category) error {
+	pool := o.db.GetPool()
+
+	err := pool.QueryRow(ctx, `
+		INSERT INTO proxy_categories (slug, name, description, created_at, updated_at)
+		VALUES ($1, $2, NULLIF($3, ''), NOW(), NOW())
+		ON CONFLICT (slug) DO NOTHING
+		RETURNING created_at, updated_at
+	`, category.Slug, category.Name, category.Description).Scan(&category.CreatedAt, &category.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return fmt.Errorf("category already exists")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create category: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateCategory changes the name and description of a category
+func (o *Operations) UpdateCategory(ctx context.Context, category *models.Category) error {
+	pool := o.db.GetPool()
+
+	err := pool.QueryRow(ctx, `
+		UPDATE proxy_categories
+		SET name = $2, description = NULLIF($3, ''), updated_at = NOW()
+		WHERE slug = $1
+		RETURNING created_at, updated_at
+	`, category.Slug, category.Name, category.Description).Scan(&category.CreatedAt, &category.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return fmt.Errorf("category not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update category: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCategory removes a category and unassigns it from every server
+func (o *Operations) DeleteCategory(ctx context.Context, slug string) error {
+	pool := o.db.GetPool()
+
+	result, err := pool.Exec(ctx, "DELETE FROM proxy_categories WHERE slug = $1", slug)
+	if err != nil {
+		return fmt.Errorf("failed to delete category: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("category not found")
+	}
+
+	return nil
+}
+
+// ListTags returns the tag vocabulary with the number of visible servers carrying each tag
+func (o *Operations) ListTags(ctx context.Context) ([]models.Tag, error) {
+	pool := o.db.GetPool()
+
+	rows, err := pool.Query(ctx, `
+		SELECT t.slug, t.name, t.created_at, COUNT(s.server_name)
+		FROM proxy_tags t
+		LEFT JOIN proxy_server_tags st ON st.tag_slug = t.slug
+		LEFT JOIN servers s ON s.server_name = st.server_id AND s.is_latest = true AND s.status IS DISTINCT FROM 'deleted'
+		GROUP BY t.slug
+		ORDER BY t.name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var t models.Tag
+		if err := rows.Scan(&t.Slug, &t.Name, &t.CreatedAt, &t.ServerCount); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return tags, nil
+}
+
+// CreateTag adds a tag to the vocabulary
+func (o *Operations) CreateTag(ctx context.Context, tag *models.Tag) error {
+	pool := o.db.GetPool()
+
+	err := pool.QueryRow(ctx, `
+		INSERT INTO proxy_tags (slug, name, created_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (slug) DO NOTHING
+		RETURNING created_at
+	`, tag.Slug, tag.Name).Scan(&tag.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return fmt.Errorf("tag already exists")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create tag: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteTag removes a tag and unassigns it from every server
+func (o *Operations) DeleteTag(ctx context.Context, slug string) error {
+	pool := o.db.GetPool()
+
+	result, err := pool.Exec(ctx, "DELETE FROM proxy_tags WHERE slug = $1", slug)
+	if err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("tag not found")
+	}
+
+	return nil
+}
+
+// CategoryExists checks whether a category is part of the taxonomy
+func (o *Operations) CategoryExists(ctx context.Context, slug string) (bool, error) {
+	pool := o.db.GetPool()
+
+	var exists bool
+	err := pool.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM proxy_categories WHERE slug = $1)", slug).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check category: %w", err)
+	}
+
+	return exists, nil
+}
+
+// MissingTags returns the tags that are not part of the vocabulary
+func (o *Operations) MissingTags(ctx context.Context, tags []string) ([]string, error) {
+	return missingTags(ctx, o.db.GetPool(), tags)
+}
+
+// missingTags returns the tags that are not part of the vocabulary, in input order
+func missingTags(ctx context.Context, q Querier, tags []string) ([]string, error) {
+	rows, err := q.Query(ctx, `
+		SELECT t
+		FROM unnest($1::text[]) WITH ORDINALITY AS input(t, ord)
+		WHERE NOT EXISTS (SELECT 1 FROM proxy_tags WHERE slug = input.t)
+		ORDER BY ord
+	`, tags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check tags: %w", err)
+	}
+	defer rows.Close()
+
+	missing := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		missing = append(missing, tag)
+	}
+
+	return missing, rows.Err()
+}
+
+// GetServerTaxonomy returns the category and tags assigned to a server
+func (o *Operations) GetServerTaxonomy(ctx context.Context, serverName string) (*models.ServerTaxonomy, error) {
+	pool := o.db.GetPool()
+
+	taxonomy := &models.ServerTaxonomy{Tags: []string{}}
+	err := pool.QueryRow(ctx, `
+		SELECT
+			COALESCE((SELECT category_slug FROM proxy_server_categories WHERE server_id = $1), ''),
+			COALESCE((SELECT array_agg(tag_slug ORDER BY tag_slug) FROM proxy_server_tags WHERE server_id = $1), '{}')
+	`, serverName).Scan(&taxonomy.Category, &taxonomy.Tags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server taxonomy: %w", err)
+	}
+
+	return taxonomy, nil
+}
+
+// SetServerCategory assigns a category to a server; an empty slug clears the assignment
+func (o *Operations) SetServerCategory(ctx context.Context, serverName, slug, user string) error {
+	pool := o.db.GetPool()
+
+	if slug == "" {
+		if _, err := pool.Exec(ctx, "DELETE FROM proxy_server_categories WHERE server_id = $1", serverName); err != nil {
+			return fmt.Errorf("failed to clear category: %w", err)
+		}
+		return nil
+	}
+
+	_, err := pool.Exec(ctx, `
+		INSERT INTO proxy_server_categories (server_id, category_slug, assigned_by, assigned_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (server_id) DO UPDATE
+		SET category_slug = EXCLUDED.category_slug, source = 'manual', assigned_by = EXCLUDED.assigned_by, assigned_at = NOW()
+	`, serverName, slug, user)
+	if isForeignKeyViolation(err) {
+		return fmt.Errorf("category not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set category: %w", err)
+	}
+
+	return nil
+}
+
+// SetServerTags replaces the tags assigned to a server
+func (o *Operations) SetServerTags(ctx context.Context, serverName string, tags []string, user string) error {
+	tx, err := o.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }() // No-op once committed
+
+	missing, err := missingTags(ctx, tx, tags)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("unknown tags: %s", strings.Join(missing, ", "))
+	}
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM proxy_server_tags
+		WHERE server_id = $1 AND NOT (tag_slug = ANY($2::text[]))
+	`, serverName, tags); err != nil {
+		return fmt.Errorf("failed to remove tags: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO proxy_server_tags (server_id, tag_slug, assigned_by, assigned_at)
+		SELECT $1, t, $3, NOW() FROM unnest($2::text[]) AS t
+		ON CONFLICT (server_id, tag_slug) DO UPDATE
+		SET source = 'manual', assigned_by = EXCLUDED.assigned_by, assigned_at = NOW()
+		WHERE proxy_server_tags.source <> 'manual'
+	`, serverName, tags, user); err != nil {
+		return fmt.Errorf("failed to add tags: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit tags: %w", err)
+	}
+
+	return nil
+}
+
+// isForeignKeyViolation reports whether err is a PostgreSQL foreign key violation
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgForeignKeyViolation
+}
+
+// EnsureTaxonomy creates any of the given categories and tags that do not exist yet.
+// Existing entries are left untouched so admin edits to names and descriptions survive.
+func (o *Operations) EnsureTaxonomy(ctx context.Context, categories []models.Category, tags []models.Tag) error {
+	pool := o.db.GetPool()
+
+	for _, c := range categories {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO proxy_categories (slug, name, description, created_at, updated_at)
+			VALUES ($1, $2, NULLIF($3, ''), NOW(), NOW())
+			ON CONFLICT (slug) DO NOTHING
+		`, c.Slug, c.Name, c.Description); err != nil {
+			return fmt.Errorf("failed to create category %s: %w", c.Slug, err)
+		}
+	}
+
+	for _, t := range tags {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO proxy_tags (slug, name, created_at)
+			VALUES ($1, $2, NOW())
+			ON CONFLICT (slug) DO NOTHING
+		`, t.Slug, t.Name); err != nil {
+			return fmt.Errorf("failed to create tag %s: %w", t.Slug, err)
+		}
+	}
+
+	return nil
+}
+
+// ApplyInferredTaxonomy stores an inferred category and tags for a server and reports
+// whether anything changed. Manual assignments always win: an inferred category never
+// replaces a manual one, and inferred tags are skipped for servers with manual tags.
+func (o *Operations) ApplyInferredTaxonomy(ctx context.Context, serverName string, inferred models.ServerTaxonomy) (bool, error) {
+	tx, err := o.BeginTx(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }() // No-op once committed
+
+	var changed int64
+
+	if inferred.Category == "" {
+		res, err := tx.Exec(ctx, `
+			DELETE FROM proxy_server_categories WHERE server_id = $1 AND source = 'inferred'
+		`, serverName)
+		if err != nil {
+			return false, fmt.Errorf("failed to clear inferred category: %w", err)
+		}
+		changed += res.RowsAffected()
+	} else {
+		res, err := tx.Exec(ctx, `
+			INSERT INTO proxy_server_categories (server_id, category_slug, source, assigned_by, assigned_at)
+			VALUES ($1, $2, 'inferred', 'classifier', NOW())
+			ON CONFLICT (server_id) DO UPDATE
+			SET category_slug = EXCLUDED.category_slug, assigned_at = NOW()
+			WHERE proxy_server_categories.source = 'inferred'
+				AND proxy_server_categories.category_slug IS DISTINCT FROM EXCLUDED.category_slug
+		`, serverName, inferred.Category)
+		if err != nil {
+			return false, fmt.Errorf("failed to set inferred category: %w", err)
+		}
+		changed += res.RowsAffected()
+	}
+
+	var hasManualTags bool
+	if err := tx.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM proxy_server_tags WHERE server_id = $1 AND source = 'manual')
+	`, serverName).Scan(&hasManualTags); err != nil {
+		return false, fmt.Errorf("failed to check manual tags: %w", err)
+	}
+
+	if !hasManualTags {
+		tags := inferred.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+
+		res, err := tx.Exec(ctx, `
+			DELETE FROM proxy_server_tags
+			WHERE server_id = $1 AND source = 'inferred' AND NOT (tag_slug = ANY($2::text[]))
+		`, serverName, tags)
+		if err != nil {
+			return false, fmt.Errorf("failed to remove inferred tags: %w", err)
+		}
+		changed += res.RowsAffected()
+
+		res, err = tx.Exec(ctx, `
+			INSERT INTO proxy_server_tags (server_id, tag_slug, source, assigned_by, assigned_at)
+			SELECT $1, t, 'inferred', 'classifier', NOW() FROM unnest($2::text[]) AS t
+			ON CONFLICT (server_id, tag_slug) DO NOTHING
+		`, serverName, tags)
+		if err != nil {
+			return false, fmt.Errorf("failed to add inferred tags: %w", err)
+		}
+		changed += res.RowsAffected()
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit inferred taxonomy: %w", err)
+	}
+
+	return changed > 0, nil
+}
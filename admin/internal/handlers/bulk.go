@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/pluggedin/registry-admin/internal/middleware"
+	"github.com/pluggedin/registry-admin/internal/models"
+)
+
+// maxBulkNames caps the number of server names in a single bulk request
+const maxBulkNames = 10000
+
+// BulkUpdate handles POST /api/servers/bulk
+//
+// Applies one action to servers selected either by name or by filter. All changes run in
+// a single transaction; with dry_run=true the response reports what would change and
+// nothing is written. Every changed server gets its own audit entry, written in the same
+// transaction as the update.
+func (h *ServersHandler) BulkUpdate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.BulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validateBulkRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Categories and tags must come from the managed taxonomy
+	switch req.Action {
+	case models.BulkActionSetCategory:
+		if req.Category != "" {
+			exists, err := h.ops.CategoryExists(r.Context(), req.Category)
+			if err != nil {
+				http.Error(w, "Failed to check category", http.StatusInternalServerError)
+				return
+			}
+			if !exists {
+				http.Error(w, "Unknown category: "+req.Category, http.StatusBadRequest)
+				return
+			}
+		}
+	case models.BulkActionAddTags:
+		missing, err := h.ops.MissingTags(r.Context(), req.Tags)
+		if err != nil {
+			http.Error(w, "Failed to check tags", http.StatusInternalServerError)
+			return
+		}
+		if len(missing) > 0 {
+			http.Error(w, "Unknown tags: "+strings.Join(missing, ", "), http.StatusBadRequest)
+			return
+		}
+	}
+
+	matched, changed, notFound, err := h.ops.BulkUpdateServers(r.Context(), &req, models.AuditLog{
+		User:    middleware.GetUserFromContext(r.Context()),
+		Action:  "BULK_" + strings.ToUpper(string(req.Action)),
+		Details: bulkAuditDetails(&req),
+		IP:      r.RemoteAddr,
+	})
+	if err != nil {
+		log.Printf("Bulk %s failed: %v", req.Action, err)
+		http.Error(w, "Failed to apply bulk update", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.BulkResponse{
+		Action:   req.Action,
+		DryRun:   req.DryRun,
+		Matched:  matched,
+		Changed:  len(changed),
+		Servers:  changed,
+		NotFound: notFound,
+	})
+}
+
+// validateBulkRequest checks the selection and action parameters and normalizes tags
+func validateBulkRequest(req *models.BulkRequest) error {
+	if len(req.Names) > 0 && req.Filter != nil {
+		return fmt.Errorf("names and filter are mutually exclusive")
+	}
+	if len(req.Names) == 0 && req.Filter == nil {
+		return fmt.Errorf("either names or filter is required")
+	}
+	if len(req.Names) > maxBulkNames {
+		return fmt.Errorf("at most %d names are allowed per request", maxBulkNames)
+	}
+	if req.Filter != nil && *req.Filter == (models.BulkFilter{}) {
+		return fmt.Errorf("filter must set at least one condition")
+	}
+
+	switch req.Action {
+	case models.BulkActionSetStatus:
+		switch req.Status {
+		case models.ServerStatusActive, models.ServerStatusDeprecated, models.ServerStatusDeleted:
+		default:
+			return fmt.Errorf("invalid status value '%s'", req.Status)
+		}
+	case models.BulkActionAddTags, models.BulkActionRemoveTags:
+		req.Tags = normalizeTags(req.Tags)
+		if len(req.Tags) == 0 {
+			return fmt.Errorf("tags are required for %s", req.Action)
+		}
+	case models.BulkActionSetCategory:
+		req.Category = strings.TrimSpace(req.Category)
+	case models.BulkActionSoftDelete, models.BulkActionPin, models.BulkActionUnpin:
+	case "":
+		return fmt.Errorf("action is required")
+	default:
+		return fmt.Errorf("unsupported action '%s'", req.Action)
+	}
+
+	return nil
+}
+
+// normalizeTags trims tags and drops empty and duplicate entries, keeping the first occurrence
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// bulkAuditDetails describes a bulk action for the audit log
+func bulkAuditDetails(req *models.BulkRequest) string {
+	switch req.Action {
+	case models.BulkActionSetStatus:
+		return "Bulk update: set status to " + string(req.Status)
+	case models.BulkActionAddTags:
+		return "Bulk update: added tags " + strings.Join(req.Tags, ", ")
+	case models.BulkActionRemoveTags:
+		return "Bulk update: removed tags " + strings.Join(req.Tags, ", ")
+	case models.BulkActionSetCategory:
+		if req.Category == "" {
+			return "Bulk update: cleared category"
+		}
+		return "Bulk update: set category to " + req.Category
+	case models.BulkActionSoftDelete:
+		return "Bulk update: soft-deleted server"
+	case models.BulkActionPin:
+		return "Bulk update: pinned server"
+	case models.BulkActionUnpin:
+		return "Bulk update: unpinned server"
+	}
+	return "Bulk update: " + string(req.Action)
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pluggedin/registry-admin/internal/models"
+)
+
+func TestValidateBulkRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     models.BulkRequest
+		wantErr bool
+	}{
+		{
+			name: "set status by names",
+			req:  models.BulkRequest{Names: []string{"io.github.a/one"}, Action: models.BulkActionSetStatus, Status: models.ServerStatusDeprecated},
+		},
+		{
+			name: "soft delete by name prefix",
+			req:  models.BulkRequest{Filter: &models.BulkFilter{NamePrefix: "io.github.spam/"}, Action: models.BulkActionSoftDelete},
+		},
+		{
+			name:    "no selection",
+			req:     models.BulkRequest{Action: models.BulkActionPin},
+			wantErr: true,
+		},
+		{
+			name:    "empty filter",
+			req:     models.BulkRequest{Filter: &models.BulkFilter{}, Action: models.BulkActionPin},
+			wantErr: true,
+		},
+		{
+			name:    "names and filter",
+			req:     models.BulkRequest{Names: []string{"a"}, Filter: &models.BulkFilter{Search: "a"}, Action: models.BulkActionPin},
+			wantErr: true,
+		},
+		{
+			name:    "invalid status",
+			req:     models.BulkRequest{Names: []string{"a"}, Action: models.BulkActionSetStatus, Status: "archived"},
+			wantErr: true,
+		},
+		{
+			name:    "add tags without tags",
+			req:     models.BulkRequest{Names: []string{"a"}, Action: models.BulkActionAddTags, Tags: []string{" ", ""}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown action",
+			req:     models.BulkRequest{Names: []string{"a"}, Action: "purge"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBulkRequest(&tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateBulkRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	got := normalizeTags([]string{" ai ", "search", "", "ai", "search "})
+	want := []string{"ai", "search"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("normalizeTags() = %v, want %v", got, want)
+	}
+}
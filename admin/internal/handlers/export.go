@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pluggedin/registry-admin/internal/middleware"
+	"github.com/pluggedin/registry-admin/internal/models"
+)
+
+// Supported export formats
+const (
+	exportFormatNDJSON = "ndjson"
+	exportFormatJSON   = "json"
+	exportFormatCSV    = "csv"
+)
+
+// csvExportHeader lists the CSV columns written for every server
+var csvExportHeader = []string{
+	"name", "description", "status", "version", "release_date",
+	"repository_url", "repository_source", "registry_types", "packages", "remotes",
+}
+
+// csvStatsHeader lists the CSV columns appended when stats are included
+var csvStatsHeader = []string{"rating", "rating_count", "installation_count"}
+
+// exportWriter writes servers in a specific export format
+type exportWriter interface {
+	Write(server *models.ExportedServer) error
+	Close() error
+}
+
+// ExportServers handles GET /api/servers/export
+//
+// Streams the catalog (optionally filtered by status, registry_name and search, as in
+// ListServers) as NDJSON, a JSON document accepted by /api/servers/import, or CSV.
+// Set include_stats=true to attach ratings and installation counts.
+func (h *ServersHandler) ExportServers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = exportFormatNDJSON
+	}
+
+	includeStats := parseBoolParam(query.Get("include_stats"))
+
+	var contentType string
+	switch format {
+	case exportFormatNDJSON:
+		contentType = "application/x-ndjson"
+	case exportFormatJSON:
+		contentType = "application/json"
+	case exportFormatCSV:
+		contentType = "text/csv"
+	default:
+		http.Error(w, "Invalid format, must be one of: ndjson, json, csv", http.StatusBadRequest)
+		return
+	}
+
+	// Full catalog exports outlive the server's default write timeout
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	filename := fmt.Sprintf("servers-export-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	writer := newExportWriter(w, format, includeStats)
+
+	count := 0
+	err := h.ops.ExportServers(r.Context(), query.Get("status"), query.Get("registry_name"), query.Get("search"), includeStats,
+		func(server *models.ExportedServer) error {
+			count++
+			return writer.Write(server)
+		})
+	if err != nil {
+		// Once data is sent, the error can only be logged
+		log.Printf("Export failed after %d servers: %v", count, err)
+		if count == 0 {
+			// Nothing is written yet: answer with a plain error rather than a download
+			w.Header().Del("Content-Disposition")
+			http.Error(w, "Failed to export servers", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := writer.Close(); err != nil {
+		log.Printf("Error finishing export: %v", err)
+		return
+	}
+
+	// Log audit entry
+	h.ops.LogAuditEntry(r.Context(), &models.AuditLog{
+		User:    middleware.GetUserFromContext(r.Context()),
+		Action:  "EXPORT_SERVERS",
+		Details: fmt.Sprintf("Exported %d servers as %s", count, format),
+		IP:      r.RemoteAddr,
+	})
+}
+
+// newExportWriter creates a writer for the given format
+func newExportWriter(w io.Writer, format string, includeStats bool) exportWriter {
+	switch format {
+	case exportFormatJSON:
+		return &jsonExportWriter{w: w}
+	case exportFormatCSV:
+		return &csvExportWriter{w: csv.NewWriter(w), includeStats: includeStats}
+	default:
+		return &ndjsonExportWriter{encoder: json.NewEncoder(w)}
+	}
+}
+
+// ndjsonExportWriter writes one server per line
+type ndjsonExportWriter struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonExportWriter) Write(server *models.ExportedServer) error {
+	return e.encoder.Encode(server)
+}
+
+func (e *ndjsonExportWriter) Close() error {
+	return nil
+}
+
+// jsonExportWriter writes an ImportRequest-compatible {"servers": [...]} document
+type jsonExportWriter struct {
+	w       io.Writer
+	started bool
+}
+
+func (e *jsonExportWriter) Write(server *models.ExportedServer) error {
+	prefix := ","
+	if !e.started {
+		prefix = `{"servers":[`
+		e.started = true
+	}
+	data, err := json.Marshal(server)
+	if err != nil {
+		return fmt.Errorf("failed to marshal server: %w", err)
+	}
+	if _, err := io.WriteString(e.w, prefix); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonExportWriter) Close() error {
+	if !e.started {
+		_, err := io.WriteString(e.w, `{"servers":[]}`+"\n")
+		return err
+	}
+	_, err := io.WriteString(e.w, "]}\n")
+	return err
+}
+
+// csvExportWriter writes one row per server with flattened package and remote columns
+type csvExportWriter struct {
+	w            *csv.Writer
+	includeStats bool
+	started      bool
+}
+
+func (e *csvExportWriter) Write(server *models.ExportedServer) error {
+	if !e.started {
+		header := csvExportHeader
+		if e.includeStats {
+			header = append(append([]string{}, csvExportHeader...), csvStatsHeader...)
+		}
+		if err := e.w.Write(header); err != nil {
+			return err
+		}
+		e.started = true
+	}
+
+	if err := e.w.Write(csvExportRow(server, e.includeStats)); err != nil {
+		return err
+	}
+
+	// Flush per row so the response streams instead of buffering
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvExportWriter) Close() error {
+	if !e.started {
+		header := csvExportHeader
+		if e.includeStats {
+			header = append(append([]string{}, csvExportHeader...), csvStatsHeader...)
+		}
+		if err := e.w.Write(header); err != nil {
+			return err
+		}
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// csvExportRow flattens a server into CSV columns; multi-valued fields are joined with ';'
+func csvExportRow(server *models.ExportedServer, includeStats bool) []string {
+	packages := make([]string, 0, len(server.Packages))
+	for _, pkg := range server.Packages {
+		packages = append(packages, pkg.Name+"@"+pkg.Version)
+	}
+
+	remotes := make([]string, 0, len(server.Remotes))
+	for _, remote := range server.Remotes {
+		remotes = append(remotes, remote.URL)
+	}
+
+	row := []string{
+		server.Name,
+		server.Description,
+		string(server.Status),
+		server.VersionDetail.Version,
+		server.VersionDetail.ReleaseDate,
+		server.Repository.URL,
+		server.Repository.Source,
+		strings.Join(extractServerTypes(&server.ServerDetail), ";"),
+		strings.Join(packages, ";"),
+		strings.Join(remotes, ";"),
+	}
+
+	if includeStats {
+		if server.Stats != nil {
+			row = append(row,
+				strconv.FormatFloat(server.Stats.Rating, 'f', 2, 64),
+				strconv.Itoa(server.Stats.RatingCount),
+				strconv.Itoa(server.Stats.InstallationCount),
+			)
+		} else {
+			row = append(row, "", "", "")
+		}
+	}
+
+	return row
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+
+	"github.com/pluggedin/registry-admin/internal/models"
+)
+
+func testExportServers() []*models.ExportedServer {
+	one := &models.ExportedServer{Stats: &models.ServerStats{Rating: 4.5, RatingCount: 2, InstallationCount: 10}}
+	one.Name = "io.github.a/one"
+	one.Description = "First, with a comma"
+	one.Status = models.ServerStatusActive
+	one.Packages = []models.Package{
+		{RegistryName: "npm", Name: "@a/one", Version: "1.0.0"},
+		{RegistryName: "pypi", Name: "a-one", Version: "1.0.1"},
+	}
+
+	two := &models.ExportedServer{}
+	two.Name = "io.github.a/two"
+	two.Remotes = []models.Remote{{TransportType: "sse", URL: "https://example.com/sse"}}
+
+	return []*models.ExportedServer{one, two}
+}
+
+func TestJSONExportIsImportable(t *testing.T) {
+	var buf bytes.Buffer
+	writer := newExportWriter(&buf, exportFormatJSON, true)
+	for _, server := range testExportServers() {
+		if err := writer.Write(server); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	var req models.ImportRequest
+	if err := json.Unmarshal(buf.Bytes(), &req); err != nil {
+		t.Fatalf("export is not a valid ImportRequest: %v\n%s", err, buf.String())
+	}
+	if len(req.Servers) != 2 || req.Servers[0].Name != "io.github.a/one" || req.Servers[1].Name != "io.github.a/two" {
+		t.Errorf("unexpected servers: %+v", req.Servers)
+	}
+}
+
+func TestJSONExportEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	writer := newExportWriter(&buf, exportFormatJSON, false)
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	var req models.ImportRequest
+	if err := json.Unmarshal(buf.Bytes(), &req); err != nil {
+		t.Fatalf("empty export is not valid JSON: %v", err)
+	}
+}
+
+func TestCSVExport(t *testing.T) {
+	var buf bytes.Buffer
+	writer := newExportWriter(&buf, exportFormatCSV, true)
+	for _, server := range testExportServers() {
+		if err := writer.Write(server); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want header + 2 rows", len(records))
+	}
+
+	header := records[0]
+	if len(header) != len(csvExportHeader)+len(csvStatsHeader) {
+		t.Fatalf("header has %d columns, want %d", len(header), len(csvExportHeader)+len(csvStatsHeader))
+	}
+
+	column := func(record []string, name string) string {
+		for i, h := range header {
+			if h == name && i < len(record) {
+				return record[i]
+			}
+		}
+		return ""
+	}
+
+	if got := column(records[1], "description"); got != "First, with a comma" {
+		t.Errorf("description = %q", got)
+	}
+	if got := column(records[1], "packages"); got != "@a/one@1.0.0;a-one@1.0.1" {
+		t.Errorf("packages = %q", got)
+	}
+	if got := column(records[1], "rating"); got != "4.50" {
+		t.Errorf("rating = %q", got)
+	}
+	if got := column(records[2], "remotes"); got != "https://example.com/sse" {
+		t.Errorf("remotes = %q", got)
+	}
+}
@@ -0,0 +1,381 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pluggedin/registry-admin/internal/db"
+	"github.com/pluggedin/registry-admin/internal/middleware"
+	"github.com/pluggedin/registry-admin/internal/models"
+	"github.com/pluggedin/registry-admin/internal/verifier"
+)
+
+const (
+	// maxImportBodyBytes caps the size of a streaming import request body
+	maxImportBodyBytes = 512 << 20
+	// maxImportLineBytes caps the size of a single NDJSON line
+	maxImportLineBytes = 10 << 20
+)
+
+// importStreamOptions holds the query parameters of a streaming import
+type importStreamOptions struct {
+	DryRun           bool
+	Atomic           bool
+	ValidatePackages bool
+	OnConflict       models.ConflictPolicy
+}
+
+// parseImportStreamOptions reads streaming import options from the query string
+func parseImportStreamOptions(r *http.Request) (importStreamOptions, error) {
+	query := r.URL.Query()
+	opts := importStreamOptions{
+		DryRun:           parseBoolParam(query.Get("dry_run")),
+		Atomic:           parseBoolParam(query.Get("atomic")),
+		ValidatePackages: parseBoolParam(query.Get("validate_packages")),
+		OnConflict:       models.ConflictUpdate,
+	}
+
+	if onConflict := query.Get("on_conflict"); onConflict != "" {
+		opts.OnConflict = models.ConflictPolicy(onConflict)
+		switch opts.OnConflict {
+		case models.ConflictUpdate, models.ConflictSkip, models.ConflictFail:
+		default:
+			return opts, fmt.Errorf("invalid on_conflict value '%s'", onConflict)
+		}
+	}
+
+	return opts, nil
+}
+
+// parseBoolParam parses a boolean query parameter, treating anything unparseable as false
+func parseBoolParam(value string) bool {
+	b, _ := strconv.ParseBool(value)
+	return b
+}
+
+// StreamImport handles POST /api/servers/import/stream
+//
+// The body is either NDJSON (one server per line) or a JSON array of servers. Results
+// are streamed back as NDJSON, one line per input server, followed by a summary line.
+// With atomic=true every server is imported in a single transaction that is only
+// committed if all of them succeed; with dry_run=true the transaction is always rolled back.
+func (h *ServersHandler) StreamImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	opts, err := parseImportStreamOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Large imports outlive the server's default read/write timeouts
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	body := http.MaxBytesReader(w, r.Body, maxImportBodyBytes)
+	next, err := newServerStream(body)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	emit := func(v interface{}) {
+		if err := encoder.Encode(v); err != nil {
+			log.Printf("Error writing import result: %v", err)
+			return
+		}
+		_ = rc.Flush()
+	}
+
+	summary := h.runStreamImport(r.Context(), next, opts, emit, middleware.GetUserFromContext(r.Context()), r.RemoteAddr)
+	emit(map[string]interface{}{"summary": summary})
+}
+
+// importStore is the part of db.Operations streaming imports run against
+type importStore interface {
+	BeginTx(ctx context.Context) (pgx.Tx, error)
+	ImportServer(ctx context.Context, q db.Querier, server *models.ServerDetail, policy models.ConflictPolicy) (models.ImportAction, error)
+	LogAuditEntry(ctx context.Context, entry *models.AuditLog) error
+}
+
+// runStreamImport imports every server produced by next and reports each result through emit
+func (h *ServersHandler) runStreamImport(
+	ctx context.Context,
+	next func() (int, *models.ServerDetail, error),
+	opts importStreamOptions,
+	emit func(interface{}),
+	user, ip string,
+) models.ImportStreamSummary {
+	summary := models.ImportStreamSummary{DryRun: opts.DryRun, Atomic: opts.Atomic}
+
+	// Atomic and dry-run imports share one outer transaction; each server runs in a
+	// savepoint so a failure does not abort the remaining servers
+	var outer pgx.Tx
+	if opts.Atomic || opts.DryRun {
+		tx, err := h.imports.BeginTx(ctx)
+		if err != nil {
+			summary.Error = "Failed to start import transaction"
+			return summary
+		}
+		outer = tx
+		defer func() { _ = outer.Rollback(ctx) }() // No-op once committed
+	}
+
+	// Audit entries are only written once the changes are committed
+	var pending []models.AuditLog
+
+	for {
+		line, server, err := next()
+		if err == io.EOF {
+			break
+		}
+
+		summary.Total++
+		result := models.ImportLineResult{Line: line}
+
+		if err != nil {
+			result.Action = models.ImportActionFailed
+			result.Error = err.Error()
+			summary.Failed++
+			emit(result)
+
+			var fatal *fatalStreamError
+			if errors.As(err, &fatal) {
+				summary.Error = "Import aborted: request body could not be parsed"
+				break
+			}
+			continue
+		}
+
+		result.Name = server.Name
+		action, importErr := h.importStreamServer(ctx, outer, server, opts)
+		if importErr != nil {
+			result.Action = models.ImportActionFailed
+			result.Error = importErr.Error()
+			summary.Failed++
+			emit(result)
+			continue
+		}
+
+		result.Action = action
+		result.ID = server.ID
+		switch action {
+		case models.ImportActionCreated:
+			summary.Created++
+		case models.ImportActionUpdated:
+			summary.Updated++
+		case models.ImportActionSkipped:
+			summary.Skipped++
+		}
+		emit(result)
+
+		if action != models.ImportActionSkipped && !opts.DryRun {
+			entry := importAuditEntry(action, server.Name, user, ip)
+			if outer == nil {
+				h.imports.LogAuditEntry(ctx, &entry)
+			} else {
+				pending = append(pending, entry)
+			}
+		}
+	}
+
+	if outer == nil {
+		summary.Committed = summary.Created+summary.Updated > 0
+	} else if !opts.DryRun && (summary.Failed == 0 || !opts.Atomic) {
+		if err := outer.Commit(ctx); err != nil {
+			summary.Error = "Failed to commit import transaction"
+			return summary
+		}
+		summary.Committed = true
+		for i := range pending {
+			h.imports.LogAuditEntry(ctx, &pending[i])
+		}
+	}
+
+	if !opts.DryRun {
+		h.imports.LogAuditEntry(ctx, &models.AuditLog{
+			User:   user,
+			Action: "BATCH_IMPORT",
+			Details: fmt.Sprintf("Streaming import: %d created, %d updated, %d skipped, %d failed (committed: %t)",
+				summary.Created, summary.Updated, summary.Skipped, summary.Failed, summary.Committed),
+			IP: ip,
+		})
+	}
+
+	return summary
+}
+
+// importStreamServer verifies packages if requested, then imports the server
+func (h *ServersHandler) importStreamServer(ctx context.Context, outer pgx.Tx, server *models.ServerDetail, opts importStreamOptions) (models.ImportAction, error) {
+	if opts.ValidatePackages && h.verifier != nil {
+		if failures := verifier.Failures(h.verifier.VerifyServer(ctx, server)); len(failures) > 0 {
+			return "", fmt.Errorf("package verification failed: %s", strings.Join(failures, "; "))
+		}
+	}
+
+	return h.importServer(ctx, outer, server, opts.OnConflict)
+}
+
+// importServer imports a single server inside a savepoint of outer, or in its own
+// transaction when outer is nil
+func (h *ServersHandler) importServer(ctx context.Context, outer pgx.Tx, server *models.ServerDetail, policy models.ConflictPolicy) (models.ImportAction, error) {
+	var (
+		tx  pgx.Tx
+		err error
+	)
+	if outer != nil {
+		tx, err = outer.Begin(ctx)
+	} else {
+		tx, err = h.imports.BeginTx(ctx)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	action, err := h.imports.ImportServer(ctx, tx, server, policy)
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("failed to commit server: %w", err)
+	}
+
+	return action, nil
+}
+
+// importAuditEntry builds the audit entry for an imported server
+func importAuditEntry(action models.ImportAction, name, user, ip string) models.AuditLog {
+	entry := models.AuditLog{
+		User:     user,
+		Action:   "CREATE_SERVER",
+		ServerID: name,
+		Details:  "Created server via import: " + name,
+		IP:       ip,
+	}
+	if action == models.ImportActionUpdated {
+		entry.Action = "UPDATE_SERVER"
+		entry.Details = "Updated server via import: " + name
+	}
+	return entry
+}
+
+// fatalStreamError marks a parse error after which the stream cannot continue
+type fatalStreamError struct {
+	err error
+}
+
+func (e *fatalStreamError) Error() string {
+	return fmt.Sprintf("invalid JSON: %v", e.err)
+}
+
+func (e *fatalStreamError) Unwrap() error {
+	return e.err
+}
+
+// newServerStream returns an iterator over the servers in body. A body starting with
+// '[' is read as a JSON array, anything else as NDJSON. The iterator returns io.EOF
+// when the input is exhausted.
+func newServerStream(body io.Reader) (func() (int, *models.ServerDetail, error), error) {
+	reader := bufio.NewReaderSize(body, 64<<10)
+
+	first, err := peekNonSpace(reader)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if first == '[' {
+		return newJSONArrayStream(reader)
+	}
+	return newNDJSONStream(reader), nil
+}
+
+// peekNonSpace returns the first non-whitespace byte without consuming it
+func peekNonSpace(reader *bufio.Reader) (byte, error) {
+	for {
+		b, err := reader.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			_, _ = reader.ReadByte()
+		default:
+			return b[0], nil
+		}
+	}
+}
+
+// newJSONArrayStream iterates over the elements of a JSON array of servers
+func newJSONArrayStream(reader io.Reader) (func() (int, *models.ServerDetail, error), error) {
+	decoder := json.NewDecoder(reader)
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+
+	index := 0
+	done := false
+	return func() (int, *models.ServerDetail, error) {
+		if done || !decoder.More() {
+			done = true
+			return 0, nil, io.EOF
+		}
+
+		index++
+		var server models.ServerDetail
+		if err := decoder.Decode(&server); err != nil {
+			// The decoder cannot resynchronise after a syntax error
+			done = true
+			return index, nil, &fatalStreamError{err: err}
+		}
+		return index, &server, nil
+	}, nil
+}
+
+// newNDJSONStream iterates over newline-delimited servers, skipping blank lines
+func newNDJSONStream(reader io.Reader) func() (int, *models.ServerDetail, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxImportLineBytes)
+
+	line := 0
+	return func() (int, *models.ServerDetail, error) {
+		for scanner.Scan() {
+			line++
+			data := bytes.TrimSpace(scanner.Bytes())
+			if len(data) == 0 {
+				continue
+			}
+
+			var server models.ServerDetail
+			if err := json.Unmarshal(data, &server); err != nil {
+				return line, nil, fmt.Errorf("invalid JSON: %v", err)
+			}
+			return line, &server, nil
+		}
+
+		if err := scanner.Err(); err != nil {
+			return line + 1, nil, &fatalStreamError{err: err}
+		}
+		return 0, nil, io.EOF
+	}
+}
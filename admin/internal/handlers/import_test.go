@@ -0,0 +1,369 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pluggedin/registry-admin/internal/db"
+	"github.com/pluggedin/registry-admin/internal/models"
+)
+
+// collectStream drains a server stream into line numbers, names and errors
+func collectStream(t *testing.T, body string) ([]int, []string, []error) {
+	t.Helper()
+
+	next, err := newServerStream(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("newServerStream() error = %v", err)
+	}
+
+	var lines []int
+	var names []string
+	var errs []error
+	for {
+		line, server, err := next()
+		if err == io.EOF {
+			break
+		}
+		lines = append(lines, line)
+		errs = append(errs, err)
+		if server != nil {
+			names = append(names, server.Name)
+		} else {
+			names = append(names, "")
+		}
+
+		var fatal *fatalStreamError
+		if errors.As(err, &fatal) {
+			break
+		}
+	}
+	return lines, names, errs
+}
+
+func TestServerStreamNDJSON(t *testing.T) {
+	body := `{"name":"io.github.a/one"}
+
+{"name":"io.github.a/two"}
+{not json}
+{"name":"io.github.a/three"}
+`
+	lines, names, errs := collectStream(t, body)
+
+	wantLines := []int{1, 3, 4, 5}
+	wantNames := []string{"io.github.a/one", "io.github.a/two", "", "io.github.a/three"}
+	if len(lines) != len(wantLines) {
+		t.Fatalf("got %d results, want %d", len(lines), len(wantLines))
+	}
+	for i := range wantLines {
+		if lines[i] != wantLines[i] || names[i] != wantNames[i] {
+			t.Errorf("result %d = (line %d, %q), want (line %d, %q)", i, lines[i], names[i], wantLines[i], wantNames[i])
+		}
+	}
+	if errs[2] == nil {
+		t.Error("expected an error for the malformed line")
+	}
+	var fatal *fatalStreamError
+	if errors.As(errs[2], &fatal) {
+		t.Error("a malformed NDJSON line should not abort the stream")
+	}
+}
+
+func TestServerStreamJSONArray(t *testing.T) {
+	lines, names, errs := collectStream(t, `  [{"name":"io.github.a/one"}, {"name":"io.github.a/two"}]`)
+
+	if len(names) != 2 || names[0] != "io.github.a/one" || names[1] != "io.github.a/two" {
+		t.Fatalf("names = %v, want [io.github.a/one io.github.a/two]", names)
+	}
+	if lines[0] != 1 || lines[1] != 2 {
+		t.Errorf("lines = %v, want [1 2]", lines)
+	}
+	for _, err := range errs {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestServerStreamJSONArraySyntaxErrorIsFatal(t *testing.T) {
+	_, names, errs := collectStream(t, `[{"name":"io.github.a/one"}, {"name": }]`)
+
+	if len(names) != 2 {
+		t.Fatalf("got %d results, want 2", len(names))
+	}
+	var fatal *fatalStreamError
+	if !errors.As(errs[1], &fatal) {
+		t.Errorf("expected a fatal stream error, got %v", errs[1])
+	}
+}
+
+func TestParseImportStreamOptions(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    importStreamOptions
+		wantErr bool
+	}{
+		{
+			query: "",
+			want:  importStreamOptions{OnConflict: models.ConflictUpdate},
+		},
+		{
+			query: "dry_run=true&atomic=1&on_conflict=skip&validate_packages=true",
+			want:  importStreamOptions{DryRun: true, Atomic: true, ValidatePackages: true, OnConflict: models.ConflictSkip},
+		},
+		{
+			query:   "on_conflict=overwrite",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/api/servers/import/stream?"+tt.query, nil)
+			got, err := parseImportStreamOptions(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseImportStreamOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseImportStreamOptions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// storedServer is a row of the servers table kept by fakeImportStore
+type storedServer struct {
+	version string
+	status  string
+}
+
+// fakeImportStore runs db.ImportServer against an in-memory servers table and records
+// audit entries
+type fakeImportStore struct {
+	servers map[string]storedServer
+	audit   []models.AuditLog
+}
+
+func (s *fakeImportStore) BeginTx(context.Context) (pgx.Tx, error) {
+	return newFakeTx(s.servers, func(rows map[string]storedServer) { s.servers = rows }), nil
+}
+
+func (s *fakeImportStore) ImportServer(ctx context.Context, q db.Querier, server *models.ServerDetail, policy models.ConflictPolicy) (models.ImportAction, error) {
+	return new(db.Operations).ImportServer(ctx, q, server, policy)
+}
+
+func (s *fakeImportStore) LogAuditEntry(_ context.Context, entry *models.AuditLog) error {
+	s.audit = append(s.audit, *entry)
+	return nil
+}
+
+// auditActions returns the actions of the recorded audit entries
+func (s *fakeImportStore) auditActions() []string {
+	var actions []string
+	for _, entry := range s.audit {
+		actions = append(actions, entry.Action)
+	}
+	return actions
+}
+
+// fakeTx interprets the servers statements of db.ImportServer. It works on a copy of
+// the table that replaces its parent's on commit, so nested transactions act as savepoints.
+type fakeTx struct {
+	pgx.Tx
+	servers map[string]storedServer
+	commit  func(map[string]storedServer)
+	closed  bool
+}
+
+func newFakeTx(servers map[string]storedServer, commit func(map[string]storedServer)) *fakeTx {
+	rows := make(map[string]storedServer, len(servers))
+	for name, row := range servers {
+		rows[name] = row
+	}
+	return &fakeTx{servers: rows, commit: commit}
+}
+
+func (tx *fakeTx) Begin(context.Context) (pgx.Tx, error) {
+	return newFakeTx(tx.servers, func(rows map[string]storedServer) { tx.servers = rows }), nil
+}
+
+func (tx *fakeTx) Commit(context.Context) error {
+	if tx.closed {
+		return pgx.ErrTxClosed
+	}
+	tx.closed = true
+	tx.commit(tx.servers)
+	return nil
+}
+
+func (tx *fakeTx) Rollback(context.Context) error {
+	if tx.closed {
+		return pgx.ErrTxClosed
+	}
+	tx.closed = true
+	return nil
+}
+
+func (tx *fakeTx) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	switch {
+	case strings.Contains(sql, "INSERT INTO servers"):
+		tx.servers[args[0].(string)] = storedServer{version: args[1].(string), status: fmt.Sprint(args[3])}
+		return pgconn.NewCommandTag("INSERT 0 1"), nil
+	case strings.Contains(sql, "UPDATE servers"):
+		name := args[3].(string)
+		row, ok := tx.servers[name]
+		if !ok {
+			return pgconn.NewCommandTag("UPDATE 0"), nil
+		}
+		row.status = fmt.Sprint(args[1])
+		if version := args[4].(string); version != "" {
+			row.version = version
+		}
+		tx.servers[name] = row
+		return pgconn.NewCommandTag("UPDATE 1"), nil
+	}
+	return pgconn.CommandTag{}, fmt.Errorf("unexpected statement: %s", sql)
+}
+
+func (tx *fakeTx) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	if !strings.Contains(sql, "SELECT status") {
+		return fakeRow{err: fmt.Errorf("unexpected query: %s", sql)}
+	}
+	row, ok := tx.servers[args[0].(string)]
+	if !ok {
+		return fakeRow{err: pgx.ErrNoRows}
+	}
+	return fakeRow{status: row.status}
+}
+
+// fakeRow scans the status of a looked up server
+type fakeRow struct {
+	status string
+	err    error
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	*dest[0].(*string) = r.status
+	return nil
+}
+
+// serversOf returns a stream over servers
+func serversOf(servers ...models.ServerDetail) func() (int, *models.ServerDetail, error) {
+	i := 0
+	return func() (int, *models.ServerDetail, error) {
+		if i == len(servers) {
+			return 0, nil, io.EOF
+		}
+		i++
+		return i, &servers[i-1], nil
+	}
+}
+
+// runImport imports servers into store and returns the summary and per-line results
+func runImport(store *fakeImportStore, opts importStreamOptions, servers ...models.ServerDetail) (models.ImportStreamSummary, []models.ImportLineResult) {
+	h := &ServersHandler{imports: store}
+	var results []models.ImportLineResult
+	emit := func(v interface{}) { results = append(results, v.(models.ImportLineResult)) }
+	summary := h.runStreamImport(context.Background(), serversOf(servers...), opts, emit, "admin", "127.0.0.1")
+	return summary, results
+}
+
+func importedServer(name, version string) models.ServerDetail {
+	return models.ServerDetail{Server: models.Server{Name: name, VersionDetail: models.VersionDetail{Version: version}}}
+}
+
+func TestRunStreamImportAtomicRollsBackOnFailure(t *testing.T) {
+	store := &fakeImportStore{servers: map[string]storedServer{}}
+
+	summary, results := runImport(store, importStreamOptions{Atomic: true, OnConflict: models.ConflictFail},
+		importedServer("io.github.example/a", "1.0.0"), importedServer("", "1.0.0"))
+
+	if summary.Created != 1 || summary.Failed != 1 || summary.Committed {
+		t.Errorf("summary = %+v, want 1 created, 1 failed and nothing committed", summary)
+	}
+	if len(results) != 2 || results[1].Action != models.ImportActionFailed {
+		t.Errorf("results = %+v, want the second line failed", results)
+	}
+	if len(store.servers) != 0 {
+		t.Errorf("servers = %v, want none after the rollback", store.servers)
+	}
+	if actions := store.auditActions(); !reflect.DeepEqual(actions, []string{"BATCH_IMPORT"}) {
+		t.Errorf("audit = %v, want only the batch summary", actions)
+	}
+}
+
+func TestRunStreamImportKeepsOtherServersWhenNotAtomic(t *testing.T) {
+	store := &fakeImportStore{servers: map[string]storedServer{}}
+
+	summary, _ := runImport(store, importStreamOptions{OnConflict: models.ConflictFail},
+		importedServer("io.github.example/a", "1.0.0"), importedServer("", "1.0.0"))
+
+	if summary.Created != 1 || summary.Failed != 1 || !summary.Committed {
+		t.Errorf("summary = %+v, want 1 created and committed, 1 failed", summary)
+	}
+	if _, ok := store.servers["io.github.example/a"]; !ok || len(store.servers) != 1 {
+		t.Errorf("servers = %v, want io.github.example/a only", store.servers)
+	}
+	if actions := store.auditActions(); !reflect.DeepEqual(actions, []string{"CREATE_SERVER", "BATCH_IMPORT"}) {
+		t.Errorf("audit = %v, want the created server and the batch summary", actions)
+	}
+}
+
+func TestRunStreamImportDryRunWritesNothing(t *testing.T) {
+	store := &fakeImportStore{servers: map[string]storedServer{"io.github.example/a": {version: "1.0.0", status: "active"}}}
+
+	summary, _ := runImport(store, importStreamOptions{DryRun: true, OnConflict: models.ConflictUpdate},
+		importedServer("io.github.example/a", "2.0.0"), importedServer("io.github.example/b", "1.0.0"))
+
+	if summary.Created != 1 || summary.Updated != 1 || summary.Committed {
+		t.Errorf("summary = %+v, want 1 created and 1 updated, not committed", summary)
+	}
+	want := map[string]storedServer{"io.github.example/a": {version: "1.0.0", status: "active"}}
+	if !reflect.DeepEqual(store.servers, want) {
+		t.Errorf("servers = %v, want %v", store.servers, want)
+	}
+	if len(store.audit) != 0 {
+		t.Errorf("audit = %v, want none for a dry run", store.auditActions())
+	}
+}
+
+func TestRunStreamImportConflictPolicies(t *testing.T) {
+	tests := []struct {
+		policy     models.ConflictPolicy
+		wantAction models.ImportAction
+		want       storedServer
+	}{
+		// Updates match the server by name, take the new version and keep the status
+		{models.ConflictUpdate, models.ImportActionUpdated, storedServer{version: "2.0.0", status: "deprecated"}},
+		{models.ConflictSkip, models.ImportActionSkipped, storedServer{version: "1.0.0", status: "deprecated"}},
+		{models.ConflictFail, models.ImportActionFailed, storedServer{version: "1.0.0", status: "deprecated"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			store := &fakeImportStore{servers: map[string]storedServer{"io.github.example/a": {version: "1.0.0", status: "deprecated"}}}
+
+			server := importedServer("io.github.example/a", "2.0.0")
+			server.ID = "an-old-id"
+			_, results := runImport(store, importStreamOptions{OnConflict: tt.policy}, server)
+
+			if len(results) != 1 || results[0].Action != tt.wantAction {
+				t.Fatalf("results = %+v, want %s", results, tt.wantAction)
+			}
+			if len(store.servers) != 1 || store.servers["io.github.example/a"] != tt.want {
+				t.Errorf("servers = %v, want io.github.example/a as %+v", store.servers, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pluggedin/registry-admin/internal/db"
+	"github.com/pluggedin/registry-admin/internal/middleware"
+	"github.com/pluggedin/registry-admin/internal/models"
+)
+
+// ModerationHandler handles the review moderation queue and user bans
+type ModerationHandler struct {
+	ops *db.Operations
+}
+
+// NewModerationHandler creates a new moderation handler
+func NewModerationHandler(ops *db.Operations) *ModerationHandler {
+	return &ModerationHandler{ops: ops}
+}
+
+// ListQueue handles GET /api/moderation/reviews
+// The status parameter selects pending, reported or hidden reviews; by default the
+// queue holds every pending or reported review
+func (h *ModerationHandler) ListQueue(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter := r.URL.Query().Get("status")
+	switch filter {
+	case db.ModerationFilterQueue, db.ModerationFilterPending, db.ModerationFilterReported, db.ModerationFilterHidden:
+	default:
+		http.Error(w, "Invalid status: use pending, reported or hidden", http.StatusBadRequest)
+		return
+	}
+
+	reviews, total, err := h.ops.ListModerationQueue(r.Context(), page, limit, filter)
+	if err != nil {
+		http.Error(w, "Failed to fetch moderation queue", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"reviews": reviews,
+		"pagination": map[string]interface{}{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HideReview handles POST /api/moderation/reviews/{id}/{userId}/hide
+func (h *ModerationHandler) HideReview(w http.ResponseWriter, r *http.Request) {
+	h.setReviewStatus(w, r, models.ReviewStatusHidden, "HIDE_REVIEW")
+}
+
+// RestoreReview handles POST /api/moderation/reviews/{id}/{userId}/restore
+func (h *ModerationHandler) RestoreReview(w http.ResponseWriter, r *http.Request) {
+	h.setReviewStatus(w, r, models.ReviewStatusVisible, "RESTORE_REVIEW")
+}
+
+func (h *ModerationHandler) setReviewStatus(w http.ResponseWriter, r *http.Request, status models.ReviewStatus, action string) {
+	vars := mux.Vars(r)
+	serverID, userID := vars["id"], vars["userId"]
+
+	user := middleware.GetUserFromContext(r.Context())
+	if err := h.ops.SetReviewStatus(r.Context(), serverID, userID, status, user); err != nil {
+		if err.Error() == "review not found" {
+			http.Error(w, "Review not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to update review", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Log audit entry
+	h.ops.LogAuditEntry(r.Context(), &models.AuditLog{
+		User:     user,
+		Action:   action,
+		ServerID: serverID,
+		Details:  "Review by " + userID + " set to " + string(status),
+		IP:       r.RemoteAddr,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"server_id": serverID,
+		"user_id":   userID,
+		"status":    string(status),
+	})
+}
+
+// ListBans handles GET /api/moderation/bans
+func (h *ModerationHandler) ListBans(w http.ResponseWriter, r *http.Request) {
+	users, err := h.ops.ListBannedUsers(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to fetch banned users", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"users": users})
+}
+
+// BanUser handles POST /api/moderation/bans
+// Banned users cannot submit reviews and all of their existing reviews are hidden
+func (h *ModerationHandler) BanUser(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID string `json:"user_id"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	req.UserID = strings.TrimSpace(req.UserID)
+	if req.UserID == "" {
+		http.Error(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	hidden, err := h.ops.BanUser(r.Context(), req.UserID, strings.TrimSpace(req.Reason), user)
+	if err != nil {
+		http.Error(w, "Failed to ban user", http.StatusInternalServerError)
+		return
+	}
+
+	// Log audit entry
+	h.ops.LogAuditEntry(r.Context(), &models.AuditLog{
+		User:    user,
+		Action:  "BAN_USER",
+		Details: "Banned " + req.UserID + " and hid " + strconv.Itoa(hidden) + " reviews",
+		IP:      r.RemoteAddr,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user_id":        req.UserID,
+		"hidden_reviews": hidden,
+	})
+}
+
+// UnbanUser handles DELETE /api/moderation/bans/{userId}
+func (h *ModerationHandler) UnbanUser(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userId"]
+
+	if err := h.ops.UnbanUser(r.Context(), userID); err != nil {
+		if err.Error() == "user not banned" {
+			http.Error(w, "User is not banned", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to unban user", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Log audit entry
+	h.ops.LogAuditEntry(r.Context(), &models.AuditLog{
+		User:    middleware.GetUserFromContext(r.Context()),
+		Action:  "UNBAN_USER",
+		Details: "Unbanned " + userID,
+		IP:      r.RemoteAddr,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestModerationReviewRouteSplitsServerAndUser(t *testing.T) {
+	var gotServer, gotUser string
+	router := mux.NewRouter()
+	router.HandleFunc("/api/moderation/reviews/{id:.+}/{userId}/hide", func(w http.ResponseWriter, r *http.Request) {
+		gotServer, gotUser = mux.Vars(r)["id"], mux.Vars(r)["userId"]
+	}).Methods("POST")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/moderation/reviews/io.github.example/weather/user-42/hide", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if gotServer != "io.github.example/weather" || gotUser != "user-42" {
+		t.Errorf("vars = (%q, %q), want (%q, %q)", gotServer, gotUser, "io.github.example/weather", "user-42")
+	}
+}
+
+func TestListQueueRejectsUnknownStatus(t *testing.T) {
+	h := NewModerationHandler(nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/moderation/reviews?status=visible", nil)
+	rec := httptest.NewRecorder()
+	h.ListQueue(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -7,22 +7,26 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/pluggedin/registry-admin/internal/db"
 	"github.com/pluggedin/registry-admin/internal/middleware"
 	"github.com/pluggedin/registry-admin/internal/models"
+	"github.com/pluggedin/registry-admin/internal/verifier"
 )
 
 // ServersHandler handles server management endpoints
 type ServersHandler struct {
-	ops *db.Operations
+	ops      *db.Operations
+	imports  importStore
+	verifier *verifier.PackageVerifier
 }
 
 // NewServersHandler creates a new servers handler
-func NewServersHandler(ops *db.Operations) *ServersHandler {
+func NewServersHandler(ops *db.Operations, packageVerifier *verifier.PackageVerifier) *ServersHandler {
 	return &ServersHandler{
-		ops: ops,
+		ops:      ops,
+		imports:  ops,
+		verifier: packageVerifier,
 	}
 }
 
@@ -302,72 +306,41 @@ func (h *ServersHandler) ImportServers(w http.ResponseWriter, r *http.Request) {
 	}
 
 	user := middleware.GetUserFromContext(r.Context())
+	policy := importConflictPolicy(req.Options)
 
 	for _, server := range req.Servers {
-		// Check if server exists
-		if !req.Options.UpdateExisting {
-			exists, _ := h.ops.ServerExists(r.Context(), server.Name)
-			if exists {
-				if req.Options.SkipExisting {
-					continue
-				}
+		// Verify packages against their registries when requested
+		if req.Options.ValidatePackages && h.verifier != nil {
+			if failures := verifier.Failures(h.verifier.VerifyServer(r.Context(), &server)); len(failures) > 0 {
 				response.Failed = append(response.Failed, models.ImportResult{
 					Name:  server.Name,
-					Error: "Server already exists",
+					Error: "Package verification failed: " + strings.Join(failures, "; "),
 				})
 				continue
 			}
 		}
 
-		// Generate ID if not provided
-		if server.ID == "" {
-			server.ID = uuid.New().String()
+		// Upsert by server name inside a per-server transaction
+		action, err := h.importServer(r.Context(), nil, &server, policy)
+		if err != nil {
+			response.Failed = append(response.Failed, models.ImportResult{
+				Name:  server.Name,
+				Error: err.Error(),
+			})
+			continue
 		}
-
-		// Create or update server
-		if req.Options.UpdateExisting {
-			if err := h.ops.UpdateServer(r.Context(), server.ID, &server); err != nil {
-				response.Failed = append(response.Failed, models.ImportResult{
-					Name:  server.Name,
-					Error: err.Error(),
-				})
-			} else {
-				response.Success = append(response.Success, models.ImportResult{
-					Name: server.Name,
-					ID:   server.ID,
-				})
-				
-				// Log audit entry
-				h.ops.LogAuditEntry(r.Context(), &models.AuditLog{
-					User:     user,
-					Action:   "UPDATE_SERVER",
-					ServerID: server.ID,
-					Details:  "Updated server via import: " + server.Name,
-					IP:       r.RemoteAddr,
-				})
-			}
-		} else {
-			if err := h.ops.CreateServer(r.Context(), &server); err != nil {
-				response.Failed = append(response.Failed, models.ImportResult{
-					Name:  server.Name,
-					Error: err.Error(),
-				})
-			} else {
-				response.Success = append(response.Success, models.ImportResult{
-					Name: server.Name,
-					ID:   server.ID,
-				})
-				
-				// Log audit entry
-				h.ops.LogAuditEntry(r.Context(), &models.AuditLog{
-					User:     user,
-					Action:   "CREATE_SERVER",
-					ServerID: server.ID,
-					Details:  "Created server via import: " + server.Name,
-					IP:       r.RemoteAddr,
-				})
-			}
+		if action == models.ImportActionSkipped {
+			continue
 		}
+
+		response.Success = append(response.Success, models.ImportResult{
+			Name: server.Name,
+			ID:   server.ID,
+		})
+
+		// Log audit entry
+		entry := importAuditEntry(action, server.Name, user, r.RemoteAddr)
+		h.ops.LogAuditEntry(r.Context(), &entry)
 	}
 
 	response.Summary = models.ImportSummary{
@@ -388,6 +361,18 @@ func (h *ServersHandler) ImportServers(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// importConflictPolicy maps batch import options onto a conflict policy
+func importConflictPolicy(options models.ImportOptions) models.ConflictPolicy {
+	switch {
+	case options.UpdateExisting:
+		return models.ConflictUpdate
+	case options.SkipExisting:
+		return models.ConflictSkip
+	default:
+		return models.ConflictFail
+	}
+}
+
 // ValidateServer handles POST /api/servers/validate
 func (h *ServersHandler) ValidateServer(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -460,6 +445,15 @@ func (h *ServersHandler) ValidateServer(w http.ResponseWriter, r *http.Request)
 			errors = append(errors, fmt.Sprintf("Package %d: Version is required", i))
 		}
 	}
+
+	// Verify packages exist in their registries and carry the ownership proof
+	if h.verifier != nil && len(errors) == 0 {
+		for i, result := range h.verifier.VerifyServer(r.Context(), &server) {
+			if !result.OK() {
+				errors = append(errors, fmt.Sprintf("Package %d: %s", i, result.Message()))
+			}
+		}
+	}
 	
 	if len(errors) > 0 {
 		response.Valid = false
@@ -9,33 +9,40 @@ import (
 	"strings"
 	"time"
 
+	"github.com/pluggedin/registry-admin/internal/classifier"
 	"github.com/pluggedin/registry-admin/internal/db"
 	"github.com/pluggedin/registry-admin/internal/middleware"
 	"github.com/pluggedin/registry-admin/internal/models"
+	"github.com/pluggedin/registry-admin/internal/verifier"
 )
 
 // SyncHandler handles registry synchronization endpoints
 type SyncHandler struct {
 	ops                 *db.Operations
 	officialRegistryURL string
+	verifier            *verifier.PackageVerifier
+	classifier          *classifier.Classifier
 }
 
-// NewSyncHandler creates a new sync handler
-func NewSyncHandler(ops *db.Operations, officialRegistryURL string) *SyncHandler {
+// NewSyncHandler creates a new sync handler. A nil classifier disables category and tag inference.
+func NewSyncHandler(ops *db.Operations, officialRegistryURL string, packageVerifier *verifier.PackageVerifier, taxonomyClassifier *classifier.Classifier) *SyncHandler {
 	if officialRegistryURL == "" {
 		officialRegistryURL = "https://registry.modelcontextprotocol.io"
 	}
 	return &SyncHandler{
 		ops:                 ops,
 		officialRegistryURL: officialRegistryURL,
+		verifier:            packageVerifier,
+		classifier:          taxonomyClassifier,
 	}
 }
 
 // SyncRequest represents a sync request
 type SyncRequest struct {
-	DryRun         bool `json:"dry_run"`
-	UpdateExisting bool `json:"update_existing"`
-	AddNew         bool `json:"add_new"`
+	DryRun           bool `json:"dry_run"`
+	UpdateExisting   bool `json:"update_existing"`
+	AddNew           bool `json:"add_new"`
+	ValidatePackages bool `json:"validate_packages"`
 }
 
 // SyncResult represents sync results
@@ -46,6 +53,7 @@ type SyncResult struct {
 	Errors     []string        `json:"errors,omitempty"`
 	Added      int             `json:"added"`
 	Updated    int             `json:"updated"`
+	Classified int             `json:"classified"` // Servers whose inferred category or tags changed
 }
 
 // ServerSummary represents a server summary
@@ -55,6 +63,8 @@ type ServerSummary struct {
 	Version     string   `json:"version"`
 	RepoSource  string   `json:"repo_source"`
 	Types       []string `json:"types"` // Package registry types or remote types
+	Category    string   `json:"category,omitempty"` // Inferred category, when rules are configured
+	Tags        []string `json:"tags,omitempty"`     // Inferred tags, when rules are configured
 }
 
 // UpdateSummary represents an update summary
@@ -211,20 +221,41 @@ func (h *SyncHandler) performSync(ctx context.Context, req SyncRequest) (*SyncRe
 		existingMap[existingServers[i].Name] = &existingServers[i]
 	}
 
+	// Make sure every category and tag the rules can assign exists
+	if h.classifier != nil && !req.DryRun {
+		if err := h.ops.EnsureTaxonomy(ctx, h.classifier.Categories(), h.classifier.Tags()); err != nil {
+			return nil, fmt.Errorf("creating taxonomy from rules: %w", err)
+		}
+	}
+
 	// Process each official server (already filtered to latest versions only)
 	for _, officialServer := range officialServers {
 		existing, exists := existingMap[officialServer.Name]
 
+		// Skip servers whose packages fail registry verification
+		if req.ValidatePackages && h.needsSync(req, existing, &officialServer) {
+			if failures := h.verifyPackages(ctx, &officialServer); len(failures) > 0 {
+				result.Errors = append(result.Errors, fmt.Sprintf("Skipped %s: %s", officialServer.Name, strings.Join(failures, "; ")))
+				continue
+			}
+		}
+
 		if !exists {
 			// New server
 			if req.AddNew {
-				result.NewServers = append(result.NewServers, ServerSummary{
+				summary := ServerSummary{
 					Name:        officialServer.Name,
 					Description: officialServer.Description,
 					Version:     officialServer.VersionDetail.Version,
 					RepoSource:  officialServer.Repository.Source,
 					Types:       extractServerTypes(&officialServer),
-				})
+				}
+				if h.classifier != nil {
+					inferred := h.classifier.Classify(&officialServer)
+					summary.Category = inferred.Category
+					summary.Tags = inferred.Tags
+				}
+				result.NewServers = append(result.NewServers, summary)
 
 				if !req.DryRun {
 					// Add source metadata
@@ -235,6 +266,7 @@ func (h *SyncHandler) performSync(ctx context.Context, req SyncRequest) (*SyncRe
 						result.Errors = append(result.Errors, fmt.Sprintf("Failed to add %s: %v", officialServer.Name, err))
 					} else {
 						result.Added++
+						h.applyInferredTaxonomy(ctx, &officialServer, result)
 					}
 				}
 			}
@@ -258,6 +290,7 @@ func (h *SyncHandler) performSync(ctx context.Context, req SyncRequest) (*SyncRe
 						result.Errors = append(result.Errors, fmt.Sprintf("Failed to update %s: %v", officialServer.Name, err))
 					} else {
 						result.Updated++
+						h.applyInferredTaxonomy(ctx, &officialServer, result)
 					}
 				}
 			} else {
@@ -269,6 +302,38 @@ func (h *SyncHandler) performSync(ctx context.Context, req SyncRequest) (*SyncRe
 	return result, nil
 }
 
+// applyInferredTaxonomy classifies a synced server and stores the inferred category and tags
+func (h *SyncHandler) applyInferredTaxonomy(ctx context.Context, server *models.ServerDetail, result *SyncResult) {
+	if h.classifier == nil {
+		return
+	}
+
+	changed, err := h.ops.ApplyInferredTaxonomy(ctx, server.Name, h.classifier.Classify(server))
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Failed to classify %s: %v", server.Name, err))
+		return
+	}
+	if changed {
+		result.Classified++
+	}
+}
+
+// needsSync reports whether a sync request would add or update the given server
+func (h *SyncHandler) needsSync(req SyncRequest, existing *models.ServerDetail, official *models.ServerDetail) bool {
+	if existing == nil {
+		return req.AddNew
+	}
+	return req.UpdateExisting && h.shouldUpdate(existing, official)
+}
+
+// verifyPackages returns package verification failures for a server, if a verifier is configured
+func (h *SyncHandler) verifyPackages(ctx context.Context, server *models.ServerDetail) []string {
+	if h.verifier == nil {
+		return nil
+	}
+	return verifier.Failures(h.verifier.VerifyServer(ctx, server))
+}
+
 // buildPaginatedURL constructs a URL with pagination parameters
 func (h *SyncHandler) buildPaginatedURL(cursor string, limit int) string {
 	url := fmt.Sprintf("%s/v0/servers?limit=%d", h.officialRegistryURL, limit)
@@ -0,0 +1,357 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pluggedin/registry-admin/internal/db"
+	"github.com/pluggedin/registry-admin/internal/middleware"
+	"github.com/pluggedin/registry-admin/internal/models"
+)
+
+// maxSlugLength matches the slug columns of the taxonomy tables
+const maxSlugLength = 64
+
+var (
+	// validSlug matches lowercase, dash-separated slugs such as "developer-tools"
+	validSlug = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+	// slugSeparators matches runs of characters that are not allowed in a slug
+	slugSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// TaxonomyHandler handles the managed category taxonomy and tag vocabulary
+type TaxonomyHandler struct {
+	ops *db.Operations
+}
+
+// NewTaxonomyHandler creates a new taxonomy handler
+func NewTaxonomyHandler(ops *db.Operations) *TaxonomyHandler {
+	return &TaxonomyHandler{ops: ops}
+}
+
+// ListCategories handles GET /api/categories
+func (h *TaxonomyHandler) ListCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.ops.ListCategories(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to fetch categories", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"categories": categories})
+}
+
+// CreateCategory handles POST /api/categories
+func (h *TaxonomyHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var category models.Category
+	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" {
+		http.Error(w, "Category name is required", http.StatusBadRequest)
+		return
+	}
+	if category.Slug == "" {
+		category.Slug = slugify(category.Name)
+	}
+	if !isValidSlug(category.Slug) {
+		http.Error(w, "Invalid slug: use lowercase letters, digits and dashes", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.ops.CreateCategory(r.Context(), &category); err != nil {
+		if err.Error() == "category already exists" {
+			http.Error(w, "Category already exists", http.StatusConflict)
+		} else {
+			http.Error(w, "Failed to create category", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Log audit entry
+	h.ops.LogAuditEntry(r.Context(), &models.AuditLog{
+		User:    middleware.GetUserFromContext(r.Context()),
+		Action:  "CREATE_CATEGORY",
+		Details: "Created category: " + category.Slug,
+		IP:      r.RemoteAddr,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(category)
+}
+
+// UpdateCategory handles PUT /api/categories/{slug}
+func (h *TaxonomyHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	var category models.Category
+	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	category.Slug = mux.Vars(r)["slug"]
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" {
+		http.Error(w, "Category name is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.ops.UpdateCategory(r.Context(), &category); err != nil {
+		if err.Error() == "category not found" {
+			http.Error(w, "Category not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to update category", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Log audit entry
+	h.ops.LogAuditEntry(r.Context(), &models.AuditLog{
+		User:    middleware.GetUserFromContext(r.Context()),
+		Action:  "UPDATE_CATEGORY",
+		Details: "Updated category: " + category.Slug,
+		IP:      r.RemoteAddr,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(category)
+}
+
+// DeleteCategory handles DELETE /api/categories/{slug}
+func (h *TaxonomyHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+
+	if err := h.ops.DeleteCategory(r.Context(), slug); err != nil {
+		if err.Error() == "category not found" {
+			http.Error(w, "Category not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to delete category", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Log audit entry
+	h.ops.LogAuditEntry(r.Context(), &models.AuditLog{
+		User:    middleware.GetUserFromContext(r.Context()),
+		Action:  "DELETE_CATEGORY",
+		Details: "Deleted category: " + slug,
+		IP:      r.RemoteAddr,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListTags handles GET /api/tags
+func (h *TaxonomyHandler) ListTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.ops.ListTags(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to fetch tags", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"tags": tags})
+}
+
+// CreateTag handles POST /api/tags
+func (h *TaxonomyHandler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	var tag models.Tag
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tag.Name = strings.TrimSpace(tag.Name)
+	if tag.Name == "" {
+		http.Error(w, "Tag name is required", http.StatusBadRequest)
+		return
+	}
+	if tag.Slug == "" {
+		tag.Slug = slugify(tag.Name)
+	}
+	if !isValidSlug(tag.Slug) {
+		http.Error(w, "Invalid slug: use lowercase letters, digits and dashes", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.ops.CreateTag(r.Context(), &tag); err != nil {
+		if err.Error() == "tag already exists" {
+			http.Error(w, "Tag already exists", http.StatusConflict)
+		} else {
+			http.Error(w, "Failed to create tag", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Log audit entry
+	h.ops.LogAuditEntry(r.Context(), &models.AuditLog{
+		User:    middleware.GetUserFromContext(r.Context()),
+		Action:  "CREATE_TAG",
+		Details: "Created tag: " + tag.Slug,
+		IP:      r.RemoteAddr,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tag)
+}
+
+// DeleteTag handles DELETE /api/tags/{slug}
+func (h *TaxonomyHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+
+	if err := h.ops.DeleteTag(r.Context(), slug); err != nil {
+		if err.Error() == "tag not found" {
+			http.Error(w, "Tag not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to delete tag", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Log audit entry
+	h.ops.LogAuditEntry(r.Context(), &models.AuditLog{
+		User:    middleware.GetUserFromContext(r.Context()),
+		Action:  "DELETE_TAG",
+		Details: "Deleted tag: " + slug,
+		IP:      r.RemoteAddr,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetServerTaxonomy handles GET /api/servers/{id}/taxonomy
+func (h *TaxonomyHandler) GetServerTaxonomy(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if !h.serverExists(w, r, id) {
+		return
+	}
+
+	taxonomy, err := h.ops.GetServerTaxonomy(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Failed to fetch server taxonomy", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(taxonomy)
+}
+
+// SetServerCategory handles PUT /api/servers/{id}/category
+func (h *TaxonomyHandler) SetServerCategory(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var req struct {
+		Category string `json:"category"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if !h.serverExists(w, r, id) {
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	req.Category = strings.TrimSpace(req.Category)
+	if err := h.ops.SetServerCategory(r.Context(), id, req.Category, user); err != nil {
+		if err.Error() == "category not found" {
+			http.Error(w, "Unknown category: "+req.Category, http.StatusBadRequest)
+		} else {
+			http.Error(w, "Failed to set category", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Log audit entry
+	details := "Set category to: " + req.Category
+	if req.Category == "" {
+		details = "Cleared category"
+	}
+	h.ops.LogAuditEntry(r.Context(), &models.AuditLog{
+		User:     user,
+		Action:   "SET_CATEGORY",
+		ServerID: id,
+		Details:  details,
+		IP:       r.RemoteAddr,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"category": req.Category})
+}
+
+// SetServerTags handles PUT /api/servers/{id}/tags
+func (h *TaxonomyHandler) SetServerTags(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var req struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if !h.serverExists(w, r, id) {
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	tags := normalizeTags(req.Tags)
+	if err := h.ops.SetServerTags(r.Context(), id, tags, user); err != nil {
+		if strings.HasPrefix(err.Error(), "unknown tags") {
+			http.Error(w, "Unknown tags: "+strings.TrimPrefix(err.Error(), "unknown tags: "), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Failed to set tags", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Log audit entry
+	h.ops.LogAuditEntry(r.Context(), &models.AuditLog{
+		User:     user,
+		Action:   "SET_TAGS",
+		ServerID: id,
+		Details:  "Set tags to: " + strings.Join(tags, ", "),
+		IP:       r.RemoteAddr,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{"tags": tags})
+}
+
+// serverExists writes a 404 (or 500) response and returns false when the server does not exist
+func (h *TaxonomyHandler) serverExists(w http.ResponseWriter, r *http.Request, id string) bool {
+	exists, err := h.ops.ServerExists(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Failed to check server", http.StatusInternalServerError)
+		return false
+	}
+	if !exists {
+		http.Error(w, "Server not found", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// isValidSlug reports whether s can be used as a category or tag slug
+func isValidSlug(s string) bool {
+	return len(s) <= maxSlugLength && validSlug.MatchString(s)
+}
+
+// slugify derives a slug from a display name, e.g. "Developer Tools" -> "developer-tools"
+func slugify(name string) string {
+	slug := strings.Trim(slugSeparators.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(slug) > maxSlugLength {
+		slug = strings.TrimRight(slug[:maxSlugLength], "-")
+	}
+	return slug
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Developer Tools", "developer-tools"},
+		{"  AI / ML  ", "ai-ml"},
+		{"Databases & Storage!", "databases-storage"},
+		{"already-a-slug", "already-a-slug"},
+		{strings.Repeat("a", 70), strings.Repeat("a", 64)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := slugify(tt.name)
+			if got != tt.want {
+				t.Errorf("slugify(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+			if !isValidSlug(got) {
+				t.Errorf("slugify(%q) produced invalid slug %q", tt.name, got)
+			}
+		})
+	}
+}
+
+func TestIsValidSlug(t *testing.T) {
+	for _, slug := range []string{"", "Dev", "dev tools", "-dev", "dev-", "dev--tools", strings.Repeat("a", 65)} {
+		if isValidSlug(slug) {
+			t.Errorf("isValidSlug(%q) = true, want false", slug)
+		}
+	}
+}
@@ -10,6 +10,7 @@ type ServerStatus string
 const (
 	ServerStatusActive     ServerStatus = "active"
 	ServerStatusDeprecated ServerStatus = "deprecated"
+	ServerStatusDeleted    ServerStatus = "deleted"
 )
 
 // Repository represents a source code repository
@@ -17,6 +18,7 @@ type Repository struct {
 	URL    string `json:"url" bson:"url"`
 	Source string `json:"source" bson:"source"`
 	ID     string `json:"id" bson:"id"`
+	Topics []string `json:"topics,omitempty" bson:"topics,omitempty"`
 }
 
 // VersionDetail represents version information
@@ -59,6 +61,7 @@ type Server struct {
 	Status        ServerStatus  `json:"status,omitempty" bson:"status,omitempty"`
 	Repository    Repository    `json:"repository" bson:"repository"`
 	VersionDetail VersionDetail `json:"version_detail" bson:"version_detail"`
+	Pinned        bool          `json:"pinned,omitempty" bson:"pinned,omitempty"`
 }
 
 // ServerDetail represents detailed server information
@@ -74,6 +77,19 @@ type Remote struct {
 	URL           string `json:"url" bson:"url"`
 }
 
+// ServerStats represents rating and installation stats for a server
+type ServerStats struct {
+	Rating            float64 `json:"rating"`
+	RatingCount       int     `json:"rating_count"`
+	InstallationCount int     `json:"installation_count"`
+}
+
+// ExportedServer is a server as written by the catalog export
+type ExportedServer struct {
+	ServerDetail
+	Stats *ServerStats `json:"stats,omitempty"`
+}
+
 // AuditLog represents an audit log entry
 type AuditLog struct {
 	ID        string    `json:"id" bson:"_id"`
@@ -142,8 +158,155 @@ type ImportSummary struct {
 	Failed  int `json:"failed"`
 }
 
+// ConflictPolicy controls how an import treats servers that already exist
+type ConflictPolicy string
+
+const (
+	ConflictUpdate ConflictPolicy = "update"
+	ConflictSkip   ConflictPolicy = "skip"
+	ConflictFail   ConflictPolicy = "fail"
+)
+
+// ImportAction describes what an import did with a single server
+type ImportAction string
+
+const (
+	ImportActionCreated ImportAction = "created"
+	ImportActionUpdated ImportAction = "updated"
+	ImportActionSkipped ImportAction = "skipped"
+	ImportActionFailed  ImportAction = "failed"
+)
+
+// ImportLineResult is one NDJSON line of a streaming import response
+type ImportLineResult struct {
+	Line   int          `json:"line"`
+	Name   string       `json:"name,omitempty"`
+	ID     string       `json:"id,omitempty"`
+	Action ImportAction `json:"action"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// ImportStreamSummary is the final NDJSON line of a streaming import response
+type ImportStreamSummary struct {
+	Total     int    `json:"total"`
+	Created   int    `json:"created"`
+	Updated   int    `json:"updated"`
+	Skipped   int    `json:"skipped"`
+	Failed    int    `json:"failed"`
+	DryRun    bool   `json:"dry_run"`
+	Atomic    bool   `json:"atomic"`
+	Committed bool   `json:"committed"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Category is an entry of the managed category taxonomy
+type Category struct {
+	Slug        string    `json:"slug"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	ServerCount int       `json:"server_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Tag is an entry of the managed tag vocabulary
+type Tag struct {
+	Slug        string    `json:"slug"`
+	Name        string    `json:"name"`
+	ServerCount int       `json:"server_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ServerTaxonomy holds the category and tags assigned to a server
+type ServerTaxonomy struct {
+	Category string   `json:"category"`
+	Tags     []string `json:"tags"`
+}
+
+// BulkAction is an operation applied by a bulk update
+type BulkAction string
+
+const (
+	BulkActionSetStatus   BulkAction = "set_status"
+	BulkActionAddTags     BulkAction = "add_tags"
+	BulkActionRemoveTags  BulkAction = "remove_tags"
+	BulkActionSetCategory BulkAction = "set_category"
+	BulkActionSoftDelete  BulkAction = "soft_delete"
+	BulkActionPin         BulkAction = "pin"
+	BulkActionUnpin       BulkAction = "unpin"
+)
+
+// BulkFilter selects servers for a bulk update; the fields match the ListServers filters
+// plus a server name prefix
+type BulkFilter struct {
+	Status       string `json:"status,omitempty"`
+	RegistryName string `json:"registry_name,omitempty"`
+	Search       string `json:"search,omitempty"`
+	NamePrefix   string `json:"name_prefix,omitempty"`
+}
+
+// BulkRequest represents a bulk update of servers selected by name or by filter
+type BulkRequest struct {
+	Names    []string     `json:"names,omitempty"`
+	Filter   *BulkFilter  `json:"filter,omitempty"`
+	Action   BulkAction   `json:"action"`
+	Status   ServerStatus `json:"status,omitempty"`
+	Tags     []string     `json:"tags,omitempty"`
+	Category string       `json:"category,omitempty"`
+	DryRun   bool         `json:"dry_run"`
+}
+
+// BulkResponse represents the result of a bulk update
+type BulkResponse struct {
+	Action   BulkAction `json:"action"`
+	DryRun   bool       `json:"dry_run"`
+	Matched  int        `json:"matched"`
+	Changed  int        `json:"changed"`
+	Servers  []string   `json:"servers"`
+	NotFound []string   `json:"not_found,omitempty"`
+}
+
 // ValidationResponse represents schema validation result
 type ValidationResponse struct {
 	Valid  bool     `json:"valid"`
 	Errors []string `json:"errors,omitempty"`
-}
\ No newline at end of file
+}
+// ReviewStatus represents the moderation status of a review
+type ReviewStatus string
+
+const (
+	ReviewStatusVisible ReviewStatus = "visible"
+	ReviewStatusPending ReviewStatus = "pending"
+	ReviewStatusHidden  ReviewStatus = "hidden"
+)
+
+// Review represents a user review as seen by moderators
+type Review struct {
+	ServerID    string         `json:"server_id"`
+	UserID      string         `json:"user_id"`
+	Rating      int            `json:"rating"`
+	Comment     string         `json:"comment,omitempty"`
+	Status      ReviewStatus   `json:"status"`
+	FlagReason  string         `json:"flag_reason,omitempty"` // Why the comment filter held the review
+	OpenReports int            `json:"open_reports"`
+	Reports     []ReviewReport `json:"reports"`
+	ModeratedBy string         `json:"moderated_by,omitempty"`
+	ModeratedAt *time.Time     `json:"moderated_at,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// ReviewReport represents an unresolved abuse report against a review
+type ReviewReport struct {
+	ReporterID string    `json:"reporter_id"`
+	Reason     string    `json:"reason,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// BannedUser represents a user banned from submitting reviews
+type BannedUser struct {
+	UserID   string    `json:"user_id"`
+	Reason   string    `json:"reason,omitempty"`
+	BannedBy string    `json:"banned_by,omitempty"`
+	BannedAt time.Time `json:"banned_at"`
+}
@@ -0,0 +1,260 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pluggedin/registry-admin/internal/models"
+)
+
+// manifestAcceptHeader lists the manifest media types we can read
+var manifestAcceptHeader = strings.Join([]string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}, ", ")
+
+// ociManifest covers both image indexes and single-platform manifests
+type ociManifest struct {
+	MediaType string `json:"mediaType"`
+	Config    struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+	Manifests []struct {
+		Digest   string `json:"digest"`
+		Platform struct {
+			Architecture string `json:"architecture"`
+			OS           string `json:"os"`
+		} `json:"platform"`
+	} `json:"manifests"`
+}
+
+// ociImageConfig is the subset of the image config blob we need
+type ociImageConfig struct {
+	Config struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"config"`
+}
+
+// verifyOCI checks that an image tag exists and carries the server name label
+func (v *PackageVerifier) verifyOCI(ctx context.Context, serverName string, pkg models.Package) (bool, bool, error) {
+	ref := parseOCIReference(pkg.Name, pkg.Version)
+
+	// Docker Hub is reached through the configured base URL, other registries directly
+	baseURL := v.cfg.OCIBaseURL
+	if ref.registry != "" {
+		baseURL = "https://" + ref.registry
+	}
+	oci := &ociSession{verifier: v, baseURL: baseURL, repo: ref.repo}
+
+	manifest, err := oci.manifest(ctx, ref.reference)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			return false, false, nil
+		}
+		return false, false, fmt.Errorf("OCI registry request failed: %w", err)
+	}
+
+	// Resolve image indexes to a single platform manifest, preferring linux/amd64
+	if len(manifest.Manifests) > 0 {
+		digest := manifest.Manifests[0].Digest
+		for _, m := range manifest.Manifests {
+			if m.Platform.OS == "linux" && m.Platform.Architecture == "amd64" {
+				digest = m.Digest
+				break
+			}
+		}
+		manifest, err = oci.manifest(ctx, digest)
+		if err != nil {
+			return true, false, fmt.Errorf("OCI registry request failed: %w", err)
+		}
+	}
+
+	if manifest.Config.Digest == "" {
+		return true, false, nil
+	}
+
+	var config ociImageConfig
+	if err := oci.getJSON(ctx, fmt.Sprintf("/v2/%s/blobs/%s", ref.repo, manifest.Config.Digest), "", &config); err != nil {
+		return true, false, fmt.Errorf("OCI config request failed: %w", err)
+	}
+
+	return true, serverName != "" && config.Config.Labels[OCIServerNameLabel] == serverName, nil
+}
+
+// dockerHubHosts name Docker Hub in image identifiers
+var dockerHubHosts = map[string]bool{
+	"docker.io":            true,
+	"index.docker.io":      true,
+	"registry-1.docker.io": true,
+}
+
+// ociReference is an image identifier split into its parts
+type ociReference struct {
+	registry  string // Registry host, empty for Docker Hub
+	repo      string // Repository path within the registry
+	reference string // Tag or digest
+}
+
+// parseOCIReference splits an image identifier such as "ghcr.io/owner/image:1.0.0" or
+// "owner/image@sha256:..." into its registry, repository and tag or digest. A digest in
+// the identifier wins over the version; otherwise the version wins over a tag.
+func parseOCIReference(identifier, version string) ociReference {
+	var ref ociReference
+	name := identifier
+
+	// The first path component is a registry host when it looks like one, as in Docker
+	if host, rest, ok := strings.Cut(name, "/"); ok && (strings.ContainsAny(host, ".:") || host == "localhost") {
+		if !dockerHubHosts[host] {
+			ref.registry = host
+		}
+		name = rest
+	}
+
+	name, digest, hasDigest := strings.Cut(name, "@")
+	tag := ""
+	if idx := strings.LastIndex(name, ":"); idx > strings.LastIndex(name, "/") {
+		tag = name[idx+1:]
+		name = name[:idx]
+	}
+
+	// Official Docker Hub images live under the library namespace
+	if ref.registry == "" && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	ref.repo = name
+
+	switch {
+	case hasDigest:
+		ref.reference = digest
+	case version != "":
+		ref.reference = version
+	case tag != "":
+		ref.reference = tag
+	default:
+		ref.reference = "latest"
+	}
+
+	return ref
+}
+
+// ociSession performs registry requests for one repository, handling bearer token challenges
+type ociSession struct {
+	verifier *PackageVerifier
+	baseURL  string
+	repo     string
+	token    string
+}
+
+// manifest fetches a manifest by tag or digest
+func (s *ociSession) manifest(ctx context.Context, reference string) (*ociManifest, error) {
+	var manifest ociManifest
+	if err := s.getJSON(ctx, fmt.Sprintf("/v2/%s/manifests/%s", s.repo, reference), manifestAcceptHeader, &manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// getJSON fetches a registry path, retrying once with a bearer token when challenged
+func (s *ociSession) getJSON(ctx context.Context, path, accept string, dest interface{}) error {
+	endpoint := s.baseURL + path
+
+	resp, err := s.do(ctx, endpoint, accept)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && s.token == "" {
+		challenge := resp.Header.Get("Www-Authenticate")
+		resp.Body.Close()
+
+		if err := s.authenticate(ctx, challenge); err != nil {
+			return err
+		}
+		if resp, err = s.do(ctx, endpoint, accept); err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 10<<20)).Decode(dest); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// do sends a registry request with the session token, if any
+func (s *ociSession) do(ctx context.Context, endpoint, accept string) (*http.Response, error) {
+	header := http.Header{}
+	if accept != "" {
+		header.Set("Accept", accept)
+	}
+	if s.token != "" {
+		header.Set("Authorization", "Bearer "+s.token)
+	}
+	return s.verifier.do(ctx, endpoint, header)
+}
+
+// authenticate obtains an anonymous pull token from the realm named in a Bearer challenge
+func (s *ociSession) authenticate(ctx context.Context, challenge string) error {
+	params := parseBearerChallenge(challenge)
+	realm := params["realm"]
+	if realm == "" {
+		return fmt.Errorf("registry requires authentication but sent no bearer challenge")
+	}
+
+	query := url.Values{}
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	// Only pull access is needed, so the scope is built locally rather than parsed from the challenge
+	query.Set("scope", fmt.Sprintf("repository:%s:pull", s.repo))
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := s.verifier.getJSON(ctx, realm+"?"+query.Encode(), nil, &token); err != nil {
+		return fmt.Errorf("failed to obtain registry token: %w", err)
+	}
+
+	s.token = token.Token
+	if s.token == "" {
+		s.token = token.AccessToken
+	}
+	if s.token == "" {
+		return fmt.Errorf("registry token response did not include a token")
+	}
+	return nil
+}
+
+// parseBearerChallenge parses a `Bearer realm="...",service="..."` header into its parameters
+func parseBearerChallenge(header string) map[string]string {
+	params := make(map[string]string)
+	if !strings.HasPrefix(strings.ToLower(header), "bearer ") {
+		return params
+	}
+
+	for _, part := range strings.Split(header[len("bearer "):], ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		params[strings.ToLower(key)] = strings.Trim(value, `"`)
+	}
+	return params
+}
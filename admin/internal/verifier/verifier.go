@@ -0,0 +1,386 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pluggedin/registry-admin/internal/models"
+)
+
+const (
+	// DefaultNPMBaseURL is the public npm registry
+	DefaultNPMBaseURL = "https://registry.npmjs.org"
+	// DefaultPyPIBaseURL is the public Python Package Index
+	DefaultPyPIBaseURL = "https://pypi.org"
+	// DefaultOCIBaseURL is the Docker Hub registry API
+	DefaultOCIBaseURL = "https://registry-1.docker.io"
+	// DefaultNuGetBaseURL is the public NuGet gallery
+	DefaultNuGetBaseURL = "https://api.nuget.org"
+
+	// OCIServerNameLabel is the image label the MCP registry uses as ownership proof
+	OCIServerNameLabel = "io.modelcontextprotocol.server.name"
+
+	// mcpNameMarker is the README marker PyPI and NuGet packages use as ownership proof
+	mcpNameMarker = "mcp-name:"
+)
+
+// errNotFound is returned by fetch helpers when the registry answers 404
+var errNotFound = errors.New("not found")
+
+// Config holds the registry endpoints and cache settings used by the verifier
+type Config struct {
+	NPMBaseURL   string
+	PyPIBaseURL  string
+	OCIBaseURL   string
+	NuGetBaseURL string
+	CacheTTL     time.Duration
+	Timeout      time.Duration
+}
+
+// ConfigFromEnv builds a Config from environment variables, falling back to the public registries
+func ConfigFromEnv() Config {
+	cfg := Config{
+		NPMBaseURL:   envOrDefault("NPM_REGISTRY_URL", DefaultNPMBaseURL),
+		PyPIBaseURL:  envOrDefault("PYPI_REGISTRY_URL", DefaultPyPIBaseURL),
+		OCIBaseURL:   envOrDefault("OCI_REGISTRY_URL", DefaultOCIBaseURL),
+		NuGetBaseURL: envOrDefault("NUGET_REGISTRY_URL", DefaultNuGetBaseURL),
+		CacheTTL:     time.Hour,
+		Timeout:      15 * time.Second,
+	}
+
+	if ttl := os.Getenv("PACKAGE_VERIFY_CACHE_TTL"); ttl != "" {
+		if d, err := time.ParseDuration(ttl); err == nil {
+			cfg.CacheTTL = d
+		}
+	}
+
+	return cfg
+}
+
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// Result describes the verification outcome for a single package
+type Result struct {
+	RegistryType      string `json:"registry_type"`
+	Identifier        string `json:"identifier"`
+	Version           string `json:"version"`
+	Exists            bool   `json:"exists"`
+	OwnershipVerified bool   `json:"ownership_verified"`
+	Skipped           bool   `json:"skipped,omitempty"`
+	Error             string `json:"error,omitempty"`
+}
+
+// OK reports whether the package passed verification
+func (r Result) OK() bool {
+	return r.Skipped || (r.Exists && r.OwnershipVerified && r.Error == "")
+}
+
+// Message returns a human readable description of a failed result
+func (r Result) Message() string {
+	ref := fmt.Sprintf("%s package %s@%s", r.RegistryType, r.Identifier, r.Version)
+	switch {
+	case r.Error != "":
+		return fmt.Sprintf("%s: %s", ref, r.Error)
+	case !r.Exists:
+		return fmt.Sprintf("%s does not exist", ref)
+	case !r.OwnershipVerified:
+		return fmt.Sprintf("%s is missing the ownership proof for this server", ref)
+	default:
+		return ref
+	}
+}
+
+// Failures returns the messages of all failed results
+func Failures(results []Result) []string {
+	var messages []string
+	for _, result := range results {
+		if !result.OK() {
+			messages = append(messages, result.Message())
+		}
+	}
+	return messages
+}
+
+// cacheEntry holds a cached result and its expiry
+type cacheEntry struct {
+	result    Result
+	expiresAt time.Time
+}
+
+// PackageVerifier checks that packages exist in their registries and carry the ownership proof
+type PackageVerifier struct {
+	cfg        Config
+	httpClient *http.Client
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+// NewPackageVerifier creates a new package verifier
+func NewPackageVerifier(cfg Config) *PackageVerifier {
+	if cfg.NPMBaseURL == "" {
+		cfg.NPMBaseURL = DefaultNPMBaseURL
+	}
+	if cfg.PyPIBaseURL == "" {
+		cfg.PyPIBaseURL = DefaultPyPIBaseURL
+	}
+	if cfg.OCIBaseURL == "" {
+		cfg.OCIBaseURL = DefaultOCIBaseURL
+	}
+	if cfg.NuGetBaseURL == "" {
+		cfg.NuGetBaseURL = DefaultNuGetBaseURL
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 15 * time.Second
+	}
+
+	cfg.NPMBaseURL = strings.TrimRight(cfg.NPMBaseURL, "/")
+	cfg.PyPIBaseURL = strings.TrimRight(cfg.PyPIBaseURL, "/")
+	cfg.OCIBaseURL = strings.TrimRight(cfg.OCIBaseURL, "/")
+	cfg.NuGetBaseURL = strings.TrimRight(cfg.NuGetBaseURL, "/")
+
+	return &PackageVerifier{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+		cache:      make(map[string]cacheEntry),
+	}
+}
+
+// VerifyServer verifies every package of a server
+func (v *PackageVerifier) VerifyServer(ctx context.Context, server *models.ServerDetail) []Result {
+	results := make([]Result, 0, len(server.Packages))
+	for _, pkg := range server.Packages {
+		results = append(results, v.VerifyPackage(ctx, server.Name, pkg))
+	}
+	return results
+}
+
+// VerifyPackage checks that a package version exists and that it declares serverName as its owner
+func (v *PackageVerifier) VerifyPackage(ctx context.Context, serverName string, pkg models.Package) Result {
+	result := Result{
+		RegistryType: pkg.RegistryName,
+		Identifier:   pkg.Name,
+		Version:      pkg.Version,
+	}
+
+	if pkg.Name == "" || pkg.Version == "" {
+		result.Error = "identifier and version are required"
+		return result
+	}
+
+	key := strings.Join([]string{pkg.RegistryName, pkg.Name, pkg.Version, serverName}, "|")
+	if cached, ok := v.getCached(key); ok {
+		return cached
+	}
+
+	var err error
+	switch strings.ToLower(pkg.RegistryName) {
+	case "npm":
+		result.Exists, result.OwnershipVerified, err = v.verifyNPM(ctx, serverName, pkg)
+	case "pypi":
+		result.Exists, result.OwnershipVerified, err = v.verifyPyPI(ctx, serverName, pkg)
+	case "oci", "docker":
+		result.Exists, result.OwnershipVerified, err = v.verifyOCI(ctx, serverName, pkg)
+	case "nuget":
+		result.Exists, result.OwnershipVerified, err = v.verifyNuGet(ctx, serverName, pkg)
+	default:
+		result.Skipped = true
+		return result
+	}
+
+	if err != nil {
+		// Transient failures are reported but never cached
+		result.Error = err.Error()
+		return result
+	}
+
+	v.setCached(key, result)
+	return result
+}
+
+// getCached returns a cached result if it has not expired
+func (v *PackageVerifier) getCached(key string) (Result, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	entry, ok := v.cache[key]
+	if !ok {
+		return Result{}, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(v.cache, key)
+		return Result{}, false
+	}
+	return entry.result, true
+}
+
+// setCached stores a result for the configured TTL
+func (v *PackageVerifier) setCached(key string, result Result) {
+	if v.cfg.CacheTTL <= 0 {
+		return
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.cache[key] = cacheEntry{result: result, expiresAt: time.Now().Add(v.cfg.CacheTTL)}
+}
+
+// verifyNPM checks the npm version manifest and its mcpName field
+func (v *PackageVerifier) verifyNPM(ctx context.Context, serverName string, pkg models.Package) (bool, bool, error) {
+	endpoint := fmt.Sprintf("%s/%s/%s", v.cfg.NPMBaseURL, escapeNPMName(pkg.Name), url.PathEscape(pkg.Version))
+
+	var manifest struct {
+		MCPName string `json:"mcpName"`
+	}
+	if err := v.getJSON(ctx, endpoint, nil, &manifest); err != nil {
+		if errors.Is(err, errNotFound) {
+			return false, false, nil
+		}
+		return false, false, fmt.Errorf("npm registry request failed: %w", err)
+	}
+
+	return true, serverName != "" && manifest.MCPName == serverName, nil
+}
+
+// verifyPyPI checks the PyPI release and the mcp-name marker in its description
+func (v *PackageVerifier) verifyPyPI(ctx context.Context, serverName string, pkg models.Package) (bool, bool, error) {
+	endpoint := fmt.Sprintf("%s/pypi/%s/%s/json", v.cfg.PyPIBaseURL, url.PathEscape(pkg.Name), url.PathEscape(pkg.Version))
+
+	var release struct {
+		Info struct {
+			Description string `json:"description"`
+		} `json:"info"`
+	}
+	if err := v.getJSON(ctx, endpoint, nil, &release); err != nil {
+		if errors.Is(err, errNotFound) {
+			return false, false, nil
+		}
+		return false, false, fmt.Errorf("PyPI request failed: %w", err)
+	}
+
+	return true, hasMCPNameMarker(release.Info.Description, serverName), nil
+}
+
+// verifyNuGet checks the NuGet version list and the mcp-name marker in the package README
+func (v *PackageVerifier) verifyNuGet(ctx context.Context, serverName string, pkg models.Package) (bool, bool, error) {
+	id := strings.ToLower(pkg.Name)
+	version := strings.ToLower(pkg.Version)
+
+	var index struct {
+		Versions []string `json:"versions"`
+	}
+	endpoint := fmt.Sprintf("%s/v3-flatcontainer/%s/index.json", v.cfg.NuGetBaseURL, url.PathEscape(id))
+	if err := v.getJSON(ctx, endpoint, nil, &index); err != nil {
+		if errors.Is(err, errNotFound) {
+			return false, false, nil
+		}
+		return false, false, fmt.Errorf("NuGet request failed: %w", err)
+	}
+
+	found := false
+	for _, candidate := range index.Versions {
+		if strings.EqualFold(candidate, version) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return false, false, nil
+	}
+
+	readmeURL := fmt.Sprintf("%s/v3-flatcontainer/%s/%s/readme", v.cfg.NuGetBaseURL, url.PathEscape(id), url.PathEscape(version))
+	readme, err := v.get(ctx, readmeURL, nil)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			// Package exists but ships no README, so there is no ownership proof
+			return true, false, nil
+		}
+		return true, false, fmt.Errorf("NuGet README request failed: %w", err)
+	}
+
+	return true, hasMCPNameMarker(string(readme), serverName), nil
+}
+
+// hasMCPNameMarker reports whether text contains "mcp-name: <serverName>"
+func hasMCPNameMarker(text, serverName string) bool {
+	if serverName == "" {
+		return false
+	}
+	for _, line := range strings.Split(text, "\n") {
+		idx := strings.Index(line, mcpNameMarker)
+		if idx < 0 {
+			continue
+		}
+		fields := strings.Fields(line[idx+len(mcpNameMarker):])
+		if len(fields) > 0 && fields[0] == serverName {
+			return true
+		}
+	}
+	return false
+}
+
+// escapeNPMName escapes an npm package name, keeping the scope separator readable for the registry
+func escapeNPMName(name string) string {
+	if strings.HasPrefix(name, "@") {
+		return "@" + url.PathEscape(strings.TrimPrefix(name, "@"))
+	}
+	return url.PathEscape(name)
+}
+
+// get performs a GET request and returns the body, mapping 404 to errNotFound
+func (v *PackageVerifier) get(ctx context.Context, endpoint string, header http.Header) ([]byte, error) {
+	resp, err := v.do(ctx, endpoint, header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+}
+
+// getJSON performs a GET request and decodes the JSON body into dest
+func (v *PackageVerifier) getJSON(ctx context.Context, endpoint string, header http.Header, dest interface{}) error {
+	body, err := v.get(ctx, endpoint, header)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, dest); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// do sends a GET request with the given headers
+func (v *PackageVerifier) do(ctx context.Context, endpoint string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	return v.httpClient.Do(req)
+}
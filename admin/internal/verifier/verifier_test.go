@@ -0,0 +1,291 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pluggedin/registry-admin/internal/models"
+)
+
+const testServerName = "io.github.example/weather"
+
+// newStandInRegistry returns a local server that mimics the npm, PyPI, NuGet and OCI APIs
+func newStandInRegistry(t *testing.T, hits *int32) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+
+	// npm (scoped names arrive with an encoded slash)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/@example%2Fweather/1.0.0" {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(hits, 1)
+		json.NewEncoder(w).Encode(map[string]string{"name": "@example/weather", "mcpName": testServerName})
+	})
+	mux.HandleFunc("/unowned/1.0.0", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"name": "unowned"})
+	})
+
+	// PyPI
+	mux.HandleFunc("/pypi/weather-mcp/0.2.0/json", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"info": map[string]string{"description": "# Weather\n\n<!-- mcp-name: " + testServerName + " -->\n"},
+		})
+	})
+
+	// NuGet
+	mux.HandleFunc("/v3-flatcontainer/example.weather/index.json", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string][]string{"versions": {"1.0.0", "1.1.0"}})
+	})
+	mux.HandleFunc("/v3-flatcontainer/example.weather/1.1.0/readme", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("mcp-name: " + testServerName))
+	})
+
+	// OCI registry with a bearer token challenge
+	var registryURL string
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("scope") != "repository:example/weather:pull" {
+			http.Error(w, "bad scope", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"token": "pull-token"})
+	})
+	authorized := func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("Authorization") != "Bearer pull-token" {
+			w.Header().Set("Www-Authenticate", `Bearer realm="`+registryURL+`/token",service="registry.test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return false
+		}
+		return true
+	}
+	mux.HandleFunc("/v2/example/weather/manifests/1.0.0", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(w, r) {
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"mediaType": "application/vnd.oci.image.index.v1+json",
+			"manifests": []map[string]interface{}{
+				{"digest": "sha256:arm", "platform": map[string]string{"os": "linux", "architecture": "arm64"}},
+				{"digest": "sha256:amd", "platform": map[string]string{"os": "linux", "architecture": "amd64"}},
+			},
+		})
+	})
+	mux.HandleFunc("/v2/example/weather/manifests/sha256:amd", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(w, r) {
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"mediaType": "application/vnd.oci.image.manifest.v1+json",
+			"config":    map[string]string{"digest": "sha256:config"},
+		})
+	})
+	mux.HandleFunc("/v2/example/weather/blobs/sha256:config", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(w, r) {
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"config": map[string]interface{}{"Labels": map[string]string{OCIServerNameLabel: testServerName}},
+		})
+	})
+
+	server := httptest.NewServer(mux)
+	registryURL = server.URL
+	return server
+}
+
+func newTestVerifier(baseURL string) *PackageVerifier {
+	return NewPackageVerifier(Config{
+		NPMBaseURL:   baseURL,
+		PyPIBaseURL:  baseURL,
+		OCIBaseURL:   baseURL,
+		NuGetBaseURL: baseURL,
+		CacheTTL:     time.Minute,
+	})
+}
+
+func TestVerifyPackage(t *testing.T) {
+	var hits int32
+	server := newStandInRegistry(t, &hits)
+	defer server.Close()
+
+	v := newTestVerifier(server.URL)
+
+	tests := []struct {
+		name          string
+		pkg           models.Package
+		wantExists    bool
+		wantOwnership bool
+		wantOK        bool
+	}{
+		{
+			name:          "npm package with mcpName",
+			pkg:           models.Package{RegistryName: "npm", Name: "@example/weather", Version: "1.0.0"},
+			wantExists:    true,
+			wantOwnership: true,
+			wantOK:        true,
+		},
+		{
+			name:       "npm package without mcpName",
+			pkg:        models.Package{RegistryName: "npm", Name: "unowned", Version: "1.0.0"},
+			wantExists: true,
+		},
+		{
+			name: "npm version that does not exist",
+			pkg:  models.Package{RegistryName: "npm", Name: "@example/weather", Version: "9.9.9"},
+		},
+		{
+			name:          "pypi package with mcp-name marker",
+			pkg:           models.Package{RegistryName: "pypi", Name: "weather-mcp", Version: "0.2.0"},
+			wantExists:    true,
+			wantOwnership: true,
+			wantOK:        true,
+		},
+		{
+			name:          "nuget package with readme marker",
+			pkg:           models.Package{RegistryName: "nuget", Name: "Example.Weather", Version: "1.1.0"},
+			wantExists:    true,
+			wantOwnership: true,
+			wantOK:        true,
+		},
+		{
+			name: "nuget version that does not exist",
+			pkg:  models.Package{RegistryName: "nuget", Name: "Example.Weather", Version: "2.0.0"},
+		},
+		{
+			name:          "oci image with server name label",
+			pkg:           models.Package{RegistryName: "oci", Name: "docker.io/example/weather", Version: "1.0.0"},
+			wantExists:    true,
+			wantOwnership: true,
+			wantOK:        true,
+		},
+		{
+			name: "oci tag that does not exist",
+			pkg:  models.Package{RegistryName: "docker", Name: "example/weather", Version: "0.0.1"},
+		},
+		{
+			name:   "unsupported registry is skipped",
+			pkg:    models.Package{RegistryName: "mcpb", Name: "https://example.com/weather.mcpb", Version: "1.0.0"},
+			wantOK: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := v.VerifyPackage(context.Background(), testServerName, tt.pkg)
+			if result.Exists != tt.wantExists {
+				t.Errorf("Exists = %v, want %v (error: %q)", result.Exists, tt.wantExists, result.Error)
+			}
+			if result.OwnershipVerified != tt.wantOwnership {
+				t.Errorf("OwnershipVerified = %v, want %v", result.OwnershipVerified, tt.wantOwnership)
+			}
+			if result.OK() != tt.wantOK {
+				t.Errorf("OK() = %v, want %v (%s)", result.OK(), tt.wantOK, result.Message())
+			}
+		})
+	}
+}
+
+func TestVerifyPackageCachesResults(t *testing.T) {
+	var hits int32
+	server := newStandInRegistry(t, &hits)
+	defer server.Close()
+
+	v := newTestVerifier(server.URL)
+	pkg := models.Package{RegistryName: "npm", Name: "@example/weather", Version: "1.0.0"}
+
+	for i := 0; i < 3; i++ {
+		if result := v.VerifyPackage(context.Background(), testServerName, pkg); !result.OK() {
+			t.Fatalf("VerifyPackage() failed: %s", result.Message())
+		}
+	}
+
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("registry was queried %d times, want 1", got)
+	}
+}
+
+func TestVerifyPackageDoesNotCacheTransientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	v := newTestVerifier(server.URL)
+	pkg := models.Package{RegistryName: "npm", Name: "weather", Version: "1.0.0"}
+
+	for i := 0; i < 2; i++ {
+		result := v.VerifyPackage(context.Background(), testServerName, pkg)
+		if result.Error == "" {
+			t.Fatal("expected an error for an unavailable registry")
+		}
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("registry was queried %d times, want 2", got)
+	}
+}
+
+func TestVerifyOCIOtherRegistry(t *testing.T) {
+	var hosts []string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts = append(hosts, r.Host)
+		switch r.URL.Path {
+		case "/v2/owner/weather/manifests/sha256:abc":
+			json.NewEncoder(w).Encode(map[string]interface{}{"config": map[string]string{"digest": "sha256:config"}})
+		case "/v2/owner/weather/blobs/sha256:config":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"config": map[string]interface{}{"Labels": map[string]string{OCIServerNameLabel: testServerName}},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	// Docker Hub requests would fail: only the image's own registry is reachable
+	v := newTestVerifier("https://hub.invalid")
+	v.httpClient = server.Client()
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	pkg := models.Package{RegistryName: "oci", Name: host + "/owner/weather@sha256:abc", Version: "1.0.0"}
+	if result := v.VerifyPackage(context.Background(), testServerName, pkg); !result.OK() {
+		t.Fatalf("VerifyPackage() failed: %s", result.Message())
+	}
+	if len(hosts) != 2 || hosts[0] != host {
+		t.Errorf("requests went to %v, want 2 to %s", hosts, host)
+	}
+}
+
+func TestParseOCIReference(t *testing.T) {
+	tests := []struct {
+		identifier string
+		version    string
+		want       ociReference
+	}{
+		{"docker.io/example/weather", "1.0.0", ociReference{"", "example/weather", "1.0.0"}},
+		{"example/weather:2.0.0", "", ociReference{"", "example/weather", "2.0.0"}},
+		{"alpine", "", ociReference{"", "library/alpine", "latest"}},
+		{"localhost:5000/weather", "1.0.0", ociReference{"localhost:5000", "weather", "1.0.0"}},
+		{"ghcr.io/owner/weather:1.0.0", "1.2.0", ociReference{"ghcr.io", "owner/weather", "1.2.0"}},
+		{"example/weather@sha256:abc", "1.0.0", ociReference{"", "example/weather", "sha256:abc"}},
+		{"ghcr.io/owner/weather:1.0.0@sha256:abc", "", ociReference{"ghcr.io", "owner/weather", "sha256:abc"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.identifier, func(t *testing.T) {
+			if got := parseOCIReference(tt.identifier, tt.version); got != tt.want {
+				t.Errorf("parseOCIReference(%q, %q) = %+v, want %+v", tt.identifier, tt.version, got, tt.want)
+			}
+		})
+	}
+}
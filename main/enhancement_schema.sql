@@ -48,17 +48,288 @@ CREATE TABLE IF NOT EXISTS collections (
 -- Servers in collections
 CREATE TABLE IF NOT EXISTS collection_servers (
   collection_id UUID REFERENCES collections(id) ON DELETE CASCADE,
-  server_id UUID NOT NULL,
+  server_id TEXT NOT NULL,
   added_at TIMESTAMP DEFAULT NOW(),
   PRIMARY KEY (collection_id, server_id)
 );
 
+-- Managed category taxonomy
+CREATE TABLE IF NOT EXISTS proxy_categories (
+  slug VARCHAR(64) PRIMARY KEY,
+  name VARCHAR(255) NOT NULL,
+  description TEXT,
+  created_at TIMESTAMP DEFAULT NOW(),
+  updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Managed tag vocabulary
+CREATE TABLE IF NOT EXISTS proxy_tags (
+  slug VARCHAR(64) PRIMARY KEY,
+  name VARCHAR(255) NOT NULL,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Category assigned to each server (at most one)
+CREATE TABLE IF NOT EXISTS proxy_server_categories (
+  server_id TEXT PRIMARY KEY,
+  category_slug VARCHAR(64) NOT NULL REFERENCES proxy_categories(slug) ON DELETE CASCADE,
+  source VARCHAR(20) NOT NULL DEFAULT 'manual', -- 'manual' or 'inferred'
+  assigned_by VARCHAR(255),
+  assigned_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Tags assigned to each server
+CREATE TABLE IF NOT EXISTS proxy_server_tags (
+  server_id TEXT NOT NULL,
+  tag_slug VARCHAR(64) NOT NULL REFERENCES proxy_tags(slug) ON DELETE CASCADE,
+  source VARCHAR(20) NOT NULL DEFAULT 'manual', -- 'manual' or 'inferred'
+  assigned_by VARCHAR(255),
+  assigned_at TIMESTAMP DEFAULT NOW(),
+  PRIMARY KEY (server_id, tag_slug)
+);
+
+-- Assignment source for installations created before rule-based inference
+ALTER TABLE proxy_server_categories ADD COLUMN IF NOT EXISTS source VARCHAR(20) NOT NULL DEFAULT 'manual';
+ALTER TABLE proxy_server_tags ADD COLUMN IF NOT EXISTS source VARCHAR(20) NOT NULL DEFAULT 'manual';
+
+-- Review moderation: only 'visible' reviews are published and counted in stats;
+-- 'pending' reviews await moderation and 'hidden' reviews were removed by a moderator
+ALTER TABLE proxy_user_ratings ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'visible'
+  CHECK (status IN ('visible', 'pending', 'hidden'));
+ALTER TABLE proxy_user_ratings ADD COLUMN IF NOT EXISTS moderated_by VARCHAR(255);
+ALTER TABLE proxy_user_ratings ADD COLUMN IF NOT EXISTS moderated_at TIMESTAMP;
+-- Comment filter: why a comment was held, and a normalized hash for duplicate detection
+ALTER TABLE proxy_user_ratings ADD COLUMN IF NOT EXISTS moderation_reason TEXT;
+ALTER TABLE proxy_user_ratings ADD COLUMN IF NOT EXISTS comment_fingerprint VARCHAR(64);
+
+-- Abuse reports against reviews, one per reporter and review
+CREATE TABLE IF NOT EXISTS proxy_review_reports (
+  server_id TEXT NOT NULL,
+  user_id VARCHAR(255) NOT NULL,
+  reporter_id VARCHAR(255) NOT NULL,
+  reason TEXT,
+  created_at TIMESTAMP DEFAULT NOW(),
+  resolved_at TIMESTAMP,
+  PRIMARY KEY (server_id, user_id, reporter_id),
+  FOREIGN KEY (server_id, user_id) REFERENCES proxy_user_ratings(server_id, user_id) ON DELETE CASCADE
+);
+
+-- Helpful/unhelpful votes on reviews, one per voter and review
+CREATE TABLE IF NOT EXISTS proxy_review_votes (
+  server_id TEXT NOT NULL,
+  user_id VARCHAR(255) NOT NULL, -- Review author
+  voter_id VARCHAR(255) NOT NULL,
+  helpful BOOLEAN NOT NULL,
+  created_at TIMESTAMP DEFAULT NOW(),
+  updated_at TIMESTAMP DEFAULT NOW(),
+  PRIMARY KEY (server_id, user_id, voter_id),
+  FOREIGN KEY (server_id, user_id) REFERENCES proxy_user_ratings(server_id, user_id) ON DELETE CASCADE
+);
+
+-- Vote totals kept on the review for sorting by helpfulness
+ALTER TABLE proxy_user_ratings ADD COLUMN IF NOT EXISTS helpful_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE proxy_user_ratings ADD COLUMN IF NOT EXISTS unhelpful_count INTEGER NOT NULL DEFAULT 0;
+
+-- Public responses by the server's publisher, one per review
+CREATE TABLE IF NOT EXISTS proxy_review_responses (
+  server_id TEXT NOT NULL,
+  user_id VARCHAR(255) NOT NULL, -- Review author
+  responder VARCHAR(255) NOT NULL, -- GitHub login of the publisher
+  body TEXT NOT NULL,
+  created_at TIMESTAMP DEFAULT NOW(),
+  updated_at TIMESTAMP DEFAULT NOW(),
+  PRIMARY KEY (server_id, user_id),
+  FOREIGN KEY (server_id, user_id) REFERENCES proxy_user_ratings(server_id, user_id) ON DELETE CASCADE
+);
+
+-- Audit trail of publisher responses; kept after the response or review is deleted
+CREATE TABLE IF NOT EXISTS proxy_review_response_audit (
+  id BIGSERIAL PRIMARY KEY,
+  server_id TEXT NOT NULL,
+  user_id VARCHAR(255) NOT NULL,
+  responder VARCHAR(255) NOT NULL,
+  action VARCHAR(10) NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+  previous_body TEXT,
+  body TEXT,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Public user profiles shown next to reviews (PROFILE_PROVIDER=table)
+CREATE TABLE IF NOT EXISTS proxy_user_profiles (
+  user_id VARCHAR(255) PRIMARY KEY,
+  username VARCHAR(255),
+  avatar_url TEXT,
+  opted_out BOOLEAN NOT NULL DEFAULT false, -- Shown as anonymous when true
+  updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Bayesian-weighted rating (RATING_PRIOR_MEAN/RATING_PRIOR_WEIGHT) and per-star histogram
+ALTER TABLE proxy_server_stats ADD COLUMN IF NOT EXISTS weighted_rating DECIMAL(3,2) NOT NULL DEFAULT 0;
+ALTER TABLE proxy_server_stats ADD COLUMN IF NOT EXISTS rating_1_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE proxy_server_stats ADD COLUMN IF NOT EXISTS rating_2_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE proxy_server_stats ADD COLUMN IF NOT EXISTS rating_3_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE proxy_server_stats ADD COLUMN IF NOT EXISTS rating_4_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE proxy_server_stats ADD COLUMN IF NOT EXISTS rating_5_count INTEGER NOT NULL DEFAULT 0;
+
+-- Recomputes a server's rating columns from its visible reviews; called by the proxy and
+-- the admin so both write the same weighted rating. The prior mean (1-5) falls back to
+-- 3.5 and its weight (in reviews) to 10 when NULL or out of range. The aggregate always
+-- yields a row, so stats drop to zero when the last visible review goes.
+CREATE OR REPLACE FUNCTION proxy_recalculate_rating_stats(p_server_id TEXT, p_mean FLOAT8, p_weight FLOAT8)
+RETURNS void AS $$
+DECLARE
+  prior_mean FLOAT8 := CASE WHEN p_mean BETWEEN 1 AND 5 THEN p_mean ELSE 3.5 END;
+  prior_weight FLOAT8 := CASE WHEN p_weight >= 0 THEN p_weight ELSE 10 END;
+BEGIN
+  INSERT INTO proxy_server_stats (
+    server_id, rating, rating_count, weighted_rating,
+    rating_1_count, rating_2_count, rating_3_count, rating_4_count, rating_5_count, updated_at
+  )
+  SELECT
+    p_server_id,
+    COALESCE(AVG(rating) FILTER (WHERE status = 'visible'), 0)::numeric(3,2),
+    COUNT(*) FILTER (WHERE status = 'visible')::integer,
+    CASE WHEN COUNT(*) FILTER (WHERE status = 'visible') = 0 THEN 0 ELSE
+      ((prior_mean * prior_weight + SUM(rating) FILTER (WHERE status = 'visible'))
+        / (prior_weight + COUNT(*) FILTER (WHERE status = 'visible')))::numeric(3,2)
+    END,
+    COUNT(*) FILTER (WHERE status = 'visible' AND rating = 1)::integer,
+    COUNT(*) FILTER (WHERE status = 'visible' AND rating = 2)::integer,
+    COUNT(*) FILTER (WHERE status = 'visible' AND rating = 3)::integer,
+    COUNT(*) FILTER (WHERE status = 'visible' AND rating = 4)::integer,
+    COUNT(*) FILTER (WHERE status = 'visible' AND rating = 5)::integer,
+    NOW()
+  FROM proxy_user_ratings
+  WHERE server_id = p_server_id
+  ON CONFLICT (server_id)
+  DO UPDATE SET
+    rating = EXCLUDED.rating,
+    rating_count = EXCLUDED.rating_count,
+    weighted_rating = EXCLUDED.weighted_rating,
+    rating_1_count = EXCLUDED.rating_1_count,
+    rating_2_count = EXCLUDED.rating_2_count,
+    rating_3_count = EXCLUDED.rating_3_count,
+    rating_4_count = EXCLUDED.rating_4_count,
+    rating_5_count = EXCLUDED.rating_5_count,
+    updated_at = NOW();
+END;
+$$ LANGUAGE plpgsql;
+
+-- Append-only log of install and rating events, purged after EVENT_RETENTION_DAYS
+CREATE TABLE IF NOT EXISTS proxy_server_events (
+  id BIGSERIAL PRIMARY KEY,
+  server_id TEXT NOT NULL,
+  user_id VARCHAR(255) NOT NULL,
+  event_type VARCHAR(20) NOT NULL, -- install, uninstall, rating
+  rating SMALLINT, -- Stars of rating events
+  source VARCHAR(100),
+  version VARCHAR(50),
+  platform VARCHAR(50),
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Daily rollup of proxy_server_events, maintained with each event
+CREATE TABLE IF NOT EXISTS proxy_server_daily_stats (
+  server_id TEXT NOT NULL,
+  day DATE NOT NULL,
+  installs INTEGER NOT NULL DEFAULT 0,
+  ratings INTEGER NOT NULL DEFAULT 0,
+  rating_sum INTEGER NOT NULL DEFAULT 0,
+  PRIMARY KEY (server_id, day)
+);
+
+-- Trending scores per period, precomputed from proxy_server_daily_stats by the proxy
+CREATE TABLE IF NOT EXISTS proxy_server_trending (
+  period VARCHAR(10) NOT NULL, -- 7d, 30d, 90d
+  server_id TEXT NOT NULL,
+  install_velocity DOUBLE PRECISION NOT NULL DEFAULT 0,
+  rating_velocity DOUBLE PRECISION NOT NULL DEFAULT 0,
+  score DOUBLE PRECISION NOT NULL DEFAULT 0,
+  computed_at TIMESTAMP DEFAULT NOW(),
+  PRIMARY KEY (period, server_id)
+);
+
+-- Uninstalls and heartbeats: an installation is active while it is not uninstalled and
+-- has been seen within ACTIVE_INSTALL_DAYS
+ALTER TABLE proxy_user_installations ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMP;
+ALTER TABLE proxy_user_installations ADD COLUMN IF NOT EXISTS uninstalled_at TIMESTAMP;
+ALTER TABLE proxy_server_daily_stats ADD COLUMN IF NOT EXISTS uninstalls INTEGER NOT NULL DEFAULT 0;
+-- installation_count mirrors total_installs; retention is the share of installations older
+-- than the window that are still active
+ALTER TABLE proxy_server_stats ADD COLUMN IF NOT EXISTS total_installs INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE proxy_server_stats ADD COLUMN IF NOT EXISTS active_installs INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE proxy_server_stats ADD COLUMN IF NOT EXISTS retention DECIMAL(5,4) NOT NULL DEFAULT 0;
+
+-- User IDs are stored as HMAC hashes (USER_ID_PEPPER). When the pepper is rotated a
+-- returning user's rows are re-keyed, so votes, reports and responses follow their review.
+ALTER TABLE proxy_review_reports DROP CONSTRAINT IF EXISTS proxy_review_reports_server_id_user_id_fkey;
+ALTER TABLE proxy_review_reports ADD CONSTRAINT proxy_review_reports_server_id_user_id_fkey
+  FOREIGN KEY (server_id, user_id) REFERENCES proxy_user_ratings(server_id, user_id) ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE proxy_review_votes DROP CONSTRAINT IF EXISTS proxy_review_votes_server_id_user_id_fkey;
+ALTER TABLE proxy_review_votes ADD CONSTRAINT proxy_review_votes_server_id_user_id_fkey
+  FOREIGN KEY (server_id, user_id) REFERENCES proxy_user_ratings(server_id, user_id) ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE proxy_review_responses DROP CONSTRAINT IF EXISTS proxy_review_responses_server_id_user_id_fkey;
+ALTER TABLE proxy_review_responses ADD CONSTRAINT proxy_review_responses_server_id_user_id_fkey
+  FOREIGN KEY (server_id, user_id) REFERENCES proxy_user_ratings(server_id, user_id) ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- Related servers, precomputed by the related scorer from shared tags, category, env
+-- vars, description text and co-installations
+CREATE TABLE IF NOT EXISTS proxy_server_related (
+  server_id TEXT NOT NULL,
+  related_id TEXT NOT NULL,
+  score DOUBLE PRECISION NOT NULL,
+  co_installs INTEGER NOT NULL DEFAULT 0, -- Users who installed both servers
+  reasons TEXT[] NOT NULL DEFAULT '{}', -- shared_tags, same_category, shared_env, similar_description, co_installed
+  computed_at TIMESTAMP DEFAULT NOW(),
+  PRIMARY KEY (server_id, related_id)
+);
+
+-- Collections hold server names like every other proxy table, not UUIDs
+ALTER TABLE collection_servers ALTER COLUMN server_id TYPE TEXT;
+
+-- Users banned from submitting reviews
+CREATE TABLE IF NOT EXISTS proxy_banned_users (
+  user_id VARCHAR(255) PRIMARY KEY,
+  reason TEXT,
+  banned_by VARCHAR(255),
+  banned_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Audit trail of admin operations
+CREATE TABLE IF NOT EXISTS proxy_admin_audit_log (
+  id BIGSERIAL PRIMARY KEY,
+  username VARCHAR(255) NOT NULL,
+  action VARCHAR(64) NOT NULL,
+  server_id TEXT,
+  details TEXT,
+  ip VARCHAR(64),
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
 -- Indexes for performance
 CREATE INDEX IF NOT EXISTS idx_proxy_server_stats_rating ON proxy_server_stats(rating DESC, rating_count DESC);
+CREATE INDEX IF NOT EXISTS idx_proxy_server_stats_weighted ON proxy_server_stats(weighted_rating DESC, rating_count DESC);
 CREATE INDEX IF NOT EXISTS idx_proxy_ratings_server ON proxy_user_ratings(server_id);
 CREATE INDEX IF NOT EXISTS idx_proxy_ratings_user ON proxy_user_ratings(user_id);
 CREATE INDEX IF NOT EXISTS idx_proxy_installations_server ON proxy_user_installations(server_id);
 CREATE INDEX IF NOT EXISTS idx_proxy_installations_user ON proxy_user_installations(user_id);
 CREATE INDEX IF NOT EXISTS idx_proxy_installations_date ON proxy_user_installations(installed_at DESC);
+CREATE INDEX IF NOT EXISTS idx_proxy_installations_current ON proxy_user_installations(server_id, last_seen_at) WHERE uninstalled_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_proxy_server_events_server ON proxy_server_events(server_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_proxy_server_events_created ON proxy_server_events(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_proxy_server_events_user ON proxy_server_events(user_id);
+CREATE INDEX IF NOT EXISTS idx_proxy_review_votes_voter ON proxy_review_votes(voter_id);
+CREATE INDEX IF NOT EXISTS idx_proxy_review_reports_reporter ON proxy_review_reports(reporter_id);
+CREATE INDEX IF NOT EXISTS idx_proxy_server_daily_stats_day ON proxy_server_daily_stats(day DESC);
+CREATE INDEX IF NOT EXISTS idx_proxy_server_trending_score ON proxy_server_trending(period, score DESC);
+CREATE INDEX IF NOT EXISTS idx_proxy_server_related_score ON proxy_server_related(server_id, score DESC);
+CREATE INDEX IF NOT EXISTS idx_proxy_user_ratings_status ON proxy_user_ratings(status) WHERE status <> 'visible';
+CREATE INDEX IF NOT EXISTS idx_proxy_user_ratings_fingerprint ON proxy_user_ratings(comment_fingerprint) WHERE comment_fingerprint IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_proxy_user_ratings_user_updated ON proxy_user_ratings(user_id, updated_at DESC);
+CREATE INDEX IF NOT EXISTS idx_proxy_review_reports_unresolved ON proxy_review_reports(server_id, user_id) WHERE resolved_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_proxy_review_response_audit_review ON proxy_review_response_audit(server_id, user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_proxy_admin_audit_log_created ON proxy_admin_audit_log(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_proxy_server_categories_category ON proxy_server_categories(category_slug);
+CREATE INDEX IF NOT EXISTS idx_proxy_server_tags_tag ON proxy_server_tags(tag_slug);
 CREATE INDEX IF NOT EXISTS idx_collections_owner ON collections(owner_id);
 CREATE INDEX IF NOT EXISTS idx_collections_public ON collections(is_public) WHERE is_public = true;
\ No newline at end of file
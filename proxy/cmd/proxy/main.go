@@ -6,15 +6,15 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"strings"
 	"time"
 
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/veriteknik/registry-proxy/internal/cache"
 	"github.com/veriteknik/registry-proxy/internal/db"
 	"github.com/veriteknik/registry-proxy/internal/handlers"
 	_ "github.com/veriteknik/registry-proxy/internal/metrics" // Import metrics for auto-registration
 	"github.com/veriteknik/registry-proxy/internal/middleware"
+	"github.com/veriteknik/registry-proxy/internal/related"
+	"github.com/veriteknik/registry-proxy/internal/trending"
 	"github.com/veriteknik/registry-proxy/internal/utils"
 	"go.uber.org/zap"
 )
@@ -49,6 +49,18 @@ func main() {
 	}
 	defer database.Close()
 
+	// Refresh weighted ratings and distributions so a changed rating prior applies everywhere
+	if err := database.RecalculateAllRatingStats(context.Background()); err != nil {
+		log.Printf("Warning: failed to recalculate rating stats: %v", err)
+	}
+
+	// Hash user IDs stored before USER_ID_PEPPER was set, before any new writes can race it
+	if n, err := database.HashLegacyUserIDs(context.Background()); err != nil {
+		log.Printf("Warning: failed to hash legacy user IDs: %v", err)
+	} else if n > 0 {
+		log.Printf("Hashed the stored IDs of %d users", n)
+	}
+
 	// Initialize registry database connection
 	registryDB, err := db.NewRegistryDB()
 	if err != nil {
@@ -56,108 +68,47 @@ func main() {
 	}
 	defer registryDB.Close()
 
+	// Precompute trending scores and related servers and run hourly maintenance in the background
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	go trending.NewScorer(database, trending.ConfigFromEnv()).Run(backgroundCtx)
+	go related.NewScorer(registryDB, related.ConfigFromEnv()).Run(backgroundCtx)
+	go runMaintenance(backgroundCtx, database)
+
 	// Initialize handlers
 	serversHandler := handlers.NewServersHandler(registryURL, proxyCache, database, registryDB)
-	ratingsHandler := handlers.NewRatingsHandler(database, proxyCache)
+	ratingsHandler, err := handlers.NewRatingsHandler(database, proxyCache)
+	if err != nil {
+		log.Fatalf("Failed to create ratings handler: %v", err)
+	}
+	usersHandler := handlers.NewUsersHandler(database, serversHandler, proxyCache)
 	enhancedHandler := handlers.NewEnhancedHandler(registryDB, database)
+	categoriesHandler := handlers.NewCategoriesHandler(registryDB)
 	passthroughHandler, err := handlers.NewPassthroughHandler(registryURL, proxyCache)
 	if err != nil {
 		log.Fatalf("Failed to create passthrough handler: %v", err)
 	}
+	graphqlHandler, err := handlers.NewGraphQLHandler(serversHandler, ratingsHandler)
+	if err != nil {
+		log.Fatalf("Failed to create GraphQL handler: %v", err)
+	}
+	openapiHandler, err := handlers.NewOpenAPIHandler()
+	if err != nil {
+		log.Fatalf("Failed to generate OpenAPI document: %v", err)
+	}
 
 	// Setup routes
-	mux := http.NewServeMux()
-	
-	// Health check (internal)
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprintf(w, `{"status":"ok","service":"registry-proxy"}`)
+	mux := newMux(apiHandlers{
+		servers:     serversHandler,
+		ratings:     ratingsHandler,
+		users:       usersHandler,
+		enhanced:    enhancedHandler,
+		categories:  categoriesHandler,
+		passthrough: passthroughHandler,
+		graphql:     graphqlHandler,
+		openapi:     openapiHandler,
 	})
 
-	// Prometheus metrics endpoint (IP-filtered for security)
-	mux.Handle("/metrics", middleware.MetricsIPFilter(promhttp.Handler()))
-
-	// Registry-compatible health check
-	mux.HandleFunc("/v0/health", func(w http.ResponseWriter, r *http.Request) {
-		// Proxy to upstream to get GitHub client ID
-		passthroughHandler.ProxySpecificEndpoint().ServeHTTP(w, r)
-	})
-
-	// Enriched servers endpoint (only for GET /v0/servers exactly)
-	mux.HandleFunc("/v0/servers", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet && r.URL.Path == "/v0/servers" {
-			// Use our enriched handler for list
-			serversHandler.HandleList(w, r)
-		} else if r.URL.Path == "/v0/servers" {
-			// Other methods on /v0/servers (POST for publish, etc.) - proxy to upstream
-			passthroughHandler.ProxySpecificEndpoint().ServeHTTP(w, r)
-		}
-		// If path is not exactly "/v0/servers", let it fall through to the "/v0/servers/" handler
-	})
-
-	// Server detail and rating endpoints
-	mux.HandleFunc("/v0/servers/", func(w http.ResponseWriter, r *http.Request) {
-		path := strings.TrimPrefix(r.URL.Path, "/v0/servers/")
-		parts := strings.Split(path, "/")
-
-		// Check if it's a user rating endpoint: /servers/{id}/rating/{userId}
-		if len(parts) >= 3 && parts[len(parts)-2] == "rating" {
-			ratingsHandler.HandleGetUserRating(w, r)
-			return
-		}
-
-		// Check if the last part of the path is a special endpoint
-		if len(parts) >= 2 {
-			lastPart := parts[len(parts)-1]
-			switch lastPart {
-			case "rate":
-				// Write operation - requires authentication
-				middleware.APIKeyAuth(ratingsHandler.HandleRate)(w, r)
-				return
-			case "install":
-				// Write operation - requires authentication
-				middleware.APIKeyAuth(ratingsHandler.HandleInstall)(w, r)
-				return
-			case "stats":
-				// Read operation - public
-				ratingsHandler.HandleStats(w, r)
-				return
-			case "reviews":
-				// Read operation - public
-				ratingsHandler.HandleGetReviews(w, r)
-				return
-			case "feedback":
-				// Read operation - public (with pagination)
-				ratingsHandler.HandleGetFeedback(w, r)
-				return
-			}
-		}
-
-		// If it's a GET request for a server ID (not ending with special endpoint), use our handler
-		// Server IDs are like "io.github.user/repo" which has 2 parts when split by "/"
-		if r.Method == http.MethodGet && len(parts) <= 2 && parts[0] != "" {
-			serversHandler.HandleDetail(w, r)
-			return
-		}
-
-		// If not a rating endpoint or server detail, proxy to upstream
-		passthroughHandler.ProxySpecificEndpoint().ServeHTTP(w, r)
-	})
-
-	// Publish endpoint (proxy to upstream)
-	mux.HandleFunc("/v0/publish", passthroughHandler.ProxySpecificEndpoint())
-
-	// Cache refresh endpoint (our custom endpoint)
-	mux.HandleFunc("/v0/cache/refresh", serversHandler.HandleRefresh)
-
-	// Enhanced endpoints (NEW - query registry database directly)
-	mux.HandleFunc("/v0/enhanced/servers", enhancedHandler.HandleEnhancedServers)
-	mux.HandleFunc("/v0/enhanced/stats/aggregate", enhancedHandler.HandleStats)
-	mux.HandleFunc("/v0/enhanced/stats/trending", enhancedHandler.HandleTrending)
-
-	// Catch-all for any other endpoints
-	mux.HandleFunc("/", passthroughHandler.ProxySpecificEndpoint())
-
 	// Apply middleware stack: timeout -> CORS -> routes
 	handler := timeoutMiddleware(corsMiddleware(mux))
 
@@ -177,6 +128,31 @@ func main() {
 	defer utils.Sync()
 }
 
+// runMaintenance runs hourly upkeep until ctx is done: it recomputes active installs so
+// installations without a recent heartbeat drop out of the count, and purges detailed
+// events past their retention window
+func runMaintenance(ctx context.Context, database *db.DB) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if err := database.RecalculateAllInstallStats(ctx); err != nil {
+			utils.Logger.Warn("Failed to recalculate install stats", zap.Error(err))
+		}
+		if n, err := database.PurgeExpiredEvents(ctx); err != nil {
+			utils.Logger.Warn("Failed to purge expired events", zap.Error(err))
+		} else if n > 0 {
+			utils.Logger.Info("Purged expired events", zap.Int64("events", n))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 // timeoutMiddleware adds request timeout to prevent long-running requests
 func timeoutMiddleware(next http.Handler) http.Handler {
 	// Get timeout from environment or default to 30 seconds
@@ -214,7 +190,7 @@ func corsMiddleware(next http.Handler) http.Handler {
 		// Set CORS headers
 		w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, X-GitHub-Token")
 		w.Header().Set("Access-Control-Max-Age", "86400")
 
 		// For public APIs, also set these headers for better compatibility
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/veriteknik/registry-proxy/internal/handlers"
+	"github.com/veriteknik/registry-proxy/internal/middleware"
+	"github.com/veriteknik/registry-proxy/internal/utils"
+)
+
+// Authentication required by an endpoint, named as the security schemes of the OpenAPI document
+const (
+	authAPIKey    = "apiKey"    // middleware.APIKeyAuth
+	authUserToken = "userToken" // middleware.UserTokenAuth
+)
+
+// apiHandlers are the handlers the routes dispatch to
+type apiHandlers struct {
+	servers     *handlers.ServersHandler
+	ratings     *handlers.RatingsHandler
+	users       *handlers.UsersHandler
+	enhanced    *handlers.EnhancedHandler
+	categories  *handlers.CategoriesHandler
+	passthrough *handlers.PassthroughHandler
+	graphql     *handlers.GraphQLHandler
+	openapi     *handlers.OpenAPIHandler
+}
+
+// endpoint is a handler method and the authentication it requires. They are kept apart,
+// rather than wrapping the handler in its middleware, so tests can check every route of
+// the OpenAPI document against the handler and authentication the mux picks for it.
+type endpoint struct {
+	handler http.HandlerFunc
+	auth    string // authAPIKey, authUserToken or "" for public endpoints
+}
+
+func (e endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch e.auth {
+	case authAPIKey:
+		middleware.APIKeyAuth(e.handler)(w, r)
+	case authUserToken:
+		middleware.UserTokenAuth(e.handler)(w, r)
+	default:
+		e.handler(w, r)
+	}
+}
+
+// subroute is an endpoint under a dispatcher's prefix, picked when match accepts the
+// request and the segments of its path after the prefix
+type subroute struct {
+	match func(r *http.Request, parts []string) bool
+	endpoint
+}
+
+// dispatcher serves the first of its subroutes matching a request, or fallback
+type dispatcher struct {
+	prefix   string
+	routes   []subroute
+	fallback http.Handler
+}
+
+// pick returns the handler of a request
+func (d dispatcher) pick(r *http.Request) http.Handler {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, d.prefix), "/")
+	for _, route := range d.routes {
+		if route.match(r, parts) {
+			return route.endpoint
+		}
+	}
+	return d.fallback
+}
+
+func (d dispatcher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.pick(r).ServeHTTP(w, r)
+}
+
+// tail matches paths ending with the given segments, "*" matching any, after at least
+// one segment naming the resource
+func tail(segments ...string) func(r *http.Request, parts []string) bool {
+	return func(r *http.Request, parts []string) bool {
+		if len(parts) <= len(segments) {
+			return false
+		}
+		end := parts[len(parts)-len(segments):]
+		for i, s := range segments {
+			if s != "*" && end[i] != s {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// notFound answers requests no route of a dispatcher matches
+var notFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSONError(w, "Not found", http.StatusNotFound)
+})
+
+// routes maps each pattern of the mux to its handler
+func routes(h apiHandlers) map[string]http.Handler {
+	upstream := h.passthrough.ProxySpecificEndpoint()
+
+	return map[string]http.Handler{
+		// Health check (internal)
+		"/health": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, `{"status":"ok","service":"registry-proxy"}`)
+		}),
+
+		// Prometheus metrics endpoint (IP-filtered for security)
+		"/metrics": middleware.MetricsIPFilter(promhttp.Handler()),
+
+		// Registry-compatible health check, proxied to upstream to get the GitHub client ID
+		"/v0/health": upstream,
+
+		// Enriched list for GET /v0/servers; other methods (POST for publish, etc.) go upstream
+		"/v0/servers": dispatcher{
+			prefix: "/v0/servers",
+			routes: []subroute{
+				{func(r *http.Request, _ []string) bool { return r.Method == http.MethodGet }, endpoint{h.servers.HandleList, ""}},
+			},
+			fallback: upstream,
+		},
+
+		// Update check for installed server versions (public, read-only)
+		"/v0/servers/check-updates": endpoint{h.servers.HandleCheckUpdates, ""},
+
+		// Batch lookup of enriched servers (public, read-only)
+		"/v0/servers/batch": endpoint{h.servers.HandleBatch, ""},
+
+		// Server detail and rating endpoints: /v0/servers/{id}/..., where server IDs such
+		// as "io.github.user/repo" span two segments
+		"/v0/servers/": dispatcher{
+			prefix: "/v0/servers/",
+			routes: []subroute{
+				{tail("reviews", "*", "report"), endpoint{h.ratings.HandleReportReview, ""}},
+				{tail("reviews", "*", "vote"), endpoint{h.ratings.HandleVoteReview, authAPIKey}},
+				// Also requires the publisher's GitHub token
+				{tail("reviews", "*", "response"), endpoint{h.ratings.HandleReviewResponse, authAPIKey}},
+				{tail("rating", "*"), endpoint{h.ratings.HandleGetUserRating, ""}},
+				{tail("rate"), endpoint{h.ratings.HandleRate, authAPIKey}},
+				{tail("install"), endpoint{h.ratings.HandleInstall, authAPIKey}},
+				{tail("uninstall"), endpoint{h.ratings.HandleUninstall, authAPIKey}},
+				{tail("heartbeat"), endpoint{h.ratings.HandleHeartbeat, authAPIKey}},
+				{tail("stats"), endpoint{h.ratings.HandleStats, ""}},
+				{tail("stats", "timeseries"), endpoint{h.ratings.HandleStatsTimeseries, ""}},
+				{tail("stats", "breakdown"), endpoint{h.ratings.HandleStatsBreakdown, ""}},
+				{tail("reviews"), endpoint{h.ratings.HandleGetReviews, ""}},
+				{tail("feedback"), endpoint{h.ratings.HandleGetFeedback, ""}},
+				{tail("related"), endpoint{h.servers.HandleRelated, ""}},
+				// Any other GET of at most two segments is a server ID
+				{func(r *http.Request, parts []string) bool {
+					return r.Method == http.MethodGet && len(parts) <= 2 && parts[0] != ""
+				}, endpoint{h.servers.HandleDetail, ""}},
+			},
+			fallback: upstream,
+		},
+
+		// Publish endpoint (proxy to upstream)
+		"/v0/publish": upstream,
+
+		// Cache refresh endpoint (our custom endpoint)
+		"/v0/cache/refresh": endpoint{h.servers.HandleRefresh, ""},
+
+		// Enhanced endpoints (query registry database directly)
+		"/v0/enhanced/servers":         endpoint{h.enhanced.HandleEnhancedServers, ""},
+		"/v0/enhanced/stats/aggregate": endpoint{h.enhanced.HandleStats, ""},
+		"/v0/enhanced/stats/trending":  endpoint{h.enhanced.HandleTrending, ""},
+
+		// User data export for the user holding a signed token
+		"/v0/users/me/export": endpoint{h.users.HandleExport, authUserToken},
+
+		// User data endpoints: the user's own installs, ratings and recommendations require
+		// the user's token; profiles and erasure are written by services with an API key
+		"/v0/users/": dispatcher{
+			prefix: "/v0/users/",
+			routes: []subroute{
+				{tail("installs"), endpoint{h.users.HandleInstalls, authUserToken}},
+				{tail("ratings"), endpoint{h.users.HandleRatings, authUserToken}},
+				{tail("recommendations"), endpoint{h.users.HandleRecommendations, authUserToken}},
+				{tail("profile"), endpoint{h.users.HandleProfile, authAPIKey}},
+				// A single segment is the user ID itself
+				{func(_ *http.Request, parts []string) bool {
+					return len(parts) == 1 && parts[0] != ""
+				}, endpoint{h.users.HandleErase, authAPIKey}},
+			},
+			fallback: notFound,
+		},
+
+		// Managed category taxonomy with server counts
+		"/v0/categories": endpoint{h.categories.HandleList, ""},
+
+		// GraphQL over the enriched catalog
+		"/v0/graphql": endpoint{h.graphql.HandleQuery, ""},
+
+		// OpenAPI document of the endpoints above, generated from the handlers
+		"/v0/openapi.json": endpoint{h.openapi.HandleSpec, ""},
+
+		// Catch-all for any other endpoints
+		"/": upstream,
+	}
+}
+
+// newMux registers the routes
+func newMux(h apiHandlers) *http.ServeMux {
+	mux := http.NewServeMux()
+	for pattern, handler := range routes(h) {
+		mux.Handle(pattern, handler)
+	}
+	return mux
+}
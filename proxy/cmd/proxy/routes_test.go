@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/veriteknik/registry-proxy/internal/handlers"
+	"github.com/veriteknik/registry-proxy/internal/openapi"
+)
+
+func testHandlers() apiHandlers {
+	return apiHandlers{
+		servers:     &handlers.ServersHandler{},
+		ratings:     &handlers.RatingsHandler{},
+		users:       &handlers.UsersHandler{},
+		enhanced:    &handlers.EnhancedHandler{},
+		categories:  &handlers.CategoriesHandler{},
+		passthrough: &handlers.PassthroughHandler{},
+		graphql:     &handlers.GraphQLHandler{},
+		openapi:     &handlers.OpenAPIHandler{},
+	}
+}
+
+// served returns the handler the mux picks for a request, resolving dispatchers
+func served(mux *http.ServeMux, r *http.Request) http.Handler {
+	h, _ := mux.Handler(r)
+	for {
+		d, ok := h.(dispatcher)
+		if !ok {
+			return h
+		}
+		h = d.pick(r)
+	}
+}
+
+// TestDocumentedRoutesAreServed requests every route of the OpenAPI document from the mux,
+// with a server ID spanning two segments, and checks it reaches the declared handler with
+// the declared authentication
+func TestDocumentedRoutesAreServed(t *testing.T) {
+	mux := newMux(testHandlers())
+	paths := strings.NewReplacer("{id}", "io.github.example/weather", "{userId}", "alice")
+
+	for _, route := range handlers.APIRoutes() {
+		r := httptest.NewRequest(route.Method, paths.Replace(route.Path), nil)
+
+		e, ok := served(mux, r).(endpoint)
+		if !ok {
+			t.Errorf("%s %s: not served by the proxy's handlers", route.Method, route.Path)
+			continue
+		}
+		if got, want := openapi.HandlerName(e.handler), openapi.HandlerName(route.Handler); got != want {
+			t.Errorf("%s %s: served by %s, declared as %s", route.Method, route.Path, got, want)
+		}
+		if e.auth != route.Security {
+			t.Errorf("%s %s: authenticated with %q, declared as %q", route.Method, route.Path, e.auth, route.Security)
+		}
+	}
+}
+
+// TestServedRoutesAreDocumented checks that every endpoint of the mux is in the OpenAPI
+// document with the authentication it is served with
+func TestServedRoutesAreDocumented(t *testing.T) {
+	declared := make(map[string][]openapi.Route)
+	for _, route := range handlers.APIRoutes() {
+		name := openapi.HandlerName(route.Handler)
+		declared[name] = append(declared[name], route)
+	}
+
+	var endpoints []endpoint
+	for _, h := range routes(testHandlers()) {
+		switch h := h.(type) {
+		case endpoint:
+			endpoints = append(endpoints, h)
+		case dispatcher:
+			for _, route := range h.routes {
+				endpoints = append(endpoints, route.endpoint)
+			}
+			if e, ok := h.fallback.(endpoint); ok {
+				t.Errorf("%s falls back to %s; fallbacks must not serve documented endpoints", h.prefix, openapi.HandlerName(e.handler))
+			}
+		}
+	}
+
+	for _, e := range endpoints {
+		name := openapi.HandlerName(e.handler)
+		routes, ok := declared[name]
+		if !ok {
+			t.Errorf("%s is served but missing from the OpenAPI document", name)
+			continue
+		}
+		for _, route := range routes {
+			if route.Security != e.auth {
+				t.Errorf("%s %s: declared as %q, authenticated with %q", route.Method, route.Path, route.Security, e.auth)
+			}
+		}
+	}
+}
+
+// TestUnknownUserPathsAreNotFound checks that paths under /v0/users/ matching no route are
+// answered with 404 rather than reaching an endpoint
+func TestUnknownUserPathsAreNotFound(t *testing.T) {
+	mux := newMux(testHandlers())
+
+	for _, path := range []string{"/v0/users/", "/v0/users/alice/unknown", "/v0/users/alice/installs/extra"} {
+		r := httptest.NewRequest(http.MethodDelete, path, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("DELETE %s: status = %d, want %d", path, w.Code, http.StatusNotFound)
+		}
+	}
+}
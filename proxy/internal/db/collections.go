@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Collection is a named list of servers curated by a user
+type Collection struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	IsPublic    bool      `json:"is_public"`
+	ServerIDs   []string  `json:"server_ids"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// GetPublicCollections returns a page of public collections, newest first
+func (db *DB) GetPublicCollections(ctx context.Context, limit, offset int) ([]Collection, error) {
+	collections := []Collection{}
+	err := queryRows(ctx, db, selectCollections("COALESCE(c.is_public, true)", "c.created_at DESC, c.id")+`
+		LIMIT $1 OFFSET $2
+	`, []interface{}{limit, offset}, func(rows *sql.Rows) error {
+		c, err := scanCollection(rows)
+		if err != nil {
+			return err
+		}
+		collections = append(collections, c)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query collections: %w", err)
+	}
+	return collections, nil
+}
+
+// GetCollection returns a public collection, or nil when there is no public collection with that ID
+func (db *DB) GetCollection(ctx context.Context, id string) (*Collection, error) {
+	var found *Collection
+	// Compared as text so malformed IDs are not found rather than rejected by Postgres
+	err := queryRows(ctx, db, selectCollections("c.id::text = $1 AND COALESCE(c.is_public, true)", "c.id"),
+		[]interface{}{id}, func(rows *sql.Rows) error {
+			c, err := scanCollection(rows)
+			if err != nil {
+				return err
+			}
+			found = &c
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query collection: %w", err)
+	}
+	return found, nil
+}
+
+// selectCollections selects the collections matching where, each with the IDs of its
+// servers in the order they were added, for scanCollection
+func selectCollections(where, orderBy string) string {
+	return `
+		SELECT c.id::text, c.name, COALESCE(c.description, ''), COALESCE(c.is_public, true),
+			COALESCE(array_agg(cs.server_id::text ORDER BY cs.added_at) FILTER (WHERE cs.server_id IS NOT NULL), '{}'),
+			c.created_at, c.updated_at
+		FROM collections c
+		LEFT JOIN collection_servers cs ON cs.collection_id = c.id
+		WHERE ` + where + `
+		GROUP BY c.id
+		ORDER BY ` + orderBy
+}
+
+// scanCollection scans a row selected by selectCollections
+func scanCollection(rows *sql.Rows) (Collection, error) {
+	var c Collection
+	err := rows.Scan(&c.ID, &c.Name, &c.Description, &c.IsPublic, pq.Array(&c.ServerIDs), &c.CreatedAt, &c.UpdatedAt)
+	return c, err
+}
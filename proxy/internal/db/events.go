@@ -0,0 +1,162 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Event types recorded in proxy_server_events
+const (
+	EventInstall   = "install"
+	EventUninstall = "uninstall"
+	EventRating    = "rating"
+)
+
+// defaultEventRetentionDays is how long detailed events are kept; daily rollups are kept indefinitely
+const defaultEventRetentionDays = 90
+
+// ErrInvalidTimeseries is returned for an unknown timeseries metric or interval
+var ErrInvalidTimeseries = errors.New("invalid timeseries query")
+
+// Event is an install, uninstall or rating recorded in the append-only event log
+type Event struct {
+	ServerID string
+	UserID   string
+	Type     string
+	Rating   int // Stars, for rating events
+	Source   string
+	Version  string
+	Platform string
+}
+
+// timeseriesMetrics maps metric names to their proxy_server_daily_stats column
+var timeseriesMetrics = map[string]string{
+	"installs":   "installs",
+	"uninstalls": "uninstalls",
+	"ratings":    "ratings",
+}
+
+// timeseriesIntervals lists the supported bucket sizes, as date_trunc fields
+var timeseriesIntervals = map[string]bool{
+	"day":   true,
+	"week":  true,
+	"month": true,
+}
+
+// TimeseriesPoint is one bucket of a timeseries, starting at Date
+type TimeseriesPoint struct {
+	Date  string `json:"date"`
+	Value int    `json:"value"`
+}
+
+// EventRetentionDaysFromEnv reads EVENT_RETENTION_DAYS, falling back to 90 days
+func EventRetentionDaysFromEnv() int {
+	if v, err := strconv.Atoi(os.Getenv("EVENT_RETENTION_DAYS")); err == nil && v > 0 {
+		return v
+	}
+	return defaultEventRetentionDays
+}
+
+// PurgeExpiredEvents deletes the detailed events older than the retention window and
+// returns how many were deleted. Their daily rollups are kept.
+func (db *DB) PurgeExpiredEvents(ctx context.Context) (int64, error) {
+	result, err := db.ExecContext(ctx, `
+		DELETE FROM proxy_server_events
+		WHERE created_at < NOW() - make_interval(days => $1)
+	`, db.eventRetentionDays)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge events: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// recordEvent appends an event to the log and adds it to today's rollup in the same transaction
+func recordEvent(ctx context.Context, tx *sql.Tx, e Event) error {
+	var rating sql.NullInt64
+	if e.Type == EventRating {
+		rating = sql.NullInt64{Int64: int64(e.Rating), Valid: true}
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO proxy_server_events (server_id, user_id, event_type, rating, source, version, platform, created_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), NOW())
+	`, e.ServerID, e.UserID, e.Type, rating, e.Source, e.Version, e.Platform)
+	if err != nil {
+		return fmt.Errorf("failed to record %s event: %w", e.Type, err)
+	}
+
+	var installs, uninstalls, ratings int
+	switch e.Type {
+	case EventInstall:
+		installs = 1
+	case EventUninstall:
+		uninstalls = 1
+	case EventRating:
+		ratings = 1
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO proxy_server_daily_stats (server_id, day, installs, uninstalls, ratings, rating_sum)
+		VALUES ($1, CURRENT_DATE, $2, $3, $4, $5)
+		ON CONFLICT (server_id, day)
+		DO UPDATE SET
+			installs = proxy_server_daily_stats.installs + EXCLUDED.installs,
+			uninstalls = proxy_server_daily_stats.uninstalls + EXCLUDED.uninstalls,
+			ratings = proxy_server_daily_stats.ratings + EXCLUDED.ratings,
+			rating_sum = proxy_server_daily_stats.rating_sum + EXCLUDED.rating_sum
+	`, e.ServerID, installs, uninstalls, ratings, rating.Int64)
+	if err != nil {
+		return fmt.Errorf("failed to update daily stats: %w", err)
+	}
+
+	return nil
+}
+
+// GetTimeseries returns a metric of a server from the daily rollup, bucketed by interval
+// (day, week or month) from the bucket containing since up to today. Empty buckets are zero.
+func (db *DB) GetTimeseries(ctx context.Context, serverID, metric, interval string, since time.Time) ([]TimeseriesPoint, error) {
+	column, ok := timeseriesMetrics[metric]
+	if !ok || !timeseriesIntervals[interval] {
+		return nil, ErrInvalidTimeseries
+	}
+
+	// column comes from the whitelist above; interval is passed as a parameter
+	rows, err := db.QueryContext(ctx, `
+		SELECT b.bucket::date, COALESCE(SUM(d.`+column+`), 0)::integer
+		FROM generate_series(
+			date_trunc($2, $3::date::timestamp),
+			date_trunc($2, CURRENT_DATE::timestamp),
+			('1 ' || $2)::interval
+		) AS b(bucket)
+		LEFT JOIN proxy_server_daily_stats d
+			ON d.server_id = $1 AND date_trunc($2, d.day::timestamp) = b.bucket
+		GROUP BY b.bucket
+		ORDER BY b.bucket
+	`, serverID, interval, since.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query timeseries: %w", err)
+	}
+	defer rows.Close()
+
+	points := []TimeseriesPoint{}
+	for rows.Next() {
+		var day time.Time
+		var p TimeseriesPoint
+		if err := rows.Scan(&day, &p.Value); err != nil {
+			return nil, fmt.Errorf("failed to scan timeseries point: %w", err)
+		}
+		p.Date = day.Format("2006-01-02")
+		points = append(points, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating timeseries: %w", err)
+	}
+
+	return points, nil
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRecordEvent_AddsToDailyRollup(t *testing.T) {
+	tests := []struct {
+		event                                    Event
+		rating                                   sql.NullInt64
+		installs, uninstalls, ratings, ratingSum int64
+	}{
+		{Event{ServerID: "server-a", UserID: "alice", Type: EventInstall, Source: "cli"}, sql.NullInt64{}, 1, 0, 0, 0},
+		{Event{ServerID: "server-a", UserID: "alice", Type: EventUninstall}, sql.NullInt64{}, 0, 1, 0, 0},
+		{Event{ServerID: "server-a", UserID: "alice", Type: EventRating, Rating: 4}, sql.NullInt64{Int64: 4, Valid: true}, 0, 0, 1, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.event.Type, func(t *testing.T) {
+			database, mock := newMockDB(t, nil)
+
+			mock.ExpectBegin()
+			mock.ExpectExec(stmt(`INSERT INTO proxy_server_events`)).
+				WithArgs("server-a", "alice", tt.event.Type, tt.rating, tt.event.Source, "", "").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(stmt(`INSERT INTO proxy_server_daily_stats`)).
+				WithArgs("server-a", tt.installs, tt.uninstalls, tt.ratings, tt.ratingSum).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectRollback()
+
+			tx, err := database.Begin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = tx.Rollback() }()
+
+			if err := recordEvent(context.Background(), tx, tt.event); err != nil {
+				t.Fatalf("recordEvent() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestGetTimeseries(t *testing.T) {
+	t.Run("reads the metric's rollup column", func(t *testing.T) {
+		database, mock := newMockDB(t, nil)
+		since := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+
+		mock.ExpectQuery(`COALESCE\(SUM\(d\.uninstalls\), 0\)`).WithArgs("server-a", "week", "2026-03-02").
+			WillReturnRows(sqlmock.NewRows([]string{"bucket", "value"}).
+				AddRow(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), 3).
+				AddRow(time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC), 0))
+
+		points, err := database.GetTimeseries(context.Background(), "server-a", "uninstalls", "week", since)
+		if err != nil {
+			t.Fatalf("GetTimeseries() error = %v", err)
+		}
+		want := []TimeseriesPoint{{Date: "2026-03-02", Value: 3}, {Date: "2026-03-09", Value: 0}}
+		if len(points) != len(want) || points[0] != want[0] || points[1] != want[1] {
+			t.Errorf("GetTimeseries() = %+v, want %+v", points, want)
+		}
+	})
+
+	// Metrics are spliced into the query, so anything off the whitelist never reaches it
+	for _, q := range []struct{ metric, interval string }{
+		{"installs; DROP TABLE proxy_server_stats", "day"},
+		{"installs", "hour"},
+	} {
+		t.Run("rejects "+q.metric+" by "+q.interval, func(t *testing.T) {
+			database, _ := newMockDB(t, nil)
+			if _, err := database.GetTimeseries(context.Background(), "server-a", q.metric, q.interval, time.Now()); !errors.Is(err, ErrInvalidTimeseries) {
+				t.Errorf("GetTimeseries() error = %v, want ErrInvalidTimeseries", err)
+			}
+		})
+	}
+}
+
+func TestGetTimeseries_Postgres(t *testing.T) {
+	database := newTestDB(t, nil)
+	ctx := context.Background()
+
+	for _, user := range []string{"alice", "bob"} {
+		if err := database.TrackInstallation(ctx, "server-a", user, "cli", "1.0.0", "linux"); err != nil {
+			t.Fatalf("TrackInstallation(%s) error = %v", user, err)
+		}
+	}
+	// Reinstalling over a current installation is not a new install
+	if err := database.TrackInstallation(ctx, "server-a", "alice", "cli", "1.0.1", "linux"); err != nil {
+		t.Fatalf("TrackInstallation(alice) again error = %v", err)
+	}
+	if err := database.UninstallServer(ctx, "server-a", "bob"); err != nil {
+		t.Fatalf("UninstallServer() error = %v", err)
+	}
+	if err := database.UpsertRating(ctx, "server-a", "carol", 4, "", "", ""); err != nil {
+		t.Fatalf("UpsertRating() error = %v", err)
+	}
+	// Editing a rating is not a new rating
+	if err := database.UpsertRating(ctx, "server-a", "carol", 5, "", "", ""); err != nil {
+		t.Fatalf("UpsertRating() edit error = %v", err)
+	}
+
+	var installs, uninstalls, ratings, ratingSum int
+	err := database.QueryRow(`
+		SELECT installs, uninstalls, ratings, rating_sum FROM proxy_server_daily_stats
+		WHERE server_id = 'server-a' AND day = CURRENT_DATE
+	`).Scan(&installs, &uninstalls, &ratings, &ratingSum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if installs != 2 || uninstalls != 1 || ratings != 1 || ratingSum != 4 {
+		t.Errorf("today's rollup = %d installs, %d uninstalls, %d ratings summing %d; want 2, 1, 1 and 4",
+			installs, uninstalls, ratings, ratingSum)
+	}
+
+	// An older rollup, with the days in between empty
+	var since time.Time
+	if err := database.QueryRow(`SELECT CURRENT_DATE - 3`).Scan(&since); err != nil {
+		t.Fatal(err)
+	}
+	_, err = database.Exec(`
+		INSERT INTO proxy_server_daily_stats (server_id, day, installs, uninstalls, ratings, rating_sum)
+		VALUES ('server-a', $1, 5, 0, 0, 0)
+	`, since)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	points, err := database.GetTimeseries(ctx, "server-a", "installs", "day", since)
+	if err != nil {
+		t.Fatalf("GetTimeseries() error = %v", err)
+	}
+	want := []int{5, 0, 0, 2}
+	if len(points) != len(want) {
+		t.Fatalf("GetTimeseries() = %+v, want %d daily points", points, len(want))
+	}
+	for i, p := range points {
+		if day := since.AddDate(0, 0, i).Format("2006-01-02"); p.Date != day || p.Value != want[i] {
+			t.Errorf("points[%d] = %+v, want %s with %d", i, p, day, want[i])
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/veriteknik/registry-proxy/internal/profiles"
+)
+
+// UserExport is everything stored about a user, as returned by ExportUser
+type UserExport struct {
+	Profile       *profiles.Profile  `json:"profile"`
+	Ban           *ExportedBan       `json:"ban"`
+	Ratings       []UserRating       `json:"ratings"`
+	Installations []UserInstallation `json:"installations"`
+	Votes         []ExportedVote     `json:"votes"`
+	Reports       []ExportedReport   `json:"reports"`
+	Events        []ExportedEvent    `json:"events"`
+	Collections   []Collection       `json:"collections"`
+}
+
+// UserRating is a rating and review written by a user
+type UserRating struct {
+	ServerID         string    `json:"server_id"`
+	Rating           int       `json:"rating"`
+	Comment          string    `json:"comment"`
+	Status           string    `json:"status"`
+	ModerationReason string    `json:"moderation_reason,omitempty"`
+	HelpfulCount     int       `json:"helpful_count"`
+	UnhelpfulCount   int       `json:"unhelpful_count"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// UserInstallation is a user's installation of a server
+type UserInstallation struct {
+	ServerID      string     `json:"server_id"`
+	Source        string     `json:"source"`
+	Version       string     `json:"version"`
+	Platform      string     `json:"platform"`
+	InstalledAt   time.Time  `json:"installed_at"`
+	LastSeenAt    *time.Time `json:"last_seen_at"`
+	UninstalledAt *time.Time `json:"uninstalled_at"`
+}
+
+// ExportedVote is a helpful/unhelpful vote cast by the user
+type ExportedVote struct {
+	ServerID     string    `json:"server_id"`
+	ReviewUserID string    `json:"review_user_id"`
+	Helpful      bool      `json:"helpful"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ExportedReport is an abuse report filed by the user
+type ExportedReport struct {
+	ServerID     string     `json:"server_id"`
+	ReviewUserID string     `json:"review_user_id"`
+	Reason       string     `json:"reason"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ResolvedAt   *time.Time `json:"resolved_at"`
+}
+
+// ExportedEvent is an install, uninstall or rating event still within the retention window
+type ExportedEvent struct {
+	ServerID  string    `json:"server_id"`
+	Type      string    `json:"type"`
+	Rating    *int      `json:"rating,omitempty"`
+	Source    string    `json:"source,omitempty"`
+	Version   string    `json:"version,omitempty"`
+	Platform  string    `json:"platform,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ExportedBan records that the user was banned from reviewing
+type ExportedBan struct {
+	Reason   string    `json:"reason"`
+	BannedAt time.Time `json:"banned_at"`
+}
+
+// ExportUser collects everything stored about a user in one consistent snapshot
+func (db *DB) ExportUser(ctx context.Context, userID string) (*UserExport, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // Read-only; nothing to commit
+
+	ids := pq.Array(db.userIDs.Candidates(userID))
+	export := &UserExport{
+		Votes:       []ExportedVote{},
+		Reports:     []ExportedReport{},
+		Events:      []ExportedEvent{},
+		Collections: []Collection{},
+	}
+
+	var profile profiles.Profile
+	var username, avatarURL sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT user_id, username, avatar_url, opted_out
+		FROM proxy_user_profiles WHERE user_id = ANY($1)
+		LIMIT 1
+	`, ids).Scan(&profile.UserID, &username, &avatarURL, &profile.OptedOut)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to export profile: %w", err)
+	}
+	if err == nil {
+		profile.Username = username.String
+		profile.AvatarURL = avatarURL.String
+		export.Profile = &profile
+	}
+
+	var ban ExportedBan
+	var banReason sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT reason, banned_at FROM proxy_banned_users WHERE user_id = ANY($1)
+		LIMIT 1
+	`, ids).Scan(&banReason, &ban.BannedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to export ban: %w", err)
+	}
+	if err == nil {
+		ban.Reason = banReason.String
+		export.Ban = &ban
+	}
+
+	if export.Ratings, err = userRatings(ctx, tx, ids, false); err != nil {
+		return nil, fmt.Errorf("failed to export ratings: %w", err)
+	}
+	if export.Installations, err = userInstallations(ctx, tx, ids, false); err != nil {
+		return nil, fmt.Errorf("failed to export installations: %w", err)
+	}
+
+	err = queryRows(ctx, tx, `
+		SELECT server_id, user_id, helpful, created_at, updated_at
+		FROM proxy_review_votes WHERE voter_id = ANY($1)
+		ORDER BY created_at
+	`, []interface{}{ids}, func(rows *sql.Rows) error {
+		var v ExportedVote
+		if err := rows.Scan(&v.ServerID, &v.ReviewUserID, &v.Helpful, &v.CreatedAt, &v.UpdatedAt); err != nil {
+			return err
+		}
+		export.Votes = append(export.Votes, v)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export votes: %w", err)
+	}
+
+	err = queryRows(ctx, tx, `
+		SELECT server_id, user_id, COALESCE(reason, ''), created_at, resolved_at
+		FROM proxy_review_reports WHERE reporter_id = ANY($1)
+		ORDER BY created_at
+	`, []interface{}{ids}, func(rows *sql.Rows) error {
+		var r ExportedReport
+		var resolved sql.NullTime
+		if err := rows.Scan(&r.ServerID, &r.ReviewUserID, &r.Reason, &r.CreatedAt, &resolved); err != nil {
+			return err
+		}
+		r.ResolvedAt = nullTime(resolved)
+		export.Reports = append(export.Reports, r)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export reports: %w", err)
+	}
+
+	err = queryRows(ctx, tx, `
+		SELECT server_id, event_type, rating, COALESCE(source, ''), COALESCE(version, ''),
+			COALESCE(platform, ''), created_at
+		FROM proxy_server_events WHERE user_id = ANY($1)
+		ORDER BY created_at
+	`, []interface{}{ids}, func(rows *sql.Rows) error {
+		var e ExportedEvent
+		var rating sql.NullInt64
+		if err := rows.Scan(&e.ServerID, &e.Type, &rating, &e.Source, &e.Version, &e.Platform, &e.CreatedAt); err != nil {
+			return err
+		}
+		if rating.Valid {
+			stars := int(rating.Int64)
+			e.Rating = &stars
+		}
+		export.Events = append(export.Events, e)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export events: %w", err)
+	}
+
+	err = queryRows(ctx, tx, selectCollections("c.owner_id = ANY($1)", "c.created_at"), []interface{}{ids}, func(rows *sql.Rows) error {
+		c, err := scanCollection(rows)
+		if err != nil {
+			return err
+		}
+		export.Collections = append(export.Collections, c)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export collections: %w", err)
+	}
+
+	return export, nil
+}
+
+// queryer is implemented by both DB and its transactions
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// userRatings returns the ratings stored under any of ids, oldest first, leaving out
+// pending and hidden ones when visibleOnly is set
+func userRatings(ctx context.Context, q queryer, ids interface{}, visibleOnly bool) ([]UserRating, error) {
+	ratings := []UserRating{}
+	err := queryRows(ctx, q, `
+		SELECT server_id, rating, COALESCE(comment, ''), status, COALESCE(moderation_reason, ''),
+			helpful_count, unhelpful_count, created_at, updated_at
+		FROM proxy_user_ratings
+		WHERE user_id = ANY($1) AND (NOT $2 OR status = 'visible')
+		ORDER BY created_at
+	`, []interface{}{ids, visibleOnly}, func(rows *sql.Rows) error {
+		var r UserRating
+		if err := rows.Scan(&r.ServerID, &r.Rating, &r.Comment, &r.Status, &r.ModerationReason,
+			&r.HelpfulCount, &r.UnhelpfulCount, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			return err
+		}
+		ratings = append(ratings, r)
+		return nil
+	})
+	return ratings, err
+}
+
+// userInstallations returns the installations stored under any of ids, oldest first,
+// leaving out uninstalled servers when currentOnly is set
+func userInstallations(ctx context.Context, q queryer, ids interface{}, currentOnly bool) ([]UserInstallation, error) {
+	installs := []UserInstallation{}
+	err := queryRows(ctx, q, `
+		SELECT server_id, COALESCE(source, ''), COALESCE(version, ''), COALESCE(platform, ''),
+			installed_at, last_seen_at, uninstalled_at
+		FROM proxy_user_installations
+		WHERE user_id = ANY($1) AND (NOT $2 OR uninstalled_at IS NULL)
+		ORDER BY installed_at
+	`, []interface{}{ids, currentOnly}, func(rows *sql.Rows) error {
+		var i UserInstallation
+		var lastSeen, uninstalled sql.NullTime
+		if err := rows.Scan(&i.ServerID, &i.Source, &i.Version, &i.Platform,
+			&i.InstalledAt, &lastSeen, &uninstalled); err != nil {
+			return err
+		}
+		i.LastSeenAt = nullTime(lastSeen)
+		i.UninstalledAt = nullTime(uninstalled)
+		installs = append(installs, i)
+		return nil
+	})
+	return installs, err
+}
+
+// queryRows runs a query and calls scan for every row
+func queryRows(ctx context.Context, q queryer, query string, args []interface{}, scan func(*sql.Rows) error) error {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// nullTime converts a nullable timestamp to a pointer, nil when NULL
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
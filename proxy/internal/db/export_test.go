@@ -0,0 +1,131 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/veriteknik/registry-proxy/internal/privacy"
+	"github.com/veriteknik/registry-proxy/internal/profiles"
+)
+
+func TestExportUser_CollectsEveryStoredID(t *testing.T) {
+	hasher := newHasher(t)
+	database, mock := newMockDB(t, hasher)
+	ids := pq.Array(hasher.Candidates("alice"))
+	created := time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC)
+	uninstalled := created.Add(48 * time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(stmt(`SELECT user_id, username, avatar_url, opted_out FROM proxy_user_profiles`)).WithArgs(ids).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "avatar_url", "opted_out"}).
+			AddRow(hasher.Hash("alice"), "alice", nil, true))
+	mock.ExpectQuery(stmt(`SELECT reason, banned_at FROM proxy_banned_users`)).WithArgs(ids).
+		WillReturnError(sql.ErrNoRows)
+	// Ratings and installations are exported whatever their status
+	mock.ExpectQuery(stmt(`SELECT server_id, rating`)).WithArgs(ids, false).
+		WillReturnRows(sqlmock.NewRows([]string{"server_id", "rating", "comment", "status", "moderation_reason", "helpful_count", "unhelpful_count", "created_at", "updated_at"}).
+			AddRow("server-a", 2, "Spam?", ReviewStatusPending, "reported", 0, 1, created, created))
+	mock.ExpectQuery(stmt(`SELECT server_id, COALESCE(source, '')`)).WithArgs(ids, false).
+		WillReturnRows(sqlmock.NewRows([]string{"server_id", "source", "version", "platform", "installed_at", "last_seen_at", "uninstalled_at"}).
+			AddRow("server-a", "cli", "1.0.0", "linux", created, nil, uninstalled))
+	mock.ExpectQuery(stmt(`SELECT server_id, user_id, helpful`)).WithArgs(ids).
+		WillReturnRows(sqlmock.NewRows([]string{"server_id", "user_id", "helpful", "created_at", "updated_at"}))
+	mock.ExpectQuery(stmt(`SELECT server_id, user_id, COALESCE(reason, '')`)).WithArgs(ids).
+		WillReturnRows(sqlmock.NewRows([]string{"server_id", "user_id", "reason", "created_at", "resolved_at"}).
+			AddRow("server-b", "bob", "abusive", created, nil))
+	mock.ExpectQuery(stmt(`SELECT server_id, event_type, rating`)).WithArgs(ids).
+		WillReturnRows(sqlmock.NewRows([]string{"server_id", "event_type", "rating", "source", "version", "platform", "created_at"}).
+			AddRow("server-a", EventInstall, nil, "cli", "1.0.0", "linux", created).
+			AddRow("server-a", EventRating, 2, "", "", "", created))
+	mock.ExpectQuery(`FROM collections`).WithArgs(ids).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	export, err := database.ExportUser(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("ExportUser() error = %v", err)
+	}
+
+	if export.Profile == nil || export.Profile.Username != "alice" || !export.Profile.OptedOut {
+		t.Errorf("Profile = %+v, want alice's opted-out profile", export.Profile)
+	}
+	if export.Ban != nil {
+		t.Errorf("Ban = %+v, want none", export.Ban)
+	}
+	if len(export.Ratings) != 1 || export.Ratings[0].Status != ReviewStatusPending || export.Ratings[0].ModerationReason != "reported" {
+		t.Errorf("Ratings = %+v, want the pending review with its reason", export.Ratings)
+	}
+	if len(export.Installations) != 1 || export.Installations[0].UninstalledAt == nil || !export.Installations[0].UninstalledAt.Equal(uninstalled) {
+		t.Errorf("Installations = %+v, want the uninstalled installation", export.Installations)
+	}
+	if export.Votes == nil || len(export.Votes) != 0 || export.Collections == nil {
+		t.Errorf("Votes = %v, Collections = %v, want empty lists rather than null", export.Votes, export.Collections)
+	}
+	if len(export.Reports) != 1 || export.Reports[0].ReviewUserID != "bob" || export.Reports[0].ResolvedAt != nil {
+		t.Errorf("Reports = %+v, want the open report on bob's review", export.Reports)
+	}
+	if len(export.Events) != 2 || export.Events[0].Rating != nil || export.Events[1].Rating == nil || *export.Events[1].Rating != 2 {
+		t.Errorf("Events = %+v, want an install without rating and a 2-star rating", export.Events)
+	}
+}
+
+func TestExportUser_Postgres(t *testing.T) {
+	ctx := context.Background()
+	previous, err := privacy.NewHasher(privacy.Pepper{ID: "1", Secret: []byte("previous")})
+	if err != nil {
+		t.Fatalf("NewHasher() error = %v", err)
+	}
+	database := newTestDB(t, previous)
+
+	// alice installed server-b before the pepper was rotated
+	if err := database.TrackInstallation(ctx, "server-b", "alice", "cli", "1.0.0", "linux"); err != nil {
+		t.Fatalf("TrackInstallation(server-b) error = %v", err)
+	}
+	database.userIDs = newHasher(t)
+
+	if err := database.UpsertRating(ctx, "server-a", "bob", 4, "Good", "", ""); err != nil {
+		t.Fatalf("UpsertRating(bob) error = %v", err)
+	}
+	bob := database.userIDs.Hash("bob")
+	if err := database.PutUserProfile(ctx, "alice", profiles.Profile{Username: "alice", OptedOut: true}); err != nil {
+		t.Fatalf("PutUserProfile() error = %v", err)
+	}
+	if err := database.UpsertRating(ctx, "server-a", "alice", 2, "Meh", "", ""); err != nil {
+		t.Fatalf("UpsertRating(alice) error = %v", err)
+	}
+	if _, err := database.VoteReview(ctx, "server-a", bob, "alice", false); err != nil {
+		t.Fatalf("VoteReview() error = %v", err)
+	}
+	if _, err := database.ReportReview(ctx, "server-a", bob, "alice", "off topic", 3); err != nil {
+		t.Fatalf("ReportReview() error = %v", err)
+	}
+
+	export, err := database.ExportUser(ctx, "alice")
+	if err != nil {
+		t.Fatalf("ExportUser() error = %v", err)
+	}
+	if export.Profile == nil || export.Profile.Username != "alice" || !export.Profile.OptedOut {
+		t.Errorf("Profile = %+v, want alice's opted out profile", export.Profile)
+	}
+	if export.Ban != nil {
+		t.Errorf("Ban = %+v, want none", export.Ban)
+	}
+	if len(export.Ratings) != 1 || export.Ratings[0].ServerID != "server-a" || export.Ratings[0].Comment != "Meh" {
+		t.Errorf("Ratings = %+v, want alice's review of server-a", export.Ratings)
+	}
+	if len(export.Installations) != 1 || export.Installations[0].ServerID != "server-b" {
+		t.Errorf("Installations = %+v, want server-b under the previous pepper", export.Installations)
+	}
+	if len(export.Votes) != 1 || export.Votes[0].ReviewUserID != bob || export.Votes[0].Helpful {
+		t.Errorf("Votes = %+v, want an unhelpful vote on bob's review", export.Votes)
+	}
+	if len(export.Reports) != 1 || export.Reports[0].Reason != "off topic" || export.Reports[0].ResolvedAt != nil {
+		t.Errorf("Reports = %+v, want an open report of bob's review", export.Reports)
+	}
+	if len(export.Events) != 2 || export.Events[0].Type != EventInstall || export.Events[1].Type != EventRating {
+		t.Errorf("Events = %+v, want the install and the rating", export.Events)
+	}
+}
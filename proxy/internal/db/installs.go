@@ -0,0 +1,345 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// defaultActiveInstallDays is how recently an installation must have sent a heartbeat to count as active
+const defaultActiveInstallDays = 30
+
+// ErrInstallationNotFound is returned when a user has no current installation of a server
+var ErrInstallationNotFound = errors.New("installation not found")
+
+// ActiveInstallDaysFromEnv reads ACTIVE_INSTALL_DAYS, falling back to 30 days
+func ActiveInstallDaysFromEnv() int {
+	if v, err := strconv.Atoi(os.Getenv("ACTIVE_INSTALL_DAYS")); err == nil && v > 0 {
+		return v
+	}
+	return defaultActiveInstallDays
+}
+
+// installStatsColumns aggregates installations into the install columns of
+// proxy_server_stats, in the order of installStatsInsertColumns. An installation is
+// active while it is not uninstalled and was installed or seen within the window of
+// days read from the given query parameter. Retention is the share of installations
+// older than the window that are still active.
+func installStatsColumns(daysParam int) string {
+	return fmt.Sprintf(`
+		COUNT(*)::integer,
+		COUNT(*)::integer,
+		COUNT(*) FILTER (WHERE uninstalled_at IS NULL
+			AND COALESCE(last_seen_at, installed_at) > NOW() - make_interval(days => $%[1]d))::integer,
+		COALESCE(
+			COUNT(*) FILTER (WHERE installed_at <= NOW() - make_interval(days => $%[1]d)
+				AND uninstalled_at IS NULL
+				AND COALESCE(last_seen_at, installed_at) > NOW() - make_interval(days => $%[1]d))::float8
+			/ NULLIF(COUNT(*) FILTER (WHERE installed_at <= NOW() - make_interval(days => $%[1]d)), 0),
+		0)::numeric(5,4)`, daysParam)
+}
+
+// installStatsInsertColumns and installStatsUpdate name the columns filled by
+// installStatsColumns. installation_count mirrors total_installs for existing clients.
+const (
+	installStatsInsertColumns = `installation_count, total_installs, active_installs, retention`
+	installStatsUpdate        = `
+			installation_count = EXCLUDED.installation_count,
+			total_installs = EXCLUDED.total_installs,
+			active_installs = EXCLUDED.active_installs,
+			retention = EXCLUDED.retention,
+			updated_at = NOW()`
+)
+
+// recalculateInstallStats recomputes a server's install stats from its installations
+func recalculateInstallStats(ctx context.Context, tx *sql.Tx, serverID string, activeDays int) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO proxy_server_stats (server_id, `+installStatsInsertColumns+`, updated_at)
+		SELECT $1, `+installStatsColumns(2)+`, NOW()
+		FROM proxy_user_installations
+		WHERE server_id = $1
+		ON CONFLICT (server_id)
+		DO UPDATE SET`+installStatsUpdate, serverID, activeDays)
+	if err != nil {
+		return fmt.Errorf("failed to update installation count: %w", err)
+	}
+	return nil
+}
+
+// RecalculateAllInstallStats recomputes the install stats of every installed server, so
+// installations drop out of the active count once their last heartbeat leaves the window
+func (db *DB) RecalculateAllInstallStats(ctx context.Context) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO proxy_server_stats (server_id, `+installStatsInsertColumns+`, updated_at)
+		SELECT server_id, `+installStatsColumns(1)+`, NOW()
+		FROM proxy_user_installations
+		GROUP BY server_id
+		ON CONFLICT (server_id)
+		DO UPDATE SET`+installStatsUpdate, db.activeInstallDays)
+	if err != nil {
+		return fmt.Errorf("failed to recalculate install stats: %w", err)
+	}
+	return nil
+}
+
+// TrackInstallation tracks a server installation; installing again after an uninstall
+// makes the installation current again
+func (db *DB) TrackInstallation(ctx context.Context, serverID, rawUserID, source, version, platform string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // Rollback on error; ignore error if already committed
+
+	userID, err := db.storedUserID(ctx, tx, rawUserID)
+	if err != nil {
+		return err
+	}
+
+	// Lock the user's installation, if any, so a reinstall racing an uninstall is seen as either
+	var current bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT uninstalled_at IS NULL FROM proxy_user_installations
+		WHERE server_id = $1 AND user_id = $2
+		FOR UPDATE
+	`, serverID, userID).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check installation: %w", err)
+	}
+
+	// Insert installation (or update if exists)
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO proxy_user_installations (server_id, user_id, source, version, platform, installed_at, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		ON CONFLICT (server_id, user_id)
+		DO UPDATE SET
+			source = $3,
+			version = $4,
+			platform = $5,
+			installed_at = NOW(),
+			last_seen_at = NOW(),
+			uninstalled_at = NULL
+	`, serverID, userID, source, version, platform)
+	if err != nil {
+		return fmt.Errorf("failed to track installation: %w", err)
+	}
+
+	// Only new and revived installations are logged: reinstalling over a current one just
+	// refreshes the row above, so repeating it cannot inflate the daily installs
+	if !current {
+		err = recordEvent(ctx, tx, Event{
+			ServerID: serverID,
+			UserID:   userID,
+			Type:     EventInstall,
+			Source:   source,
+			Version:  version,
+			Platform: platform,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := recalculateInstallStats(ctx, tx, serverID, db.activeInstallDays); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UninstallServer marks a user's current installation of a server as removed. It stays
+// in total_installs but no longer counts as active.
+func (db *DB) UninstallServer(ctx context.Context, serverID, rawUserID string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // Rollback on error; ignore error if already committed
+
+	userID, err := db.storedUserID(ctx, tx, rawUserID)
+	if err != nil {
+		return err
+	}
+
+	var version, platform sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		UPDATE proxy_user_installations
+		SET uninstalled_at = NOW()
+		WHERE server_id = $1 AND user_id = $2 AND uninstalled_at IS NULL
+		RETURNING version, platform
+	`, serverID, userID).Scan(&version, &platform)
+	if err == sql.ErrNoRows {
+		return ErrInstallationNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to track uninstall: %w", err)
+	}
+
+	err = recordEvent(ctx, tx, Event{
+		ServerID: serverID,
+		UserID:   userID,
+		Type:     EventUninstall,
+		Version:  version.String,
+		Platform: platform.String,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := recalculateInstallStats(ctx, tx, serverID, db.activeInstallDays); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RecordHeartbeat marks a user's current installation of a server as seen now, updating
+// its version and platform when given. Stats are only recomputed when the heartbeat
+// makes an inactive installation active again.
+func (db *DB) RecordHeartbeat(ctx context.Context, serverID, rawUserID, version, platform string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // Rollback on error; ignore error if already committed
+
+	userID, err := db.storedUserID(ctx, tx, rawUserID)
+	if err != nil {
+		return err
+	}
+
+	var wasActive bool
+	err = tx.QueryRowContext(ctx, `
+		UPDATE proxy_user_installations i
+		SET
+			last_seen_at = NOW(),
+			version = COALESCE(NULLIF($3, ''), i.version),
+			platform = COALESCE(NULLIF($4, ''), i.platform)
+		FROM (
+			SELECT COALESCE(last_seen_at, installed_at) AS seen_at
+			FROM proxy_user_installations
+			WHERE server_id = $1 AND user_id = $2 AND uninstalled_at IS NULL
+			FOR UPDATE
+		) prev
+		WHERE i.server_id = $1 AND i.user_id = $2
+		RETURNING prev.seen_at > NOW() - make_interval(days => $5)
+	`, serverID, userID, version, platform, db.activeInstallDays).Scan(&wasActive)
+	if err == sql.ErrNoRows {
+		return ErrInstallationNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record heartbeat: %w", err)
+	}
+
+	if !wasActive {
+		if err := recalculateInstallStats(ctx, tx, serverID, db.activeInstallDays); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// BreakdownEntry counts the installations sharing one version, platform or source
+type BreakdownEntry struct {
+	Value    string `json:"value"`
+	Installs int    `json:"installs"`
+}
+
+// InstallBreakdown groups current installations by the version, platform and source
+// client they last reported. Missing values are grouped as "unknown".
+type InstallBreakdown struct {
+	Total     int              `json:"total"`
+	Versions  []BreakdownEntry `json:"versions"`
+	Platforms []BreakdownEntry `json:"platforms"`
+	Sources   []BreakdownEntry `json:"sources"`
+}
+
+// add files a grouped row under its dimension
+func (b *InstallBreakdown) add(dimension, value string, installs int) {
+	entry := BreakdownEntry{Value: value, Installs: installs}
+	switch dimension {
+	case "version":
+		b.Versions = append(b.Versions, entry)
+	case "platform":
+		b.Platforms = append(b.Platforms, entry)
+	case "source":
+		b.Sources = append(b.Sources, entry)
+	case "total":
+		b.Total = installs
+	}
+}
+
+// GetInstallBreakdown counts the current installations of a server, or of every server
+// when serverID is empty, by version, platform and source, keeping the limit most common
+// values of each. activeOnly restricts the counts to active installations.
+func (db *DB) GetInstallBreakdown(ctx context.Context, serverID string, activeOnly bool, limit int) (InstallBreakdown, error) {
+	where := "uninstalled_at IS NULL"
+	args := []interface{}{limit}
+	if activeOnly {
+		args = append(args, db.activeInstallDays)
+		where += fmt.Sprintf(" AND COALESCE(last_seen_at, installed_at) > NOW() - make_interval(days => $%d)", len(args))
+	}
+	if serverID != "" {
+		args = append(args, serverID)
+		where += fmt.Sprintf(" AND server_id = $%d", len(args))
+	}
+
+	// One scan groups every dimension; the empty grouping set yields the total
+	rows, err := db.QueryContext(ctx, `
+		WITH current_installs AS (
+			SELECT
+				COALESCE(NULLIF(version, ''), 'unknown') AS version,
+				COALESCE(NULLIF(platform, ''), 'unknown') AS platform,
+				COALESCE(NULLIF(source, ''), 'unknown') AS source
+			FROM proxy_user_installations
+			WHERE `+where+`
+		), grouped AS (
+			SELECT
+				CASE
+					WHEN GROUPING(version) = 0 THEN 'version'
+					WHEN GROUPING(platform) = 0 THEN 'platform'
+					WHEN GROUPING(source) = 0 THEN 'source'
+					ELSE 'total'
+				END AS dimension,
+				COALESCE(version, platform, source, '') AS value,
+				COUNT(*)::integer AS installs
+			FROM current_installs
+			GROUP BY GROUPING SETS ((version), (platform), (source), ())
+		)
+		SELECT dimension, value, installs
+		FROM (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY dimension ORDER BY installs DESC, value) AS rank
+			FROM grouped
+		) ranked
+		WHERE rank <= $1
+		ORDER BY dimension, installs DESC, value
+	`, args...)
+	if err != nil {
+		return InstallBreakdown{}, fmt.Errorf("failed to query install breakdown: %w", err)
+	}
+	defer rows.Close()
+
+	breakdown := InstallBreakdown{
+		Versions:  []BreakdownEntry{},
+		Platforms: []BreakdownEntry{},
+		Sources:   []BreakdownEntry{},
+	}
+	for rows.Next() {
+		var dimension, value string
+		var installs int
+		if err := rows.Scan(&dimension, &value, &installs); err != nil {
+			return InstallBreakdown{}, fmt.Errorf("failed to scan install breakdown: %w", err)
+		}
+		breakdown.add(dimension, value, installs)
+	}
+
+	if err = rows.Err(); err != nil {
+		return InstallBreakdown{}, fmt.Errorf("error iterating install breakdown: %w", err)
+	}
+
+	return breakdown, nil
+}
@@ -0,0 +1,217 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestInstallBreakdown_Add(t *testing.T) {
+	breakdown := InstallBreakdown{
+		Versions:  []BreakdownEntry{},
+		Platforms: []BreakdownEntry{},
+		Sources:   []BreakdownEntry{},
+	}
+
+	rows := []struct {
+		dimension string
+		value     string
+		installs  int
+	}{
+		{"platform", "darwin", 7},
+		{"source", "unknown", 10},
+		{"total", "", 10},
+		{"version", "1.2.0", 6},
+		{"version", "1.1.0", 4},
+		{"ignored", "x", 1},
+	}
+	for _, row := range rows {
+		breakdown.add(row.dimension, row.value, row.installs)
+	}
+
+	if breakdown.Total != 10 {
+		t.Errorf("Expected total 10, got %d", breakdown.Total)
+	}
+	if len(breakdown.Versions) != 2 || breakdown.Versions[0] != (BreakdownEntry{Value: "1.2.0", Installs: 6}) {
+		t.Errorf("Unexpected versions: %+v", breakdown.Versions)
+	}
+	if len(breakdown.Platforms) != 1 || len(breakdown.Sources) != 1 {
+		t.Errorf("Unexpected platforms %+v or sources %+v", breakdown.Platforms, breakdown.Sources)
+	}
+
+}
+
+// expectEvent expects recordEvent to log an event and add it to today's rollup
+func expectEvent(mock sqlmock.Sqlmock, eventType string) {
+	mock.ExpectExec(stmt(`INSERT INTO proxy_server_events`)).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), eventType, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(stmt(`INSERT INTO proxy_server_daily_stats`)).WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func TestTrackInstallation_LogsOnlyNewOrRevivedInstalls(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing *sqlmock.Rows // nil when the user never installed the server
+		wantLog  bool
+	}{
+		{"first install", nil, true},
+		{"reinstall over a current install", sqlmock.NewRows([]string{"current"}).AddRow(true), false},
+		{"reinstall after an uninstall", sqlmock.NewRows([]string{"current"}).AddRow(false), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, mock := newMockDB(t, nil)
+
+			mock.ExpectBegin()
+			check := mock.ExpectQuery(stmt(`SELECT uninstalled_at IS NULL FROM proxy_user_installations`)).WithArgs("server-a", "alice")
+			if tt.existing != nil {
+				check.WillReturnRows(tt.existing)
+			} else {
+				check.WillReturnError(sql.ErrNoRows)
+			}
+			mock.ExpectExec(stmt(`INSERT INTO proxy_user_installations`)).
+				WithArgs("server-a", "alice", "cli", "1.0.0", "linux").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			if tt.wantLog {
+				expectEvent(mock, EventInstall)
+			}
+			mock.ExpectExec(stmt(`INSERT INTO proxy_server_stats`)).WithArgs("server-a", 30).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			if err := database.TrackInstallation(context.Background(), "server-a", "alice", "cli", "1.0.0", "linux"); err != nil {
+				t.Fatalf("TrackInstallation() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestUninstallServer(t *testing.T) {
+	t.Run("logs the uninstall and recomputes active installs", func(t *testing.T) {
+		database, mock := newMockDB(t, nil)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(stmt(`UPDATE proxy_user_installations SET uninstalled_at = NOW()`)).WithArgs("server-a", "alice").
+			WillReturnRows(sqlmock.NewRows([]string{"version", "platform"}).AddRow("1.0.0", "linux"))
+		mock.ExpectExec(stmt(`INSERT INTO proxy_server_events`)).
+			WithArgs("server-a", "alice", EventUninstall, sql.NullInt64{}, "", "1.0.0", "linux").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(stmt(`INSERT INTO proxy_server_daily_stats`)).WithArgs("server-a", int64(0), int64(1), int64(0), int64(0)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(stmt(`INSERT INTO proxy_server_stats`)).WithArgs("server-a", 30).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		if err := database.UninstallServer(context.Background(), "server-a", "alice"); err != nil {
+			t.Fatalf("UninstallServer() error = %v", err)
+		}
+	})
+
+	t.Run("no current installation", func(t *testing.T) {
+		database, mock := newMockDB(t, nil)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(stmt(`UPDATE proxy_user_installations SET uninstalled_at = NOW()`)).WithArgs("server-a", "alice").
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		if err := database.UninstallServer(context.Background(), "server-a", "alice"); !errors.Is(err, ErrInstallationNotFound) {
+			t.Errorf("UninstallServer() error = %v, want ErrInstallationNotFound", err)
+		}
+	})
+}
+
+func TestRecordHeartbeat_RecomputesOnlyWhenReactivated(t *testing.T) {
+	for _, wasActive := range []bool{true, false} {
+		database, mock := newMockDB(t, nil)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(stmt(`UPDATE proxy_user_installations i SET last_seen_at = NOW()`)).
+			WithArgs("server-a", "alice", "1.1.0", "", 30).
+			WillReturnRows(sqlmock.NewRows([]string{"was_active"}).AddRow(wasActive))
+		if !wasActive {
+			mock.ExpectExec(stmt(`INSERT INTO proxy_server_stats`)).WithArgs("server-a", 30).WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		mock.ExpectCommit()
+
+		if err := database.RecordHeartbeat(context.Background(), "server-a", "alice", "1.1.0", ""); err != nil {
+			t.Fatalf("RecordHeartbeat(wasActive=%v) error = %v", wasActive, err)
+		}
+	}
+
+	database, mock := newMockDB(t, nil)
+	mock.ExpectBegin()
+	mock.ExpectQuery(stmt(`UPDATE proxy_user_installations i`)).WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+	if err := database.RecordHeartbeat(context.Background(), "server-a", "alice", "", ""); !errors.Is(err, ErrInstallationNotFound) {
+		t.Errorf("RecordHeartbeat() error = %v, want ErrInstallationNotFound", err)
+	}
+}
+
+func TestInstallLifecycle_Postgres(t *testing.T) {
+	database := newTestDB(t, nil)
+	ctx := context.Background()
+
+	for _, user := range []string{"alice", "bob", "carol"} {
+		if err := database.TrackInstallation(ctx, "server-a", user, "cli", "1.0.0", "linux"); err != nil {
+			t.Fatalf("TrackInstallation(%s) error = %v", user, err)
+		}
+	}
+
+	wantStats := func(step string, total, active int, retention float64) {
+		t.Helper()
+		stats, err := database.GetServerStats(ctx, "server-a")
+		if err != nil {
+			t.Fatalf("GetServerStats() error = %v", err)
+		}
+		if stats.InstallationCount != total || stats.ActiveInstalls != active || stats.Retention != retention {
+			t.Errorf("%s: stats = %+v, want %d installs, %d active and retention %v", step, stats, total, active, retention)
+		}
+	}
+	wantStats("installed", 3, 3, 0)
+
+	// carol installed before the window and has not been seen since
+	_, err := database.Exec(`
+		UPDATE proxy_user_installations
+		SET installed_at = NOW() - INTERVAL '40 days', last_seen_at = NOW() - INTERVAL '40 days'
+		WHERE user_id = 'carol'
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.RecalculateAllInstallStats(ctx); err != nil {
+		t.Fatalf("RecalculateAllInstallStats() error = %v", err)
+	}
+	wantStats("carol inactive", 3, 2, 0)
+
+	// Her heartbeat makes the installation active again and updates its version
+	if err := database.RecordHeartbeat(ctx, "server-a", "carol", "1.1.0", ""); err != nil {
+		t.Fatalf("RecordHeartbeat() error = %v", err)
+	}
+	wantStats("carol seen", 3, 3, 1)
+	var version, platform string
+	if err := database.QueryRow(`SELECT version, platform FROM proxy_user_installations WHERE user_id = 'carol'`).Scan(&version, &platform); err != nil {
+		t.Fatal(err)
+	}
+	if version != "1.1.0" || platform != "linux" {
+		t.Errorf("carol's installation = %s on %s, want 1.1.0 on linux", version, platform)
+	}
+
+	if err := database.UninstallServer(ctx, "server-a", "bob"); err != nil {
+		t.Fatalf("UninstallServer() error = %v", err)
+	}
+	wantStats("bob uninstalled", 3, 2, 1)
+	if err := database.UninstallServer(ctx, "server-a", "bob"); !errors.Is(err, ErrInstallationNotFound) {
+		t.Errorf("UninstallServer() again error = %v, want ErrInstallationNotFound", err)
+	}
+	if err := database.RecordHeartbeat(ctx, "server-a", "bob", "", ""); !errors.Is(err, ErrInstallationNotFound) {
+		t.Errorf("RecordHeartbeat() after uninstall error = %v, want ErrInstallationNotFound", err)
+	}
+
+	if err := database.TrackInstallation(ctx, "server-a", "bob", "cli", "1.1.0", "darwin"); err != nil {
+		t.Fatalf("TrackInstallation(bob) again error = %v", err)
+	}
+	wantStats("bob reinstalled", 3, 3, 1)
+}
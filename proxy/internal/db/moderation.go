@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Review statuses. Only visible reviews are published and counted in server stats.
+const (
+	ReviewStatusVisible = "visible"
+	ReviewStatusPending = "pending"
+	ReviewStatusHidden  = "hidden"
+)
+
+var (
+	// ErrUserBanned is returned when a banned user submits a review
+	ErrUserBanned = errors.New("user is banned from reviewing")
+	// ErrReviewNotFound is returned when a review does not exist or is not visible
+	ErrReviewNotFound = errors.New("review not found")
+	// ErrSelfReport is returned when a user reports their own review
+	ErrSelfReport = errors.New("cannot report own review")
+)
+
+// ReportReview records an abuse report against a visible review. Each reporter counts
+// once per review; once the unresolved reports reach threshold the review is held as
+// pending until a moderator hides or restores it. Returns the review's resulting status.
+func (db *DB) ReportReview(ctx context.Context, serverID, userID, reporterID, reason string, threshold int) (string, error) {
+	if db.isUser(reporterID, userID) {
+		return "", ErrSelfReport
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // Rollback on error; ignore error if already committed
+
+	if err := lockVisibleReview(ctx, tx, serverID, userID); err != nil {
+		return "", err
+	}
+	status := ReviewStatusVisible
+
+	reporter, err := db.storedUserID(ctx, tx, reporterID)
+	if err != nil {
+		return "", err
+	}
+
+	// A reporter may report again once their earlier report has been resolved
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO proxy_review_reports (server_id, user_id, reporter_id, reason, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (server_id, user_id, reporter_id)
+		DO UPDATE SET reason = EXCLUDED.reason, created_at = NOW(), resolved_at = NULL
+		WHERE proxy_review_reports.resolved_at IS NOT NULL
+	`, serverID, userID, reporter, reason)
+	if err != nil {
+		return "", fmt.Errorf("failed to record report: %w", err)
+	}
+
+	var openReports int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM proxy_review_reports
+		WHERE server_id = $1 AND user_id = $2 AND resolved_at IS NULL
+	`, serverID, userID).Scan(&openReports)
+	if err != nil {
+		return "", fmt.Errorf("failed to count reports: %w", err)
+	}
+
+	if threshold > 0 && openReports >= threshold {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE proxy_user_ratings SET status = $3
+			WHERE server_id = $1 AND user_id = $2
+		`, serverID, userID, ReviewStatusPending)
+		if err != nil {
+			return "", fmt.Errorf("failed to flag review: %w", err)
+		}
+		if err := recalculateRatingStats(ctx, tx, serverID, db.ratingPrior); err != nil {
+			return "", err
+		}
+		status = ReviewStatusPending
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit report: %w", err)
+	}
+
+	return status, nil
+}
+
+// lockVisibleReview locks a review row for the rest of the transaction, returning
+// ErrReviewNotFound unless the review exists and is visible
+func lockVisibleReview(ctx context.Context, tx *sql.Tx, serverID, userID string) error {
+	var status string
+	err := tx.QueryRowContext(ctx, `
+		SELECT status FROM proxy_user_ratings
+		WHERE server_id = $1 AND user_id = $2
+		FOR UPDATE
+	`, serverID, userID).Scan(&status)
+	if err == sql.ErrNoRows || (err == nil && status != ReviewStatusVisible) {
+		return ErrReviewNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load review: %w", err)
+	}
+	return nil
+}
+
+// CountDuplicateComments counts reviews by other users with the same comment fingerprint
+// updated since the given time
+func (db *DB) CountDuplicateComments(ctx context.Context, userID, fingerprint string, since time.Time) (int, error) {
+	var n int
+	err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM proxy_user_ratings
+		WHERE comment_fingerprint = $1 AND user_id <> ALL($2) AND updated_at >= $3
+	`, fingerprint, pq.Array(db.userIDs.Candidates(userID)), since).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count duplicate comments: %w", err)
+	}
+	return n, nil
+}
+
+// CountRecentReviews counts the reviews a user submitted or edited on other servers since the given time
+func (db *DB) CountRecentReviews(ctx context.Context, userID, excludeServerID string, since time.Time) (int, error) {
+	var n int
+	err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM proxy_user_ratings
+		WHERE user_id = ANY($1) AND server_id <> $2 AND updated_at >= $3
+	`, pq.Array(db.userIDs.Candidates(userID)), excludeServerID, since).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recent reviews: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestReportReview_HoldsReviewAtThreshold(t *testing.T) {
+	tests := []struct {
+		name        string
+		openReports int
+		want        string
+	}{
+		{"below threshold", 2, ReviewStatusVisible},
+		{"at threshold", 3, ReviewStatusPending},
+		{"above threshold", 4, ReviewStatusPending},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, mock := newMockDB(t, nil)
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(stmt(`SELECT status FROM proxy_user_ratings`)).WithArgs("server-a", "alice").
+				WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(ReviewStatusVisible))
+			mock.ExpectExec(stmt(`INSERT INTO proxy_review_reports`)).WithArgs("server-a", "alice", "bob", "spam").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectQuery(stmt(`SELECT COUNT(*) FROM proxy_review_reports`)).WithArgs("server-a", "alice").
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.openReports))
+			if tt.want == ReviewStatusPending {
+				// The held review leaves the published stats in the same transaction
+				mock.ExpectExec(stmt(`UPDATE proxy_user_ratings SET status = $3`)).
+					WithArgs("server-a", "alice", ReviewStatusPending).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(stmt(`SELECT proxy_recalculate_rating_stats`)).WithArgs("server-a", nil, nil).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			mock.ExpectCommit()
+
+			status, err := database.ReportReview(context.Background(), "server-a", "alice", "bob", "spam", 3)
+			if err != nil {
+				t.Fatalf("ReportReview() error = %v", err)
+			}
+			if status != tt.want {
+				t.Errorf("ReportReview() = %q, want %q", status, tt.want)
+			}
+		})
+	}
+}
+
+func TestReportReview_Rejects(t *testing.T) {
+	t.Run("own review", func(t *testing.T) {
+		hasher := newHasher(t)
+		database, _ := newMockDB(t, hasher)
+
+		// The author is identified by a previous pepper's hash of the reporter's ID
+		_, err := database.ReportReview(context.Background(), "server-a", hasher.Previous("alice")[0], "alice", "", 3)
+		if !errors.Is(err, ErrSelfReport) {
+			t.Errorf("ReportReview() error = %v, want ErrSelfReport", err)
+		}
+	})
+
+	for _, status := range []string{ReviewStatusPending, ReviewStatusHidden} {
+		t.Run(status+" review", func(t *testing.T) {
+			database, mock := newMockDB(t, nil)
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(stmt(`SELECT status FROM proxy_user_ratings`)).WithArgs("server-a", "alice").
+				WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(status))
+			mock.ExpectRollback()
+
+			if _, err := database.ReportReview(context.Background(), "server-a", "alice", "bob", "", 3); !errors.Is(err, ErrReviewNotFound) {
+				t.Errorf("ReportReview() error = %v, want ErrReviewNotFound", err)
+			}
+		})
+	}
+}
+
+func TestReportReview_Postgres(t *testing.T) {
+	database := newTestDB(t, nil)
+	ctx := context.Background()
+
+	if err := database.UpsertRating(ctx, "server-a", "alice", 1, "Spam", "", ""); err != nil {
+		t.Fatalf("UpsertRating(alice) error = %v", err)
+	}
+	if err := database.UpsertRating(ctx, "server-a", "bob", 5, "Great", "", ""); err != nil {
+		t.Fatalf("UpsertRating(bob) error = %v", err)
+	}
+
+	report := func(reporter, want string) {
+		t.Helper()
+		status, err := database.ReportReview(ctx, "server-a", "alice", reporter, "spam", 3)
+		if err != nil {
+			t.Fatalf("ReportReview(%s) error = %v", reporter, err)
+		}
+		if status != want {
+			t.Errorf("ReportReview(%s) = %q, want %q", reporter, status, want)
+		}
+	}
+
+	// A reporter's open report counts once however often they report
+	report("ip:1", ReviewStatusVisible)
+	report("ip:1", ReviewStatusVisible)
+	report("ip:2", ReviewStatusVisible)
+
+	// Once resolved, the report may be filed again and counts again
+	if _, err := database.Exec(`UPDATE proxy_review_reports SET resolved_at = NOW() WHERE reporter_id = 'ip:1'`); err != nil {
+		t.Fatal(err)
+	}
+	report("ip:1", ReviewStatusVisible)
+	report("ip:3", ReviewStatusPending)
+
+	var open int
+	if err := database.QueryRow(`SELECT COUNT(*) FROM proxy_review_reports WHERE resolved_at IS NULL`).Scan(&open); err != nil {
+		t.Fatal(err)
+	}
+	if open != 3 {
+		t.Errorf("open reports = %d, want 3", open)
+	}
+
+	// The held review no longer counts in the server's stats or reviews
+	stats, err := database.GetServerStats(ctx, "server-a")
+	if err != nil {
+		t.Fatalf("GetServerStats() error = %v", err)
+	}
+	if stats.RatingCount != 1 || stats.Rating != 5 || stats.Distribution.One != 0 {
+		t.Errorf("stats = %+v, want bob's rating only", stats)
+	}
+	reviews, err := database.GetReviews(ctx, "server-a")
+	if err != nil {
+		t.Fatalf("GetReviews() error = %v", err)
+	}
+	if len(reviews) != 1 || reviews[0].UserID != "bob" {
+		t.Errorf("reviews = %+v, want bob's review only", reviews)
+	}
+
+	// A held review cannot be reported again
+	if _, err := database.ReportReview(ctx, "server-a", "alice", "ip:4", "spam", 3); !errors.Is(err, ErrReviewNotFound) {
+		t.Errorf("ReportReview() on a held review error = %v, want ErrReviewNotFound", err)
+	}
+}
+
+func TestUpsertRating_Banned_Postgres(t *testing.T) {
+	database := newTestDB(t, nil)
+	ctx := context.Background()
+
+	if _, err := database.Exec(`INSERT INTO proxy_banned_users (user_id, reason) VALUES ('alice', 'spam')`); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.UpsertRating(ctx, "server-a", "alice", 5, "", "", ""); !errors.Is(err, ErrUserBanned) {
+		t.Errorf("UpsertRating() error = %v, want ErrUserBanned", err)
+	}
+}
@@ -9,11 +9,19 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"github.com/lib/pq"
+	"github.com/veriteknik/registry-proxy/internal/privacy"
 )
 
 // DB holds the database connection pool
 type DB struct {
 	*sql.DB
+
+	ratingPrior        RatingPrior
+	activeInstallDays  int             // Heartbeat window of active installations
+	eventRetentionDays int             // Age at which detailed events are purged
+	userIDs            *privacy.Hasher // Pseudonymizes user IDs before they are stored
 }
 
 // NewPostgresDB creates a new PostgreSQL database connection
@@ -45,7 +53,7 @@ func NewPostgresDB() (*DB, error) {
 
 	log.Println("✓ Connected to PostgreSQL database")
 
-	return &DB{db}, nil
+	return wrapDB(db)
 }
 
 // Close closes the database connection
@@ -54,106 +62,104 @@ func (db *DB) Close() error {
 }
 
 // GetServerStats retrieves stats for a server
-func (db *DB) GetServerStats(ctx context.Context, serverID string) (rating float64, ratingCount, installCount int, err error) {
+func (db *DB) GetServerStats(ctx context.Context, serverID string) (ServerStats, error) {
 	query := `
-		SELECT
-			COALESCE(rating, 0) as rating,
-			COALESCE(rating_count, 0) as rating_count,
-			COALESCE(installation_count, 0) as installation_count
-		FROM proxy_server_stats
-		WHERE server_id = $1
+		SELECT ` + ServerStatsColumns("ss") + `
+		FROM proxy_server_stats ss
+		WHERE ss.server_id = $1
 	`
 
-	err = db.QueryRowContext(ctx, query, serverID).Scan(&rating, &ratingCount, &installCount)
+	var stats ServerStats
+	err := db.QueryRowContext(ctx, query, serverID).Scan(stats.ScanDest()...)
 	if err == sql.ErrNoRows {
 		// No stats yet, return zeros
-		return 0, 0, 0, nil
+		return ServerStats{}, nil
+	}
+	return stats, err
+}
+
+// GetServerStatsByID retrieves the stats of several servers in one query; servers without
+// stats are left out
+func (db *DB) GetServerStatsByID(ctx context.Context, serverIDs []string) (map[string]ServerStats, error) {
+	result := make(map[string]ServerStats, len(serverIDs))
+	err := queryRows(ctx, db, `
+		SELECT ss.server_id, `+ServerStatsColumns("ss")+`
+		FROM proxy_server_stats ss
+		WHERE ss.server_id = ANY($1)
+	`, []interface{}{pq.Array(serverIDs)}, func(rows *sql.Rows) error {
+		var serverID string
+		var stats ServerStats
+		if err := rows.Scan(append([]interface{}{&serverID}, stats.ScanDest()...)...); err != nil {
+			return err
+		}
+		result[serverID] = stats
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query server stats: %w", err)
 	}
-	return rating, ratingCount, installCount, err
+	return result, nil
 }
 
-// UpsertRating inserts or updates a user rating
-func (db *DB) UpsertRating(ctx context.Context, serverID, userID string, rating int, comment string) error {
+// UpsertRating inserts or updates a user rating. fingerprint identifies the comment text
+// for duplicate detection; a non-empty heldReason stores the review as pending moderation.
+func (db *DB) UpsertRating(ctx context.Context, serverID, rawUserID string, rating int, comment, fingerprint, heldReason string) error {
 	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer func() { _ = tx.Rollback() }() // Rollback on error; ignore error if already committed
 
-	// Insert or update rating
-	_, err = tx.ExecContext(ctx, `
-		INSERT INTO proxy_user_ratings (server_id, user_id, rating, comment, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, NOW(), NOW())
-		ON CONFLICT (server_id, user_id)
-		DO UPDATE SET
-			rating = $3,
-			comment = $4,
-			updated_at = NOW()
-	`, serverID, userID, rating, comment)
+	userID, err := db.storedUserID(ctx, tx, rawUserID)
 	if err != nil {
-		return fmt.Errorf("failed to upsert rating: %w", err)
+		return err
 	}
 
-	// Recalculate and update server stats
-	_, err = tx.ExecContext(ctx, `
-		INSERT INTO proxy_server_stats (server_id, rating, rating_count, updated_at)
-		SELECT
-			server_id,
-			AVG(rating)::numeric(3,2),
-			COUNT(*)::integer,
-			NOW()
-		FROM proxy_user_ratings
-		WHERE server_id = $1
-		GROUP BY server_id
-		ON CONFLICT (server_id)
-		DO UPDATE SET
-			rating = EXCLUDED.rating,
-			rating_count = EXCLUDED.rating_count,
-			updated_at = NOW()
-	`, serverID)
+	// Banned users cannot submit or edit reviews
+	var banned bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM proxy_banned_users WHERE user_id = $1)`, userID).Scan(&banned)
 	if err != nil {
-		return fmt.Errorf("failed to update server stats: %w", err)
+		return fmt.Errorf("failed to check ban: %w", err)
+	}
+	if banned {
+		return ErrUserBanned
 	}
 
-	return tx.Commit()
-}
-
-// TrackInstallation tracks a server installation
-func (db *DB) TrackInstallation(ctx context.Context, serverID, userID, source, version, platform string) error {
-	tx, err := db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+	status := ReviewStatusVisible
+	if heldReason != "" {
+		status = ReviewStatusPending
 	}
-	defer func() { _ = tx.Rollback() }() // Rollback on error; ignore error if already committed
 
-	// Insert installation (or update if exists)
-	_, err = tx.ExecContext(ctx, `
-		INSERT INTO proxy_user_installations (server_id, user_id, source, version, platform, installed_at)
-		VALUES ($1, $2, $3, $4, $5, NOW())
+	// Insert or update rating; clean edits keep the moderation status, held edits go back to pending.
+	// xmax is 0 only for a freshly inserted row.
+	var inserted bool
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO proxy_user_ratings (server_id, user_id, rating, comment, comment_fingerprint, status, moderation_reason, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, NULLIF($7, ''), NOW(), NOW())
 		ON CONFLICT (server_id, user_id)
 		DO UPDATE SET
-			version = $4,
-			platform = $5,
-			installed_at = NOW()
-	`, serverID, userID, source, version, platform)
+			rating = $3,
+			comment = $4,
+			comment_fingerprint = EXCLUDED.comment_fingerprint,
+			status = CASE WHEN $6 = 'pending' THEN 'pending' ELSE proxy_user_ratings.status END,
+			moderation_reason = CASE WHEN $6 = 'pending' THEN EXCLUDED.moderation_reason ELSE proxy_user_ratings.moderation_reason END,
+			updated_at = NOW()
+		RETURNING xmax = 0
+	`, serverID, userID, rating, comment, fingerprint, status, heldReason).Scan(&inserted)
 	if err != nil {
-		return fmt.Errorf("failed to track installation: %w", err)
+		return fmt.Errorf("failed to upsert rating: %w", err)
 	}
 
-	// Update installation count in server_stats
-	_, err = tx.ExecContext(ctx, `
-		INSERT INTO proxy_server_stats (server_id, installation_count, updated_at)
-		SELECT server_id, COUNT(DISTINCT user_id)::integer, NOW()
-		FROM proxy_user_installations
-		WHERE server_id = $1
-		GROUP BY server_id
-		ON CONFLICT (server_id)
-		DO UPDATE SET
-			installation_count = EXCLUDED.installation_count,
-			updated_at = NOW()
-	`, serverID)
-	if err != nil {
-		return fmt.Errorf("failed to update installation count: %w", err)
+	// Only a user's first rating of a server is logged, so edits cannot inflate the daily ratings
+	if inserted {
+		if err := recordEvent(ctx, tx, Event{ServerID: serverID, UserID: userID, Type: EventRating, Rating: rating}); err != nil {
+			return err
+		}
+	}
+
+	// Recalculate and update server stats from visible reviews
+	if err := recalculateRatingStats(ctx, tx, serverID, db.ratingPrior); err != nil {
+		return err
 	}
 
 	return tx.Commit()
@@ -165,18 +171,24 @@ type Review struct {
 	ServerSource     string    `json:"server_source"`
 	ServerExternalID string    `json:"server_external_id"`
 	UserID           string    `json:"user_id"`
+	Username         string    `json:"username,omitempty"`
+	UserAvatar       string    `json:"user_avatar,omitempty"`
 	Rating           int       `json:"rating"`
 	Comment          string    `json:"comment"`
+	HelpfulCount     int       `json:"helpful_count"`
+	UnhelpfulCount   int       `json:"unhelpful_count"`
 	CreatedAt        time.Time `json:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at"`
+
+	Response *ReviewResponse `json:"response,omitempty"`
 }
 
-// GetReviews retrieves all reviews for a server
+// GetReviews retrieves all visible reviews for a server
 func (db *DB) GetReviews(ctx context.Context, serverID string) ([]Review, error) {
 	query := `
-		SELECT server_id, user_id, rating, comment, created_at, updated_at
+		SELECT server_id, user_id, rating, comment, helpful_count, unhelpful_count, created_at, updated_at
 		FROM proxy_user_ratings
-		WHERE server_id = $1
+		WHERE server_id = $1 AND status = 'visible'
 		ORDER BY created_at DESC
 	`
 
@@ -188,22 +200,10 @@ func (db *DB) GetReviews(ctx context.Context, serverID string) ([]Review, error)
 
 	var reviews []Review
 	for rows.Next() {
-		var r Review
-		var serverIDStr, userIDStr string
-		var comment sql.NullString
-
-		err := rows.Scan(&serverIDStr, &userIDStr, &r.Rating, &comment, &r.CreatedAt, &r.UpdatedAt)
+		r, err := scanReview(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan review: %w", err)
+			return nil, err
 		}
-
-		// Generate a unique UUID for this review (combination of server_id and user_id)
-		r.UUID = fmt.Sprintf("%s:%s", serverIDStr, userIDStr)
-		r.ServerSource = "REGISTRY"
-		r.ServerExternalID = serverIDStr
-		r.UserID = userIDStr
-		r.Comment = comment.String
-
 		reviews = append(reviews, r)
 	}
 
@@ -211,27 +211,20 @@ func (db *DB) GetReviews(ctx context.Context, serverID string) ([]Review, error)
 		return nil, fmt.Errorf("error iterating reviews: %w", err)
 	}
 
+	if err := db.attachReviewResponses(ctx, reviews); err != nil {
+		return nil, err
+	}
+
 	return reviews, nil
 }
 
-// GetReviewsPaginated retrieves reviews for a server with pagination and sorting
+// GetReviewsPaginated retrieves visible reviews for a server with pagination and sorting
 func (db *DB) GetReviewsPaginated(ctx context.Context, serverID string, limit, offset int, sort string) ([]Review, int, error) {
-	// Use whitelist for valid sort columns to prevent SQL injection
-	validSorts := map[string]string{
-		"newest":      "created_at DESC",
-		"oldest":      "created_at ASC",
-		"rating_high": "rating DESC, created_at DESC",
-		"rating_low":  "rating ASC, created_at DESC",
-	}
-
-	orderBy, ok := validSorts[sort]
-	if !ok {
-		orderBy = validSorts["newest"] // safe default
-	}
+	orderBy := reviewOrderBy(sort)
 
 	// Get total count
 	var totalCount int
-	countQuery := `SELECT COUNT(*) FROM proxy_user_ratings WHERE server_id = $1`
+	countQuery := `SELECT COUNT(*) FROM proxy_user_ratings WHERE server_id = $1 AND status = 'visible'`
 	err := db.QueryRowContext(ctx, countQuery, serverID).Scan(&totalCount)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to count reviews: %w", err)
@@ -239,9 +232,9 @@ func (db *DB) GetReviewsPaginated(ctx context.Context, serverID string, limit, o
 
 	// Get paginated reviews - orderBy is now from whitelist, safe to use
 	query := `
-		SELECT server_id, user_id, rating, comment, created_at, updated_at
+		SELECT server_id, user_id, rating, comment, helpful_count, unhelpful_count, created_at, updated_at
 		FROM proxy_user_ratings
-		WHERE server_id = $1
+		WHERE server_id = $1 AND status = 'visible'
 		ORDER BY ` + orderBy + `
 		LIMIT $2 OFFSET $3
 	`
@@ -254,22 +247,10 @@ func (db *DB) GetReviewsPaginated(ctx context.Context, serverID string, limit, o
 
 	var reviews []Review
 	for rows.Next() {
-		var r Review
-		var serverIDStr, userIDStr string
-		var comment sql.NullString
-
-		err := rows.Scan(&serverIDStr, &userIDStr, &r.Rating, &comment, &r.CreatedAt, &r.UpdatedAt)
+		r, err := scanReview(rows)
 		if err != nil {
-			return nil, 0, fmt.Errorf("failed to scan review: %w", err)
+			return nil, 0, err
 		}
-
-		// Generate a unique UUID for this review (combination of server_id and user_id)
-		r.UUID = fmt.Sprintf("%s:%s", serverIDStr, userIDStr)
-		r.ServerSource = "REGISTRY"
-		r.ServerExternalID = serverIDStr
-		r.UserID = userIDStr
-		r.Comment = comment.String
-
 		reviews = append(reviews, r)
 	}
 
@@ -277,9 +258,94 @@ func (db *DB) GetReviewsPaginated(ctx context.Context, serverID string, limit, o
 		return nil, 0, fmt.Errorf("error iterating reviews: %w", err)
 	}
 
+	if err := db.attachReviewResponses(ctx, reviews); err != nil {
+		return nil, 0, err
+	}
+
 	return reviews, totalCount, nil
 }
 
+// GetTopReviews retrieves the first limit visible reviews of each of several servers in
+// one query, in the given sort order (see GetReviewsPaginated); servers without reviews
+// are left out
+func (db *DB) GetTopReviews(ctx context.Context, serverIDs []string, limit int, sort string) (map[string][]Review, error) {
+	// orderBy is from the whitelist, safe to use
+	query := `
+		SELECT server_id, user_id, rating, comment, helpful_count, unhelpful_count, created_at, updated_at
+		FROM (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY server_id ORDER BY ` + reviewOrderBy(sort) + `) AS position
+			FROM proxy_user_ratings
+			WHERE server_id = ANY($1) AND status = 'visible'
+		) ranked
+		WHERE position <= $2
+		ORDER BY server_id, position
+	`
+
+	var reviews []Review
+	err := queryRows(ctx, db, query, []interface{}{pq.Array(serverIDs), limit}, func(rows *sql.Rows) error {
+		r, err := scanReview(rows)
+		if err != nil {
+			return err
+		}
+		reviews = append(reviews, r)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top reviews: %w", err)
+	}
+
+	if err := db.attachReviewResponses(ctx, reviews); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]Review)
+	for _, r := range reviews {
+		result[r.ServerExternalID] = append(result[r.ServerExternalID], r)
+	}
+	return result, nil
+}
+
+// reviewOrderBy returns the ORDER BY clause of a review sort, from a whitelist to prevent
+// SQL injection; unknown sorts order by newest
+func reviewOrderBy(sort string) string {
+	validSorts := map[string]string{
+		"newest":      "created_at DESC",
+		"oldest":      "created_at ASC",
+		"rating_high": "rating DESC, created_at DESC",
+		"rating_low":  "rating ASC, created_at DESC",
+		// Reviews without votes rank below any review with a helpful vote
+		"most_helpful": wilsonLowerBoundSQL + " DESC, created_at DESC",
+	}
+
+	orderBy, ok := validSorts[sort]
+	if !ok {
+		orderBy = validSorts["newest"] // safe default
+	}
+	return orderBy
+}
+
+// scanReview scans a review selected as server_id, user_id, rating, comment, helpful_count,
+// unhelpful_count, created_at, updated_at
+func scanReview(rows *sql.Rows) (Review, error) {
+	var r Review
+	var serverIDStr, userIDStr string
+	var comment sql.NullString
+
+	err := rows.Scan(&serverIDStr, &userIDStr, &r.Rating, &comment, &r.HelpfulCount, &r.UnhelpfulCount, &r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		return Review{}, fmt.Errorf("failed to scan review: %w", err)
+	}
+
+	// Generate a unique UUID for this review (combination of server_id and user_id)
+	r.UUID = fmt.Sprintf("%s:%s", serverIDStr, userIDStr)
+	r.ServerSource = "REGISTRY"
+	r.ServerExternalID = serverIDStr
+	r.UserID = userIDStr
+	r.Comment = comment.String
+
+	return r, nil
+}
+
 // NewRegistryDB creates a connection to the registry database
 func NewRegistryDB() (*DB, error) {
 	// Get connection string from environment or use default for docker network
@@ -310,11 +376,28 @@ func NewRegistryDB() (*DB, error) {
 
 	log.Println("✓ Connected to Registry PostgreSQL database")
 
-	return &DB{db}, nil
+	return wrapDB(db)
+}
+
+// wrapDB wraps a connection pool with the settings read from the environment
+func wrapDB(db *sql.DB) (*DB, error) {
+	userIDs, err := privacy.FromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID pepper: %w", err)
+	}
+
+	return &DB{
+		DB:                 db,
+		ratingPrior:        RatingPriorFromEnv(),
+		activeInstallDays:  ActiveInstallDaysFromEnv(),
+		eventRetentionDays: EventRetentionDaysFromEnv(),
+		userIDs:            userIDs,
+	}, nil
 }
 
 // ServerFilter contains all possible filters for servers
 type ServerFilter struct {
+	ServerIDs        []string `json:"server_ids,omitempty"` // Only these servers
 	Search           string   `json:"search,omitempty"`
 	RegistryTypes    []string `json:"registry_types,omitempty"` // npm, pypi, oci, remote, etc
 	Category         string   `json:"category,omitempty"`
@@ -326,7 +409,7 @@ type ServerFilter struct {
 
 // QueryServersEnhanced queries servers with filtering, sorting, and enrichment
 // Uses squirrel query builder to prevent SQL injection and improve maintainability
-func (db *DB) QueryServersEnhanced(ctx context.Context, filter ServerFilter, sort string, limit, offset int) ([]map[string]interface{}, int, error) {
+func (db *DB) QueryServersEnhanced(ctx context.Context, filter ServerFilter, sort string, limit, offset int) ([]ServerJSON, int, error) {
 	// Build the complete query using query builders
 	query, args, err := buildMainQuery(filter, sort, limit, offset)
 	if err != nil {
@@ -340,24 +423,17 @@ func (db *DB) QueryServersEnhanced(ctx context.Context, filter ServerFilter, sor
 	}
 	defer rows.Close()
 
-	servers := []map[string]interface{}{}
+	servers := []ServerJSON{}
 	var totalCount int
 
 	// Process each row
 	for rows.Next() {
 		// Scan row into individual fields
-		serverName, valueJSON, publishedAt, updatedAt, rating, ratingCount, installCount, total, err := scanServerRow(rows)
+		serverName, valueJSON, publishedAt, updatedAt, stats, total, err := scanServerRow(rows)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan server: %w", err)
 		}
 
-		// Create stats struct
-		stats := ServerStats{
-			Rating:            rating,
-			RatingCount:       ratingCount,
-			InstallationCount: installCount,
-		}
-
 		// Map row to server with enrichment
 		server, err := mapRowToServer(serverName, valueJSON, publishedAt, updatedAt, stats)
 		if err != nil {
@@ -372,6 +448,11 @@ func (db *DB) QueryServersEnhanced(ctx context.Context, filter ServerFilter, sor
 		return nil, 0, fmt.Errorf("error iterating servers: %w", err)
 	}
 
+	// Attach the managed category and tags
+	if err := db.applyTaxonomies(ctx, servers); err != nil {
+		return nil, 0, err
+	}
+
 	return servers, totalCount, nil
 }
 
@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/veriteknik/registry-proxy/internal/privacy"
+)
+
+// newMockDB returns a DB backed by sqlmock, hashing user IDs with userIDs (nil stores
+// them unchanged). Expectations are checked when the test ends.
+func newMockDB(t *testing.T, userIDs *privacy.Hasher) (*DB, sqlmock.Sqlmock) {
+	t.Helper()
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		conn.Close()
+	})
+	return &DB{DB: conn, ratingPrior: RatingPrior{}, activeInstallDays: 30, userIDs: userIDs}, mock
+}
+
+// registryServersTable creates the columns of the registry's servers table the proxy reads
+const registryServersTable = `
+	CREATE TABLE servers (
+		server_name TEXT NOT NULL,
+		version TEXT NOT NULL,
+		value JSONB NOT NULL,
+		status TEXT,
+		published_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		is_latest BOOLEAN NOT NULL DEFAULT true,
+		PRIMARY KEY (server_name, version)
+	)`
+
+// newTestDB returns a DB on the PostgreSQL database of TEST_DATABASE_URL (a postgres://
+// URL), skipping the test when it is unset. Each test gets its own schema holding the
+// registry's servers table and main/enhancement_schema.sql, dropped when the test ends.
+func newTestDB(t *testing.T, userIDs *privacy.Hasher) *DB {
+	t.Helper()
+	dbURL := os.Getenv("TEST_DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	ddl, err := os.ReadFile("../../../main/enhancement_schema.sql")
+	if err != nil {
+		t.Fatalf("failed to read schema: %v", err)
+	}
+
+	admin, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	schema := fmt.Sprintf("proxy_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		admin.Close()
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`); err != nil {
+			t.Errorf("failed to drop schema %s: %v", schema, err)
+		}
+		admin.Close()
+	})
+
+	u, err := url.Parse(dbURL)
+	if err != nil {
+		t.Fatalf("TEST_DATABASE_URL must be a postgres:// URL: %v", err)
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+
+	conn, err := sql.Open("postgres", u.String())
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	for _, stmt := range []string{registryServersTable, string(ddl)} {
+		if _, err := conn.Exec(stmt); err != nil {
+			t.Fatalf("failed to create test schema: %v", err)
+		}
+	}
+
+	return &DB{DB: conn, activeInstallDays: 30, eventRetentionDays: 90, userIDs: userIDs}
+}
+
+// newHasher returns a hasher writing with pepper "2" that still knows pepper "1"
+func newHasher(t *testing.T) *privacy.Hasher {
+	t.Helper()
+	h, err := privacy.NewHasher(
+		privacy.Pepper{ID: "2", Secret: []byte("current")},
+		privacy.Pepper{ID: "1", Secret: []byte("previous")},
+	)
+	if err != nil {
+		t.Fatalf("NewHasher() error = %v", err)
+	}
+	return h
+}
+
+// stmt matches a statement by its start, ignoring whitespace differences
+func stmt(prefix string) string {
+	return "^\\s*" + regexp.MustCompile(`\s+`).ReplaceAllString(regexp.QuoteMeta(prefix), `\s+`)
+}
+
+// expectRekey expects storedUserID to move the rows of the previous IDs to the current one
+func expectRekey(mock sqlmock.Sqlmock, current string, previous []string) {
+	for _, c := range userIDColumns {
+		mock.ExpectExec(stmt(`UPDATE `+c.table+` SET `+c.column+` = $1`)).
+			WithArgs(current, pq.Array(previous)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+}
+
+func TestUpsertRating_LogsOnlyFirstRating(t *testing.T) {
+	for _, inserted := range []bool{true, false} {
+		database, mock := newMockDB(t, nil)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(stmt(`SELECT EXISTS(SELECT 1 FROM proxy_banned_users`)).
+			WithArgs("alice").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(stmt(`INSERT INTO proxy_user_ratings`)).
+			WithArgs("server-a", "alice", 5, "Great", "", ReviewStatusVisible, "").
+			WillReturnRows(sqlmock.NewRows([]string{"inserted"}).AddRow(inserted))
+		if inserted {
+			expectEvent(mock, EventRating)
+		}
+		mock.ExpectExec(stmt(`SELECT proxy_recalculate_rating_stats`)).WithArgs("server-a", nil, nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		if err := database.UpsertRating(context.Background(), "server-a", "alice", 5, "Great", "", ""); err != nil {
+			t.Fatalf("UpsertRating(inserted=%v) error = %v", inserted, err)
+		}
+	}
+}
+// addTestServer publishes a version of a server in the registry's servers table
+func addTestServer(t *testing.T, database *DB, name, version, value string, latest bool) {
+	t.Helper()
+	_, err := database.Exec(`
+		INSERT INTO servers (server_name, version, value, status, is_latest)
+		VALUES ($1, $2, $3, 'active', $4)
+	`, name, version, value, latest)
+	if err != nil {
+		t.Fatalf("failed to add server %s %s: %v", name, version, err)
+	}
+}
+
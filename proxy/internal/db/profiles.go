@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/veriteknik/registry-proxy/internal/profiles"
+)
+
+// GetUserProfiles reads the public profiles of the given users from proxy_user_profiles.
+// Users without a row are left out of the result.
+func (db *DB) GetUserProfiles(ctx context.Context, userIDs []string) (map[string]profiles.Profile, error) {
+	result := make(map[string]profiles.Profile, len(userIDs))
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT user_id, COALESCE(username, ''), COALESCE(avatar_url, ''), opted_out
+		FROM proxy_user_profiles
+		WHERE user_id = ANY($1)
+	`, pq.Array(userIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user profiles: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p profiles.Profile
+		if err := rows.Scan(&p.UserID, &p.Username, &p.AvatarURL, &p.OptedOut); err != nil {
+			return nil, fmt.Errorf("failed to scan user profile: %w", err)
+		}
+		result[p.UserID] = p
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user profiles: %w", err)
+	}
+
+	return result, nil
+}
+
+// PutUserProfile stores the public profile of a user under their stored ID, so the table
+// provider finds it by the IDs reviews carry without the caller knowing how IDs are hashed
+func (db *DB) PutUserProfile(ctx context.Context, rawUserID string, p profiles.Profile) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // Rollback on error; ignore error if already committed
+
+	userID, err := db.storedUserID(ctx, tx, rawUserID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO proxy_user_profiles (user_id, username, avatar_url, opted_out, updated_at)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET
+			username = EXCLUDED.username,
+			avatar_url = EXCLUDED.avatar_url,
+			opted_out = EXCLUDED.opted_out,
+			updated_at = NOW()
+	`, userID, p.Username, p.AvatarURL, p.OptedOut)
+	if err != nil {
+		return fmt.Errorf("failed to store user profile: %w", err)
+	}
+
+	return tx.Commit()
+}
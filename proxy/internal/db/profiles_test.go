@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/veriteknik/registry-proxy/internal/profiles"
+)
+
+func TestPutUserProfile_StoresUnderHashedID(t *testing.T) {
+	hasher := newHasher(t)
+	database, mock := newMockDB(t, hasher)
+
+	mock.ExpectBegin()
+	expectRekey(mock, hasher.Hash("alice"), hasher.Previous("alice"))
+	mock.ExpectExec(stmt(`INSERT INTO proxy_user_profiles`)).
+		WithArgs(hasher.Hash("alice"), "Alice", "https://example.com/a.png", false).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := database.PutUserProfile(context.Background(), "alice", profiles.Profile{
+		Username:  "Alice",
+		AvatarURL: "https://example.com/a.png",
+	})
+	if err != nil {
+		t.Fatalf("PutUserProfile() error = %v", err)
+	}
+}
+
+func TestPutUserProfile_RollsBackOnError(t *testing.T) {
+	database, mock := newMockDB(t, nil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(stmt(`INSERT INTO proxy_user_profiles`)).
+		WithArgs("alice", "", "", true).
+		WillReturnError(context.DeadlineExceeded)
+	mock.ExpectRollback()
+
+	if err := database.PutUserProfile(context.Background(), "alice", profiles.Profile{OptedOut: true}); err == nil {
+		t.Fatal("PutUserProfile() error = nil, want the insert's error")
+	}
+}
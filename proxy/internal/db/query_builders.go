@@ -24,19 +24,11 @@ var (
 		"name_asc":      "server_name ASC",
 		"name_desc":     "server_name DESC",
 		"updated":       "updated_at DESC",
-		"rating_desc":   "rating DESC, rating_count DESC",
+		"rating_desc":   "weighted_rating DESC, rating_count DESC",
 		"reviews_desc":  "rating_count DESC",
 		"installs_desc": "installation_count DESC",
-		"trending": `(
-			installation_count * 0.3 +
-			rating_count * 0.3 +
-			rating * 10 +
-			CASE
-				WHEN updated_at > NOW() - INTERVAL '7 days' THEN 20
-				WHEN updated_at > NOW() - INTERVAL '30 days' THEN 10
-				ELSE 0
-			END
-		) DESC`,
+		// Precomputed by the trending scorer; see RefreshTrendingScores
+		"trending": "trending_score DESC, installation_count DESC",
 	}
 )
 
@@ -51,6 +43,14 @@ func buildBaseQuery() sq.SelectBuilder {
 			"rating",
 			"rating_count",
 			"installation_count",
+			"weighted_rating",
+			"rating_1_count",
+			"rating_2_count",
+			"rating_3_count",
+			"rating_4_count",
+			"rating_5_count",
+			"active_installs",
+			"retention",
 			"COUNT(*) OVER() as total_count",
 		).
 		From("filtered_servers")
@@ -68,20 +68,33 @@ func buildSearchFilter(cteWhere sq.And, filter ServerFilter) sq.And {
 	return cteWhere
 }
 
-// buildCategoryFilter adds category filtering to the query
+// buildCategoryFilter adds category filtering to the query using the managed category assignments
 func buildCategoryFilter(cteWhere sq.And, filter ServerFilter) sq.And {
 	if filter.Category != "" {
-		cteWhere = append(cteWhere, sq.Expr("s.value->>'category' = ?", filter.Category))
+		cteWhere = append(cteWhere, sq.Expr(
+			"EXISTS (SELECT 1 FROM proxy_server_categories sc WHERE sc.server_id = s.server_name AND sc.category_slug = ?)",
+			filter.Category,
+		))
 	}
 	return cteWhere
 }
 
-// buildTagsFilter adds tags filtering to the query
+// buildTagsFilter adds tags filtering to the query using the managed tag assignments
+// A server matches if it carries any of the requested tags
 func buildTagsFilter(cteWhere sq.And, filter ServerFilter) sq.And {
 	if len(filter.Tags) > 0 {
-		// Use ?| operator for array overlap (PostgreSQL)
-		// Note: ?? escapes to single ? in Squirrel, so ??| becomes ?| operator
-		cteWhere = append(cteWhere, sq.Expr("s.value->'tags' ??| ?", pq.Array(filter.Tags)))
+		cteWhere = append(cteWhere, sq.Expr(
+			"EXISTS (SELECT 1 FROM proxy_server_tags st WHERE st.server_id = s.server_name AND st.tag_slug = ANY(?))",
+			pq.Array(filter.Tags),
+		))
+	}
+	return cteWhere
+}
+
+// buildServerIDsFilter restricts the query to the requested servers
+func buildServerIDsFilter(cteWhere sq.And, filter ServerFilter) sq.And {
+	if len(filter.ServerIDs) > 0 {
+		cteWhere = append(cteWhere, sq.Expr("s.server_name = ANY(?)", pq.Array(filter.ServerIDs)))
 	}
 	return cteWhere
 }
@@ -190,7 +203,11 @@ func buildTransportFilter(filter ServerFilter) sq.And {
 	return mainWhere
 }
 
-// validateAndGetSortClause validates the sort parameter and returns the SQL ORDER BY clause
+// pinnedFirst orders servers pinned by an admin (value.pinned) before all others
+const pinnedFirst = "COALESCE(value->>'pinned' = 'true', false) DESC"
+
+// validateAndGetSortClause validates the sort parameter and returns the SQL ORDER BY clause,
+// keeping pinned servers on top of every sort order
 // This prevents SQL injection by using a whitelist approach
 func validateAndGetSortClause(sort string) (string, error) {
 	sortClause, exists := validSortOptions[sort]
@@ -205,15 +222,16 @@ func validateAndGetSortClause(sort string) (string, error) {
 		return "", fmt.Errorf("invalid sort parameter '%s'. Valid options: %s",
 			sort, strings.Join(validKeys, ", "))
 	}
-	return sortClause, nil
+	return pinnedFirst + ", " + sortClause, nil
 }
 
 // buildCTEQuery builds the Common Table Expression (CTE) for filtering servers
 func buildCTEQuery(filter ServerFilter) (string, []interface{}, error) {
-	// Start with base WHERE clause
-	cteWhere := sq.And{sq.Eq{"s.is_latest": true}}
+	// Start with base WHERE clause; soft-deleted servers are never listed
+	cteWhere := sq.And{sq.Eq{"s.is_latest": true}, sq.Expr("s.status IS DISTINCT FROM 'deleted'")}
 
 	// Apply all CTE-level filters
+	cteWhere = buildServerIDsFilter(cteWhere, filter)
 	cteWhere = buildSearchFilter(cteWhere, filter)
 	cteWhere = buildCategoryFilter(cteWhere, filter)
 	cteWhere = buildTagsFilter(cteWhere, filter)
@@ -227,12 +245,12 @@ func buildCTEQuery(filter ServerFilter) (string, []interface{}, error) {
 			"s.value",
 			"s.published_at",
 			"s.updated_at",
-			"COALESCE(ss.rating, 0) as rating",
-			"COALESCE(ss.rating_count, 0) as rating_count",
-			"COALESCE(ss.installation_count, 0) as installation_count",
+			ServerStatsColumns("ss"),
+			"COALESCE(st.score, 0) as trending_score",
 		).
 		From("servers s").
 		LeftJoin("proxy_server_stats ss ON s.server_name = ss.server_id").
+		LeftJoin("proxy_server_trending st ON s.server_name = st.server_id AND st.period = '" + DefaultTrendingPeriod + "'").
 		Where(cteWhere)
 
 	return cteSelect.ToSql()
@@ -26,7 +26,7 @@ func TestValidateAndGetSortClause(t *testing.T) {
 			name:        "valid sort: rating_desc",
 			sort:        "rating_desc",
 			wantErr:     false,
-			wantContain: "rating DESC",
+			wantContain: "weighted_rating DESC",
 		},
 		{
 			name:        "valid sort: updated",
@@ -44,7 +44,7 @@ func TestValidateAndGetSortClause(t *testing.T) {
 			name:        "valid sort: trending",
 			sort:        "trending",
 			wantErr:     false,
-			wantContain: "installation_count * 0.3",
+			wantContain: "trending_score DESC",
 		},
 		{
 			name:        "empty sort (default)",
@@ -495,8 +495,38 @@ func TestTagsArrayHandling(t *testing.T) {
 		t.Errorf("Tags not passed as pq.Array, got types: %T", args)
 	}
 
-	// Verify SQL uses ?| operator
-	if !strings.Contains(sql, "?|") {
-		t.Error("Tags filter doesn't use ?| operator")
+	// Verify SQL reads the managed tag assignments
+	if !strings.Contains(sql, "proxy_server_tags") || !strings.Contains(sql, "ANY($") {
+		t.Error("Tags filter doesn't match against proxy_server_tags")
+	}
+}
+
+func TestBuildServerIDsFilter(t *testing.T) {
+	if got := buildServerIDsFilter(sq.And{}, ServerFilter{}); len(got) != 0 {
+		t.Errorf("buildServerIDsFilter() added %d conditions without IDs, want 0", len(got))
+	}
+
+	sql, args, err := buildCTEQuery(ServerFilter{ServerIDs: []string{"a", "b"}})
+	if err != nil {
+		t.Fatalf("buildCTEQuery() failed: %v", err)
+	}
+	if !strings.Contains(sql, "s.server_name = ANY($") {
+		t.Errorf("Server IDs not matched with ANY, got SQL: %s", sql)
+	}
+	_, baseArgs, _ := buildCTEQuery(ServerFilter{})
+	if len(args)-len(baseArgs) != 1 {
+		t.Errorf("Server IDs passed as %d args, want 1 array", len(args)-len(baseArgs))
+	}
+}
+
+func TestSortClausePinnedFirst(t *testing.T) {
+	for sort := range validSortOptions {
+		got, err := validateAndGetSortClause(sort)
+		if err != nil {
+			t.Fatalf("validateAndGetSortClause(%q) error = %v", sort, err)
+		}
+		if !strings.HasPrefix(got, pinnedFirst+", ") {
+			t.Errorf("validateAndGetSortClause(%q) = %q, want pinned servers first", sort, got)
+		}
 	}
 }
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// RatingPrior is the global prior blended into every server's weighted rating, so a
+// handful of reviews cannot outrank a server rated consistently by many users. The
+// weighted rating is computed by proxy_recalculate_rating_stats, shared with the admin,
+// which also validates the prior and falls back to a mean of 3.5 weighted as 10 reviews.
+type RatingPrior struct {
+	Mean   *float64 // Rating assumed before any reviews; nil for the default
+	Weight *float64 // Number of reviews the prior counts as; nil for the default
+}
+
+// RatingPriorFromEnv reads RATING_PRIOR_MEAN and RATING_PRIOR_WEIGHT, leaving the
+// default for unset or malformed values
+func RatingPriorFromEnv() RatingPrior {
+	var prior RatingPrior
+	if v, err := strconv.ParseFloat(os.Getenv("RATING_PRIOR_MEAN"), 64); err == nil {
+		prior.Mean = &v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("RATING_PRIOR_WEIGHT"), 64); err == nil {
+		prior.Weight = &v
+	}
+	return prior
+}
+
+// recalculateRatingStats recomputes a server's rating stats from its visible reviews
+func recalculateRatingStats(ctx context.Context, tx *sql.Tx, serverID string, prior RatingPrior) error {
+	_, err := tx.ExecContext(ctx, `SELECT proxy_recalculate_rating_stats($1, $2, $3)`,
+		serverID, prior.Mean, prior.Weight)
+	if err != nil {
+		return fmt.Errorf("failed to update server stats: %w", err)
+	}
+	return nil
+}
+
+// RecalculateAllRatingStats recomputes the rating stats of every reviewed server, filling
+// the weighted rating and distribution after an upgrade or a change of prior
+func (db *DB) RecalculateAllRatingStats(ctx context.Context) error {
+	_, err := db.ExecContext(ctx, `
+		SELECT proxy_recalculate_rating_stats(server_id, $1, $2)
+		FROM (SELECT DISTINCT server_id FROM proxy_user_ratings) AS reviewed
+	`, db.ratingPrior.Mean, db.ratingPrior.Weight)
+	if err != nil {
+		return fmt.Errorf("failed to recalculate rating stats: %w", err)
+	}
+	return nil
+}
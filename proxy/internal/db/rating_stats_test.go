@@ -0,0 +1,35 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRatingPriorFromEnv(t *testing.T) {
+	t.Setenv("RATING_PRIOR_MEAN", "4.2")
+	t.Setenv("RATING_PRIOR_WEIGHT", "not-a-number")
+
+	prior := RatingPriorFromEnv()
+	if prior.Mean == nil || *prior.Mean != 4.2 {
+		t.Errorf("Mean = %v, want 4.2", prior.Mean)
+	}
+	if prior.Weight != nil {
+		t.Errorf("Weight = %v, want nil for the default", *prior.Weight)
+	}
+}
+
+// TestRecalculateAllRatingStats tests that the configured prior is passed to the shared SQL function
+func TestRecalculateAllRatingStats(t *testing.T) {
+	database, mock := newMockDB(t, nil)
+	mean, weight := 4.0, 25.0
+	database.ratingPrior = RatingPrior{Mean: &mean, Weight: &weight}
+
+	mock.ExpectExec(stmt(`SELECT proxy_recalculate_rating_stats(server_id, $1, $2)`)).WithArgs(4.0, 25.0).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	if err := database.RecalculateAllRatingStats(context.Background()); err != nil {
+		t.Fatalf("RecalculateAllRatingStats() error = %v", err)
+	}
+}
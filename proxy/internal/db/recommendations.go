@@ -0,0 +1,143 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// UserItem is a server in a user's history: installed, rated or both
+type UserItem struct {
+	ServerID  string
+	Installed bool
+	Rating    int // 0 when not rated
+}
+
+// ItemCoInstall counts the other users who installed both a server from a user's history
+// and a neighbor server
+type ItemCoInstall struct {
+	ServerID string
+	Neighbor string
+	Users    int
+}
+
+// PopularServer is a listed server ranked by popularity
+type PopularServer struct {
+	ServerID       string
+	ActiveInstalls int
+	WeightedRating float64
+}
+
+// GetUserItems returns every server a user has installed or rated
+func (db *DB) GetUserItems(ctx context.Context, userID string) ([]UserItem, error) {
+	var items []UserItem
+	err := queryRows(ctx, db, `
+		SELECT server_id, bool_or(installed), MAX(rating)
+		FROM (
+			SELECT server_id, true AS installed, 0 AS rating
+			FROM proxy_user_installations WHERE user_id = ANY($1)
+			UNION ALL
+			SELECT server_id, false, rating
+			FROM proxy_user_ratings WHERE user_id = ANY($1)
+		) history
+		GROUP BY server_id
+		ORDER BY server_id
+	`, []interface{}{pq.Array(db.userIDs.Candidates(userID))}, func(rows *sql.Rows) error {
+		var item UserItem
+		if err := rows.Scan(&item.ServerID, &item.Installed, &item.Rating); err != nil {
+			return err
+		}
+		items = append(items, item)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user history: %w", err)
+	}
+	return items, nil
+}
+
+// GetItemCoInstalls counts, for each of the given servers, the other servers installed by
+// at least minUsers of the same users, leaving out the user with the given ID. It also
+// returns how many users installed each server involved. Only current installations count.
+func (db *DB) GetItemCoInstalls(ctx context.Context, serverIDs []string, userID string, minUsers int) ([]ItemCoInstall, map[string]int, error) {
+	installers := make(map[string]int)
+	if len(serverIDs) == 0 {
+		return nil, installers, nil
+	}
+
+	var pairs []ItemCoInstall
+	err := queryRows(ctx, db, `
+		SELECT a.server_id, b.server_id, COUNT(*)
+		FROM proxy_user_installations a
+		JOIN proxy_user_installations b ON b.user_id = a.user_id AND b.server_id <> a.server_id
+		WHERE a.server_id = ANY($1) AND a.user_id <> ALL($2)
+			AND a.uninstalled_at IS NULL AND b.uninstalled_at IS NULL
+		GROUP BY a.server_id, b.server_id
+		HAVING COUNT(*) >= $3
+	`, []interface{}{pq.Array(serverIDs), pq.Array(db.userIDs.Candidates(userID)), minUsers}, func(rows *sql.Rows) error {
+		var p ItemCoInstall
+		if err := rows.Scan(&p.ServerID, &p.Neighbor, &p.Users); err != nil {
+			return err
+		}
+		pairs = append(pairs, p)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query co-installations: %w", err)
+	}
+
+	involved := append([]string{}, serverIDs...)
+	for _, p := range pairs {
+		involved = append(involved, p.Neighbor)
+	}
+	err = queryRows(ctx, db, `
+		SELECT server_id, COUNT(*) FROM proxy_user_installations
+		WHERE server_id = ANY($1) AND uninstalled_at IS NULL
+		GROUP BY server_id
+	`, []interface{}{pq.Array(involved)}, func(rows *sql.Rows) error {
+		var serverID string
+		var users int
+		if err := rows.Scan(&serverID, &users); err != nil {
+			return err
+		}
+		installers[serverID] = users
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to count installers: %w", err)
+	}
+
+	return pairs, installers, nil
+}
+
+// GetPopularServers returns the listed servers with the most active installations, then
+// the best weighted rating, leaving out the given servers
+func (db *DB) GetPopularServers(ctx context.Context, exclude []string, limit int) ([]PopularServer, error) {
+	if exclude == nil {
+		exclude = []string{} // <> ALL(NULL) would match nothing
+	}
+
+	var popular []PopularServer
+	err := queryRows(ctx, db, `
+		SELECT s.server_name, COALESCE(ss.active_installs, 0), COALESCE(ss.weighted_rating, 0)::float8
+		FROM servers s
+		LEFT JOIN proxy_server_stats ss ON ss.server_id = s.server_name
+		WHERE s.is_latest = true AND s.status IS DISTINCT FROM 'deleted'
+			AND s.server_name <> ALL($1)
+		ORDER BY COALESCE(ss.active_installs, 0) DESC, COALESCE(ss.weighted_rating, 0) DESC, s.server_name
+		LIMIT $2
+	`, []interface{}{pq.Array(exclude), limit}, func(rows *sql.Rows) error {
+		var p PopularServer
+		if err := rows.Scan(&p.ServerID, &p.ActiveInstalls, &p.WeightedRating); err != nil {
+			return err
+		}
+		popular = append(popular, p)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query popular servers: %w", err)
+	}
+	return popular, nil
+}
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGetItemCoInstalls_SkipsUninstalled(t *testing.T) {
+	database, mock := newMockDB(t, nil)
+
+	mock.ExpectQuery(`AND a\.uninstalled_at IS NULL AND b\.uninstalled_at IS NULL`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 2).
+		WillReturnRows(sqlmock.NewRows([]string{"a", "b", "count"}).AddRow("server-a", "server-b", 2))
+	mock.ExpectQuery(`WHERE server_id = ANY\(\$1\) AND uninstalled_at IS NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"server_id", "count"}).AddRow("server-a", 3).AddRow("server-b", 2))
+
+	pairs, installers, err := database.GetItemCoInstalls(context.Background(), []string{"server-a"}, "alice", 2)
+	if err != nil {
+		t.Fatalf("GetItemCoInstalls() error = %v", err)
+	}
+	if len(pairs) != 1 || pairs[0] != (ItemCoInstall{ServerID: "server-a", Neighbor: "server-b", Users: 2}) {
+		t.Errorf("pairs = %+v, want server-b installed by 2 installers of server-a", pairs)
+	}
+	if installers["server-a"] != 3 || installers["server-b"] != 2 {
+		t.Errorf("installers = %v", installers)
+	}
+}
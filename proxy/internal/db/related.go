@@ -0,0 +1,206 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Reasons why two servers are related
+const (
+	ReasonSharedTags         = "shared_tags"
+	ReasonSameCategory       = "same_category"
+	ReasonSharedEnv          = "shared_env"
+	ReasonSimilarDescription = "similar_description"
+	ReasonCoInstalled        = "co_installed"
+)
+
+// ServerFeatures is what the related scorer compares servers by
+type ServerFeatures struct {
+	ServerID    string
+	Description string
+	Category    string
+	Tags        []string
+	EnvVars     []string // Names of the environment variables of the server's packages
+}
+
+// CoInstall counts the users who installed both servers of a pair, with A < B
+type CoInstall struct {
+	A, B  string
+	Users int
+}
+
+// RelatedServer is a server related to another, as stored in proxy_server_related
+type RelatedServer struct {
+	ServerID   string   `json:"-"`
+	RelatedID  string   `json:"server_id"`
+	Score      float64  `json:"score"`
+	CoInstalls int      `json:"co_installs"`
+	Reasons    []string `json:"reasons"`
+}
+
+// GetServerFeatures loads the features of every listed server
+func (db *DB) GetServerFeatures(ctx context.Context) ([]ServerFeatures, error) {
+	query := `
+		SELECT
+			s.server_name,
+			COALESCE(s.value->>'description', ''),
+			COALESCE(sc.category_slug, ''),
+			COALESCE((
+				SELECT array_agg(st.tag_slug ORDER BY st.tag_slug)
+				FROM proxy_server_tags st
+				WHERE st.server_id = s.server_name
+			), '{}'),
+			COALESCE((
+				SELECT array_agg(DISTINCT ev->>'name')
+				FROM jsonb_array_elements(CASE WHEN jsonb_typeof(s.value->'packages') = 'array'
+					THEN s.value->'packages' ELSE '[]'::jsonb END) p,
+					jsonb_array_elements(CASE WHEN jsonb_typeof(p->'environmentVariables') = 'array'
+					THEN p->'environmentVariables' ELSE '[]'::jsonb END) ev
+				WHERE ev->>'name' <> ''
+			), '{}')
+		FROM servers s
+		LEFT JOIN proxy_server_categories sc ON sc.server_id = s.server_name
+		WHERE s.is_latest = true AND s.status IS DISTINCT FROM 'deleted'
+	`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query server features: %w", err)
+	}
+	defer rows.Close()
+
+	var servers []ServerFeatures
+	for rows.Next() {
+		var f ServerFeatures
+		if err := rows.Scan(&f.ServerID, &f.Description, &f.Category, pq.Array(&f.Tags), pq.Array(&f.EnvVars)); err != nil {
+			return nil, fmt.Errorf("failed to scan server features: %w", err)
+		}
+		servers = append(servers, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating server features: %w", err)
+	}
+
+	return servers, nil
+}
+
+// GetCoInstalls counts, for every pair of servers currently installed by the same users, how
+// many users have both installed, and for every installed server how many users have it.
+// Uninstalled servers do not count.
+func (db *DB) GetCoInstalls(ctx context.Context) ([]CoInstall, map[string]int, error) {
+	var pairs []CoInstall
+	err := queryRows(ctx, db, `
+		SELECT a.server_id, b.server_id, COUNT(*)
+		FROM proxy_user_installations a
+		JOIN proxy_user_installations b ON b.user_id = a.user_id AND b.server_id > a.server_id
+		WHERE a.uninstalled_at IS NULL AND b.uninstalled_at IS NULL
+		GROUP BY a.server_id, b.server_id
+	`, nil, func(rows *sql.Rows) error {
+		var p CoInstall
+		if err := rows.Scan(&p.A, &p.B, &p.Users); err != nil {
+			return err
+		}
+		pairs = append(pairs, p)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query co-installations: %w", err)
+	}
+
+	installers := make(map[string]int)
+	err = queryRows(ctx, db, `
+		SELECT server_id, COUNT(*) FROM proxy_user_installations
+		WHERE uninstalled_at IS NULL
+		GROUP BY server_id
+	`, nil, func(rows *sql.Rows) error {
+		var serverID string
+		var users int
+		if err := rows.Scan(&serverID, &users); err != nil {
+			return err
+		}
+		installers[serverID] = users
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to count installers: %w", err)
+	}
+
+	return pairs, installers, nil
+}
+
+// ReplaceRelatedServers replaces every stored related server in one transaction. Replicas
+// replacing them at the same time run one after the other.
+func (db *DB) ReplaceRelatedServers(ctx context.Context, related []RelatedServer) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // Rollback on error; ignore error if already committed
+
+	if err := lockRefresh(ctx, tx, "proxy_server_related"); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM proxy_server_related`); err != nil {
+		return fmt.Errorf("failed to clear related servers: %w", err)
+	}
+
+	if len(related) > 0 {
+		serverIDs := make([]string, len(related))
+		relatedIDs := make([]string, len(related))
+		scores := make([]float64, len(related))
+		coInstalls := make([]int64, len(related))
+		reasons := make([]string, len(related))
+		for i, r := range related {
+			serverIDs[i] = r.ServerID
+			relatedIDs[i] = r.RelatedID
+			scores[i] = r.Score
+			coInstalls[i] = int64(r.CoInstalls)
+			reasons[i] = strings.Join(r.Reasons, ",") // Reasons never contain commas
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO proxy_server_related (server_id, related_id, score, co_installs, reasons, computed_at)
+			SELECT server_id, related_id, score, co_installs, COALESCE(string_to_array(NULLIF(reasons, ''), ','), '{}'), NOW()
+			FROM unnest($1::text[], $2::text[], $3::float8[], $4::integer[], $5::text[])
+				AS r(server_id, related_id, score, co_installs, reasons)
+		`, pq.Array(serverIDs), pq.Array(relatedIDs), pq.Array(scores), pq.Array(coInstalls), pq.Array(reasons))
+		if err != nil {
+			return fmt.Errorf("failed to store related servers: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit related servers: %w", err)
+	}
+
+	return nil
+}
+
+// GetRelatedServers returns the servers most related to a server, best first
+func (db *DB) GetRelatedServers(ctx context.Context, serverID string, limit int) ([]RelatedServer, error) {
+	related := []RelatedServer{}
+	err := queryRows(ctx, db, `
+		SELECT server_id, related_id, score, co_installs, reasons
+		FROM proxy_server_related
+		WHERE server_id = $1
+		ORDER BY score DESC, related_id
+		LIMIT $2
+	`, []interface{}{serverID, limit}, func(rows *sql.Rows) error {
+		var r RelatedServer
+		if err := rows.Scan(&r.ServerID, &r.RelatedID, &r.Score, &r.CoInstalls, pq.Array(&r.Reasons)); err != nil {
+			return err
+		}
+		related = append(related, r)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query related servers: %w", err)
+	}
+	return related, nil
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestReplaceRelatedServers_Locks(t *testing.T) {
+	database, mock := newMockDB(t, nil)
+
+	// The lock is taken before the related servers are cleared
+	mock.ExpectBegin()
+	mock.ExpectExec(stmt(`SELECT pg_advisory_xact_lock(hashtext($1))`)).WithArgs("proxy_server_related").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(stmt(`DELETE FROM proxy_server_related`)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(stmt(`INSERT INTO proxy_server_related`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	related := []RelatedServer{{ServerID: "server-a", RelatedID: "server-b", Score: 0.5, Reasons: []string{ReasonSharedTags}}}
+	if err := database.ReplaceRelatedServers(context.Background(), related); err != nil {
+		t.Fatalf("ReplaceRelatedServers() error = %v", err)
+	}
+}
+
+func TestGetCoInstalls_SkipsUninstalled(t *testing.T) {
+	database, mock := newMockDB(t, nil)
+
+	mock.ExpectQuery(`WHERE a\.uninstalled_at IS NULL AND b\.uninstalled_at IS NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"a", "b", "count"}).AddRow("server-a", "server-b", 2))
+	mock.ExpectQuery(`FROM proxy_user_installations\s+WHERE uninstalled_at IS NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"server_id", "count"}).AddRow("server-a", 3).AddRow("server-b", 2))
+
+	pairs, installers, err := database.GetCoInstalls(context.Background())
+	if err != nil {
+		t.Fatalf("GetCoInstalls() error = %v", err)
+	}
+	if len(pairs) != 1 || pairs[0] != (CoInstall{A: "server-a", B: "server-b", Users: 2}) {
+		t.Errorf("pairs = %+v, want server-a and server-b installed by 2 users", pairs)
+	}
+	if installers["server-a"] != 3 || installers["server-b"] != 2 {
+		t.Errorf("installers = %v", installers)
+	}
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrResponseNotFound is returned when a review has no publisher response
+var ErrResponseNotFound = errors.New("response not found")
+
+// ReviewResponse is the publisher's public reply to a review
+type ReviewResponse struct {
+	Responder string    `json:"responder"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UpsertReviewResponse posts or edits the publisher response to a visible review and
+// records the change in proxy_review_response_audit
+func (db *DB) UpsertReviewResponse(ctx context.Context, serverID, userID, responder, body string) (*ReviewResponse, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // Rollback on error; ignore error if already committed
+
+	if err := lockVisibleReview(ctx, tx, serverID, userID); err != nil {
+		return nil, err
+	}
+
+	// The review row lock serializes writers, so the previous body read here is the one replaced
+	var previous sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT body FROM proxy_review_responses
+		WHERE server_id = $1 AND user_id = $2
+	`, serverID, userID).Scan(&previous)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to load response: %w", err)
+	}
+
+	var resp ReviewResponse
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO proxy_review_responses (server_id, user_id, responder, body, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		ON CONFLICT (server_id, user_id)
+		DO UPDATE SET responder = EXCLUDED.responder, body = EXCLUDED.body, updated_at = NOW()
+		RETURNING responder, body, created_at, updated_at
+	`, serverID, userID, responder, body).Scan(&resp.Responder, &resp.Body, &resp.CreatedAt, &resp.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save response: %w", err)
+	}
+
+	action := "create"
+	if previous.Valid {
+		action = "update"
+	}
+	if err := logResponseChange(ctx, tx, serverID, userID, responder, action, previous, sql.NullString{String: body, Valid: true}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit response: %w", err)
+	}
+
+	return &resp, nil
+}
+
+// DeleteReviewResponse removes the publisher response to a review and records the deletion
+func (db *DB) DeleteReviewResponse(ctx context.Context, serverID, userID, responder string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // Rollback on error; ignore error if already committed
+
+	var previous sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		DELETE FROM proxy_review_responses
+		WHERE server_id = $1 AND user_id = $2
+		RETURNING body
+	`, serverID, userID).Scan(&previous)
+	if err == sql.ErrNoRows {
+		return ErrResponseNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete response: %w", err)
+	}
+
+	if err := logResponseChange(ctx, tx, serverID, userID, responder, "delete", previous, sql.NullString{}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit response deletion: %w", err)
+	}
+
+	return nil
+}
+
+// logResponseChange appends an entry to the publisher response audit trail
+func logResponseChange(ctx context.Context, tx *sql.Tx, serverID, userID, responder, action string, previous, body sql.NullString) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO proxy_review_response_audit (server_id, user_id, responder, action, previous_body, body, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+	`, serverID, userID, responder, action, previous, body)
+	if err != nil {
+		return fmt.Errorf("failed to audit response change: %w", err)
+	}
+	return nil
+}
+
+// attachReviewResponses loads the publisher responses for a page of reviews, of one or
+// several servers, in one query
+func (db *DB) attachReviewResponses(ctx context.Context, reviews []Review) error {
+	if len(reviews) == 0 {
+		return nil
+	}
+
+	index := make(map[[2]string]int, len(reviews))
+	serverIDs := make([]string, len(reviews))
+	userIDs := make([]string, len(reviews))
+	for i, r := range reviews {
+		index[[2]string{r.ServerExternalID, r.UserID}] = i
+		serverIDs[i] = r.ServerExternalID
+		userIDs[i] = r.UserID
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT server_id, user_id, responder, body, created_at, updated_at
+		FROM proxy_review_responses
+		WHERE server_id = ANY($1) AND user_id = ANY($2)
+	`, pq.Array(serverIDs), pq.Array(userIDs))
+	if err != nil {
+		return fmt.Errorf("failed to query review responses: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var serverID, userID string
+		var resp ReviewResponse
+		if err := rows.Scan(&serverID, &userID, &resp.Responder, &resp.Body, &resp.CreatedAt, &resp.UpdatedAt); err != nil {
+			return fmt.Errorf("failed to scan review response: %w", err)
+		}
+		if i, ok := index[[2]string{serverID, userID}]; ok {
+			reviews[i].Response = &resp
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating review responses: %w", err)
+	}
+
+	return nil
+}
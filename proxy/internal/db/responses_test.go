@@ -0,0 +1,169 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUpsertReviewResponse_AuditsCreateAndUpdate(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		previous *sqlmock.Rows // nil when the review has no response yet
+		action   string
+	}{
+		{"first response", nil, "create"},
+		{"edited response", sqlmock.NewRows([]string{"body"}).AddRow("Thanks"), "update"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, mock := newMockDB(t, nil)
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(stmt(`SELECT status FROM proxy_user_ratings`)).WithArgs("server-a", "carol").
+				WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(ReviewStatusVisible))
+			previous := mock.ExpectQuery(stmt(`SELECT body FROM proxy_review_responses`)).WithArgs("server-a", "carol")
+			wantPrevious := sql.NullString{}
+			if tt.previous != nil {
+				previous.WillReturnRows(tt.previous)
+				wantPrevious = sql.NullString{String: "Thanks", Valid: true}
+			} else {
+				previous.WillReturnError(sql.ErrNoRows)
+			}
+			mock.ExpectQuery(stmt(`INSERT INTO proxy_review_responses`)).WithArgs("server-a", "carol", "alice", "Fixed in 1.2").
+				WillReturnRows(sqlmock.NewRows([]string{"responder", "body", "created_at", "updated_at"}).
+					AddRow("alice", "Fixed in 1.2", now, now))
+			mock.ExpectExec(stmt(`INSERT INTO proxy_review_response_audit`)).
+				WithArgs("server-a", "carol", "alice", tt.action, wantPrevious, sql.NullString{String: "Fixed in 1.2", Valid: true}).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			resp, err := database.UpsertReviewResponse(context.Background(), "server-a", "carol", "alice", "Fixed in 1.2")
+			if err != nil {
+				t.Fatalf("UpsertReviewResponse() error = %v", err)
+			}
+			if resp.Responder != "alice" || resp.Body != "Fixed in 1.2" {
+				t.Errorf("UpsertReviewResponse() = %+v", resp)
+			}
+		})
+	}
+}
+
+func TestDeleteReviewResponse(t *testing.T) {
+	t.Run("audits the deleted body", func(t *testing.T) {
+		database, mock := newMockDB(t, nil)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(stmt(`DELETE FROM proxy_review_responses`)).WithArgs("server-a", "carol").
+			WillReturnRows(sqlmock.NewRows([]string{"body"}).AddRow("Thanks"))
+		mock.ExpectExec(stmt(`INSERT INTO proxy_review_response_audit`)).
+			WithArgs("server-a", "carol", "alice", "delete", sql.NullString{String: "Thanks", Valid: true}, sql.NullString{}).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		if err := database.DeleteReviewResponse(context.Background(), "server-a", "carol", "alice"); err != nil {
+			t.Fatalf("DeleteReviewResponse() error = %v", err)
+		}
+	})
+
+	t.Run("no response", func(t *testing.T) {
+		database, mock := newMockDB(t, nil)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(stmt(`DELETE FROM proxy_review_responses`)).WithArgs("server-a", "carol").
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		if err := database.DeleteReviewResponse(context.Background(), "server-a", "carol", "alice"); !errors.Is(err, ErrResponseNotFound) {
+			t.Errorf("DeleteReviewResponse() error = %v, want ErrResponseNotFound", err)
+		}
+	})
+}
+
+func TestReviewResponse_Postgres(t *testing.T) {
+	database := newTestDB(t, nil)
+	ctx := context.Background()
+
+	if err := database.UpsertRating(ctx, "server-a", "alice", 2, "Broken on Windows", "", ""); err != nil {
+		t.Fatalf("UpsertRating() error = %v", err)
+	}
+	if _, err := database.UpsertReviewResponse(ctx, "server-a", "alice", "publisher", "Looking into it"); err != nil {
+		t.Fatalf("UpsertReviewResponse() error = %v", err)
+	}
+	resp, err := database.UpsertReviewResponse(ctx, "server-a", "alice", "publisher", "Fixed in 1.1")
+	if err != nil {
+		t.Fatalf("UpsertReviewResponse() edit error = %v", err)
+	}
+	if resp.Body != "Fixed in 1.1" || resp.UpdatedAt.Before(resp.CreatedAt) {
+		t.Errorf("UpsertReviewResponse() edit = %+v, want the new body", resp)
+	}
+
+	reviews, err := database.GetReviews(ctx, "server-a")
+	if err != nil {
+		t.Fatalf("GetReviews() error = %v", err)
+	}
+	if len(reviews) != 1 || reviews[0].Response == nil || reviews[0].Response.Body != "Fixed in 1.1" {
+		t.Errorf("reviews = %+v, want alice's review with the edited response", reviews)
+	}
+
+	if err := database.DeleteReviewResponse(ctx, "server-a", "alice", "publisher"); err != nil {
+		t.Fatalf("DeleteReviewResponse() error = %v", err)
+	}
+	if err := database.DeleteReviewResponse(ctx, "server-a", "alice", "publisher"); !errors.Is(err, ErrResponseNotFound) {
+		t.Errorf("DeleteReviewResponse() again error = %v, want ErrResponseNotFound", err)
+	}
+
+	// Erasing the review author keeps the audit trail
+	if _, err := database.UpsertReviewResponse(ctx, "server-a", "alice", "publisher", "Thanks"); err != nil {
+		t.Fatalf("UpsertReviewResponse() error = %v", err)
+	}
+	if _, err := database.EraseUser(ctx, "alice"); err != nil {
+		t.Fatalf("EraseUser() error = %v", err)
+	}
+	var responses int
+	if err := database.QueryRow(`SELECT COUNT(*) FROM proxy_review_responses`).Scan(&responses); err != nil {
+		t.Fatal(err)
+	}
+	if responses != 0 {
+		t.Errorf("responses after erasure = %d, want 0", responses)
+	}
+
+	rows, err := database.Query(`
+		SELECT action, COALESCE(previous_body, ''), COALESCE(body, '')
+		FROM proxy_review_response_audit ORDER BY id
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var audit [][3]string
+	for rows.Next() {
+		var entry [3]string
+		if err := rows.Scan(&entry[0], &entry[1], &entry[2]); err != nil {
+			t.Fatal(err)
+		}
+		audit = append(audit, entry)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := [][3]string{
+		{"create", "", "Looking into it"},
+		{"update", "Looking into it", "Fixed in 1.1"},
+		{"delete", "Fixed in 1.1", ""},
+		{"create", "", "Thanks"},
+	}
+	if len(audit) != len(want) {
+		t.Fatalf("audit = %v, want %v", audit, want)
+	}
+	for i := range want {
+		if audit[i] != want[i] {
+			t.Errorf("audit[%d] = %v, want %v", i, audit[i], want[i])
+		}
+	}
+}
@@ -4,13 +4,87 @@ import (
 	"encoding/json"
 	"fmt"
 	"time"
+
+	"github.com/veriteknik/registry-proxy/internal/models"
 )
 
 // ServerStats represents aggregated statistics for a server
 type ServerStats struct {
-	Rating           float64
-	RatingCount      int
+	Rating            float64
+	RatingCount       int
 	InstallationCount int
+	WeightedRating    float64 // Bayesian average used for ranking
+	Distribution      models.RatingDistribution
+	ActiveInstalls    int     // Installations seen within the heartbeat window
+	Retention         float64 // Share of older installations that are still active
+}
+
+// ServerStatsColumns selects the proxy_server_stats columns of the given table alias in
+// the order of ScanDest, as zeros for servers without stats
+func ServerStatsColumns(alias string) string {
+	return fmt.Sprintf(`COALESCE(%[1]s.rating, 0) as rating,
+			COALESCE(%[1]s.rating_count, 0) as rating_count,
+			COALESCE(%[1]s.installation_count, 0) as installation_count,
+			COALESCE(%[1]s.weighted_rating, 0) as weighted_rating,
+			COALESCE(%[1]s.rating_1_count, 0) as rating_1_count,
+			COALESCE(%[1]s.rating_2_count, 0) as rating_2_count,
+			COALESCE(%[1]s.rating_3_count, 0) as rating_3_count,
+			COALESCE(%[1]s.rating_4_count, 0) as rating_4_count,
+			COALESCE(%[1]s.rating_5_count, 0) as rating_5_count,
+			COALESCE(%[1]s.active_installs, 0) as active_installs,
+			COALESCE(%[1]s.retention, 0) as retention`, alias)
+}
+
+// ScanDest returns the scan destinations for the columns of ServerStatsColumns
+func (s *ServerStats) ScanDest() []interface{} {
+	return []interface{}{
+		&s.Rating,
+		&s.RatingCount,
+		&s.InstallationCount,
+		&s.WeightedRating,
+		&s.Distribution.One,
+		&s.Distribution.Two,
+		&s.Distribution.Three,
+		&s.Distribution.Four,
+		&s.Distribution.Five,
+		&s.ActiveInstalls,
+		&s.Retention,
+	}
+}
+
+// ServerJSON is a server's registry JSON as published, with the fields of EnhancedServer
+// added by the proxy
+type ServerJSON map[string]interface{}
+
+// EnhancedServer documents the fields mapRowToServer and the managed taxonomy add to a
+// server's registry JSON. Servers are not decoded into it: the registry's own fields are
+// passed through whatever version of the server schema they were published with.
+type EnhancedServer struct {
+	ID                 string                    `json:"id"`
+	Name               string                    `json:"name"`
+	PublishedAt        time.Time                 `json:"published_at"`
+	UpdatedAt          time.Time                 `json:"updated_at"`
+	Category           string                    `json:"category,omitempty"`
+	Tags               []string                  `json:"tags,omitempty"`
+	Rating             float64                   `json:"rating"`
+	RatingCount        int                       `json:"rating_count"`
+	InstallationCount  int                       `json:"installation_count"`
+	WeightedRating     float64                   `json:"weighted_rating"`
+	RatingDistribution models.RatingDistribution `json:"rating_distribution"`
+	ActiveInstalls     int                       `json:"active_installs"`
+	Stats              EnhancedServerStats       `json:"stats"`
+	QualityScore       float64                   `json:"quality_score"`
+	Badges             []map[string]string       `json:"badges"` // type, label and icon
+}
+
+// EnhancedServerStats documents the nested stats of an EnhancedServer, kept for older clients
+type EnhancedServerStats struct {
+	Rating             float64                   `json:"rating"`
+	RatingCount        int                       `json:"rating_count"`
+	InstallCount       int                       `json:"install_count"`
+	WeightedRating     float64                   `json:"weighted_rating"`
+	RatingDistribution models.RatingDistribution `json:"rating_distribution"`
+	ActiveInstalls     int                       `json:"active_installs"`
 }
 
 // mapRowToServer converts a database row to a server map with enriched data
@@ -42,16 +116,22 @@ func EnrichServerWithStats(server map[string]interface{}, stats ServerStats) map
 	server["rating"] = stats.Rating
 	server["rating_count"] = stats.RatingCount
 	server["installation_count"] = stats.InstallationCount
+	server["weighted_rating"] = stats.WeightedRating
+	server["rating_distribution"] = stats.Distribution
+	server["active_installs"] = stats.ActiveInstalls
 
 	// Also keep nested stats for backward compatibility
 	server["stats"] = map[string]interface{}{
-		"rating":         stats.Rating,
-		"rating_count":   stats.RatingCount,
-		"install_count":  stats.InstallationCount,
+		"rating":              stats.Rating,
+		"rating_count":        stats.RatingCount,
+		"install_count":       stats.InstallationCount,
+		"weighted_rating":     stats.WeightedRating,
+		"rating_distribution": stats.Distribution,
+		"active_installs":     stats.ActiveInstalls,
 	}
 
-	// Calculate quality score
-	server["quality_score"] = calculateQualityScore(stats.Rating, stats.RatingCount, stats.InstallationCount)
+	// Calculate quality score from the weighted rating so a few reviews cannot dominate it
+	server["quality_score"] = calculateQualityScore(stats.WeightedRating, stats.RatingCount, stats.InstallationCount)
 
 	// Add badges
 	server["badges"] = generateBadges(server, stats.Rating, stats.RatingCount, stats.InstallationCount)
@@ -76,20 +156,13 @@ func scanServerRow(scanner interface {
 	serverName string,
 	valueJSON []byte,
 	publishedAt, updatedAt time.Time,
-	rating float64,
-	ratingCount, installCount int,
+	stats ServerStats,
 	totalCount int,
 	err error,
 ) {
-	err = scanner.Scan(
-		&serverName,
-		&valueJSON,
-		&publishedAt,
-		&updatedAt,
-		&rating,
-		&ratingCount,
-		&installCount,
-		&totalCount,
-	)
+	dest := []interface{}{&serverName, &valueJSON, &publishedAt, &updatedAt}
+	dest = append(dest, stats.ScanDest()...)
+	dest = append(dest, &totalCount)
+	err = scanner.Scan(dest...)
 	return
 }
@@ -2,8 +2,13 @@ package db
 
 import (
 	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/veriteknik/registry-proxy/internal/models"
 )
 
 func TestMapRowToServer(t *testing.T) {
@@ -182,6 +187,29 @@ func TestEnrichServerWithStats_ZeroStats(t *testing.T) {
 	}
 }
 
+func TestEnrichServerWithStats_WeightedRating(t *testing.T) {
+	// One 5-star review against 500 reviews averaging 4.8, weighted with the default prior
+	single := EnrichServerWithStats(map[string]interface{}{}, ServerStats{
+		Rating: 5, RatingCount: 1, WeightedRating: 3.64,
+		Distribution: models.RatingDistribution{Five: 1},
+	})
+	established := EnrichServerWithStats(map[string]interface{}{}, ServerStats{
+		Rating: 4.8, RatingCount: 500, WeightedRating: 4.77,
+		Distribution: models.RatingDistribution{Three: 20, Four: 60, Five: 420},
+	})
+
+	if single["quality_score"].(float64) >= established["quality_score"].(float64) {
+		t.Errorf("quality_score of a single review (%v) should be below an established server (%v)",
+			single["quality_score"], established["quality_score"])
+	}
+	if single["weighted_rating"] != 3.64 {
+		t.Errorf("weighted_rating = %v, want 3.64", single["weighted_rating"])
+	}
+	if got := established["rating_distribution"].(models.RatingDistribution); got.Five != 420 || got.One != 0 {
+		t.Errorf("rating_distribution = %+v", got)
+	}
+}
+
 func TestServerStatsStructure(t *testing.T) {
 	// Test that ServerStats can be properly marshaled/unmarshaled
 	stats := ServerStats{
@@ -212,6 +240,43 @@ func TestServerStatsStructure(t *testing.T) {
 	}
 }
 
+// TestEnhancedServerDocumentsMappedFields keeps EnhancedServer, which documents servers in
+// the OpenAPI document, in step with the fields actually added
+func TestEnhancedServerDocumentsMappedFields(t *testing.T) {
+	server, err := mapRowToServer("test-server", []byte(`{}`), time.Now(), time.Now(), ServerStats{})
+	if err != nil {
+		t.Fatalf("mapRowToServer() error = %v", err)
+	}
+	ApplyServerTaxonomy(server, ServerTaxonomy{Category: "data", Tags: []string{"ai"}})
+
+	if got, want := sortedKeys(server), jsonFields(reflect.TypeOf(EnhancedServer{})); !reflect.DeepEqual(got, want) {
+		t.Errorf("mapped fields = %v, EnhancedServer documents %v", got, want)
+	}
+	stats := server["stats"].(map[string]interface{})
+	if got, want := sortedKeys(stats), jsonFields(reflect.TypeOf(EnhancedServerStats{})); !reflect.DeepEqual(got, want) {
+		t.Errorf("mapped stats = %v, EnhancedServerStats documents %v", got, want)
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func jsonFields(t reflect.Type) []string {
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
 // Benchmark tests
 func BenchmarkMapRowToServer(b *testing.B) {
 	serverJSON := `{
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// Category represents a managed category with the number of servers assigned to it
+type Category struct {
+	Slug        string `json:"slug"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	ServerCount int    `json:"server_count"`
+}
+
+// ServerTaxonomy holds the managed category and tags assigned to a server
+type ServerTaxonomy struct {
+	Category string
+	Tags     []string
+}
+
+// ListCategories returns every managed category with its count of visible servers
+func (db *DB) ListCategories(ctx context.Context) ([]Category, error) {
+	query := `
+		SELECT c.slug, c.name, COALESCE(c.description, ''), COUNT(s.server_name)
+		FROM proxy_categories c
+		LEFT JOIN proxy_server_categories sc ON sc.category_slug = c.slug
+		LEFT JOIN servers s ON s.server_name = sc.server_id
+			AND s.is_latest = true
+			AND s.status IS DISTINCT FROM 'deleted'
+		GROUP BY c.slug, c.name, c.description
+		ORDER BY c.name
+	`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query categories: %w", err)
+	}
+	defer rows.Close()
+
+	categories := []Category{}
+	for rows.Next() {
+		var c Category
+		if err := rows.Scan(&c.Slug, &c.Name, &c.Description, &c.ServerCount); err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		categories = append(categories, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating categories: %w", err)
+	}
+
+	return categories, nil
+}
+
+// GetServerTaxonomies loads the managed category and tags of the given servers in one query
+func (db *DB) GetServerTaxonomies(ctx context.Context, serverIDs []string) (map[string]ServerTaxonomy, error) {
+	taxonomies := make(map[string]ServerTaxonomy, len(serverIDs))
+	if len(serverIDs) == 0 {
+		return taxonomies, nil
+	}
+
+	query := `
+		SELECT
+			ids.id,
+			COALESCE(sc.category_slug, ''),
+			COALESCE((
+				SELECT array_agg(st.tag_slug ORDER BY st.tag_slug)
+				FROM proxy_server_tags st
+				WHERE st.server_id = ids.id
+			), '{}')
+		FROM unnest($1::text[]) AS ids(id)
+		LEFT JOIN proxy_server_categories sc ON sc.server_id = ids.id
+	`
+
+	rows, err := db.QueryContext(ctx, query, pq.Array(serverIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query server taxonomies: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var t ServerTaxonomy
+		if err := rows.Scan(&id, &t.Category, pq.Array(&t.Tags)); err != nil {
+			return nil, fmt.Errorf("failed to scan server taxonomy: %w", err)
+		}
+		taxonomies[id] = t
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating server taxonomies: %w", err)
+	}
+
+	return taxonomies, nil
+}
+
+// ApplyServerTaxonomy replaces any category and tags found in the server JSON with the
+// managed assignments, so responses agree with the category and tags filters
+func ApplyServerTaxonomy(server map[string]interface{}, taxonomy ServerTaxonomy) {
+	delete(server, "category")
+	delete(server, "tags")
+
+	if taxonomy.Category != "" {
+		server["category"] = taxonomy.Category
+	}
+	if len(taxonomy.Tags) > 0 {
+		server["tags"] = taxonomy.Tags
+	}
+}
+
+// applyTaxonomies loads and applies the managed taxonomy to a page of servers
+func (db *DB) applyTaxonomies(ctx context.Context, servers []ServerJSON) error {
+	ids := make([]string, 0, len(servers))
+	for _, server := range servers {
+		if id, ok := server["id"].(string); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	taxonomies, err := db.GetServerTaxonomies(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, server := range servers {
+		id, _ := server["id"].(string)
+		ApplyServerTaxonomy(server, taxonomies[id])
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyServerTaxonomy(t *testing.T) {
+	tests := []struct {
+		name         string
+		taxonomy     ServerTaxonomy
+		wantCategory interface{}
+		wantTags     interface{}
+	}{
+		{
+			name:         "managed assignments replace JSON values",
+			taxonomy:     ServerTaxonomy{Category: "developer-tools", Tags: []string{"git", "search"}},
+			wantCategory: "developer-tools",
+			wantTags:     []string{"git", "search"},
+		},
+		{
+			name:     "unassigned server has no category or tags",
+			taxonomy: ServerTaxonomy{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := map[string]interface{}{
+				"id":       "io.github.example/server",
+				"category": "legacy-category",
+				"tags":     []interface{}{"legacy"},
+			}
+
+			ApplyServerTaxonomy(server, tt.taxonomy)
+
+			if got := server["category"]; got != tt.wantCategory {
+				t.Errorf("category = %v, want %v", got, tt.wantCategory)
+			}
+			if got := server["tags"]; !reflect.DeepEqual(got, tt.wantTags) {
+				t.Errorf("tags = %v, want %v", got, tt.wantTags)
+			}
+		})
+	}
+}
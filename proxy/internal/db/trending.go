@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// DefaultTrendingPeriod is the period used by the trending sort and by default in HandleTrending
+const DefaultTrendingPeriod = "30d"
+
+// TrendingPeriods maps each trending period to the days of activity it scores
+var TrendingPeriods = map[string]int{
+	"7d":  7,
+	"30d": 30,
+	"90d": 90,
+}
+
+// TrendingWeights parameterizes the trending score:
+//
+//	score = Install * install velocity + Rating * rating velocity + Quality * weighted rating
+//
+// where a velocity sums the distinct users who installed or rated each day, each day decayed
+// by half every HalfLifeDays. Ratings only count while their review is visible.
+type TrendingWeights struct {
+	HalfLifeDays float64
+	Install      float64
+	Rating       float64
+	Quality      float64
+}
+
+// RefreshTrendingScores recomputes the trending scores of a period from the event log,
+// counting each user once per server and day so repeated writes cannot game it, and replacing the period's previous scores in one transaction. Returns the servers scored.
+// Refreshes of the same period by several replicas run one after the other.
+func (db *DB) RefreshTrendingScores(ctx context.Context, period string, days int, w TrendingWeights) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // Rollback on error; ignore error if already committed
+
+	if err := lockRefresh(ctx, tx, "proxy_server_trending:"+period); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM proxy_server_trending WHERE period = $1`, period); err != nil {
+		return 0, fmt.Errorf("failed to clear trending scores: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO proxy_server_trending (period, server_id, install_velocity, rating_velocity, score, computed_at)
+		SELECT
+			$1,
+			v.server_id,
+			v.install_velocity,
+			v.rating_velocity,
+			$4 * v.install_velocity + $5 * v.rating_velocity + $6 * COALESCE(ss.weighted_rating, 0)::float8,
+			NOW()
+		FROM (
+			SELECT
+				server_id,
+				SUM(CASE WHEN event_type = $7 THEN POWER(0.5, (CURRENT_DATE - day) / $3::float8) ELSE 0 END) AS install_velocity,
+				SUM(CASE WHEN event_type = $8 THEN POWER(0.5, (CURRENT_DATE - day) / $3::float8) ELSE 0 END) AS rating_velocity
+			FROM (
+				SELECT DISTINCT e.server_id, e.user_id, e.event_type, e.created_at::date AS day
+				FROM proxy_server_events e
+				WHERE e.created_at >= CURRENT_DATE - ($2::integer - 1)
+					AND (e.event_type = $7 OR (e.event_type = $8 AND EXISTS (
+						SELECT 1 FROM proxy_user_ratings r
+						WHERE r.server_id = e.server_id AND r.user_id = e.user_id AND r.status = 'visible'
+					)))
+			) d
+			GROUP BY server_id
+		) v
+		LEFT JOIN proxy_server_stats ss ON ss.server_id = v.server_id
+	`, period, days, w.HalfLifeDays, w.Install, w.Rating, w.Quality, EventInstall, EventRating)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute trending scores: %w", err)
+	}
+
+	scored, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count trending scores: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit trending scores: %w", err)
+	}
+
+	return int(scored), nil
+}
+
+// lockRefresh takes a transaction-scoped advisory lock on key, so replicas rebuilding the
+// same precomputed rows wait for each other instead of interleaving their writes
+func lockRefresh(ctx context.Context, tx *sql.Tx, key string) error {
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, key); err != nil {
+		return fmt.Errorf("failed to lock %s: %w", key, err)
+	}
+	return nil
+}
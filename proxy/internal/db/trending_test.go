@@ -0,0 +1,30 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRefreshTrendingScores_LocksPeriod(t *testing.T) {
+	database, mock := newMockDB(t, nil)
+
+	// The lock is taken before the period's scores are cleared
+	mock.ExpectBegin()
+	mock.ExpectExec(stmt(`SELECT pg_advisory_xact_lock(hashtext($1))`)).WithArgs("proxy_server_trending:7d").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(stmt(`DELETE FROM proxy_server_trending WHERE period = $1`)).WithArgs("7d").
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec(stmt(`INSERT INTO proxy_server_trending`)).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	scored, err := database.RefreshTrendingScores(context.Background(), "7d", 7, TrendingWeights{HalfLifeDays: 3, Install: 1})
+	if err != nil {
+		t.Fatalf("RefreshTrendingScores() error = %v", err)
+	}
+	if scored != 3 {
+		t.Errorf("RefreshTrendingScores() = %d, want 3", scored)
+	}
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// InstalledVersion is a server version a client has installed
+type InstalledVersion struct {
+	ServerID string `json:"server_id"`
+	Version  string `json:"version"`
+}
+
+// VersionPair is the published data of an installed version and of the latest version of
+// its server, in the registry's JSON format. Either value is nil when it is not published.
+type VersionPair struct {
+	InstalledVersion
+	LatestVersion   string
+	LatestValue     []byte
+	LatestPublished *time.Time
+	InstalledValue  []byte
+}
+
+// GetVersionPairs looks up every installed version together with the latest version of its
+// server in one query, returning the pairs in the order requested. The latest version is
+// read from the published JSON, like the version of enriched servers, so update checks
+// and the update_available of user listings agree.
+func (db *DB) GetVersionPairs(ctx context.Context, installed []InstalledVersion) ([]VersionPair, error) {
+	if len(installed) == 0 {
+		return []VersionPair{}, nil
+	}
+
+	serverIDs := make([]string, len(installed))
+	versions := make([]string, len(installed))
+	for i, v := range installed {
+		serverIDs[i] = v.ServerID
+		versions[i] = v.Version
+	}
+
+	query := `
+		SELECT req.server_id, req.version, l.value->'version_detail'->>'version', l.value, l.published_at, i.value
+		FROM unnest($1::text[], $2::text[]) WITH ORDINALITY AS req(server_id, version, ord)
+		LEFT JOIN servers l ON l.server_name = req.server_id
+			AND l.is_latest = true AND l.status IS DISTINCT FROM 'deleted'
+		LEFT JOIN servers i ON i.server_name = req.server_id AND i.version = req.version
+		ORDER BY req.ord
+	`
+
+	rows, err := db.QueryContext(ctx, query, pq.Array(serverIDs), pq.Array(versions))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query server versions: %w", err)
+	}
+	defer rows.Close()
+
+	pairs := make([]VersionPair, 0, len(installed))
+	for rows.Next() {
+		var p VersionPair
+		var latestVersion sql.NullString
+		var published sql.NullTime
+		if err := rows.Scan(&p.ServerID, &p.Version, &latestVersion, &p.LatestValue, &published, &p.InstalledValue); err != nil {
+			return nil, fmt.Errorf("failed to scan server versions: %w", err)
+		}
+		p.LatestVersion = latestVersion.String
+		p.LatestPublished = nullTime(published)
+		pairs = append(pairs, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating server versions: %w", err)
+	}
+
+	return pairs, nil
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func TestGetVersionPairs_KeepsRequestOrderAndMissingVersions(t *testing.T) {
+	database, mock := newMockDB(t, nil)
+	published := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	installed := []InstalledVersion{
+		{ServerID: "server-b", Version: "1.0.0"},
+		{ServerID: "server-a", Version: "0.9.0"},
+		{ServerID: "gone", Version: "1.0.0"},
+	}
+	mock.ExpectQuery(stmt(`SELECT req.server_id, req.version, l.value->'version_detail'->>'version'`)).
+		WithArgs(pq.Array([]string{"server-b", "server-a", "gone"}), pq.Array([]string{"1.0.0", "0.9.0", "1.0.0"})).
+		WillReturnRows(sqlmock.NewRows([]string{"server_id", "version", "latest_version", "latest_value", "published_at", "installed_value"}).
+			AddRow("server-b", "1.0.0", "2.0.0", []byte(`{"version":"2.0.0"}`), published, []byte(`{"version":"1.0.0"}`)).
+			AddRow("server-a", "0.9.0", "1.0.0", []byte(`{"version":"1.0.0"}`), published, nil).
+			AddRow("gone", "1.0.0", nil, nil, nil, nil))
+
+	pairs, err := database.GetVersionPairs(context.Background(), installed)
+	if err != nil {
+		t.Fatalf("GetVersionPairs() error = %v", err)
+	}
+	if len(pairs) != 3 {
+		t.Fatalf("GetVersionPairs() returned %d pairs, want 3", len(pairs))
+	}
+	for i, p := range pairs {
+		if p.InstalledVersion != installed[i] {
+			t.Errorf("pair %d = %+v, want %+v in request order", i, p.InstalledVersion, installed[i])
+		}
+	}
+	if pairs[0].LatestVersion != "2.0.0" || pairs[0].LatestPublished == nil || !pairs[0].LatestPublished.Equal(published) {
+		t.Errorf("pair 0 = %+v, want latest 2.0.0", pairs[0])
+	}
+	if pairs[1].InstalledValue != nil {
+		t.Errorf("pair 1 installed value = %s, want nil for an unpublished version", pairs[1].InstalledValue)
+	}
+	if pairs[2].LatestVersion != "" || pairs[2].LatestValue != nil || pairs[2].LatestPublished != nil {
+		t.Errorf("pair 2 = %+v, want no latest version for a server no longer listed", pairs[2])
+	}
+}
+
+func TestGetVersionPairs_EmptySkipsQuery(t *testing.T) {
+	database, _ := newMockDB(t, nil)
+	pairs, err := database.GetVersionPairs(context.Background(), nil)
+	if err != nil || pairs == nil || len(pairs) != 0 {
+		t.Errorf("GetVersionPairs(nil) = %v, %v, want an empty list", pairs, err)
+	}
+}
+
+func TestGetVersionPairs_Postgres(t *testing.T) {
+	database := newTestDB(t, nil)
+	ctx := context.Background()
+
+	// The version column of server-a's latest row differs from its published JSON
+	addTestServer(t, database, "server-a", "1.0.0", `{"name": "server-a", "version_detail": {"version": "1.0.0"}}`, false)
+	addTestServer(t, database, "server-a", "v1.1.0", `{"name": "server-a", "version_detail": {"version": "1.1.0"}}`, true)
+	addTestServer(t, database, "server-b", "2.0.0", `{"name": "server-b", "version_detail": {"version": "2.0.0"}}`, true)
+
+	pairs, err := database.GetVersionPairs(ctx, []InstalledVersion{
+		{ServerID: "server-c", Version: "1.0.0"},
+		{ServerID: "server-a", Version: "1.0.0"},
+		{ServerID: "server-b", Version: "1.9.0"},
+	})
+	if err != nil {
+		t.Fatalf("GetVersionPairs() error = %v", err)
+	}
+	if len(pairs) != 3 {
+		t.Fatalf("GetVersionPairs() returned %d pairs, want 3", len(pairs))
+	}
+
+	if c := pairs[0]; c.ServerID != "server-c" || c.LatestVersion != "" || c.LatestValue != nil || c.InstalledValue != nil {
+		t.Errorf("pairs[0] = %+v, want unpublished server-c", c)
+	}
+	if a := pairs[1]; a.ServerID != "server-a" || a.LatestVersion != "1.1.0" || a.LatestPublished == nil || a.InstalledValue == nil {
+		t.Errorf("pairs[1] = %+v, want server-a 1.0.0 with latest 1.1.0 from the JSON", a)
+	}
+	if b := pairs[2]; b.ServerID != "server-b" || b.LatestVersion != "2.0.0" || b.InstalledValue != nil {
+		t.Errorf("pairs[2] = %+v, want server-b with latest 2.0.0 and 1.9.0 unpublished", b)
+	}
+}
@@ -0,0 +1,287 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/veriteknik/registry-proxy/internal/privacy"
+)
+
+// userIDColumns lists every column holding a stored user ID. Votes, reports and
+// responses follow the review author's ID through ON UPDATE CASCADE.
+var userIDColumns = []struct{ table, column string }{
+	{"proxy_user_ratings", "user_id"},
+	{"proxy_user_installations", "user_id"},
+	{"proxy_review_votes", "voter_id"},
+	{"proxy_review_reports", "reporter_id"},
+	{"proxy_review_response_audit", "user_id"},
+	{"proxy_server_events", "user_id"},
+	{"proxy_user_profiles", "user_id"},
+	{"proxy_banned_users", "user_id"},
+	{"collections", "owner_id"},
+}
+
+// UserIDCandidates returns every ID the data of a user may be stored under, for lookups
+// by the user's own ID
+func (db *DB) UserIDCandidates(userID string) []string {
+	return db.userIDs.Candidates(userID)
+}
+
+// UserIDsHashed reports whether user IDs are stored as hashes rather than as given
+func (db *DB) UserIDsHashed() bool {
+	return db.userIDs.Enabled()
+}
+
+// GetUserRatings returns every rating written by a user, whatever its moderation status
+func (db *DB) GetUserRatings(ctx context.Context, userID string) ([]UserRating, error) {
+	ratings, err := userRatings(ctx, db, pq.Array(db.userIDs.Candidates(userID)), false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user ratings: %w", err)
+	}
+	return ratings, nil
+}
+
+// GetVisibleUserRatings returns the ratings written by a user that passed moderation, as
+// anyone may read them in the server's reviews
+func (db *DB) GetVisibleUserRatings(ctx context.Context, userID string) ([]UserRating, error) {
+	ratings, err := userRatings(ctx, db, pq.Array(db.userIDs.Candidates(userID)), true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query visible user ratings: %w", err)
+	}
+	return ratings, nil
+}
+
+// GetUserInstallations returns the servers a user has installed and not uninstalled
+func (db *DB) GetUserInstallations(ctx context.Context, userID string) ([]UserInstallation, error) {
+	installs, err := userInstallations(ctx, db, pq.Array(db.userIDs.Candidates(userID)), true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user installations: %w", err)
+	}
+	return installs, nil
+}
+
+// isUser reports whether storedID belongs to the user with the given ID
+func (db *DB) isUser(userID, storedID string) bool {
+	for _, id := range db.userIDs.Candidates(userID) {
+		if id == storedID {
+			return true
+		}
+	}
+	return false
+}
+
+// storedUserID returns the ID under which the user's data is written. Data still stored
+// under a previous pepper is first moved to the current one, so a user never has rows
+// under two IDs.
+func (db *DB) storedUserID(ctx context.Context, tx *sql.Tx, userID string) (string, error) {
+	current := db.userIDs.Hash(userID)
+	if previous := db.userIDs.Previous(userID); len(previous) > 0 {
+		if err := moveUserID(ctx, tx, current, previous); err != nil {
+			return "", err
+		}
+	}
+	return current, nil
+}
+
+// moveUserID re-keys the rows stored under any of the from IDs to the stored ID to
+func moveUserID(ctx context.Context, tx *sql.Tx, to string, from []string) error {
+	for _, c := range userIDColumns {
+		_, err := tx.ExecContext(ctx,
+			`UPDATE `+c.table+` SET `+c.column+` = $1 WHERE `+c.column+` = ANY($2)`,
+			to, pq.Array(from))
+		if err != nil {
+			return fmt.Errorf("failed to re-key %s.%s: %w", c.table, c.column, err)
+		}
+	}
+	return nil
+}
+
+// HashLegacyUserIDs hashes the user IDs stored before hashing was enabled and returns how
+// many users were re-keyed. It does nothing when user ID hashing is disabled.
+func (db *DB) HashLegacyUserIDs(ctx context.Context) (int, error) {
+	if !db.userIDs.Enabled() {
+		return 0, nil
+	}
+
+	query := ""
+	for i, c := range userIDColumns {
+		if i > 0 {
+			query += " UNION "
+		}
+		query += `SELECT ` + c.column + ` FROM ` + c.table + ` WHERE ` + c.column + ` !~ $1`
+	}
+
+	rows, err := db.QueryContext(ctx, query, privacy.HashedPattern)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find unhashed user IDs: %w", err)
+	}
+	var legacy []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan user ID: %w", err)
+		}
+		legacy = append(legacy, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating user IDs: %w", err)
+	}
+
+	for _, id := range legacy {
+		if err := db.rekeyLegacyUser(ctx, id); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(legacy), nil
+}
+
+// rekeyLegacyUser moves the rows stored under a raw user ID to its hash in one transaction
+func (db *DB) rekeyLegacyUser(ctx context.Context, userID string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // Rollback on error; ignore error if already committed
+
+	if err := moveUserID(ctx, tx, db.userIDs.Hash(userID), []string{userID}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Erasure counts the rows removed by EraseUser
+type Erasure struct {
+	Ratings       int `json:"ratings"`
+	Installations int `json:"installations"`
+	Votes         int `json:"votes"`
+	Reports       int `json:"reports"`
+	Events        int `json:"events"`
+	Profiles      int `json:"profiles"`
+	Collections   int `json:"collections"`
+}
+
+// EraseUser deletes a user's ratings, installations, votes, reports, events, profile and
+// collections, then recomputes the stats of every affected server and review in the same transaction.
+// Daily rollups are anonymous aggregates and are kept.
+func (db *DB) EraseUser(ctx context.Context, userID string) (Erasure, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return Erasure{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // Rollback on error; ignore error if already committed
+
+	ids := pq.Array(db.userIDs.Candidates(userID))
+	var erased Erasure
+
+	// Deleting the user's reviews cascades to the votes, reports and responses on them
+	ratedServers, err := deleteReturningServers(ctx, tx, `
+		DELETE FROM proxy_user_ratings WHERE user_id = ANY($1) RETURNING server_id, user_id
+	`, ids)
+	if err != nil {
+		return Erasure{}, fmt.Errorf("failed to erase ratings: %w", err)
+	}
+	erased.Ratings = len(ratedServers)
+
+	votedReviews, err := deleteReturningServers(ctx, tx, `
+		DELETE FROM proxy_review_votes WHERE voter_id = ANY($1) RETURNING server_id, user_id
+	`, ids)
+	if err != nil {
+		return Erasure{}, fmt.Errorf("failed to erase votes: %w", err)
+	}
+	erased.Votes = len(votedReviews)
+
+	installedServers, err := deleteReturningServers(ctx, tx, `
+		DELETE FROM proxy_user_installations WHERE user_id = ANY($1) RETURNING server_id, user_id
+	`, ids)
+	if err != nil {
+		return Erasure{}, fmt.Errorf("failed to erase installations: %w", err)
+	}
+	erased.Installations = len(installedServers)
+
+	counts := []struct {
+		query string
+		n     *int
+	}{
+		{`DELETE FROM proxy_review_reports WHERE reporter_id = ANY($1)`, &erased.Reports},
+		{`DELETE FROM proxy_server_events WHERE user_id = ANY($1)`, &erased.Events},
+		{`DELETE FROM proxy_user_profiles WHERE user_id = ANY($1)`, &erased.Profiles},
+		{`DELETE FROM collections WHERE owner_id = ANY($1)`, &erased.Collections},
+	}
+	for _, c := range counts {
+		result, err := tx.ExecContext(ctx, c.query, ids)
+		if err != nil {
+			return Erasure{}, fmt.Errorf("failed to erase user data: %w", err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return Erasure{}, fmt.Errorf("failed to count erased rows: %w", err)
+		}
+		*c.n = int(n)
+	}
+
+	for _, review := range votedReviews {
+		if _, err := refreshVoteCounts(ctx, tx, review.serverID, review.userID); err != nil {
+			return Erasure{}, err
+		}
+	}
+	for _, serverID := range distinctServers(ratedServers) {
+		if err := recalculateRatingStats(ctx, tx, serverID, db.ratingPrior); err != nil {
+			return Erasure{}, err
+		}
+	}
+	for _, serverID := range distinctServers(installedServers) {
+		if err := recalculateInstallStats(ctx, tx, serverID, db.activeInstallDays); err != nil {
+			return Erasure{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Erasure{}, fmt.Errorf("failed to commit erasure: %w", err)
+	}
+
+	return erased, nil
+}
+
+// userRow identifies a deleted row by server and stored user ID
+type userRow struct {
+	serverID string
+	userID   string
+}
+
+// deleteReturningServers runs a DELETE ... RETURNING server_id, user_id and collects the deleted rows
+func deleteReturningServers(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]userRow, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deleted []userRow
+	for rows.Next() {
+		var row userRow
+		if err := rows.Scan(&row.serverID, &row.userID); err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, row)
+	}
+	return deleted, rows.Err()
+}
+
+// distinctServers returns the server IDs of rows, each once, in order of first appearance
+func distinctServers(rows []userRow) []string {
+	seen := make(map[string]bool, len(rows))
+	var servers []string
+	for _, row := range rows {
+		if !seen[row.serverID] {
+			seen[row.serverID] = true
+			servers = append(servers, row.serverID)
+		}
+	}
+	return servers
+}
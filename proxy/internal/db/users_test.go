@@ -0,0 +1,337 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/veriteknik/registry-proxy/internal/privacy"
+)
+
+func TestEraseUser_DeletesAndRecomputesInOneTransaction(t *testing.T) {
+	hasher := newHasher(t)
+	database, mock := newMockDB(t, hasher)
+	ids := pq.Array(hasher.Candidates("alice"))
+	alice := hasher.Hash("alice")
+	rows := func(pairs ...string) *sqlmock.Rows {
+		r := sqlmock.NewRows([]string{"server_id", "user_id"})
+		for i := 0; i < len(pairs); i += 2 {
+			r.AddRow(pairs[i], pairs[i+1])
+		}
+		return r
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(stmt(`DELETE FROM proxy_user_ratings`)).WithArgs(ids).
+		WillReturnRows(rows("server-a", alice, "server-b", "alice"))
+	mock.ExpectQuery(stmt(`DELETE FROM proxy_review_votes`)).WithArgs(ids).
+		WillReturnRows(rows("server-c", "bob", "server-c", "carol"))
+	mock.ExpectQuery(stmt(`DELETE FROM proxy_user_installations`)).WithArgs(ids).
+		WillReturnRows(rows("server-a", alice, "server-d", alice))
+	for _, c := range []struct {
+		table string
+		n     int64
+	}{{"proxy_review_reports", 1}, {"proxy_server_events", 12}, {"proxy_user_profiles", 1}, {"collections", 0}} {
+		mock.ExpectExec(stmt(`DELETE FROM ` + c.table)).WithArgs(ids).WillReturnResult(sqlmock.NewResult(0, c.n))
+	}
+
+	// Each review voted on gets its counts back from the remaining votes
+	for _, author := range []string{"bob", "carol"} {
+		mock.ExpectQuery(stmt(`UPDATE proxy_user_ratings r SET helpful_count`)).WithArgs("server-c", author).
+			WillReturnRows(sqlmock.NewRows([]string{"helpful_count", "unhelpful_count"}).AddRow(0, 0))
+	}
+	// Every rated and installed server is recomputed once
+	for _, server := range []string{"server-a", "server-b"} {
+		mock.ExpectExec(stmt(`SELECT proxy_recalculate_rating_stats`)).WithArgs(server, nil, nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	for _, server := range []string{"server-a", "server-d"} {
+		mock.ExpectExec(stmt(`INSERT INTO proxy_server_stats`)).WithArgs(server, 30).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	erased, err := database.EraseUser(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("EraseUser() error = %v", err)
+	}
+	want := Erasure{Ratings: 2, Installations: 2, Votes: 2, Reports: 1, Events: 12, Profiles: 1}
+	if erased != want {
+		t.Errorf("EraseUser() = %+v, want %+v", erased, want)
+	}
+}
+
+func TestEraseUser_RollsBackOnError(t *testing.T) {
+	database, mock := newMockDB(t, nil)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(stmt(`DELETE FROM proxy_user_ratings`)).WithArgs(pq.Array([]string{"alice"})).
+		WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+
+	if _, err := database.EraseUser(context.Background(), "alice"); err == nil {
+		t.Fatal("EraseUser() error = nil, want the delete's error")
+	}
+}
+
+func TestStoredUserID(t *testing.T) {
+	t.Run("re-keys rows of previous peppers", func(t *testing.T) {
+		hasher := newHasher(t)
+		database, mock := newMockDB(t, hasher)
+
+		mock.ExpectBegin()
+		expectRekey(mock, hasher.Hash("alice"), hasher.Previous("alice"))
+		tx, err := database.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		got, err := database.storedUserID(context.Background(), tx, "alice")
+		if err != nil {
+			t.Fatalf("storedUserID() error = %v", err)
+		}
+		if got != hasher.Hash("alice") || !privacy.IsHashed(got) {
+			t.Errorf("storedUserID() = %q, want the current hash %q", got, hasher.Hash("alice"))
+		}
+	})
+
+	t.Run("stores IDs unchanged without hashing", func(t *testing.T) {
+		database, mock := newMockDB(t, nil)
+
+		mock.ExpectBegin()
+		tx, err := database.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		if got, err := database.storedUserID(context.Background(), tx, "alice"); err != nil || got != "alice" {
+			t.Errorf("storedUserID() = %q, %v, want alice", got, err)
+		}
+	})
+}
+
+func TestHashLegacyUserIDs(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		database, _ := newMockDB(t, nil)
+		if n, err := database.HashLegacyUserIDs(context.Background()); n != 0 || err != nil {
+			t.Errorf("HashLegacyUserIDs() = %d, %v, want nothing done", n, err)
+		}
+	})
+
+	t.Run("hashes each raw ID in its own transaction", func(t *testing.T) {
+		hasher := newHasher(t)
+		database, mock := newMockDB(t, hasher)
+
+		mock.ExpectQuery(stmt(`SELECT user_id FROM proxy_user_ratings WHERE user_id !~ $1 UNION`)).
+			WithArgs(privacy.HashedPattern).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("alice").AddRow("bob"))
+		for _, id := range []string{"alice", "bob"} {
+			mock.ExpectBegin()
+			expectRekey(mock, hasher.Hash(id), []string{id})
+			mock.ExpectCommit()
+		}
+
+		if n, err := database.HashLegacyUserIDs(context.Background()); n != 2 || err != nil {
+			t.Errorf("HashLegacyUserIDs() = %d, %v, want 2", n, err)
+		}
+	})
+}
+
+func TestUserLists_FilterByVisibility(t *testing.T) {
+	hasher := newHasher(t)
+	database, mock := newMockDB(t, hasher)
+	ids := pq.Array(hasher.Candidates("alice"))
+	ratingColumns := []string{"server_id", "rating", "comment", "status", "moderation_reason", "helpful_count", "unhelpful_count", "created_at", "updated_at"}
+
+	// The owner sees their reviews held for moderation; everyone else only visible ones
+	mock.ExpectQuery(stmt(`SELECT server_id, rating`)).WithArgs(ids, false).
+		WillReturnRows(sqlmock.NewRows(ratingColumns).
+			AddRow("server-a", 5, "", ReviewStatusVisible, "", 0, 0, time.Now(), time.Now()).
+			AddRow("server-b", 1, "", ReviewStatusPending, "reported", 0, 0, time.Now(), time.Now()))
+	mock.ExpectQuery(stmt(`SELECT server_id, rating`)).WithArgs(ids, true).
+		WillReturnRows(sqlmock.NewRows(ratingColumns).
+			AddRow("server-a", 5, "", ReviewStatusVisible, "", 0, 0, time.Now(), time.Now()))
+	// Only current installations are listed
+	mock.ExpectQuery(stmt(`SELECT server_id, COALESCE(source, '')`)).WithArgs(ids, true).
+		WillReturnRows(sqlmock.NewRows([]string{"server_id", "source", "version", "platform", "installed_at", "last_seen_at", "uninstalled_at"}).
+			AddRow("server-a", "cli", "1.0.0", "linux", time.Now(), time.Now(), nil))
+
+	own, err := database.GetUserRatings(context.Background(), "alice")
+	if err != nil || len(own) != 2 || own[1].Status != ReviewStatusPending {
+		t.Errorf("GetUserRatings() = %+v, %v, want both reviews", own, err)
+	}
+	visible, err := database.GetVisibleUserRatings(context.Background(), "alice")
+	if err != nil || len(visible) != 1 {
+		t.Errorf("GetVisibleUserRatings() = %+v, %v, want the visible review", visible, err)
+	}
+	installs, err := database.GetUserInstallations(context.Background(), "alice")
+	if err != nil || len(installs) != 1 || installs[0].UninstalledAt != nil {
+		t.Errorf("GetUserInstallations() = %+v, %v, want the current installation", installs, err)
+	}
+}
+
+func TestEraseUser_Postgres(t *testing.T) {
+	database := newTestDB(t, nil)
+	ctx := context.Background()
+
+	if err := database.UpsertRating(ctx, "server-a", "alice", 5, "Great", "", ""); err != nil {
+		t.Fatalf("UpsertRating(alice) error = %v", err)
+	}
+	if err := database.UpsertRating(ctx, "server-a", "bob", 3, "Fine", "", ""); err != nil {
+		t.Fatalf("UpsertRating(bob) error = %v", err)
+	}
+	if err := database.TrackInstallation(ctx, "server-a", "alice", "cli", "1.0.0", "linux"); err != nil {
+		t.Fatalf("TrackInstallation() error = %v", err)
+	}
+	if _, err := database.VoteReview(ctx, "server-a", "bob", "alice", true); err != nil {
+		t.Fatalf("VoteReview() error = %v", err)
+	}
+
+	erased, err := database.EraseUser(ctx, "alice")
+	if err != nil {
+		t.Fatalf("EraseUser() error = %v", err)
+	}
+	if erased.Ratings != 1 || erased.Installations != 1 || erased.Votes != 1 || erased.Events != 2 {
+		t.Errorf("EraseUser() = %+v, want 1 rating, 1 installation, 1 vote and 2 events", erased)
+	}
+
+	// The stats of the server and the vote counts of bob's review no longer include alice
+	stats, err := database.GetServerStats(ctx, "server-a")
+	if err != nil {
+		t.Fatalf("GetServerStats() error = %v", err)
+	}
+	if stats.RatingCount != 1 || stats.Rating != 3 || stats.Distribution.Five != 0 || stats.InstallationCount != 0 {
+		t.Errorf("stats = %+v, want bob's rating only and no installations", stats)
+	}
+	reviews, err := database.GetReviews(ctx, "server-a")
+	if err != nil {
+		t.Fatalf("GetReviews() error = %v", err)
+	}
+	if len(reviews) != 1 || reviews[0].UserID != "bob" || reviews[0].HelpfulCount != 0 {
+		t.Errorf("reviews = %+v, want bob's review without votes", reviews)
+	}
+}
+
+func TestStoredUserID_RotatesPepper_Postgres(t *testing.T) {
+	ctx := context.Background()
+	previous, err := privacy.NewHasher(privacy.Pepper{ID: "1", Secret: []byte("previous")})
+	if err != nil {
+		t.Fatalf("NewHasher() error = %v", err)
+	}
+	database := newTestDB(t, previous)
+
+	if err := database.UpsertRating(ctx, "server-a", "alice", 4, "", "", ""); err != nil {
+		t.Fatalf("UpsertRating() error = %v", err)
+	}
+	if _, err := database.VoteReview(ctx, "server-a", previous.Hash("alice"), "bob", true); err != nil {
+		t.Fatalf("VoteReview() error = %v", err)
+	}
+
+	// After a rotation, alice's next write moves her rows to the current pepper
+	database.userIDs = newHasher(t)
+	if err := database.UpsertRating(ctx, "server-a", "alice", 5, "", "", ""); err != nil {
+		t.Fatalf("UpsertRating() after rotation error = %v", err)
+	}
+	current := database.userIDs.Hash("alice")
+
+	var ratings int
+	if err := database.QueryRow(`SELECT COUNT(*) FROM proxy_user_ratings WHERE user_id = $1`, current).Scan(&ratings); err != nil {
+		t.Fatal(err)
+	}
+	if ratings != 1 {
+		t.Errorf("ratings under the current hash = %d, want 1", ratings)
+	}
+
+	// The vote on alice's review follows it through ON UPDATE CASCADE
+	var author string
+	if err := database.QueryRow(`SELECT user_id FROM proxy_review_votes WHERE server_id = 'server-a'`).Scan(&author); err != nil {
+		t.Fatal(err)
+	}
+	if author != current {
+		t.Errorf("vote references review %q, want %q", author, current)
+	}
+}
+
+func TestPurgeExpiredEvents_Postgres(t *testing.T) {
+	database := newTestDB(t, nil)
+	ctx := context.Background()
+
+	if err := database.TrackInstallation(ctx, "server-a", "alice", "cli", "1.0.0", "linux"); err != nil {
+		t.Fatalf("TrackInstallation(alice) error = %v", err)
+	}
+	if _, err := database.Exec(`UPDATE proxy_server_events SET created_at = NOW() - INTERVAL '100 days'`); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.TrackInstallation(ctx, "server-a", "bob", "cli", "1.0.0", "linux"); err != nil {
+		t.Fatalf("TrackInstallation(bob) error = %v", err)
+	}
+
+	purged, err := database.PurgeExpiredEvents(ctx)
+	if err != nil {
+		t.Fatalf("PurgeExpiredEvents() error = %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("PurgeExpiredEvents() = %d, want 1", purged)
+	}
+
+	// The daily rollups keep counting the purged install
+	var installs int
+	if err := database.QueryRow(`SELECT SUM(installs) FROM proxy_server_daily_stats WHERE server_id = 'server-a'`).Scan(&installs); err != nil {
+		t.Fatal(err)
+	}
+	if installs != 2 {
+		t.Errorf("rolled up installs = %d, want 2", installs)
+	}
+}
+
+func TestUserServers_Postgres(t *testing.T) {
+	database := newTestDB(t, newHasher(t))
+	ctx := context.Background()
+
+	for _, server := range []string{"server-a", "server-b"} {
+		if err := database.TrackInstallation(ctx, server, "alice", "cli", "1.0.0", "linux"); err != nil {
+			t.Fatalf("TrackInstallation(%s) error = %v", server, err)
+		}
+	}
+	if err := database.UninstallServer(ctx, "server-b", "alice"); err != nil {
+		t.Fatalf("UninstallServer() error = %v", err)
+	}
+	if err := database.TrackInstallation(ctx, "server-a", "bob", "cli", "1.0.0", "linux"); err != nil {
+		t.Fatalf("TrackInstallation(bob) error = %v", err)
+	}
+	if err := database.UpsertRating(ctx, "server-a", "alice", 5, "Great", "", ""); err != nil {
+		t.Fatalf("UpsertRating(server-a) error = %v", err)
+	}
+	if err := database.UpsertRating(ctx, "server-b", "alice", 1, "Buy now", "", "spam"); err != nil {
+		t.Fatalf("UpsertRating(server-b) error = %v", err)
+	}
+
+	installs, err := database.GetUserInstallations(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetUserInstallations() error = %v", err)
+	}
+	if len(installs) != 1 || installs[0].ServerID != "server-a" || installs[0].Version != "1.0.0" {
+		t.Errorf("GetUserInstallations() = %+v, want only the current server-a installation", installs)
+	}
+
+	// The user sees their held review; others only see the published one
+	ratings, err := database.GetUserRatings(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetUserRatings() error = %v", err)
+	}
+	if len(ratings) != 2 || ratings[1].Status != ReviewStatusPending || ratings[1].ModerationReason != "spam" {
+		t.Errorf("GetUserRatings() = %+v, want both reviews with server-b held as spam", ratings)
+	}
+	visible, err := database.GetVisibleUserRatings(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetVisibleUserRatings() error = %v", err)
+	}
+	if len(visible) != 1 || visible[0].ServerID != "server-a" {
+		t.Errorf("GetVisibleUserRatings() = %+v, want only the server-a review", visible)
+	}
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// wilsonLowerBoundSQL ranks reviews by the lower bound of the Wilson score interval
+// (95% confidence) for the share of helpful votes, so a review with 40 of 50 helpful
+// votes outranks one with a single helpful vote. Reviews without votes score 0.
+const wilsonLowerBoundSQL = `(CASE WHEN helpful_count + unhelpful_count = 0 THEN 0 ELSE
+	((helpful_count + 1.9208) / (helpful_count + unhelpful_count)
+		- 1.96 * SQRT((helpful_count::float8 * unhelpful_count) / (helpful_count + unhelpful_count) + 0.9604)
+			/ (helpful_count + unhelpful_count))
+	/ (1 + 3.8416 / (helpful_count + unhelpful_count)) END)`
+
+// ErrSelfVote is returned when a user votes on their own review
+var ErrSelfVote = errors.New("cannot vote on own review")
+
+// VoteCounts holds the helpful and unhelpful vote totals of a review
+type VoteCounts struct {
+	Helpful   int `json:"helpful_count"`
+	Unhelpful int `json:"unhelpful_count"`
+}
+
+// VoteReview records or changes a user's helpful/unhelpful vote on a visible review and
+// returns the review's updated vote totals
+func (db *DB) VoteReview(ctx context.Context, serverID, userID, voterID string, helpful bool) (VoteCounts, error) {
+	if db.isUser(voterID, userID) {
+		return VoteCounts{}, ErrSelfVote
+	}
+
+	return db.changeVote(ctx, serverID, userID, func(tx *sql.Tx) error {
+		voter, err := db.storedUserID(ctx, tx, voterID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO proxy_review_votes (server_id, user_id, voter_id, helpful, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, NOW(), NOW())
+			ON CONFLICT (server_id, user_id, voter_id)
+			DO UPDATE SET helpful = EXCLUDED.helpful, updated_at = NOW()
+		`, serverID, userID, voter, helpful)
+		if err != nil {
+			return fmt.Errorf("failed to record vote: %w", err)
+		}
+		return nil
+	})
+}
+
+// RemoveReviewVote withdraws a user's vote on a visible review and returns the review's
+// updated vote totals
+func (db *DB) RemoveReviewVote(ctx context.Context, serverID, userID, voterID string) (VoteCounts, error) {
+	return db.changeVote(ctx, serverID, userID, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			DELETE FROM proxy_review_votes
+			WHERE server_id = $1 AND user_id = $2 AND voter_id = ANY($3)
+		`, serverID, userID, pq.Array(db.userIDs.Candidates(voterID)))
+		if err != nil {
+			return fmt.Errorf("failed to remove vote: %w", err)
+		}
+		return nil
+	})
+}
+
+// changeVote applies a vote change to a visible review and refreshes its vote totals in one transaction
+func (db *DB) changeVote(ctx context.Context, serverID, userID string, apply func(tx *sql.Tx) error) (VoteCounts, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return VoteCounts{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }() // Rollback on error; ignore error if already committed
+
+	// Lock the review so concurrent votes serialize their count updates
+	if err := lockVisibleReview(ctx, tx, serverID, userID); err != nil {
+		return VoteCounts{}, err
+	}
+
+	if err := apply(tx); err != nil {
+		return VoteCounts{}, err
+	}
+
+	counts, err := refreshVoteCounts(ctx, tx, serverID, userID)
+	if err != nil {
+		return VoteCounts{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return VoteCounts{}, fmt.Errorf("failed to commit vote: %w", err)
+	}
+
+	return counts, nil
+}
+
+// refreshVoteCounts recomputes the vote totals stored on a review
+func refreshVoteCounts(ctx context.Context, tx *sql.Tx, serverID, userID string) (VoteCounts, error) {
+	var counts VoteCounts
+	err := tx.QueryRowContext(ctx, `
+		UPDATE proxy_user_ratings r
+		SET helpful_count = v.helpful, unhelpful_count = v.unhelpful
+		FROM (
+			SELECT
+				COUNT(*) FILTER (WHERE helpful)::integer AS helpful,
+				COUNT(*) FILTER (WHERE NOT helpful)::integer AS unhelpful
+			FROM proxy_review_votes
+			WHERE server_id = $1 AND user_id = $2
+		) v
+		WHERE r.server_id = $1 AND r.user_id = $2
+		RETURNING r.helpful_count, r.unhelpful_count
+	`, serverID, userID).Scan(&counts.Helpful, &counts.Unhelpful)
+	if err != nil {
+		return VoteCounts{}, fmt.Errorf("failed to update vote counts: %w", err)
+	}
+	return counts, nil
+}
@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+// expectVoteChange expects changeVote to lock a visible review and end with its refreshed counts
+func expectVoteChange(mock sqlmock.Sqlmock, apply func(), helpful, unhelpful int) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(stmt(`SELECT status FROM proxy_user_ratings`)).WithArgs("server-a", "alice").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(ReviewStatusVisible))
+	apply()
+	mock.ExpectQuery(stmt(`UPDATE proxy_user_ratings r SET helpful_count = v.helpful`)).WithArgs("server-a", "alice").
+		WillReturnRows(sqlmock.NewRows([]string{"helpful_count", "unhelpful_count"}).AddRow(helpful, unhelpful))
+	mock.ExpectCommit()
+}
+
+func TestVoteReview_ReturnsRefreshedCounts(t *testing.T) {
+	hasher := newHasher(t)
+	database, mock := newMockDB(t, hasher)
+	bob := hasher.Hash("bob")
+
+	expectVoteChange(mock, func() {
+		expectRekey(mock, bob, hasher.Previous("bob"))
+		mock.ExpectExec(stmt(`INSERT INTO proxy_review_votes`)).WithArgs("server-a", "alice", bob, false).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}, 4, 2)
+
+	counts, err := database.VoteReview(context.Background(), "server-a", "alice", "bob", false)
+	if err != nil {
+		t.Fatalf("VoteReview() error = %v", err)
+	}
+	if want := (VoteCounts{Helpful: 4, Unhelpful: 2}); counts != want {
+		t.Errorf("VoteReview() = %+v, want %+v", counts, want)
+	}
+}
+
+func TestRemoveReviewVote_RemovesEveryStoredID(t *testing.T) {
+	hasher := newHasher(t)
+	database, mock := newMockDB(t, hasher)
+
+	expectVoteChange(mock, func() {
+		mock.ExpectExec(stmt(`DELETE FROM proxy_review_votes`)).
+			WithArgs("server-a", "alice", pq.Array(hasher.Candidates("bob"))).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}, 3, 2)
+
+	counts, err := database.RemoveReviewVote(context.Background(), "server-a", "alice", "bob")
+	if err != nil {
+		t.Fatalf("RemoveReviewVote() error = %v", err)
+	}
+	if want := (VoteCounts{Helpful: 3, Unhelpful: 2}); counts != want {
+		t.Errorf("RemoveReviewVote() = %+v, want %+v", counts, want)
+	}
+}
+
+func TestVoteReview_Rejects(t *testing.T) {
+	t.Run("own review", func(t *testing.T) {
+		database, _ := newMockDB(t, nil)
+		if _, err := database.VoteReview(context.Background(), "server-a", "alice", "alice", true); !errors.Is(err, ErrSelfVote) {
+			t.Errorf("VoteReview() error = %v, want ErrSelfVote", err)
+		}
+	})
+
+	t.Run("review held for moderation", func(t *testing.T) {
+		database, mock := newMockDB(t, nil)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(stmt(`SELECT status FROM proxy_user_ratings`)).WithArgs("server-a", "alice").
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(ReviewStatusPending))
+		mock.ExpectRollback()
+
+		if _, err := database.VoteReview(context.Background(), "server-a", "alice", "bob", true); !errors.Is(err, ErrReviewNotFound) {
+			t.Errorf("VoteReview() error = %v, want ErrReviewNotFound", err)
+		}
+	})
+}
+
+func TestVoteReview_Postgres(t *testing.T) {
+	database := newTestDB(t, nil)
+	ctx := context.Background()
+
+	if err := database.UpsertRating(ctx, "server-a", "alice", 4, "Good", "", ""); err != nil {
+		t.Fatalf("UpsertRating() error = %v", err)
+	}
+
+	vote := func(voter string, helpful bool, want VoteCounts) {
+		t.Helper()
+		counts, err := database.VoteReview(ctx, "server-a", "alice", voter, helpful)
+		if err != nil {
+			t.Fatalf("VoteReview(%s) error = %v", voter, err)
+		}
+		if counts != want {
+			t.Errorf("VoteReview(%s) = %+v, want %+v", voter, counts, want)
+		}
+	}
+
+	vote("bob", true, VoteCounts{Helpful: 1})
+	vote("carol", true, VoteCounts{Helpful: 2})
+	// A second vote by the same voter replaces the first
+	vote("bob", false, VoteCounts{Helpful: 1, Unhelpful: 1})
+
+	counts, err := database.RemoveReviewVote(ctx, "server-a", "alice", "carol")
+	if err != nil {
+		t.Fatalf("RemoveReviewVote() error = %v", err)
+	}
+	if counts != (VoteCounts{Unhelpful: 1}) {
+		t.Errorf("RemoveReviewVote() = %+v, want 1 unhelpful vote", counts)
+	}
+
+	reviews, err := database.GetReviews(ctx, "server-a")
+	if err != nil {
+		t.Fatalf("GetReviews() error = %v", err)
+	}
+	if len(reviews) != 1 || reviews[0].HelpfulCount != 0 || reviews[0].UnhelpfulCount != 1 {
+		t.Errorf("reviews = %+v, want alice's review with 1 unhelpful vote", reviews)
+	}
+
+	if _, err := database.VoteReview(ctx, "server-a", "alice", "alice", true); !errors.Is(err, ErrSelfVote) {
+		t.Errorf("VoteReview() on own review error = %v, want ErrSelfVote", err)
+	}
+	if _, err := database.VoteReview(ctx, "server-a", "nobody", "bob", true); !errors.Is(err, ErrReviewNotFound) {
+		t.Errorf("VoteReview() on a missing review error = %v, want ErrReviewNotFound", err)
+	}
+}
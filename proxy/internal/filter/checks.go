@@ -0,0 +1,142 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// linkPattern matches URLs, www. hosts and bare domains on common TLDs
+	linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+|\b[a-z0-9-]+(?:\.[a-z0-9-]+)*\.(?:com|net|org|io|co|me|ly|gg|xyz|top|info|biz|ru|cn|tk|dev|app|ai|site|online|shop)\b(?:/\S*)?`)
+	// wordSeparators matches runs of characters that separate words
+	wordSeparators = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+)
+
+// Store provides the review history needed by the duplicate and burst checks
+type Store interface {
+	// CountDuplicateComments counts reviews by other users with the same fingerprint since the given time
+	CountDuplicateComments(ctx context.Context, userID, fingerprint string, since time.Time) (int, error)
+	// CountRecentReviews counts reviews the user submitted or edited on other servers since the given time
+	CountRecentReviews(ctx context.Context, userID, excludeServerID string, since time.Time) (int, error)
+}
+
+// LengthCheck flags comments longer than Max characters
+type LengthCheck struct {
+	Max int
+}
+
+// Name implements Check
+func (LengthCheck) Name() string { return "length" }
+
+// Check implements Check
+func (l LengthCheck) Check(_ context.Context, c Comment) (*Verdict, error) {
+	if n := utf8.RuneCountInString(c.Text); n > l.Max {
+		return &Verdict{Check: l.Name(), Reason: fmt.Sprintf("%d characters exceeds the limit of %d", n, l.Max)}, nil
+	}
+	return nil, nil
+}
+
+// WordListCheck flags comments containing any listed word or phrase, matched case-insensitively
+// on word boundaries
+type WordListCheck struct {
+	phrases []string
+}
+
+// NewWordListCheck creates a word list check; empty entries are ignored
+func NewWordListCheck(words []string) *WordListCheck {
+	w := &WordListCheck{}
+	for _, word := range words {
+		if normalized := normalizeWords(word); strings.TrimSpace(normalized) != "" {
+			w.phrases = append(w.phrases, normalized)
+		}
+	}
+	return w
+}
+
+// Name implements Check
+func (*WordListCheck) Name() string { return "word_list" }
+
+// Check implements Check
+func (w *WordListCheck) Check(_ context.Context, c Comment) (*Verdict, error) {
+	text := normalizeWords(c.Text)
+	for _, phrase := range w.phrases {
+		if strings.Contains(text, phrase) {
+			return &Verdict{Check: w.Name(), Reason: "contains a blocked word"}, nil
+		}
+	}
+	return nil, nil
+}
+
+// LinkCheck flags comments containing more than MaxLinks links or URLs
+type LinkCheck struct {
+	MaxLinks int
+}
+
+// Name implements Check
+func (LinkCheck) Name() string { return "links" }
+
+// Check implements Check
+func (l LinkCheck) Check(_ context.Context, c Comment) (*Verdict, error) {
+	if n := len(linkPattern.FindAllString(c.Text, -1)); n > l.MaxLinks {
+		return &Verdict{Check: l.Name(), Reason: fmt.Sprintf("contains %d links", n)}, nil
+	}
+	return nil, nil
+}
+
+// DuplicateCheck flags comments whose text other users already posted within Window
+type DuplicateCheck struct {
+	Store  Store
+	Window time.Duration
+}
+
+// Name implements Check
+func (DuplicateCheck) Name() string { return "duplicate" }
+
+// Check implements Check
+func (d DuplicateCheck) Check(ctx context.Context, c Comment) (*Verdict, error) {
+	fingerprint := Fingerprint(c.Text)
+	if fingerprint == "" {
+		return nil, nil
+	}
+
+	n, err := d.Store.CountDuplicateComments(ctx, c.UserID, fingerprint, time.Now().Add(-d.Window))
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 {
+		return &Verdict{Check: d.Name(), Reason: fmt.Sprintf("same text posted by %d other users", n)}, nil
+	}
+	return nil, nil
+}
+
+// BurstCheck flags users who reviewed Limit or more other servers within Window
+type BurstCheck struct {
+	Store  Store
+	Limit  int
+	Window time.Duration
+}
+
+// Name implements Check
+func (BurstCheck) Name() string { return "burst" }
+
+// Check implements Check
+func (b BurstCheck) Check(ctx context.Context, c Comment) (*Verdict, error) {
+	n, err := b.Store.CountRecentReviews(ctx, c.UserID, c.ServerID, time.Now().Add(-b.Window))
+	if err != nil {
+		return nil, err
+	}
+	if n >= b.Limit {
+		return &Verdict{Check: b.Name(), Reason: fmt.Sprintf("%d other reviews in the last %s", n, b.Window)}, nil
+	}
+	return nil, nil
+}
+
+// normalizeWords lowercases s and reduces it to space-separated words padded with a space
+// on each side, so phrases only match on word boundaries
+func normalizeWords(s string) string {
+	return " " + strings.TrimSpace(wordSeparators.ReplaceAllString(strings.ToLower(s), " ")) + " "
+}
@@ -0,0 +1,106 @@
+package filter
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config configures the default comment filter pipeline
+type Config struct {
+	MaxLength       int           // Longest comment published without review, in characters
+	BlockedWords    []string      // Words and phrases that hold a comment
+	MaxLinks        int           // Links allowed in a comment
+	DuplicateWindow time.Duration // How far back to look for the same text from other users
+	BurstLimit      int           // Reviews of other servers within BurstWindow that hold a comment
+	BurstWindow     time.Duration
+}
+
+// DefaultConfig returns the filter defaults
+func DefaultConfig() Config {
+	return Config{
+		MaxLength:       1000,
+		MaxLinks:        0,
+		DuplicateWindow: 7 * 24 * time.Hour,
+		BurstLimit:      5,
+		BurstWindow:     10 * time.Minute,
+	}
+}
+
+// ConfigFromEnv builds a Config from REVIEW_* environment variables, falling back to
+// DefaultConfig. Blocked words come from REVIEW_BLOCKED_WORDS (comma-separated) and
+// REVIEW_BLOCKED_WORDS_FILE (one word or phrase per line, # starts a comment).
+func ConfigFromEnv() (Config, error) {
+	cfg := DefaultConfig()
+
+	if v, err := strconv.Atoi(os.Getenv("REVIEW_MAX_COMMENT_LENGTH")); err == nil && v > 0 {
+		cfg.MaxLength = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("REVIEW_MAX_LINKS")); err == nil && v >= 0 {
+		cfg.MaxLinks = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("REVIEW_DUPLICATE_WINDOW")); err == nil && v > 0 {
+		cfg.DuplicateWindow = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("REVIEW_BURST_LIMIT")); err == nil && v > 0 {
+		cfg.BurstLimit = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("REVIEW_BURST_WINDOW")); err == nil && v > 0 {
+		cfg.BurstWindow = v
+	}
+
+	for _, word := range strings.Split(os.Getenv("REVIEW_BLOCKED_WORDS"), ",") {
+		if word = strings.TrimSpace(word); word != "" {
+			cfg.BlockedWords = append(cfg.BlockedWords, word)
+		}
+	}
+
+	if path := os.Getenv("REVIEW_BLOCKED_WORDS_FILE"); path != "" {
+		words, err := readWordList(path)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.BlockedWords = append(cfg.BlockedWords, words...)
+	}
+
+	return cfg, nil
+}
+
+// NewDefaultPipeline creates the standard pipeline: length, word list, links, duplicate
+// text across users and per-user bursts
+func NewDefaultPipeline(cfg Config, store Store) *Pipeline {
+	return NewPipeline(
+		LengthCheck{Max: cfg.MaxLength},
+		NewWordListCheck(cfg.BlockedWords),
+		LinkCheck{MaxLinks: cfg.MaxLinks},
+		DuplicateCheck{Store: store, Window: cfg.DuplicateWindow},
+		BurstCheck{Store: store, Limit: cfg.BurstLimit, Window: cfg.BurstWindow},
+	)
+}
+
+// readWordList reads one word or phrase per line, skipping blank lines and # comments
+func readWordList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open word list: %w", err)
+	}
+	defer f.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read word list: %w", err)
+	}
+
+	return words, nil
+}
@@ -0,0 +1,100 @@
+// Package filter screens review comments for spam and abuse before they are published.
+//
+// A Pipeline runs a list of Checks against each comment. Comments flagged by any check
+// are stored as pending moderation instead of being rejected, and every decision is
+// recorded in the registry_proxy_comment_filter_decisions_total metric.
+package filter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/veriteknik/registry-proxy/internal/metrics"
+	"github.com/veriteknik/registry-proxy/internal/utils"
+	"go.uber.org/zap"
+)
+
+// minFingerprintLength is the shortest normalized comment that gets a fingerprint, so
+// short stock phrases like "Works great" are not treated as duplicates
+const minFingerprintLength = 20
+
+// Comment is a review comment submitted for screening
+type Comment struct {
+	ServerID string
+	UserID   string
+	Text     string
+}
+
+// Verdict explains why a check flagged a comment
+type Verdict struct {
+	Check  string `json:"check"`
+	Reason string `json:"reason"`
+}
+
+// Check inspects a comment and returns a verdict when it should be held for moderation
+type Check interface {
+	Name() string
+	Check(ctx context.Context, c Comment) (*Verdict, error)
+}
+
+// Result holds the verdicts of every check that flagged a comment
+type Result struct {
+	Verdicts []Verdict
+}
+
+// Held reports whether the comment should be stored as pending moderation
+func (r Result) Held() bool {
+	return len(r.Verdicts) > 0
+}
+
+// Reason summarizes the verdicts for moderators, e.g. "links: contains 2 links"
+func (r Result) Reason() string {
+	reasons := make([]string, len(r.Verdicts))
+	for i, v := range r.Verdicts {
+		reasons[i] = v.Check + ": " + v.Reason
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// Pipeline runs checks in order
+type Pipeline struct {
+	checks []Check
+}
+
+// NewPipeline creates a pipeline from the given checks
+func NewPipeline(checks ...Check) *Pipeline {
+	return &Pipeline{checks: checks}
+}
+
+// Evaluate runs every check against the comment. A check that fails with an error lets
+// the comment through, so an outage of the review store never blocks submissions.
+func (p *Pipeline) Evaluate(ctx context.Context, c Comment) Result {
+	var result Result
+	for _, check := range p.checks {
+		verdict, err := check.Check(ctx, c)
+		switch {
+		case err != nil:
+			utils.Logger.Warn("Comment filter check failed", zap.String("check", check.Name()), zap.Error(err))
+			metrics.RecordCommentFilterDecision(check.Name(), "error")
+		case verdict != nil:
+			result.Verdicts = append(result.Verdicts, *verdict)
+			metrics.RecordCommentFilterDecision(check.Name(), "flag")
+		default:
+			metrics.RecordCommentFilterDecision(check.Name(), "pass")
+		}
+	}
+	return result
+}
+
+// Fingerprint returns a stable hash of the comment text ignoring case, punctuation and
+// whitespace, or "" for comments too short to compare
+func Fingerprint(text string) string {
+	normalized := strings.TrimSpace(wordSeparators.ReplaceAllString(strings.ToLower(text), " "))
+	if len([]rune(normalized)) < minFingerprintLength {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
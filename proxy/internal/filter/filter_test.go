@@ -0,0 +1,84 @@
+package filter
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeStore returns fixed counts for the duplicate and burst checks
+type fakeStore struct {
+	duplicates int
+	recent     int
+	err        error
+}
+
+func (f fakeStore) CountDuplicateComments(context.Context, string, string, time.Time) (int, error) {
+	return f.duplicates, f.err
+}
+
+func (f fakeStore) CountRecentReviews(context.Context, string, string, time.Time) (int, error) {
+	return f.recent, f.err
+}
+
+func TestDefaultPipeline(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxLength = 50
+	cfg.BlockedWords = []string{"scam", "buy now"}
+
+	tests := []struct {
+		name       string
+		text       string
+		store      fakeStore
+		wantChecks []string
+	}{
+		{"clean comment", "Works well with my editor", fakeStore{}, nil},
+		{"too long", strings.Repeat("a", 51), fakeStore{}, []string{"length"}},
+		{"blocked word", "Total SCAM, avoid", fakeStore{}, []string{"word_list"}},
+		{"blocked phrase", "Buy-now while it lasts", fakeStore{}, []string{"word_list"}},
+		{"word boundary", "Scampi recipes server", fakeStore{}, nil},
+		{"url", "see https://example.test/x", fakeStore{}, []string{"links"}},
+		{"bare domain", "visit cheap-pills.xyz today", fakeStore{}, []string{"links"}},
+		{"duplicate text", "This server changed how I work entirely", fakeStore{duplicates: 2}, []string{"duplicate"}},
+		{"short duplicates ignored", "Great", fakeStore{duplicates: 2}, nil},
+		{"burst", "Fine", fakeStore{recent: 5}, []string{"burst"}},
+		{"store errors let comments through", "This server changed how I work entirely", fakeStore{err: errors.New("down")}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := NewDefaultPipeline(cfg, tt.store).Evaluate(context.Background(), Comment{ServerID: "s", UserID: "u", Text: tt.text})
+
+			var got []string
+			for _, v := range result.Verdicts {
+				got = append(got, v.Check)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantChecks, ",") {
+				t.Errorf("flagged by %v, want %v", got, tt.wantChecks)
+			}
+			if result.Held() != (len(tt.wantChecks) > 0) {
+				t.Errorf("Held() = %v, want %v", result.Held(), len(tt.wantChecks) > 0)
+			}
+		})
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	a := Fingerprint("This server is AMAZING, truly!")
+	b := Fingerprint("this server is amazing truly")
+	if a == "" || a != b {
+		t.Errorf("Fingerprint should ignore case and punctuation: %q vs %q", a, b)
+	}
+	if Fingerprint("Nice!") != "" {
+		t.Error("Fingerprint of a short comment should be empty")
+	}
+}
+
+func TestResultReason(t *testing.T) {
+	r := Result{Verdicts: []Verdict{{Check: "links", Reason: "contains 2 links"}, {Check: "burst", Reason: "5 other reviews"}}}
+	if got, want := r.Reason(), "links: contains 2 links; burst: 5 other reviews"; got != want {
+		t.Errorf("Reason() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,108 @@
+// Package graph serves the enriched catalog over GraphQL.
+//
+// Servers with their packages, remotes, stats and reviews, and public collections, are
+// exposed as one schema. Fields that would need a query per server (stats, reviews, a
+// user's rating, the servers of a collection) go through per-request loaders, so a page
+// of servers costs the same few queries whatever its size. Queries nested deeper than
+// MaxDepth or costing more than MaxComplexity are rejected before they run.
+package graph
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"github.com/veriteknik/registry-proxy/internal/db"
+	"github.com/veriteknik/registry-proxy/internal/models"
+)
+
+// Config configures the query limits
+type Config struct {
+	MaxDepth      int // Deepest field nesting, not counting introspection
+	MaxComplexity int // Highest cost; see checkLimits
+}
+
+// DefaultConfig returns the default limits
+func DefaultConfig() Config {
+	return Config{
+		MaxDepth:      10,
+		MaxComplexity: 10000,
+	}
+}
+
+// ConfigFromEnv builds a Config from GRAPHQL_* environment variables, falling back to DefaultConfig
+func ConfigFromEnv() Config {
+	cfg := DefaultConfig()
+
+	if v, err := strconv.Atoi(os.Getenv("GRAPHQL_MAX_DEPTH")); err == nil && v > 0 {
+		cfg.MaxDepth = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("GRAPHQL_MAX_COMPLEXITY")); err == nil && v > 0 {
+		cfg.MaxComplexity = v
+	}
+
+	return cfg
+}
+
+// Store reads the catalog; the batch methods leave out keys without data
+type Store interface {
+	QueryServers(ctx context.Context, filter db.ServerFilter, sort string, limit, offset int) ([]models.EnrichedServer, int, error)
+	GetServersByID(ctx context.Context, ids []string) (map[string]models.EnrichedServer, error)
+	GetServerStatsByID(ctx context.Context, ids []string) (map[string]db.ServerStats, error)
+	GetTopReviews(ctx context.Context, ids []string, limit int, sort string) (map[string][]db.Review, error)
+	GetVisibleUserRatings(ctx context.Context, userID string) ([]db.UserRating, error)
+	GetPublicCollections(ctx context.Context, limit, offset int) ([]db.Collection, error)
+	GetCollection(ctx context.Context, id string) (*db.Collection, error)
+}
+
+// Schema is the GraphQL schema of the catalog
+type Schema struct {
+	schema graphql.Schema
+	store  Store
+	cfg    Config
+}
+
+// NewSchema creates the schema over store
+func NewSchema(store Store, cfg Config) (*Schema, error) {
+	s := &Schema{store: store, cfg: cfg}
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: s.queryType()})
+	if err != nil {
+		return nil, fmt.Errorf("invalid GraphQL schema: %w", err)
+	}
+	s.schema = schema
+
+	return s, nil
+}
+
+// Execute runs a query. Errors are reported in the result: it has no data when the query
+// could not be parsed, is invalid or exceeds the limits.
+func (s *Schema) Execute(ctx context.Context, query, operationName string, variables map[string]interface{}) *graphql.Result {
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+
+	if validation := graphql.ValidateDocument(&s.schema, doc, nil); !validation.IsValid {
+		return &graphql.Result{Errors: validation.Errors}
+	}
+
+	if err := s.checkLimits(doc, operationName, variables); err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        s.schema,
+		AST:           doc,
+		OperationName: operationName,
+		Args:          variables,
+		Context:       context.WithValue(ctx, loadersKey{}, s.newLoaders()),
+	})
+}
@@ -0,0 +1,244 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/veriteknik/registry-proxy/internal/db"
+	"github.com/veriteknik/registry-proxy/internal/models"
+)
+
+// memoryStore serves a fixed catalog and counts the queries made
+type memoryStore struct {
+	servers     map[string]models.EnrichedServer
+	order       []string
+	collections []db.Collection
+	calls       map[string]int
+	filter      db.ServerFilter
+}
+
+func newMemoryStore(n int) *memoryStore {
+	s := &memoryStore{servers: make(map[string]models.EnrichedServer), calls: make(map[string]int)}
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("server-%02d", i)
+		s.servers[id] = models.EnrichedServer{
+			Server:   models.Server{ID: id, Name: id},
+			Packages: []models.Package{{RegistryName: "npm", Name: id, Transport: &models.Transport{Type: "stdio"}}},
+		}
+		s.order = append(s.order, id)
+	}
+	s.collections = []db.Collection{{ID: "c1", Name: "Favorites", ServerIDs: []string{"server-01", "gone", "server-00"}}}
+	return s
+}
+
+func (s *memoryStore) QueryServers(_ context.Context, filter db.ServerFilter, _ string, limit, offset int) ([]models.EnrichedServer, int, error) {
+	s.calls["QueryServers"]++
+	s.filter = filter
+	var page []models.EnrichedServer
+	for i := offset; i < len(s.order) && len(page) < limit; i++ {
+		page = append(page, s.servers[s.order[i]])
+	}
+	return page, len(s.order), nil
+}
+
+func (s *memoryStore) GetServersByID(_ context.Context, ids []string) (map[string]models.EnrichedServer, error) {
+	s.calls["GetServersByID"]++
+	found := make(map[string]models.EnrichedServer)
+	for _, id := range ids {
+		if server, ok := s.servers[id]; ok {
+			found[id] = server
+		}
+	}
+	return found, nil
+}
+
+func (s *memoryStore) GetServerStatsByID(_ context.Context, ids []string) (map[string]db.ServerStats, error) {
+	s.calls["GetServerStatsByID"]++
+	stats := make(map[string]db.ServerStats)
+	for i, id := range ids {
+		if i%2 == 0 { // Other servers have no stats yet
+			stats[id] = db.ServerStats{Rating: 4.5, RatingCount: 2}
+		}
+	}
+	return stats, nil
+}
+
+func (s *memoryStore) GetTopReviews(_ context.Context, ids []string, limit int, sort string) (map[string][]db.Review, error) {
+	s.calls["GetTopReviews"]++
+	reviews := make(map[string][]db.Review)
+	for _, id := range ids {
+		reviews[id] = []db.Review{{UUID: id + ":u1", ServerExternalID: id, Rating: 5, Comment: sort}}
+	}
+	return reviews, nil
+}
+
+func (s *memoryStore) GetVisibleUserRatings(_ context.Context, userID string) ([]db.UserRating, error) {
+	s.calls["GetVisibleUserRatings"]++
+	return []db.UserRating{{ServerID: "server-00", Rating: 4}}, nil
+}
+
+func (s *memoryStore) GetPublicCollections(_ context.Context, limit, offset int) ([]db.Collection, error) {
+	s.calls["GetPublicCollections"]++
+	return s.collections, nil
+}
+
+func (s *memoryStore) GetCollection(_ context.Context, id string) (*db.Collection, error) {
+	s.calls["GetCollection"]++
+	for _, c := range s.collections {
+		if c.ID == id {
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+func execute(t *testing.T, store Store, cfg Config, query string, variables map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	schema, err := NewSchema(store, cfg)
+	if err != nil {
+		t.Fatalf("NewSchema() error = %v", err)
+	}
+	result := schema.Execute(context.Background(), query, "", variables)
+	if result.HasErrors() {
+		t.Fatalf("Execute() errors = %v", result.Errors)
+	}
+	return result.Data.(map[string]interface{})
+}
+
+func TestServersBatchesPerServerFields(t *testing.T) {
+	store := newMemoryStore(60)
+	data := execute(t, store, DefaultConfig(), `{
+		servers(limit: 50) {
+			id
+			packages { name transport }
+			stats { rating ratingCount distribution { five } }
+			reviews(limit: 2, sort: NEWEST) { id rating comment }
+			top: reviews { comment }
+			userRating(userId: "alice") { rating }
+		}
+	}`, nil)
+
+	servers := data["servers"].([]interface{})
+	if len(servers) != 50 {
+		t.Fatalf("got %d servers, want 50", len(servers))
+	}
+
+	// One query per kind of data, whatever the number of servers; each reviews page is one kind
+	want := map[string]int{"QueryServers": 1, "GetServerStatsByID": 1, "GetTopReviews": 2, "GetVisibleUserRatings": 1}
+	if !reflect.DeepEqual(store.calls, want) {
+		t.Errorf("calls = %v, want %v", store.calls, want)
+	}
+
+	first := servers[0].(map[string]interface{})
+	if first["stats"].(map[string]interface{})["rating"] != 4.5 {
+		t.Errorf("stats = %v, want rating 4.5", first["stats"])
+	}
+	if second := servers[1].(map[string]interface{}); second["stats"].(map[string]interface{})["ratingCount"] != 0 {
+		t.Errorf("stats without a row = %v, want zeros", second["stats"])
+	}
+	if comment := first["reviews"].([]interface{})[0].(map[string]interface{})["comment"]; comment != "newest" {
+		t.Errorf("review sort = %v, want newest", comment)
+	}
+	if comment := first["top"].([]interface{})[0].(map[string]interface{})["comment"]; comment != "most_helpful" {
+		t.Errorf("default review sort = %v, want most_helpful", comment)
+	}
+	if first["userRating"].(map[string]interface{})["rating"] != 4 || servers[1].(map[string]interface{})["userRating"] != nil {
+		t.Errorf("userRating = %v / %v, want only server-00 rated", first["userRating"], servers[1].(map[string]interface{})["userRating"])
+	}
+	if pkg := first["packages"].([]interface{})[0].(map[string]interface{}); pkg["transport"] != "stdio" {
+		t.Errorf("package = %v, want stdio transport", pkg)
+	}
+}
+
+func TestCollectionServersAreBatched(t *testing.T) {
+	store := newMemoryStore(3)
+	data := execute(t, store, DefaultConfig(), `{
+		collections { name servers { id stats { rating } } }
+		one: collection(id: "c1") { name }
+		missing: collection(id: "nope") { name }
+		server(id: "server-02") { id }
+	}`, nil)
+
+	collection := data["collections"].([]interface{})[0].(map[string]interface{})
+	var ids []string
+	for _, s := range collection["servers"].([]interface{}) {
+		ids = append(ids, s.(map[string]interface{})["id"].(string))
+	}
+	if want := []string{"server-01", "server-00"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("collection servers = %v, want %v (missing servers left out)", ids, want)
+	}
+	if data["missing"] != nil || data["one"] == nil {
+		t.Errorf("collection lookups = %v / %v", data["one"], data["missing"])
+	}
+	if store.calls["GetServersByID"] != 1 || store.calls["GetServerStatsByID"] != 1 {
+		t.Errorf("calls = %v, want one server and one stats query", store.calls)
+	}
+}
+
+func TestServerFilterArgument(t *testing.T) {
+	store := newMemoryStore(3)
+	data := execute(t, store, DefaultConfig(), `query($tags: [String!]) {
+		servers(filter: {search: "git", tags: $tags, registryTypes: ["npm"], transports: ["stdio"], minRating: 4, minInstalls: 10}) { id }
+	}`, map[string]interface{}{"tags": []interface{}{"vcs"}})
+
+	want := db.ServerFilter{Search: "git", Tags: []string{"vcs"}, RegistryTypes: []string{"npm"},
+		HasTransport: []string{"stdio"}, MinRating: 4, MinInstalls: 10}
+	if !reflect.DeepEqual(store.filter, want) {
+		t.Errorf("filter = %+v, want %+v", store.filter, want)
+	}
+	if len(data["servers"].([]interface{})) != 3 {
+		t.Errorf("servers = %v", data["servers"])
+	}
+
+	schema, _ := NewSchema(store, DefaultConfig())
+	result := schema.Execute(context.Background(), `{ servers(filter: {registryTypes: ["cargo"]}) { id } }`, "", nil)
+	if !result.HasErrors() || !strings.Contains(result.Errors[0].Message, "invalid filter") {
+		t.Errorf("errors = %v, want an invalid filter", result.Errors)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	schema, err := NewSchema(newMemoryStore(1), Config{MaxDepth: 3, MaxComplexity: 500})
+	if err != nil {
+		t.Fatalf("NewSchema() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		wantError string
+	}{
+		{"within limits", `{ servers(limit: 10) { id stats { rating } } }`, nil, ""},
+		{"too deep", `{ servers { reviews { response { body } } } }`, nil, "depth 4 exceeds"},
+		{"too deep through a fragment", `{ servers { ...r } } fragment r on Server { reviews { response { body } } }`, nil, "depth 4"},
+		// 1 + 100 * (1 + 20 * 1)
+		{"too complex", `{ servers(limit: 100) { reviews(limit: 20) { id } } }`, nil, "complexity 2101 exceeds"},
+		{"limits above the maximum cost the maximum", `{ servers(limit: 100000) { reviews(limit: 20) { id } } }`, nil, "complexity 2101"},
+		{"limit from a variable", `query($n: Int) { servers(limit: $n) { reviews(limit: 20) { id } } }`,
+			map[string]interface{}{"n": float64(30)}, "complexity 631"},
+		{"default limits", `{ servers { reviews { id } } }`, nil, ""},
+		{"introspection is free", `{ __schema { types { name fields { name type { name ofType { name ofType { name } } } } } } }`, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := schema.Execute(context.Background(), tt.query, "", tt.variables)
+			if tt.wantError == "" {
+				if result.HasErrors() {
+					t.Errorf("errors = %v, want none", result.Errors)
+				}
+				return
+			}
+			if !result.HasErrors() || !strings.Contains(result.Errors[0].Message, tt.wantError) {
+				t.Errorf("errors = %v, want %q", result.Errors, tt.wantError)
+			}
+			if result.Data != nil {
+				t.Errorf("data = %v, want the query not run", result.Data)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package graph
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+const (
+	// unpagedListSize is the number of items assumed for list fields without a limit
+	// argument, such as the packages of a server
+	unpagedListSize = 5
+
+	// maxCost caps the complexity of a field, so the product of nested lists cannot overflow
+	maxCost = 1 << 31
+)
+
+// pageLimits is the most items served by each list field with a limit argument
+var pageLimits = map[string]int{
+	"Query.servers":     maxServers,
+	"Query.collections": maxCollections,
+	"Server.reviews":    maxReviews,
+}
+
+// cost is the depth and complexity of a selection
+type cost struct {
+	depth      int
+	complexity int
+}
+
+// checkLimits rejects an operation nested deeper than MaxDepth or costing more than
+// MaxComplexity. Every field costs 1, and the fields below a list cost once per item:
+// its limit argument up to the most served, or unpagedListSize. Introspection is free,
+// so clients can still load the schema.
+func (s *Schema) checkLimits(doc *ast.Document, operationName string, variables map[string]interface{}) error {
+	var operation *ast.OperationDefinition
+	fragments := make(map[string]*ast.FragmentDefinition)
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.OperationDefinition:
+			if operationName == "" || (def.Name != nil && def.Name.Value == operationName) {
+				operation = def
+			}
+		case *ast.FragmentDefinition:
+			fragments[def.Name.Value] = def
+		}
+	}
+	if operation == nil {
+		// Left to the executor to report
+		return nil
+	}
+
+	w := &limitWalker{schema: &s.schema, fragments: fragments, variables: variables, visiting: make(map[string]bool)}
+	c := w.selectionSet(operation.SelectionSet, s.schema.QueryType())
+
+	if c.depth > s.cfg.MaxDepth {
+		return fmt.Errorf("query depth %d exceeds the limit of %d", c.depth, s.cfg.MaxDepth)
+	}
+	if c.complexity > s.cfg.MaxComplexity {
+		return fmt.Errorf("query complexity %d exceeds the limit of %d", c.complexity, s.cfg.MaxComplexity)
+	}
+	return nil
+}
+
+// limitWalker computes the cost of the selections of a validated document
+type limitWalker struct {
+	schema    *graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	visiting  map[string]bool // Fragments being walked, against cycles
+}
+
+func (w *limitWalker) selectionSet(set *ast.SelectionSet, parent *graphql.Object) cost {
+	var total cost
+	if set == nil || parent == nil {
+		return total
+	}
+
+	add := func(c cost) {
+		total.complexity += c.complexity
+		if c.depth > total.depth {
+			total.depth = c.depth
+		}
+	}
+
+	for _, selection := range set.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			add(w.field(selection, parent))
+		case *ast.InlineFragment:
+			add(w.selectionSet(selection.SelectionSet, w.typeCondition(selection.TypeCondition, parent)))
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, ok := w.fragments[name]
+			if !ok || w.visiting[name] {
+				continue
+			}
+			w.visiting[name] = true
+			add(w.selectionSet(fragment.SelectionSet, w.typeCondition(fragment.TypeCondition, parent)))
+			w.visiting[name] = false
+		}
+	}
+	return total
+}
+
+func (w *limitWalker) field(f *ast.Field, parent *graphql.Object) cost {
+	if strings.HasPrefix(f.Name.Value, "__") {
+		return cost{}
+	}
+	def, ok := parent.Fields()[f.Name.Value]
+	if !ok {
+		return cost{}
+	}
+
+	typ, isList := unwrap(def.Type)
+	object, _ := typ.(*graphql.Object)
+	children := w.selectionSet(f.SelectionSet, object)
+
+	items := 1
+	if isList {
+		items = w.listSize(f, def, pageLimits[parent.Name()+"."+def.Name])
+	}
+	complexity := 1 + items*children.complexity
+	if complexity > maxCost {
+		complexity = maxCost
+	}
+	return cost{depth: children.depth + 1, complexity: complexity}
+}
+
+// listSize is the number of items a list field may return, from its limit argument
+// capped at max
+func (w *limitWalker) listSize(f *ast.Field, def *graphql.FieldDefinition, max int) int {
+	if max == 0 {
+		return unpagedListSize
+	}
+
+	limit := max
+	for _, arg := range def.Args {
+		if n, ok := arg.DefaultValue.(int); ok && arg.Name() == "limit" {
+			limit = n
+		}
+	}
+	for _, arg := range f.Arguments {
+		if arg.Name.Value != "limit" {
+			continue
+		}
+		switch value := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(value.Value); err == nil {
+				limit = n
+			}
+		case *ast.Variable:
+			switch n := w.variables[value.Name.Value].(type) {
+			case int:
+				limit = n
+			case float64: // Variables decoded from JSON
+				limit = int(n)
+			}
+		}
+	}
+
+	// Limits below 1 are rejected by the resolvers
+	if limit < 1 {
+		return 1
+	}
+	if limit > max {
+		return max
+	}
+	return limit
+}
+
+// typeCondition returns the object type a fragment applies to, or parent without a condition
+func (w *limitWalker) typeCondition(condition *ast.Named, parent *graphql.Object) *graphql.Object {
+	if condition == nil {
+		return parent
+	}
+	object, _ := w.schema.Type(condition.Name.Value).(*graphql.Object)
+	return object
+}
+
+// unwrap returns the named type of an output type and whether it is a list
+func unwrap(typ graphql.Type) (graphql.Type, bool) {
+	isList := false
+	for {
+		switch t := typ.(type) {
+		case *graphql.NonNull:
+			typ = t.OfType
+		case *graphql.List:
+			isList = true
+			typ = t.OfType
+		default:
+			return typ, isList
+		}
+	}
+}
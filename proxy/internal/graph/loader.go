@@ -0,0 +1,88 @@
+package graph
+
+import (
+	"context"
+	"sync"
+)
+
+// fetchFunc loads the values of several keys at once; keys without a value are left out
+type fetchFunc func(ctx context.Context, keys []string) (map[string]interface{}, error)
+
+// loaded is the outcome of loading a key
+type loaded struct {
+	value interface{}
+	err   error
+}
+
+// loader batches the keys requested while resolving one level of a query into a single
+// fetch. Resolvers return the thunk of load; the executor runs thunks only after the
+// whole level is resolved, so the first thunk run fetches every key queued by then.
+// Results are kept for the rest of the request.
+type loader struct {
+	fetch fetchFunc
+
+	mu      sync.Mutex
+	pending []string
+	results map[string]*loaded // nil while queued
+}
+
+func newLoader(fetch fetchFunc) *loader {
+	return &loader{fetch: fetch, results: make(map[string]*loaded)}
+}
+
+// load queues key and returns a thunk resolving to its value, nil when it has none
+func (l *loader) load(ctx context.Context, key string) func() (interface{}, error) {
+	l.queue(key)
+	return func() (interface{}, error) {
+		r := l.result(ctx, key)
+		return r.value, r.err
+	}
+}
+
+// loadMany queues keys and returns a thunk resolving to their values in order, leaving
+// out keys without a value
+func (l *loader) loadMany(ctx context.Context, keys []string) func() (interface{}, error) {
+	for _, key := range keys {
+		l.queue(key)
+	}
+	return func() (interface{}, error) {
+		values := []interface{}{}
+		for _, key := range keys {
+			r := l.result(ctx, key)
+			if r.err != nil {
+				return nil, r.err
+			}
+			if r.value != nil {
+				values = append(values, r.value)
+			}
+		}
+		return values, nil
+	}
+}
+
+func (l *loader) queue(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, known := l.results[key]; !known {
+		l.results[key] = nil
+		l.pending = append(l.pending, key)
+	}
+}
+
+// result returns the outcome of key, fetching every pending key first if key is one of them
+func (l *loader) result(ctx context.Context, key string) *loaded {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.results[key] == nil {
+		keys := l.pending
+		l.pending = nil
+
+		values, err := l.fetch(ctx, keys)
+		for _, k := range keys {
+			l.results[k] = &loaded{value: values[k], err: err}
+		}
+	}
+	return l.results[key]
+}
@@ -0,0 +1,257 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/graphql-go/graphql"
+	"github.com/veriteknik/registry-proxy/internal/db"
+	"github.com/veriteknik/registry-proxy/internal/models"
+	"github.com/veriteknik/registry-proxy/internal/utils"
+	"go.uber.org/zap"
+)
+
+type loadersKey struct{}
+
+// loaders holds the loaders of one request
+type loaders struct {
+	servers     *loader // Server by ID
+	stats       *loader // Stats by server ID
+	userRatings *loader // Ratings by server ID, by user ID
+
+	mu      sync.Mutex
+	reviews map[reviewPage]*loader // Reviews by server ID, one loader per page
+}
+
+// reviewPage is the arguments of a reviews field
+type reviewPage struct {
+	limit int
+	sort  string
+}
+
+func (s *Schema) newLoaders() *loaders {
+	return &loaders{
+		servers: newLoader(func(ctx context.Context, ids []string) (map[string]interface{}, error) {
+			servers, err := s.store.GetServersByID(ctx, ids)
+			if err != nil {
+				return nil, loadError("servers", err)
+			}
+			values := make(map[string]interface{}, len(servers))
+			for id, server := range servers {
+				values[id] = server
+			}
+			return values, nil
+		}),
+		stats: newLoader(func(ctx context.Context, ids []string) (map[string]interface{}, error) {
+			stats, err := s.store.GetServerStatsByID(ctx, ids)
+			if err != nil {
+				return nil, loadError("stats", err)
+			}
+			// Servers without stats yet have zeros
+			values := make(map[string]interface{}, len(ids))
+			for _, id := range ids {
+				values[id] = stats[id]
+			}
+			return values, nil
+		}),
+		userRatings: newLoader(func(ctx context.Context, userIDs []string) (map[string]interface{}, error) {
+			// Usually a single user, however many servers ask
+			values := make(map[string]interface{}, len(userIDs))
+			for _, userID := range userIDs {
+				ratings, err := s.store.GetVisibleUserRatings(ctx, userID)
+				if err != nil {
+					return nil, loadError("user ratings", err)
+				}
+				byServer := make(map[string]db.UserRating, len(ratings))
+				for _, r := range ratings {
+					byServer[r.ServerID] = r
+				}
+				values[userID] = byServer
+			}
+			return values, nil
+		}),
+		reviews: make(map[reviewPage]*loader),
+	}
+}
+
+// reviewsLoader returns the loader of a page of reviews
+func (l *loaders) reviewsLoader(s *Schema, page reviewPage) *loader {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.reviews[page]; !ok {
+		l.reviews[page] = newLoader(func(ctx context.Context, ids []string) (map[string]interface{}, error) {
+			reviews, err := s.store.GetTopReviews(ctx, ids, page.limit, page.sort)
+			if err != nil {
+				return nil, loadError("reviews", err)
+			}
+			values := make(map[string]interface{}, len(ids))
+			for _, id := range ids {
+				values[id] = append([]db.Review{}, reviews[id]...)
+			}
+			return values, nil
+		})
+	}
+	return l.reviews[page]
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// loadError logs a store error and returns the error shown to clients, which keeps
+// database details out of responses
+func loadError(what string, err error) error {
+	utils.Logger.Error("GraphQL query failed", zap.String("loading", what), zap.Error(err))
+	return fmt.Errorf("failed to load %s", what)
+}
+
+func (s *Schema) resolveServers(p graphql.ResolveParams) (interface{}, error) {
+	filter, err := filterFromArgs(p.Args)
+	if err != nil {
+		return nil, err
+	}
+	limit, offset, err := pageFromArgs(p.Args, maxServers)
+	if err != nil {
+		return nil, err
+	}
+
+	servers, _, err := s.store.QueryServers(p.Context, filter, p.Args["sort"].(string), limit, offset)
+	if err != nil {
+		return nil, loadError("servers", err)
+	}
+	return servers, nil
+}
+
+func (s *Schema) resolveServerCount(p graphql.ResolveParams) (interface{}, error) {
+	filter, err := filterFromArgs(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	// The total is counted over every match whatever the page
+	_, total, err := s.store.QueryServers(p.Context, filter, "", 1, 0)
+	if err != nil {
+		return nil, loadError("servers", err)
+	}
+	return total, nil
+}
+
+func (s *Schema) resolveReviews(p graphql.ResolveParams) (interface{}, error) {
+	limit, _, err := pageFromArgs(p.Args, maxReviews)
+	if err != nil {
+		return nil, err
+	}
+
+	page := reviewPage{limit: limit, sort: p.Args["sort"].(string)}
+	return loadersFrom(p.Context).reviewsLoader(s, page).load(p.Context, p.Source.(models.EnrichedServer).ID), nil
+}
+
+func (s *Schema) resolveUserRating(p graphql.ResolveParams) (interface{}, error) {
+	serverID := p.Source.(models.EnrichedServer).ID
+	ratings := loadersFrom(p.Context).userRatings.load(p.Context, p.Args["userId"].(string))
+
+	return func() (interface{}, error) {
+		byServer, err := ratings()
+		if err != nil {
+			return nil, err
+		}
+		rating, ok := byServer.(map[string]db.UserRating)[serverID]
+		if !ok {
+			return nil, nil
+		}
+		return rating, nil
+	}, nil
+}
+
+func (s *Schema) resolveCollections(p graphql.ResolveParams) (interface{}, error) {
+	limit, offset, err := pageFromArgs(p.Args, maxCollections)
+	if err != nil {
+		return nil, err
+	}
+
+	collections, err := s.store.GetPublicCollections(p.Context, limit, offset)
+	if err != nil {
+		return nil, loadError("collections", err)
+	}
+	return collections, nil
+}
+
+func (s *Schema) resolveCollection(p graphql.ResolveParams) (interface{}, error) {
+	collection, err := s.store.GetCollection(p.Context, p.Args["id"].(string))
+	if err != nil {
+		return nil, loadError("collection", err)
+	}
+	if collection == nil {
+		return nil, nil
+	}
+	return *collection, nil
+}
+
+// pageFromArgs returns the limit, capped at max, and the offset of a list field
+func pageFromArgs(args map[string]interface{}, max int) (limit, offset int, err error) {
+	limit, _ = args["limit"].(int)
+	offset, _ = args["offset"].(int)
+	if limit < 1 {
+		return 0, 0, errors.New("limit must be positive")
+	}
+	if offset < 0 {
+		return 0, 0, errors.New("offset must not be negative")
+	}
+	if limit > max {
+		limit = max
+	}
+	return limit, offset, nil
+}
+
+// filterFromArgs maps the filter argument onto db.ServerFilter, validated like the
+// query parameters of /v0/enhanced/servers
+func filterFromArgs(args map[string]interface{}) (db.ServerFilter, error) {
+	input, _ := args["filter"].(map[string]interface{})
+
+	filter := db.ServerFilter{
+		ServerIDs:     stringsArg(input, "ids"),
+		RegistryTypes: stringsArg(input, "registryTypes"),
+		Tags:          stringsArg(input, "tags"),
+		HasTransport:  stringsArg(input, "transports"),
+	}
+	filter.Search, _ = input["search"].(string)
+	filter.Category, _ = input["category"].(string)
+	filter.MinRating, _ = input["minRating"].(float64)
+	filter.MinInstalls, _ = input["minInstalls"].(int)
+
+	err := utils.ValidateStruct(&utils.ServerFilterRequest{
+		Search:        filter.Search,
+		Category:      filter.Category,
+		MinRating:     filter.MinRating,
+		MinInstalls:   filter.MinInstalls,
+		RegistryTypes: filter.RegistryTypes,
+		Tags:          filter.Tags,
+		HasTransport:  filter.HasTransport,
+	})
+	if err != nil {
+		return db.ServerFilter{}, fmt.Errorf("invalid filter: %w", err)
+	}
+	if len(filter.ServerIDs) > maxServers {
+		return db.ServerFilter{}, fmt.Errorf("invalid filter: at most %d ids", maxServers)
+	}
+
+	return filter, nil
+}
+
+// stringsArg returns a list of strings from an input object
+func stringsArg(input map[string]interface{}, key string) []string {
+	values, _ := input[key].([]interface{})
+	if len(values) == 0 {
+		return nil
+	}
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
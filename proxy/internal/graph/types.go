@@ -0,0 +1,347 @@
+package graph
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/veriteknik/registry-proxy/internal/db"
+	"github.com/veriteknik/registry-proxy/internal/models"
+)
+
+// Page sizes of the list fields: default and most served
+const (
+	defaultServers     = 20
+	maxServers         = 100
+	defaultReviews     = 3
+	maxReviews         = 20
+	defaultCollections = 20
+	maxCollections     = 100
+)
+
+// field resolves a field of a T source with get
+func field[T any](typ graphql.Output, get func(T) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type: typ,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return get(p.Source.(T)), nil
+		},
+	}
+}
+
+// list is a non-null list of non-null items
+func list(typ graphql.Type) graphql.Output {
+	return graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(typ)))
+}
+
+var (
+	str      = graphql.NewNonNull(graphql.String)
+	integer  = graphql.NewNonNull(graphql.Int)
+	float    = graphql.NewNonNull(graphql.Float)
+	boolean  = graphql.NewNonNull(graphql.Boolean)
+	dateTime = graphql.NewNonNull(graphql.DateTime)
+)
+
+var serverSortType = graphql.NewEnum(graphql.EnumConfig{
+	Name: "ServerSort",
+	Values: graphql.EnumValueConfigMap{
+		"CREATED":       {Value: "created", Description: "Newest first"},
+		"UPDATED":       {Value: "updated", Description: "Most recently updated first"},
+		"NAME_ASC":      {Value: "name_asc"},
+		"NAME_DESC":     {Value: "name_desc"},
+		"RATING_DESC":   {Value: "rating_desc", Description: "Best weighted rating first"},
+		"REVIEWS_DESC":  {Value: "reviews_desc"},
+		"INSTALLS_DESC": {Value: "installs_desc"},
+		"TRENDING":      {Value: "trending"},
+	},
+})
+
+var reviewSortType = graphql.NewEnum(graphql.EnumConfig{
+	Name: "ReviewSort",
+	Values: graphql.EnumValueConfigMap{
+		"NEWEST":       {Value: "newest"},
+		"OLDEST":       {Value: "oldest"},
+		"RATING_HIGH":  {Value: "rating_high"},
+		"RATING_LOW":   {Value: "rating_low"},
+		"MOST_HELPFUL": {Value: "most_helpful", Description: "Best lower bound of the helpful vote share first"},
+	},
+})
+
+// serverFilterType mirrors db.ServerFilter
+var serverFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "ServerFilter",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"ids":           {Type: graphql.NewList(graphql.NewNonNull(graphql.String)), Description: "Only these servers"},
+		"search":        {Type: graphql.String},
+		"category":      {Type: graphql.String},
+		"tags":          {Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+		"registryTypes": {Type: graphql.NewList(graphql.NewNonNull(graphql.String)), Description: "npm, pypi, oci, mcpb, nuget or remote"},
+		"transports":    {Type: graphql.NewList(graphql.NewNonNull(graphql.String)), Description: "stdio, sse or http"},
+		"minRating":     {Type: graphql.Float},
+		"minInstalls":   {Type: graphql.Int},
+	},
+})
+
+var repositoryType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Repository",
+	Fields: graphql.Fields{
+		"url":    field(str, func(r models.Repository) interface{} { return r.URL }),
+		"source": field(str, func(r models.Repository) interface{} { return r.Source }),
+		"id":     field(str, func(r models.Repository) interface{} { return r.ID }),
+	},
+})
+
+var argumentType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Argument",
+	Fields: graphql.Fields{
+		"type":        field(str, func(a models.Argument) interface{} { return a.Type }),
+		"name":        field(str, func(a models.Argument) interface{} { return a.Name }),
+		"value":       field(str, func(a models.Argument) interface{} { return a.Value }),
+		"default":     field(str, func(a models.Argument) interface{} { return a.Default }),
+		"description": field(str, func(a models.Argument) interface{} { return a.Description }),
+		"choices":     field(list(graphql.String), func(a models.Argument) interface{} { return a.Choices }),
+		"isRequired":  field(boolean, func(a models.Argument) interface{} { return a.IsRequired }),
+	},
+})
+
+var environmentVariableType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "EnvironmentVariable",
+	Fields: graphql.Fields{
+		"name":        field(str, func(e models.EnvironmentVariable) interface{} { return e.Name }),
+		"description": field(str, func(e models.EnvironmentVariable) interface{} { return e.Description }),
+		"default":     field(str, func(e models.EnvironmentVariable) interface{} { return e.Default }),
+		"isRequired":  field(boolean, func(e models.EnvironmentVariable) interface{} { return e.IsRequired }),
+		"isSecret":    field(boolean, func(e models.EnvironmentVariable) interface{} { return e.IsSecret }),
+	},
+})
+
+var packageType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Package",
+	Fields: graphql.Fields{
+		"registryName": field(str, func(p models.Package) interface{} { return p.RegistryName }),
+		"name":         field(str, func(p models.Package) interface{} { return p.Name }),
+		"version":      field(str, func(p models.Package) interface{} { return p.Version }),
+		"transport": field(graphql.String, func(p models.Package) interface{} {
+			if p.Transport == nil {
+				return nil
+			}
+			return p.Transport.Type
+		}),
+		"runtimeHint":          field(str, func(p models.Package) interface{} { return p.RuntimeHint }),
+		"runtimeArguments":     field(list(argumentType), func(p models.Package) interface{} { return p.RuntimeArguments }),
+		"packageArguments":     field(list(argumentType), func(p models.Package) interface{} { return p.PackageArguments }),
+		"environmentVariables": field(list(environmentVariableType), func(p models.Package) interface{} { return p.EnvironmentVariables }),
+	},
+})
+
+var remoteHeaderType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "RemoteHeader",
+	Fields: graphql.Fields{
+		"name":        field(str, func(h models.RemoteHeader) interface{} { return h.Name }),
+		"value":       field(str, func(h models.RemoteHeader) interface{} { return h.Value }),
+		"description": field(str, func(h models.RemoteHeader) interface{} { return h.Description }),
+		"default":     field(str, func(h models.RemoteHeader) interface{} { return h.Default }),
+		"isRequired":  field(boolean, func(h models.RemoteHeader) interface{} { return h.IsRequired }),
+		"isSecret":    field(boolean, func(h models.RemoteHeader) interface{} { return h.IsSecret }),
+	},
+})
+
+var remoteType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Remote",
+	Fields: graphql.Fields{
+		"transportType": field(str, func(r models.Remote) interface{} { return r.TransportType }),
+		"url":           field(str, func(r models.Remote) interface{} { return r.URL }),
+		"headers":       field(list(remoteHeaderType), func(r models.Remote) interface{} { return r.Headers }),
+	},
+})
+
+var ratingDistributionType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "RatingDistribution",
+	Description: "Visible reviews per star",
+	Fields: graphql.Fields{
+		"one":   field(integer, func(d models.RatingDistribution) interface{} { return d.One }),
+		"two":   field(integer, func(d models.RatingDistribution) interface{} { return d.Two }),
+		"three": field(integer, func(d models.RatingDistribution) interface{} { return d.Three }),
+		"four":  field(integer, func(d models.RatingDistribution) interface{} { return d.Four }),
+		"five":  field(integer, func(d models.RatingDistribution) interface{} { return d.Five }),
+	},
+})
+
+var statsType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Stats",
+	Fields: graphql.Fields{
+		"rating":            field(float, func(s db.ServerStats) interface{} { return s.Rating }),
+		"ratingCount":       field(integer, func(s db.ServerStats) interface{} { return s.RatingCount }),
+		"installationCount": field(integer, func(s db.ServerStats) interface{} { return s.InstallationCount }),
+		"weightedRating":    field(float, func(s db.ServerStats) interface{} { return s.WeightedRating }),
+		"activeInstalls":    field(integer, func(s db.ServerStats) interface{} { return s.ActiveInstalls }),
+		"retention":         field(float, func(s db.ServerStats) interface{} { return s.Retention }),
+		"distribution":      field(graphql.NewNonNull(ratingDistributionType), func(s db.ServerStats) interface{} { return s.Distribution }),
+	},
+})
+
+var reviewResponseType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "ReviewResponse",
+	Description: "The publisher's reply to a review",
+	Fields: graphql.Fields{
+		"responder": field(str, func(r *db.ReviewResponse) interface{} { return r.Responder }),
+		"body":      field(str, func(r *db.ReviewResponse) interface{} { return r.Body }),
+		"createdAt": field(dateTime, func(r *db.ReviewResponse) interface{} { return r.CreatedAt }),
+		"updatedAt": field(dateTime, func(r *db.ReviewResponse) interface{} { return r.UpdatedAt }),
+	},
+})
+
+var reviewType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Review",
+	Fields: graphql.Fields{
+		"id":             field(graphql.NewNonNull(graphql.ID), func(r db.Review) interface{} { return r.UUID }),
+		"serverId":       field(str, func(r db.Review) interface{} { return r.ServerExternalID }),
+		"userId":         field(str, func(r db.Review) interface{} { return r.UserID }),
+		"username":       field(str, func(r db.Review) interface{} { return r.Username }),
+		"userAvatar":     field(str, func(r db.Review) interface{} { return r.UserAvatar }),
+		"rating":         field(integer, func(r db.Review) interface{} { return r.Rating }),
+		"comment":        field(str, func(r db.Review) interface{} { return r.Comment }),
+		"helpfulCount":   field(integer, func(r db.Review) interface{} { return r.HelpfulCount }),
+		"unhelpfulCount": field(integer, func(r db.Review) interface{} { return r.UnhelpfulCount }),
+		"createdAt":      field(dateTime, func(r db.Review) interface{} { return r.CreatedAt }),
+		"updatedAt":      field(dateTime, func(r db.Review) interface{} { return r.UpdatedAt }),
+		"response": field(reviewResponseType, func(r db.Review) interface{} {
+			if r.Response == nil {
+				return nil
+			}
+			return r.Response
+		}),
+	},
+})
+
+var userRatingType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "UserRating",
+	Description: "A user's rating of a server, once it passed moderation",
+	Fields: graphql.Fields{
+		"serverId":  field(str, func(r db.UserRating) interface{} { return r.ServerID }),
+		"rating":    field(integer, func(r db.UserRating) interface{} { return r.Rating }),
+		"comment":   field(str, func(r db.UserRating) interface{} { return r.Comment }),
+		"createdAt": field(dateTime, func(r db.UserRating) interface{} { return r.CreatedAt }),
+		"updatedAt": field(dateTime, func(r db.UserRating) interface{} { return r.UpdatedAt }),
+	},
+})
+
+// serverType builds the Server type, whose stats, reviews and user rating are loaded in batches
+func (s *Schema) serverType() *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Server",
+		Fields: graphql.Fields{
+			"id":          field(graphql.NewNonNull(graphql.ID), func(e models.EnrichedServer) interface{} { return e.ID }),
+			"name":        field(str, func(e models.EnrichedServer) interface{} { return e.Name }),
+			"description": field(str, func(e models.EnrichedServer) interface{} { return e.Description }),
+			"status":      field(str, func(e models.EnrichedServer) interface{} { return e.Status }),
+			"repository":  field(graphql.NewNonNull(repositoryType), func(e models.EnrichedServer) interface{} { return e.Repository }),
+			"version":     field(str, func(e models.EnrichedServer) interface{} { return e.VersionDetail.Version }),
+			"releaseDate": field(str, func(e models.EnrichedServer) interface{} { return e.VersionDetail.ReleaseDate }),
+			"isLatest":    field(boolean, func(e models.EnrichedServer) interface{} { return e.VersionDetail.IsLatest }),
+			"category":    field(str, func(e models.EnrichedServer) interface{} { return e.Category }),
+			"tags":        field(list(graphql.String), func(e models.EnrichedServer) interface{} { return e.Tags }),
+			"packages":    field(list(packageType), func(e models.EnrichedServer) interface{} { return e.Packages }),
+			"remotes":     field(list(remoteType), func(e models.EnrichedServer) interface{} { return e.Remotes }),
+			"stats": {
+				Type: graphql.NewNonNull(statsType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loadersFrom(p.Context).stats.load(p.Context, p.Source.(models.EnrichedServer).ID), nil
+				},
+			},
+			"reviews": {
+				Type:        list(reviewType),
+				Description: "The first visible reviews",
+				Args: graphql.FieldConfigArgument{
+					"limit": {Type: graphql.Int, DefaultValue: defaultReviews, Description: "At most 20"},
+					"sort":  {Type: reviewSortType, DefaultValue: "most_helpful"},
+				},
+				Resolve: s.resolveReviews,
+			},
+			"userRating": {
+				Type:        userRatingType,
+				Description: "The visible rating the user gave the server, if any; pending and hidden ones are left out",
+				Args: graphql.FieldConfigArgument{
+					"userId": {Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: s.resolveUserRating,
+			},
+		},
+	})
+}
+
+// collectionType builds the Collection type over the given Server type
+func (s *Schema) collectionType(server *graphql.Object) *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Collection",
+		Description: "A public list of servers curated by a user",
+		Fields: graphql.Fields{
+			"id":          field(graphql.NewNonNull(graphql.ID), func(c db.Collection) interface{} { return c.ID }),
+			"name":        field(str, func(c db.Collection) interface{} { return c.Name }),
+			"description": field(str, func(c db.Collection) interface{} { return c.Description }),
+			"serverIds":   field(list(graphql.String), func(c db.Collection) interface{} { return c.ServerIDs }),
+			"servers": {
+				Type:        list(server),
+				Description: "The collection's servers still in the registry, in the order they were added",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loadersFrom(p.Context).servers.loadMany(p.Context, p.Source.(db.Collection).ServerIDs), nil
+				},
+			},
+			"createdAt": field(dateTime, func(c db.Collection) interface{} { return c.CreatedAt }),
+			"updatedAt": field(dateTime, func(c db.Collection) interface{} { return c.UpdatedAt }),
+		},
+	})
+}
+
+// queryType builds the root Query type
+func (s *Schema) queryType() *graphql.Object {
+	server := s.serverType()
+	collection := s.collectionType(server)
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"servers": {
+				Type:        list(server),
+				Description: "A page of the latest versions of listed servers",
+				Args: graphql.FieldConfigArgument{
+					"filter": {Type: serverFilterType},
+					"sort":   {Type: serverSortType, DefaultValue: "created"},
+					"limit":  {Type: graphql.Int, DefaultValue: defaultServers, Description: "At most 100"},
+					"offset": {Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: s.resolveServers,
+			},
+			"serverCount": {
+				Type:        integer,
+				Description: "The number of servers matching the filter",
+				Args: graphql.FieldConfigArgument{
+					"filter": {Type: serverFilterType},
+				},
+				Resolve: s.resolveServerCount,
+			},
+			"server": {
+				Type: server,
+				Args: graphql.FieldConfigArgument{
+					"id": {Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loadersFrom(p.Context).servers.load(p.Context, p.Args["id"].(string)), nil
+				},
+			},
+			"collections": {
+				Type:        list(collection),
+				Description: "A page of public collections, newest first",
+				Args: graphql.FieldConfigArgument{
+					"limit":  {Type: graphql.Int, DefaultValue: defaultCollections, Description: "At most 100"},
+					"offset": {Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: s.resolveCollections,
+			},
+			"collection": {
+				Type: collection,
+				Args: graphql.FieldConfigArgument{
+					"id": {Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: s.resolveCollection,
+			},
+		},
+	})
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/veriteknik/registry-proxy/internal/db"
+	"github.com/veriteknik/registry-proxy/internal/utils"
+	"go.uber.org/zap"
+)
+
+// CategoriesHandler serves the managed category taxonomy
+type CategoriesHandler struct {
+	registryDB *db.DB
+	logger     *zap.Logger
+}
+
+// NewCategoriesHandler creates a new categories handler
+func NewCategoriesHandler(registryDB *db.DB) *CategoriesHandler {
+	return &CategoriesHandler{
+		registryDB: registryDB,
+		logger:     utils.Logger,
+	}
+}
+
+// CategoriesResponse lists the managed categories
+type CategoriesResponse struct {
+	Categories []db.Category `json:"categories"`
+	Total      int           `json:"total"`
+}
+
+// HandleList handles GET /v0/categories
+// Returns every category with the number of servers assigned to it
+func (h *CategoriesHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	if !utils.RequireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	categories, err := h.registryDB.ListCategories(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to list categories", zap.Error(err))
+		utils.WriteJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response := CategoriesResponse{
+		Categories: categories,
+		Total:      len(categories),
+	}
+
+	if err := utils.WriteJSON(w, http.StatusOK, response); err != nil {
+		h.logger.Error("Error encoding categories response", zap.Error(err))
+	}
+}
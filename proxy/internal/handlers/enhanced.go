@@ -13,6 +13,9 @@ import (
 	"go.uber.org/zap"
 )
 
+// aggregateBreakdownLimit is the number of values per dimension in the registry-wide install breakdown
+const aggregateBreakdownLimit = 10
+
 // EnhancedHandler handles enhanced endpoints that query the registry database directly
 type EnhancedHandler struct {
 	registryDB *db.DB
@@ -29,7 +32,65 @@ func NewEnhancedHandler(registryDB, proxyDB *db.DB) *EnhancedHandler {
 	}
 }
 
-// HandleEnhancedServers handles /v0/enhanced/servers with filtering and sorting
+// EnhancedServersResponse is a page of servers from the registry database
+type EnhancedServersResponse struct {
+	Servers    []db.ServerJSON `json:"servers"`
+	TotalCount int             `json:"total_count"`
+	Limit      int             `json:"limit"`
+	Offset     int             `json:"offset"`
+	Filters    db.ServerFilter `json:"filters"`
+	Sort       string          `json:"sort"`
+}
+
+// AggregateStatsResponse summarizes the whole registry
+type AggregateStatsResponse struct {
+	TotalServers        int            `json:"total_servers"`
+	ServersWithPackages int            `json:"servers_with_packages"`
+	RatedServers        int            `json:"rated_servers"`
+	AvgRating           float64        `json:"average_rating"`
+	TotalReviews        int            `json:"total_reviews"`
+	TotalInstalls       int            `json:"total_installs"`
+	NPMCount            int            `json:"npm_count"`
+	PyPICount           int            `json:"pypi_count"`
+	OCICount            int            `json:"oci_count"`
+	RemoteCount         int            `json:"remote_count"`
+	NewThisWeek         int            `json:"new_this_week"`
+	UpdatedThisWeek     int            `json:"updated_this_week"`
+	RegistryBreakdown   map[string]int `json:"registry_breakdown"`
+
+	InstallBreakdown *db.InstallBreakdown `json:"install_breakdown,omitempty"`
+}
+
+// TrendingServerJSON is a server's registry JSON with the fields of TrendingServer added
+type TrendingServerJSON map[string]interface{}
+
+// TrendingServer documents the fields HandleTrending adds to a server's registry JSON
+type TrendingServer struct {
+	ID    string        `json:"id"`
+	Name  string        `json:"name"`
+	Stats TrendingStats `json:"stats"`
+}
+
+// TrendingStats are the stats and precomputed trending score of a trending server
+type TrendingStats struct {
+	Rating          float64 `json:"rating"`
+	RatingCount     int     `json:"rating_count"`
+	InstallCount    int     `json:"install_count"`
+	WeightedRating  float64 `json:"weighted_rating"`
+	InstallVelocity float64 `json:"install_velocity"`
+	RatingVelocity  float64 `json:"rating_velocity"`
+	TrendingScore   float64 `json:"trending_score"`
+}
+
+// TrendingResponse lists the trending servers of a period
+type TrendingResponse struct {
+	Trending   []TrendingServerJSON `json:"trending"`
+	Period     string               `json:"period"`
+	Timestamp  string               `json:"timestamp"`
+	ComputedAt string               `json:"computed_at,omitempty"` // When the scores were computed
+}
+
+// HandleEnhancedServers handles GET /v0/enhanced/servers with filtering and sorting
 func (h *EnhancedHandler) HandleEnhancedServers(w http.ResponseWriter, r *http.Request) {
 	if !utils.RequireMethod(w, r, http.MethodGet) {
 		return
@@ -130,13 +191,13 @@ func (h *EnhancedHandler) HandleEnhancedServers(w http.ResponseWriter, r *http.R
 	}
 
 	// Prepare response
-	response := map[string]interface{}{
-		"servers":     servers,
-		"total_count": totalCount,
-		"limit":       limit,
-		"offset":      offset,
-		"filters":     filter,
-		"sort":        sort,
+	response := EnhancedServersResponse{
+		Servers:    servers,
+		TotalCount: totalCount,
+		Limit:      limit,
+		Offset:     offset,
+		Filters:    filter,
+		Sort:       sort,
 	}
 
 	// Set headers
@@ -148,7 +209,7 @@ func (h *EnhancedHandler) HandleEnhancedServers(w http.ResponseWriter, r *http.R
 	}
 }
 
-// HandleStats handles /v0/enhanced/stats/aggregate
+// HandleStats handles GET /v0/enhanced/stats/aggregate
 func (h *EnhancedHandler) HandleStats(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -187,24 +248,10 @@ func (h *EnhancedHandler) HandleStats(w http.ResponseWriter, r *http.Request) {
 			END) as updated_this_week
 		FROM servers s
 		LEFT JOIN proxy_server_stats ss ON s.server_name = ss.server_id
-		WHERE s.is_latest = true
+		WHERE s.is_latest = true AND s.status IS DISTINCT FROM 'deleted'
 	`
 
-	var stats struct {
-		TotalServers        int            `json:"total_servers"`
-		ServersWithPackages int            `json:"servers_with_packages"`
-		RatedServers        int            `json:"rated_servers"`
-		AvgRating           float64        `json:"average_rating"`
-		TotalReviews        int            `json:"total_reviews"`
-		TotalInstalls       int            `json:"total_installs"`
-		NPMCount            int            `json:"npm_count"`
-		PyPICount           int            `json:"pypi_count"`
-		OCICount            int            `json:"oci_count"`
-		RemoteCount         int            `json:"remote_count"`
-		NewThisWeek         int            `json:"new_this_week"`
-		UpdatedThisWeek     int            `json:"updated_this_week"`
-		RegistryBreakdown   map[string]int `json:"registry_breakdown"`
-	}
+	var stats AggregateStatsResponse
 
 	err := h.registryDB.QueryRowContext(r.Context(), query).Scan(
 		&stats.TotalServers,
@@ -235,6 +282,14 @@ func (h *EnhancedHandler) HandleStats(w http.ResponseWriter, r *http.Request) {
 		"remote": stats.RemoteCount,
 	}
 
+	// Add registry-wide install breakdown; the aggregate is still served without it
+	installBreakdown, err := h.proxyDB.GetInstallBreakdown(r.Context(), "", false, aggregateBreakdownLimit)
+	if err != nil {
+		h.logger.Error("Error querying install breakdown", zap.Error(err))
+	} else {
+		stats.InstallBreakdown = &installBreakdown
+	}
+
 	// Set headers
 	w.Header().Set("Content-Type", "application/json")
 
@@ -244,66 +299,73 @@ func (h *EnhancedHandler) HandleStats(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// HandleTrending handles /v0/enhanced/stats/trending
+// defaultTrendingPeriodName is the period HandleTrending reports when none is requested,
+// unchanged since the endpoint only served the last 30 days
+const defaultTrendingPeriodName = "30_days"
+
+// trendingPeriodAliases maps the older period names to the scored periods
+var trendingPeriodAliases = map[string]string{
+	"7_days":  "7d",
+	"30_days": db.DefaultTrendingPeriod,
+	"90_days": "90d",
+}
+
+// HandleTrending handles GET /v0/enhanced/stats/trending
+// Query parameters: period (7d, 30d, 90d or 7_days, 30_days, 90_days; default 30_days) and
+// limit (default 10, max 100). The response reports the period as requested.
 func (h *EnhancedHandler) HandleTrending(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !utils.RequireMethod(w, r, http.MethodGet) {
 		return
 	}
 
-	// Get trending servers (high activity in last 7 days)
-	query := `
-		WITH trending AS (
-			SELECT
-				s.server_name,
-				s.value,
-				COALESCE(ss.rating, 0) as rating,
-				COALESCE(ss.rating_count, 0) as rating_count,
-				COALESCE(ss.installation_count, 0) as installation_count,
-				(
-					COALESCE(ss.installation_count, 0) * 0.3 +
-					COALESCE(ss.rating_count, 0) * 0.3 +
-					COALESCE(ss.rating, 0) * 10 +
-					CASE
-						WHEN s.updated_at > NOW() - INTERVAL '7 days' THEN 20
-						WHEN s.updated_at > NOW() - INTERVAL '30 days' THEN 10
-						ELSE 0
-					END
-				) as trending_score
-			FROM servers s
-			LEFT JOIN proxy_server_stats ss ON s.server_name = ss.server_id
-			WHERE s.is_latest = true
-			AND (
-				s.published_at > NOW() - INTERVAL '30 days'
-				OR s.updated_at > NOW() - INTERVAL '30 days'
-				OR ss.updated_at > NOW() - INTERVAL '30 days'
-			)
-		)
-		SELECT
-			server_name,
-			value,
-			rating,
-			rating_count,
-			installation_count,
-			trending_score
-		FROM trending
-		ORDER BY trending_score DESC
-		LIMIT 10
-	`
+	query := r.URL.Query()
+	period := query.Get("period")
+	if period == "" {
+		period = defaultTrendingPeriodName
+	}
+	scored := period
+	if alias, ok := trendingPeriodAliases[period]; ok {
+		scored = alias
+	}
+	if _, ok := db.TrendingPeriods[scored]; !ok {
+		utils.WriteJSONError(w, "period must be one of 7d, 30d, 90d", http.StatusBadRequest)
+		return
+	}
+	limit := utils.ParseIntParam(query, "limit", 10, 100)
 
-	rows, err := h.registryDB.QueryContext(r.Context(), query)
+	// Scores are precomputed by the trending scorer from decayed install and rating velocity
+	rows, err := h.registryDB.QueryContext(r.Context(), `
+		SELECT
+			s.server_name,
+			s.value,
+			COALESCE(ss.rating, 0) as rating,
+			COALESCE(ss.rating_count, 0) as rating_count,
+			COALESCE(ss.installation_count, 0) as installation_count,
+			COALESCE(ss.weighted_rating, 0) as weighted_rating,
+			t.install_velocity,
+			t.rating_velocity,
+			t.score,
+			t.computed_at
+		FROM proxy_server_trending t
+		JOIN servers s ON s.server_name = t.server_id
+		LEFT JOIN proxy_server_stats ss ON s.server_name = ss.server_id
+		WHERE t.period = $1 AND s.is_latest = true AND s.status IS DISTINCT FROM 'deleted'
+		ORDER BY t.score DESC
+		LIMIT $2
+	`, scored, limit)
 	if err != nil {
 		h.logger.Error("Error querying trending servers", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		utils.WriteJSONError(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
 
-	trending := []map[string]interface{}{}
+	trending := []TrendingServerJSON{}
+	var computedAt time.Time
 	for rows.Next() {
 		var serverName string
 		var valueJSON []byte
-		var rating, trendingScore float64
+		var rating, weightedRating, installVelocity, ratingVelocity, trendingScore float64
 		var ratingCount, installCount int
 
 		err := rows.Scan(
@@ -312,7 +374,11 @@ func (h *EnhancedHandler) HandleTrending(w http.ResponseWriter, r *http.Request)
 			&rating,
 			&ratingCount,
 			&installCount,
+			&weightedRating,
+			&installVelocity,
+			&ratingVelocity,
 			&trendingScore,
+			&computedAt,
 		)
 		if err != nil {
 			h.logger.Warn("Error scanning trending server", zap.Error(err))
@@ -320,7 +386,7 @@ func (h *EnhancedHandler) HandleTrending(w http.ResponseWriter, r *http.Request)
 		}
 
 		// Parse the JSON value
-		var value map[string]interface{}
+		var value TrendingServerJSON
 		if err := json.Unmarshal(valueJSON, &value); err != nil {
 			h.logger.Warn("Error parsing server JSON", zap.String("server", serverName), zap.Error(err))
 			continue
@@ -329,21 +395,27 @@ func (h *EnhancedHandler) HandleTrending(w http.ResponseWriter, r *http.Request)
 		// Add enhanced fields
 		value["id"] = serverName
 		value["name"] = serverName
-		value["stats"] = map[string]interface{}{
-			"rating":         rating,
-			"rating_count":   ratingCount,
-			"install_count":  installCount,
-			"trending_score": trendingScore,
+		value["stats"] = TrendingStats{
+			Rating:          rating,
+			RatingCount:     ratingCount,
+			InstallCount:    installCount,
+			WeightedRating:  weightedRating,
+			InstallVelocity: installVelocity,
+			RatingVelocity:  ratingVelocity,
+			TrendingScore:   trendingScore,
 		}
 
 		trending = append(trending, value)
 	}
 
 	// Write response
-	response := map[string]interface{}{
-		"trending":  trending,
-		"period":    "30_days",
-		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	response := TrendingResponse{
+		Trending:  trending,
+		Period:    period,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+	if !computedAt.IsZero() {
+		response.ComputedAt = computedAt.UTC().Format(time.RFC3339)
 	}
 
 	if err := utils.WriteJSON(w, http.StatusOK, response); err != nil {
@@ -373,12 +445,10 @@ func (h *EnhancedHandler) HandleServerDetail(w http.ResponseWriter, r *http.Requ
 		SELECT
 			s.server_name,
 			s.value,
-			COALESCE(ss.rating, 0) as rating,
-			COALESCE(ss.rating_count, 0) as rating_count,
-			COALESCE(ss.installation_count, 0) as installation_count
+			` + db.ServerStatsColumns("ss") + `
 		FROM servers s
 		LEFT JOIN proxy_server_stats ss ON s.server_name = ss.server_id
-		WHERE s.server_name = $1 AND s.is_latest = true
+		WHERE s.server_name = $1 AND s.is_latest = true AND s.status IS DISTINCT FROM 'deleted'
 		LIMIT 1
 	`
 
@@ -397,25 +467,18 @@ func (h *EnhancedHandler) HandleServerDetail(w http.ResponseWriter, r *http.Requ
 	}
 
 	var (
-		serverName   string
-		valueJSON    []byte
-		rating       float64
-		ratingCount  int
-		installCount int
+		serverName string
+		valueJSON  []byte
+		stats      db.ServerStats
 	)
 
-	if err := rows.Scan(&serverName, &valueJSON, &rating, &ratingCount, &installCount); err != nil {
+	if err := rows.Scan(append([]interface{}{&serverName, &valueJSON}, stats.ScanDest()...)...); err != nil {
 		h.logger.Error("Failed to scan row", zap.Error(err))
 		utils.WriteJSONError(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
 	// Parse and enrich server data using helper
-	stats := db.ServerStats{
-		Rating:            rating,
-		RatingCount:       ratingCount,
-		InstallationCount: installCount,
-	}
 
 	var value map[string]interface{}
 	if err := json.Unmarshal(valueJSON, &value); err != nil {
@@ -428,6 +491,15 @@ func (h *EnhancedHandler) HandleServerDetail(w http.ResponseWriter, r *http.Requ
 	value["id"] = serverName
 	value["name"] = serverName
 
+	// Attach the managed category and tags
+	taxonomies, err := h.registryDB.GetServerTaxonomies(ctx, []string{serverName})
+	if err != nil {
+		h.logger.Error("Failed to load server taxonomy", zap.Error(err))
+		utils.WriteJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	db.ApplyServerTaxonomy(value, taxonomies[serverName])
+
 	// Use the helper function to add stats
 	value = db.EnrichServerWithStats(value, stats)
 
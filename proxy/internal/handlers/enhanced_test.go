@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/veriteknik/registry-proxy/internal/db"
+)
+
+func TestHandleTrending_Period(t *testing.T) {
+	tests := []struct {
+		query, scored, reported string
+	}{
+		{"", "30d", "30_days"},
+		{"?period=7d", "7d", "7d"},
+		{"?period=90_days", "90d", "90_days"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.reported, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("sqlmock.New() error = %v", err)
+			}
+			defer conn.Close()
+
+			mock.ExpectQuery(`FROM proxy_server_trending t`).WithArgs(tt.scored, 10).
+				WillReturnRows(sqlmock.NewRows([]string{"server_name"}))
+
+			w := httptest.NewRecorder()
+			h := &EnhancedHandler{registryDB: &db.DB{DB: conn}}
+			h.HandleTrending(w, httptest.NewRequest(http.MethodGet, "/v0/enhanced/stats/trending"+tt.query, nil))
+
+			var response TrendingResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("status %d, decoding response: %v", w.Code, err)
+			}
+			if response.Period != tt.reported {
+				t.Errorf("period = %q, want %q", response.Period, tt.reported)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestHandleTrending_UnknownPeriod(t *testing.T) {
+	w := httptest.NewRecorder()
+	(&EnhancedHandler{}).HandleTrending(w, httptest.NewRequest(http.MethodGet, "/v0/enhanced/stats/trending?period=1_year", nil))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
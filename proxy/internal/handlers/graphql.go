@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/veriteknik/registry-proxy/internal/db"
+	"github.com/veriteknik/registry-proxy/internal/graph"
+	"github.com/veriteknik/registry-proxy/internal/models"
+	"github.com/veriteknik/registry-proxy/internal/utils"
+)
+
+// maxGraphQLBodyBytes caps GraphQL requests; the query limits bound the work, this bounds parsing
+const maxGraphQLBodyBytes = 64 << 10
+
+// GraphQLHandler handles the GraphQL endpoint over the enriched catalog
+type GraphQLHandler struct {
+	schema *graph.Schema
+}
+
+// NewGraphQLHandler creates a new GraphQL handler; servers and ratings supply the catalog
+// and its reviews, as they do for the REST endpoints
+func NewGraphQLHandler(servers *ServersHandler, ratings *RatingsHandler) (*GraphQLHandler, error) {
+	schema, err := graph.NewSchema(&graphStore{servers: servers, ratings: ratings}, graph.ConfigFromEnv())
+	if err != nil {
+		return nil, err
+	}
+	return &GraphQLHandler{schema: schema}, nil
+}
+
+// GraphQLRequest is the body of POST /v0/graphql
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// GraphQLResponse is the result of a GraphQL query: the data, field errors, or both
+type GraphQLResponse struct {
+	Data   interface{}                `json:"data"`
+	Errors []gqlerrors.FormattedError `json:"errors,omitempty"`
+}
+
+// HandleQuery handles GET and POST /v0/graphql
+// Runs a query given as a JSON body, or as the query, operationName and variables
+// parameters of a GET request
+func (h *GraphQLHandler) HandleQuery(w http.ResponseWriter, r *http.Request) {
+	var req GraphQLRequest
+	switch r.Method {
+	case http.MethodGet:
+		params := r.URL.Query()
+		req.Query = params.Get("query")
+		req.OperationName = params.Get("operationName")
+		if v := params.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				utils.WriteJSONError(w, fmt.Sprintf("Invalid variables: %v", err), http.StatusBadRequest)
+				return
+			}
+		}
+	case http.MethodPost:
+		r.Body = http.MaxBytesReader(w, r.Body, maxGraphQLBodyBytes)
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteJSONError(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+	default:
+		utils.WriteJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if req.Query == "" {
+		utils.WriteJSONError(w, "query is required", http.StatusBadRequest)
+		return
+	}
+
+	result := h.schema.Execute(r.Context(), req.Query, req.OperationName, req.Variables)
+
+	// A query that did not run is a bad request; field errors come with partial data
+	status := http.StatusOK
+	if result.Data == nil && result.HasErrors() {
+		status = http.StatusBadRequest
+	}
+	response := GraphQLResponse{Data: result.Data, Errors: result.Errors}
+	if err := utils.WriteJSON(w, status, response); err != nil {
+		log.Printf("Error encoding GraphQL response: %v", err)
+	}
+}
+
+// graphStore reads the catalog for the GraphQL schema: servers from the registry
+// database, ratings, reviews and collections from the proxy database
+type graphStore struct {
+	servers *ServersHandler
+	ratings *RatingsHandler
+}
+
+func (s *graphStore) QueryServers(ctx context.Context, filter db.ServerFilter, sort string, limit, offset int) ([]models.EnrichedServer, int, error) {
+	serverMaps, total, err := s.servers.registryDB.QueryServersEnhanced(ctx, filter, sort, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	servers := make([]models.EnrichedServer, len(serverMaps))
+	for i, serverMap := range serverMaps {
+		servers[i] = s.servers.convertMapToEnrichedServer(serverMap)
+	}
+	return servers, total, nil
+}
+
+func (s *graphStore) GetServersByID(ctx context.Context, ids []string) (map[string]models.EnrichedServer, error) {
+	return s.servers.getEnrichedServersByID(ctx, ids)
+}
+
+func (s *graphStore) GetServerStatsByID(ctx context.Context, ids []string) (map[string]db.ServerStats, error) {
+	return s.ratings.db.GetServerStatsByID(ctx, ids)
+}
+
+func (s *graphStore) GetTopReviews(ctx context.Context, ids []string, limit int, sort string) (map[string][]db.Review, error) {
+	reviews, err := s.ratings.db.GetTopReviews(ctx, ids, limit, sort)
+	if err != nil {
+		return nil, err
+	}
+
+	// Look up the reviewers of every server at once
+	var all []db.Review
+	reviewed := make([]string, 0, len(reviews))
+	for id, page := range reviews {
+		reviewed = append(reviewed, id)
+		all = append(all, page...)
+	}
+	s.ratings.applyProfiles(ctx, all)
+
+	for _, id := range reviewed {
+		n := len(reviews[id])
+		reviews[id], all = all[:n:n], all[n:]
+	}
+	return reviews, nil
+}
+
+func (s *graphStore) GetVisibleUserRatings(ctx context.Context, userID string) ([]db.UserRating, error) {
+	return s.ratings.db.GetVisibleUserRatings(ctx, userID)
+}
+
+func (s *graphStore) GetPublicCollections(ctx context.Context, limit, offset int) ([]db.Collection, error) {
+	return s.ratings.db.GetPublicCollections(ctx, limit, offset)
+}
+
+func (s *graphStore) GetCollection(ctx context.Context, id string) (*db.Collection, error) {
+	return s.ratings.db.GetCollection(ctx, id)
+}
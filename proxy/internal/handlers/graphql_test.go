@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestHandleQuery_Status tests the request parsing and status codes of the GraphQL endpoint,
+// with queries that need no database
+func TestHandleQuery_Status(t *testing.T) {
+	handler, err := NewGraphQLHandler(&ServersHandler{}, &RatingsHandler{})
+	if err != nil {
+		t.Fatalf("NewGraphQLHandler() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		body     string
+		want     int
+		wantBody string
+	}{
+		{"wrong method", http.MethodDelete, "/v0/graphql", "", http.StatusMethodNotAllowed, ""},
+		{"invalid body", http.MethodPost, "/v0/graphql", "{", http.StatusBadRequest, "Invalid request body"},
+		{"missing query", http.MethodPost, "/v0/graphql", `{}`, http.StatusBadRequest, "query is required"},
+		{"syntax error", http.MethodPost, "/v0/graphql", `{"query": "{ servers"}`, http.StatusBadRequest, `"errors"`},
+		{"unknown field", http.MethodPost, "/v0/graphql", `{"query": "{ nope }"}`, http.StatusBadRequest, "nope"},
+		{"post", http.MethodPost, "/v0/graphql", `{"query": "query Q { __typename }", "operationName": "Q"}`, http.StatusOK, `"__typename":"Query"`},
+		{"get", http.MethodGet, "/v0/graphql?query=" + url.QueryEscape("{ __typename }"), "", http.StatusOK, `"__typename":"Query"`},
+		{"get with invalid variables", http.MethodGet, "/v0/graphql?query=x&variables=nope", "", http.StatusBadRequest, "Invalid variables"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			handler.HandleQuery(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("Expected status %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("Expected body containing %q, got %s", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,446 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/veriteknik/registry-proxy/internal/db"
+	"github.com/veriteknik/registry-proxy/internal/models"
+	"github.com/veriteknik/registry-proxy/internal/openapi"
+	"github.com/veriteknik/registry-proxy/internal/utils"
+)
+
+// OpenAPIHandler serves the OpenAPI document of the proxy API
+type OpenAPIHandler struct {
+	spec []byte
+}
+
+// NewOpenAPIHandler creates a new OpenAPI handler; the document is generated once, from
+// apiRoutes and the request and response types of the handlers
+func NewOpenAPIHandler() (*OpenAPIHandler, error) {
+	spec, err := openAPIDocument()
+	if err != nil {
+		return nil, err
+	}
+	return &OpenAPIHandler{spec: spec}, nil
+}
+
+// HandleSpec handles GET /v0/openapi.json
+func (h *OpenAPIHandler) HandleSpec(w http.ResponseWriter, r *http.Request) {
+	if !utils.RequireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(h.spec); err != nil {
+		log.Printf("Error writing OpenAPI document: %v", err)
+	}
+}
+
+// openAPIDocument generates the OpenAPI document as JSON
+func openAPIDocument() ([]byte, error) {
+	doc, err := openapi.Build(openapi.Spec{
+		Info: openapi.Info{
+			Title: "Plugged.in Registry Proxy API",
+			Description: "Enriched access to the MCP server registry: ratings, reviews, installation " +
+				"statistics, categories, recommendations and user data. Paths not listed here are " +
+				"forwarded to the upstream registry unchanged.",
+			Version: "1.0.0",
+		},
+		SecuritySchemes: map[string]openapi.SecurityScheme{
+			securityAPIKey: {
+				Type:        "http",
+				Scheme:      "bearer",
+				Description: "The proxy API key (API_KEY), for trusted clients writing on behalf of users",
+			},
+			securityUserToken: {
+				Type:         "http",
+				Scheme:       "bearer",
+				BearerFormat: "JWT",
+				Description:  "A token signed with USER_TOKEN_SECRET for the user whose data is read",
+			},
+		},
+		Routes: apiRoutes,
+		Loose: []openapi.Loose{
+			{Value: db.ServerJSON{}, Doc: db.EnhancedServer{}},
+			{Value: TrendingServerJSON{}, Doc: TrendingServer{}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return doc.Marshal()
+}
+
+// Security schemes of the routes
+const (
+	securityAPIKey    = "apiKey"    // middleware.APIKeyAuth
+	securityUserToken = "userToken" // middleware.UserTokenAuth
+)
+
+// Parameters shared by several routes
+var (
+	serverIDParam = openapi.Param{
+		Name:        "id",
+		In:          "path",
+		Description: "Server name, such as io.github.user/repo; the slash is not escaped",
+	}
+	reviewerParam = openapi.Param{
+		Name:        "userId",
+		In:          "path",
+		Description: "ID of the user who wrote the review",
+	}
+	userIDParam = openapi.Param{
+		Name:        "userId",
+		In:          "path",
+		Description: "ID of the user, which must be the one the token was issued for",
+	}
+	gitHubTokenParam = openapi.Param{
+		Name:        "X-GitHub-Token",
+		In:          "header",
+		Description: "GitHub token of the server's publisher",
+		Required:    true,
+	}
+	totalCountHeader = openapi.Param{
+		Name:        "X-Total-Count",
+		Description: "Number of servers matching the filters",
+		Type:        0,
+	}
+)
+
+// apiRoutes declares every endpoint served by the proxy's own handlers; the contract tests
+// check it against the mux of cmd/proxy and the parameters the handlers read
+var apiRoutes = []openapi.Route{
+	// Servers
+	{
+		Method: http.MethodGet, Path: "/v0/servers", Handler: (*ServersHandler).HandleList,
+		OperationID: "listServers", Tag: "servers",
+		Summary:     "List servers with their ratings and installation counts",
+		Description: "Serves the cached catalog; at most 500 servers per page.",
+		Params: []openapi.Param{
+			{Name: "search", Description: "Case-insensitive text in the name or description"},
+			{Name: "registry_name", Description: "Package registry, such as npm or pypi"},
+			{Name: "packageRegistry", Description: "Alias of registry_name"},
+			{Name: "sort", Description: "Sort order; newest first by default",
+				Enum: []string{"newest", "release_date_desc", "date_desc", "release_date_asc", "date_asc", "alphabetical", "name_asc", "name_desc"}},
+			{Name: "limit", Type: 0, Default: 30, Max: 500},
+			{Name: "offset", Type: 0, Default: 0},
+		},
+		Response: models.ProxyResponse{},
+		Headers:  []openapi.Param{totalCountHeader},
+	},
+	{
+		Method: http.MethodGet, Path: "/v0/servers/{id}", Handler: (*ServersHandler).HandleDetail,
+		OperationID: "getServer", Tag: "servers",
+		Summary:  "Get a server with its ratings and installation counts",
+		Params:   []openapi.Param{serverIDParam},
+		Response: models.EnrichedServer{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v0/servers/batch", Handler: (*ServersHandler).HandleBatch,
+		OperationID: "batchGetServers", Tag: "servers",
+		Summary:     "Get several servers at once",
+		Description: "Looks up to 500 servers, returned in the order requested; unknown IDs are listed as missing.",
+		Request:     BatchServersRequest{},
+		Response:    BatchServersResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v0/servers/check-updates", Handler: (*ServersHandler).HandleCheckUpdates,
+		OperationID: "checkUpdates", Tag: "servers",
+		Summary:     "Check installed versions for updates",
+		Description: "Compares up to 500 installed versions with the latest published versions, flagging breaking changes.",
+		Request:     CheckUpdatesRequest{},
+		Response:    CheckUpdatesResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v0/servers/{id}/related", Handler: (*ServersHandler).HandleRelated,
+		OperationID: "getRelatedServers", Tag: "servers",
+		Summary: "List the servers most similar to a server",
+		Params: []openapi.Param{
+			serverIDParam,
+			{Name: "limit", Type: 0, Default: 10, Max: 50},
+		},
+		Response: RelatedServersResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v0/cache/refresh", Handler: (*ServersHandler).HandleRefresh,
+		OperationID: "refreshCache", Tag: "servers",
+		Summary:  "Reload the server cache from the registry database",
+		Response: RefreshResponse{},
+	},
+
+	// Enhanced queries over the registry database
+	{
+		Method: http.MethodGet, Path: "/v0/enhanced/servers", Handler: (*EnhancedHandler).HandleEnhancedServers,
+		OperationID: "searchServers", Tag: "enhanced",
+		Summary: "Filter and sort servers in the registry database",
+		Params: []openapi.Param{
+			{Name: "search", Description: "Text in the name or description"},
+			{Name: "category", Description: "Category slug"},
+			{Name: "tags", Type: []string{}, Description: "Servers with any of these tags"},
+			{Name: "registry_types", Type: []string{}, Description: "Package registries or remote, such as npm, pypi, oci, remote"},
+			{Name: "transports", Type: []string{}, Description: "Transports, such as stdio, sse, http"},
+			{Name: "min_rating", Type: 0.0},
+			{Name: "min_installs", Type: 0},
+			{Name: "sort", Default: "created",
+				Enum: []string{"created", "updated", "name_asc", "name_desc", "rating_desc", "reviews_desc", "installs_desc", "trending"}},
+			{Name: "limit", Type: 0, Default: 20, Max: 1000},
+			{Name: "offset", Type: 0, Default: 0},
+		},
+		Response: EnhancedServersResponse{},
+		Headers:  []openapi.Param{totalCountHeader},
+	},
+	{
+		Method: http.MethodGet, Path: "/v0/enhanced/stats/aggregate", Handler: (*EnhancedHandler).HandleStats,
+		OperationID: "getAggregateStats", Tag: "enhanced",
+		Summary:  "Summarize the whole registry",
+		Response: AggregateStatsResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v0/enhanced/stats/trending", Handler: (*EnhancedHandler).HandleTrending,
+		OperationID: "getTrendingServers", Tag: "enhanced",
+		Summary: "List the servers with the fastest growing installs and ratings",
+		Params: []openapi.Param{
+			{Name: "period", Default: defaultTrendingPeriodName, Enum: []string{"7d", "30d", "90d", "7_days", "30_days", "90_days"}},
+			{Name: "limit", Type: 0, Default: 10, Max: 100},
+		},
+		Response: TrendingResponse{},
+	},
+
+	// Ratings and installations
+	{
+		Method: http.MethodPost, Path: "/v0/servers/{id}/rate", Handler: (*RatingsHandler).HandleRate,
+		OperationID: "rateServer", Tag: "ratings", Security: securityAPIKey,
+		Summary:     "Rate and review a server",
+		Description: "Reviews the comment filter flags are held for moderation.",
+		Params:      []openapi.Param{serverIDParam},
+		Request:     RatingRequest{},
+		Response:    RateResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v0/servers/{id}/install", Handler: (*RatingsHandler).HandleInstall,
+		OperationID: "trackInstall", Tag: "ratings", Security: securityAPIKey,
+		Summary:  "Record an installation",
+		Params:   []openapi.Param{serverIDParam},
+		Request:  InstallRequest{},
+		Response: InstallResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v0/servers/{id}/uninstall", Handler: (*RatingsHandler).HandleUninstall,
+		OperationID: "trackUninstall", Tag: "ratings", Security: securityAPIKey,
+		Summary:  "Record an uninstallation",
+		Params:   []openapi.Param{serverIDParam},
+		Request:  InstallRequest{},
+		Response: UninstallResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v0/servers/{id}/heartbeat", Handler: (*RatingsHandler).HandleHeartbeat,
+		OperationID: "recordHeartbeat", Tag: "ratings", Security: securityAPIKey,
+		Summary:     "Keep an installation counted as active",
+		Description: "Installed clients call this periodically to stay counted in active_installs.",
+		Params:      []openapi.Param{serverIDParam},
+		Request:     HeartbeatRequest{},
+		Status:      http.StatusNoContent,
+	},
+	{
+		Method: http.MethodGet, Path: "/v0/servers/{id}/stats", Handler: (*RatingsHandler).HandleStats,
+		OperationID: "getServerStats", Tag: "ratings",
+		Summary:  "Get a server's rating and installation statistics",
+		Params:   []openapi.Param{serverIDParam},
+		Response: StatsResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v0/servers/{id}/stats/timeseries", Handler: (*RatingsHandler).HandleStatsTimeseries,
+		OperationID: "getServerTimeseries", Tag: "ratings",
+		Summary: "Get a server's installs, uninstalls or ratings per interval",
+		Params: []openapi.Param{
+			serverIDParam,
+			{Name: "metric", Default: "installs", Enum: []string{"installs", "uninstalls", "ratings"}},
+			{Name: "interval", Default: "day", Enum: []string{"day", "week", "month"}},
+			{Name: "days", Type: 0, Max: maxTimeseriesDays,
+				Description: "Length of the window; 30, 84 or 365 days by default, depending on the interval"},
+		},
+		Response: TimeseriesResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v0/servers/{id}/stats/breakdown", Handler: (*RatingsHandler).HandleStatsBreakdown,
+		OperationID: "getServerInstallBreakdown", Tag: "ratings",
+		Summary: "Group a server's installations by version, platform and source",
+		Params: []openapi.Param{
+			serverIDParam,
+			{Name: "active", Type: false, Description: "Count only active installations"},
+			{Name: "limit", Type: 0, Default: defaultBreakdownLimit, Max: maxBreakdownLimit,
+				Description: "Most common values returned per dimension"},
+		},
+		Response: BreakdownResponse{},
+	},
+
+	// Reviews
+	{
+		Method: http.MethodGet, Path: "/v0/servers/{id}/reviews", Handler: (*RatingsHandler).HandleGetReviews,
+		OperationID: "listReviews", Tag: "reviews",
+		Summary:  "List a server's visible reviews",
+		Params:   []openapi.Param{serverIDParam},
+		Response: ReviewsResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v0/servers/{id}/feedback", Handler: (*RatingsHandler).HandleGetFeedback,
+		OperationID: "listFeedback", Tag: "reviews",
+		Summary: "Page through a server's visible reviews",
+		Params: []openapi.Param{
+			serverIDParam,
+			{Name: "sort", Default: "newest", Enum: []string{"newest", "oldest", "rating_high", "rating_low", "most_helpful"}},
+			{Name: "limit", Type: 0, Default: 20},
+			{Name: "offset", Type: 0, Default: 0},
+		},
+		Response: FeedbackResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v0/servers/{id}/rating/{userId}", Handler: (*RatingsHandler).HandleGetUserRating,
+		OperationID: "getUserRating", Tag: "reviews",
+		Summary:     "Get a user's rating of a server",
+		Description: "Responds 404 with has_rated false when the user has not rated the server.",
+		Params: []openapi.Param{
+			serverIDParam,
+			{Name: "userId", In: "path", Description: "ID of the user"},
+		},
+		Response: UserRatingLookup{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v0/servers/{id}/reviews/{userId}/report", Handler: (*RatingsHandler).HandleReportReview,
+		OperationID: "reportReview", Tag: "reviews",
+		Summary: "Report an abusive review",
+		Description: "Reviews with enough open reports are held for moderation. Anonymous reports count once per client IP " +
+			"and are rate limited (429); reporter_id is only honored with the API key.",
+		Params:   []openapi.Param{serverIDParam, reviewerParam},
+		Request:  ReportRequest{},
+		Status:   http.StatusAccepted,
+		Response: MessageResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v0/servers/{id}/reviews/{userId}/vote", Handler: (*RatingsHandler).HandleVoteReview,
+		OperationID: "voteReview", Tag: "reviews", Security: securityAPIKey,
+		Summary:  "Vote a review helpful or unhelpful",
+		Params:   []openapi.Param{serverIDParam, reviewerParam},
+		Request:  VoteRequest{},
+		Response: VoteResponse{},
+	},
+	{
+		Method: http.MethodDelete, Path: "/v0/servers/{id}/reviews/{userId}/vote", Handler: (*RatingsHandler).HandleVoteReview,
+		OperationID: "deleteReviewVote", Tag: "reviews", Security: securityAPIKey,
+		Summary:     "Withdraw a vote on a review",
+		Description: "Only voter_id is read from the body.",
+		Params:      []openapi.Param{serverIDParam, reviewerParam},
+		Request:     VoteRequest{},
+		Response:    VoteResponse{},
+	},
+	{
+		Method: http.MethodPut, Path: "/v0/servers/{id}/reviews/{userId}/response", Handler: (*RatingsHandler).HandleReviewResponse,
+		OperationID: "respondToReview", Tag: "reviews", Security: securityAPIKey,
+		Summary:     "Post or edit the publisher's response to a review",
+		Description: "Only for io.github.<owner>/ servers, by the GitHub user or organization owning the namespace.",
+		Params:      []openapi.Param{serverIDParam, reviewerParam, gitHubTokenParam},
+		Request:     ResponseRequest{},
+		Response:    ReviewResponseResult{},
+	},
+	{
+		Method: http.MethodDelete, Path: "/v0/servers/{id}/reviews/{userId}/response", Handler: (*RatingsHandler).HandleReviewResponse,
+		OperationID: "deleteReviewResponse", Tag: "reviews", Security: securityAPIKey,
+		Summary: "Delete the publisher's response to a review",
+		Params:  []openapi.Param{serverIDParam, reviewerParam, gitHubTokenParam},
+		Status:  http.StatusNoContent,
+	},
+
+	// Categories
+	{
+		Method: http.MethodGet, Path: "/v0/categories", Handler: (*CategoriesHandler).HandleList,
+		OperationID: "listCategories", Tag: "categories",
+		Summary:  "List the categories with the number of servers in each",
+		Response: CategoriesResponse{},
+	},
+
+	// Users
+	{
+		Method: http.MethodGet, Path: "/v0/users/me/export", Handler: (*UsersHandler).HandleExport,
+		OperationID: "exportUserData", Tag: "users", Security: securityUserToken,
+		Summary:  "Export everything stored about the authenticated user",
+		Response: UserExportResponse{},
+		Headers: []openapi.Param{
+			{Name: "Content-Disposition", Description: "Names the archive plugged-in-export.json"},
+		},
+	},
+	{
+		Method: http.MethodGet, Path: "/v0/users/{userId}/installs", Handler: (*UsersHandler).HandleInstalls,
+		OperationID: "listUserInstalls", Tag: "users", Security: securityUserToken,
+		Summary:  "List the servers a user has installed, with available updates",
+		Params:   []openapi.Param{userIDParam},
+		Response: UserInstallsResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v0/users/{userId}/ratings", Handler: (*UsersHandler).HandleRatings,
+		OperationID: "listUserRatings", Tag: "users", Security: securityUserToken,
+		Summary:  "List the servers a user has rated",
+		Params:   []openapi.Param{userIDParam},
+		Response: UserRatingsResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v0/users/{userId}/recommendations", Handler: (*UsersHandler).HandleRecommendations,
+		OperationID: "listUserRecommendations", Tag: "users", Security: securityUserToken,
+		Summary: "Recommend servers a user has not installed",
+		Params: []openapi.Param{
+			userIDParam,
+			{Name: "limit", Type: 0, Default: 10, Max: 50},
+		},
+		Response: UserRecommendationsResponse{},
+	},
+	{
+		Method: http.MethodPut, Path: "/v0/users/{userId}/profile", Handler: (*UsersHandler).HandleProfile,
+		OperationID: "putUserProfile", Tag: "users", Security: securityAPIKey,
+		Summary:     "Store the public profile shown with a user's reviews",
+		Description: "Called by the user service with the user's own ID, which is hashed like every stored user ID.",
+		Params:      []openapi.Param{{Name: "userId", In: "path", Description: "ID of the user"}},
+		Request:     ProfileRequest{},
+		Status:      http.StatusNoContent,
+	},
+	{
+		Method: http.MethodDelete, Path: "/v0/users/{userId}", Handler: (*UsersHandler).HandleErase,
+		OperationID: "eraseUserData", Tag: "users", Security: securityAPIKey,
+		Summary:     "Erase everything stored about a user",
+		Description: "Recomputes the statistics of the servers the user rated or installed.",
+		Params:      []openapi.Param{{Name: "userId", In: "path", Description: "ID of the user"}},
+		Response:    EraseResponse{},
+	},
+
+	// GraphQL
+	{
+		Method: http.MethodGet, Path: "/v0/graphql", Handler: (*GraphQLHandler).HandleQuery,
+		OperationID: "graphqlGet", Tag: "graphql",
+		Summary: "Run a GraphQL query given as parameters",
+		Params: []openapi.Param{
+			{Name: "query", Required: true},
+			{Name: "operationName"},
+			{Name: "variables", Description: "Variables as a JSON object"},
+		},
+		Response: GraphQLResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v0/graphql", Handler: (*GraphQLHandler).HandleQuery,
+		OperationID: "graphqlPost", Tag: "graphql",
+		Summary:  "Run a GraphQL query",
+		Request:  GraphQLRequest{},
+		Response: GraphQLResponse{},
+	},
+
+	// This document
+	{
+		Method: http.MethodGet, Path: "/v0/openapi.json", Handler: (*OpenAPIHandler).HandleSpec,
+		OperationID: "getOpenAPIDocument", Tag: "meta",
+		Summary:  "Get this OpenAPI document",
+		Response: map[string]interface{}{},
+	},
+}
+
+// APIRoutes returns a copy of the routes declared in the OpenAPI document
+func APIRoutes() []openapi.Route {
+	return append([]openapi.Route(nil), apiRoutes...)
+}
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"bytes"
+	"flag"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/veriteknik/registry-proxy/internal/openapi"
+)
+
+var updateOpenAPI = flag.Bool("update", false, "rewrite openapi.json from the route table and handler types")
+
+// openAPIFile is the checked-in document client SDKs are generated from
+const openAPIFile = "../../openapi.json"
+
+// TestOpenAPIDocumentIsCurrent fails when a route, parameter or field changed without
+// openapi.json being regenerated
+func TestOpenAPIDocumentIsCurrent(t *testing.T) {
+	spec, err := openAPIDocument()
+	if err != nil {
+		t.Fatalf("openAPIDocument() error = %v", err)
+	}
+
+	if *updateOpenAPI {
+		if err := os.WriteFile(openAPIFile, spec, 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", openAPIFile, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(openAPIFile)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", openAPIFile, err)
+	}
+	if !bytes.Equal(spec, want) {
+		t.Errorf("openapi.json is out of date; review the API change, then run " +
+			"`go test ./internal/handlers -run TestOpenAPIDocumentIsCurrent -update` (make openapi) and commit the result")
+	}
+}
+
+func TestHandleSpec(t *testing.T) {
+	handler, err := NewOpenAPIHandler()
+	if err != nil {
+		t.Fatalf("NewOpenAPIHandler() error = %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.HandleSpec(rec, httptest.NewRequest(http.MethodGet, "/v0/openapi.json", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("GET: status %d, Content-Type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Body.String(), `"openapi": "3.1.0"`) {
+		t.Errorf("GET: body does not look like an OpenAPI 3.1 document: %.100s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.HandleSpec(rec, httptest.NewRequest(http.MethodPost, "/v0/openapi.json", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: status %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+// handlerRoutes groups apiRoutes by handler name, such as "RatingsHandler.HandleStats"
+func handlerRoutes() map[string][]openapi.Route {
+	routes := make(map[string][]openapi.Route)
+	for _, route := range apiRoutes {
+		name := openapi.HandlerName(route.Handler)
+		routes[name] = append(routes[name], route)
+	}
+	return routes
+}
+
+// parseFuncs parses the non-test Go files of dir and returns their methods by
+// "Receiver.Method"
+func parseFuncs(t *testing.T, dir string) map[string]*ast.FuncDecl {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	funcs := make(map[string]*ast.FuncDecl)
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", file, err)
+		}
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil {
+				continue
+			}
+			recv := fn.Recv.List[0].Type
+			if star, ok := recv.(*ast.StarExpr); ok {
+				recv = star.X
+			}
+			funcs[types.ExprString(recv)+"."+fn.Name.Name] = fn
+		}
+	}
+	return funcs
+}
+
+// docRoutePattern matches the first line of a handler comment, such as
+// "HandleVoteReview handles POST and DELETE /v0/servers/:id/reviews/:userId/vote"
+var docRoutePattern = regexp.MustCompile(`^Handle\w+ handles ((?:[A-Z]+(?:, | and )?)+) (/[^\s,]+)`)
+
+// TestOpenAPIRoutesMatchHandlers checks each route against the source of its handler: the
+// method and path in the handler's comment, the query and header parameters it reads and
+// the JSON body it decodes
+func TestOpenAPIRoutesMatchHandlers(t *testing.T) {
+	funcs := parseFuncs(t, ".")
+
+	for name, routes := range handlerRoutes() {
+		fn, ok := funcs[name]
+		if !ok {
+			t.Errorf("%s: handler not found", name)
+			continue
+		}
+
+		// Documented methods and path
+		match := docRoutePattern.FindStringSubmatch(fn.Doc.Text())
+		if match == nil {
+			t.Errorf("%s: comment does not start with \"%s handles METHOD /path\"", name, fn.Name.Name)
+			continue
+		}
+		path := regexp.MustCompile(`:(\w+)`).ReplaceAllString(match[2], "{$1}")
+		methods := strings.FieldsFunc(match[1], func(r rune) bool { return r < 'A' || r > 'Z' })
+		var declared []string
+		for _, route := range routes {
+			if route.Path != path {
+				t.Errorf("%s: route path %s, handler comment says %s", name, route.Path, path)
+			}
+			declared = append(declared, route.Method)
+		}
+		if !sameSet(declared, methods) {
+			t.Errorf("%s: route methods %v, handler comment says %v", name, declared, methods)
+		}
+
+		// Parameters read
+		read, decoded := handlerInputs(fn)
+		var params, requests []string
+		for _, route := range routes {
+			for _, p := range route.Params {
+				switch p.In {
+				case "", "query":
+					params = append(params, "query "+p.Name)
+				case "header":
+					params = append(params, "header "+http.CanonicalHeaderKey(p.Name))
+				}
+			}
+			if route.Request != nil {
+				requests = append(requests, strings.TrimPrefix(reflect.TypeOf(route.Request).String(), "handlers."))
+			}
+		}
+		if !sameSet(params, read) {
+			t.Errorf("%s: routes declare parameters %v, handler reads %v", name, unique(params), unique(read))
+		}
+		if !sameSet(requests, decoded) {
+			t.Errorf("%s: routes declare request bodies %v, handler decodes %v", name, unique(requests), unique(decoded))
+		}
+	}
+}
+
+// handlerInputs returns the query and header parameters a handler reads, as "query name"
+// and "header Name", and the types of the JSON bodies it decodes
+func handlerInputs(fn *ast.FuncDecl) (params, bodies []string) {
+	// Variables holding the query, as in query := r.URL.Query(), and declared types
+	queryVars := make(map[string]bool)
+	varTypes := make(map[string]string)
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if len(n.Lhs) == 1 && len(n.Rhs) == 1 && isQueryCall(n.Rhs[0]) {
+				if id, ok := n.Lhs[0].(*ast.Ident); ok {
+					queryVars[id.Name] = true
+				}
+			}
+		case *ast.ValueSpec:
+			if n.Type != nil {
+				for _, id := range n.Names {
+					varTypes[id.Name] = types.ExprString(n.Type)
+				}
+			}
+		}
+		return true
+	})
+
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+
+		switch fun := types.ExprString(sel); {
+		case sel.Sel.Name == "Get" && len(call.Args) == 1:
+			key, ok := stringLit(call.Args[0])
+			if !ok {
+				break
+			}
+			if x, ok := sel.X.(*ast.SelectorExpr); ok && x.Sel.Name == "Header" {
+				params = append(params, "header "+http.CanonicalHeaderKey(key))
+			} else if id, ok := sel.X.(*ast.Ident); (ok && queryVars[id.Name]) || isQueryCall(sel.X) {
+				params = append(params, "query "+key)
+			}
+		case fun == "utils.ParseIntParam" || fun == "utils.ParseFloatParam" || fun == "utils.ParseList":
+			if key, ok := stringLit(call.Args[1]); ok {
+				params = append(params, "query "+key)
+			}
+		case sel.Sel.Name == "Decode" && len(call.Args) == 1:
+			if addr, ok := call.Args[0].(*ast.UnaryExpr); ok && addr.Op == token.AND {
+				if id, ok := addr.X.(*ast.Ident); ok {
+					bodies = append(bodies, varTypes[id.Name])
+				}
+			}
+		}
+		return true
+	})
+	return params, bodies
+}
+
+// isQueryCall reports whether e is a call of a URL's Query method
+func isQueryCall(e ast.Expr) bool {
+	call, ok := e.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == "Query" && strings.HasSuffix(types.ExprString(sel.X), ".URL")
+}
+
+func stringLit(e ast.Expr) (string, bool) {
+	lit, ok := e.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+func unique(values []string) []string {
+	set := make(map[string]bool)
+	for _, v := range values {
+		set[v] = true
+	}
+	list := make([]string, 0, len(set))
+	for v := range set {
+		list = append(list, v)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func sameSet(a, b []string) bool {
+	return reflect.DeepEqual(unique(a), unique(b))
+}
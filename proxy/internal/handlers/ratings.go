@@ -1,21 +1,69 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/veriteknik/registry-proxy/internal/db"
+	"github.com/veriteknik/registry-proxy/internal/filter"
+	"github.com/veriteknik/registry-proxy/internal/metrics"
+	"github.com/veriteknik/registry-proxy/internal/middleware"
+	"github.com/veriteknik/registry-proxy/internal/models"
+	"github.com/veriteknik/registry-proxy/internal/profiles"
+	"github.com/veriteknik/registry-proxy/internal/publisher"
+	"github.com/veriteknik/registry-proxy/internal/utils"
 )
 
+// defaultReportThreshold is the number of open reports that holds a review for moderation
+const defaultReportThreshold = 3
+
+// defaultReportRateLimit is the number of reports an anonymous client IP may file per hour
+const defaultReportRateLimit = 20
+
+// maxReportReasonLength caps the free-text reason of an abuse report
+const maxReportReasonLength = 1000
+
+// maxResponseLength caps a publisher's response to a review
+const maxResponseLength = 2000
+
+// Timeseries windows: default number of days per interval and the longest window served
+var defaultTimeseriesDays = map[string]int{"day": 30, "week": 84, "month": 365}
+
+const maxTimeseriesDays = 730
+
+// Install breakdown: most common values returned per dimension by default and at most
+const (
+	defaultBreakdownLimit = 20
+	maxBreakdownLimit     = 100
+)
+
+// maxRatingBodyBytes caps rating submissions; longer comments than the filter allows are
+// held for moderation, but nothing larger than this is read
+const maxRatingBodyBytes = 64 << 10
+
 // RatingsHandler handles rating and installation tracking
 type RatingsHandler struct {
-	db    *db.DB
-	cache Cache
+	db              *db.DB
+	cache           Cache
+	reportThreshold int
+	reportLimiter   *middleware.RateLimiter
+	comments        *filter.Pipeline
+	publishers      PublisherVerifier
+	profiles        profiles.Provider
+}
+
+// PublisherVerifier resolves a publisher's GitHub token to a verified identity
+type PublisherVerifier interface {
+	Verify(ctx context.Context, token string) (*publisher.Identity, error)
 }
 
 // Cache interface for invalidating cached server data
@@ -23,12 +71,38 @@ type Cache interface {
 	Clear()
 }
 
-// NewRatingsHandler creates a new ratings handler
-func NewRatingsHandler(database *db.DB, cache Cache) *RatingsHandler {
-	return &RatingsHandler{
-		db:    database,
-		cache: cache,
+// NewRatingsHandler creates a new ratings handler, failing when the comment filter's word
+// list cannot be read or the profile provider is misconfigured
+func NewRatingsHandler(database *db.DB, cache Cache) (*RatingsHandler, error) {
+	reportThreshold := defaultReportThreshold
+	if v, err := strconv.Atoi(os.Getenv("REVIEW_REPORT_THRESHOLD")); err == nil && v >= 0 {
+		reportThreshold = v
+	}
+
+	reportRateLimit := defaultReportRateLimit
+	if v, err := strconv.Atoi(os.Getenv("REVIEW_REPORT_RATE_LIMIT")); err == nil && v >= 0 {
+		reportRateLimit = v
+	}
+
+	filterConfig, err := filter.ConfigFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load comment filter word list: %w", err)
+	}
+
+	profileProvider, err := profiles.FromEnv(database, database.UserIDsHashed())
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure user profiles: %w", err)
 	}
+
+	return &RatingsHandler{
+		db:              database,
+		cache:           cache,
+		reportThreshold: reportThreshold,
+		reportLimiter:   middleware.NewRateLimiter(reportRateLimit, time.Hour),
+		comments:        filter.NewDefaultPipeline(filterConfig, database),
+		publishers:      publisher.NewGitHubVerifier(),
+		profiles:        profileProvider,
+	}, nil
 }
 
 // RatingRequest represents a rating submission
@@ -40,6 +114,23 @@ type RatingRequest struct {
 	Timestamp string `json:"timestamp"`
 }
 
+// ReportRequest represents an abuse report against a review
+type ReportRequest struct {
+	ReporterID string `json:"reporter_id"` // Only honored for callers with the API key
+	Reason     string `json:"reason"`
+}
+
+// VoteRequest represents a helpful/unhelpful vote on a review
+type VoteRequest struct {
+	VoterID string `json:"voter_id"`
+	Helpful *bool  `json:"helpful"`
+}
+
+// ResponseRequest represents a publisher's response to a review
+type ResponseRequest struct {
+	Body string `json:"body"`
+}
+
 // InstallRequest represents an installation tracking request
 type InstallRequest struct {
 	UserID   string `json:"user_id"`
@@ -48,16 +139,115 @@ type InstallRequest struct {
 	Platform string `json:"platform"`
 }
 
+// HeartbeatRequest represents a periodic ping from an installed client
+type HeartbeatRequest struct {
+	UserID   string `json:"user_id"`
+	Version  string `json:"version"`
+	Platform string `json:"platform"`
+}
+
 // StatsResponse represents server statistics
 type StatsResponse struct {
 	Stats struct {
-		ServerID          string  `json:"server_id"`
-		InstallationCount int     `json:"installation_count"`
-		Rating            float64 `json:"rating"`
-		RatingCount       int     `json:"rating_count"`
+		ServerID           string                    `json:"server_id"`
+		InstallationCount  int                       `json:"installation_count"`
+		TotalInstalls      int                       `json:"total_installs"`
+		ActiveInstalls     int                       `json:"active_installs"`
+		Retention          float64                   `json:"retention"`
+		Rating             float64                   `json:"rating"`
+		RatingCount        int                       `json:"rating_count"`
+		WeightedRating     float64                   `json:"weighted_rating"`
+		RatingDistribution models.RatingDistribution `json:"rating_distribution"`
 	} `json:"stats"`
 }
 
+// RatingStats are a server's rating and install counts after a rating
+type RatingStats struct {
+	ServerID          string  `json:"server_id"`
+	Rating            float64 `json:"rating"`
+	RatingCount       int     `json:"rating_count"`
+	InstallationCount int     `json:"installation_count"`
+}
+
+// RateResponse is the result of POST /v0/servers/:id/rate; Stats is left out when they
+// cannot be read
+type RateResponse struct {
+	Success           bool         `json:"success"`
+	Message           string       `json:"message,omitempty"`
+	PendingModeration bool         `json:"pending_moderation"`
+	Stats             *RatingStats `json:"stats,omitempty"`
+}
+
+// InstallStats are a server's rating and install counts after an install
+type InstallStats struct {
+	RatingStats
+	TotalInstalls  int `json:"total_installs"`
+	ActiveInstalls int `json:"active_installs"`
+}
+
+// InstallResponse is the result of POST /v0/servers/:id/install; Stats is left out when
+// they cannot be read
+type InstallResponse struct {
+	Success bool          `json:"success"`
+	Message string        `json:"message,omitempty"`
+	Stats   *InstallStats `json:"stats,omitempty"`
+}
+
+// InstallCounts are a server's install counts after an uninstall
+type InstallCounts struct {
+	ServerID          string `json:"server_id"`
+	InstallationCount int    `json:"installation_count"`
+	TotalInstalls     int    `json:"total_installs"`
+	ActiveInstalls    int    `json:"active_installs"`
+}
+
+// UninstallResponse is the result of POST /v0/servers/:id/uninstall; Stats is left out
+// when they cannot be read
+type UninstallResponse struct {
+	Success bool           `json:"success"`
+	Message string         `json:"message"`
+	Stats   *InstallCounts `json:"stats,omitempty"`
+}
+
+// TimeseriesResponse is a server's metric per interval
+type TimeseriesResponse struct {
+	ServerID string               `json:"server_id"`
+	Metric   string               `json:"metric"`
+	Interval string               `json:"interval"`
+	Points   []db.TimeseriesPoint `json:"points"`
+}
+
+// BreakdownResponse groups a server's installations by version, platform and source
+type BreakdownResponse struct {
+	ServerID   string `json:"server_id"`
+	ActiveOnly bool   `json:"active_only"`
+	db.InstallBreakdown
+}
+
+// ReviewsResponse lists a server's visible reviews
+type ReviewsResponse struct {
+	Reviews []db.Review `json:"reviews"`
+}
+
+// MessageResponse acknowledges a request that returns no data
+type MessageResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+// VoteResponse holds a review's vote counts after a vote
+type VoteResponse struct {
+	Success        bool `json:"success"`
+	HelpfulCount   int  `json:"helpful_count"`
+	UnhelpfulCount int  `json:"unhelpful_count"`
+}
+
+// ReviewResponseResult holds a publisher response after it was posted or edited
+type ReviewResponseResult struct {
+	Success  bool               `json:"success"`
+	Response *db.ReviewResponse `json:"response"`
+}
+
 // HandleRate handles POST /v0/servers/:id/rate
 func (h *RatingsHandler) HandleRate(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -75,6 +265,7 @@ func (h *RatingsHandler) HandleRate(w http.ResponseWriter, r *http.Request) {
 
 	// Parse request body
 	var req RatingRequest
+	r.Body = http.MaxBytesReader(w, r.Body, maxRatingBodyBytes)
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
 		return
@@ -92,8 +283,21 @@ func (h *RatingsHandler) HandleRate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Screen the comment; flagged reviews are stored as pending moderation
+	var held filter.Result
+	if h.comments != nil {
+		held = h.comments.Evaluate(r.Context(), filter.Comment{ServerID: serverID, UserID: req.UserID, Text: req.Comment})
+	}
+	if held.Held() {
+		metrics.RecordReviewHeld()
+	}
+
 	// Save rating to database
-	if err := h.db.UpsertRating(r.Context(), serverID, req.UserID, req.Rating, req.Comment); err != nil {
+	if err := h.db.UpsertRating(r.Context(), serverID, req.UserID, req.Rating, req.Comment, filter.Fingerprint(req.Comment), held.Reason()); err != nil {
+		if errors.Is(err, db.ErrUserBanned) {
+			http.Error(w, "User is not allowed to submit reviews", http.StatusForbidden)
+			return
+		}
 		log.Printf("Failed to save rating: %v", err)
 		http.Error(w, "Failed to save rating", http.StatusInternalServerError)
 		return
@@ -106,14 +310,15 @@ func (h *RatingsHandler) HandleRate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get updated stats
-	rating, ratingCount, installCount, err := h.db.GetServerStats(r.Context(), serverID)
+	stats, err := h.db.GetServerStats(r.Context(), serverID)
 	if err != nil {
 		log.Printf("Failed to get stats: %v", err)
 		// Don't fail the request, just return success without stats
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": true,
-			"message": "Rating saved successfully",
+		if err := json.NewEncoder(w).Encode(RateResponse{
+			Success:           true,
+			Message:           "Rating saved successfully",
+			PendingModeration: held.Held(),
 		}); err != nil {
 			log.Printf("Error encoding rating response: %v", err)
 		}
@@ -122,13 +327,14 @@ func (h *RatingsHandler) HandleRate(w http.ResponseWriter, r *http.Request) {
 
 	// Return success with updated stats
 	w.Header().Set("Content-Type", "application/json")
-	response := map[string]interface{}{
-		"success": true,
-		"stats": map[string]interface{}{
-			"server_id":          serverID,
-			"rating":             rating,
-			"rating_count":       ratingCount,
-			"installation_count": installCount,
+	response := RateResponse{
+		Success:           true,
+		PendingModeration: held.Held(),
+		Stats: &RatingStats{
+			ServerID:          serverID,
+			Rating:            stats.Rating,
+			RatingCount:       stats.RatingCount,
+			InstallationCount: stats.InstallationCount,
 		},
 	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
@@ -172,14 +378,14 @@ func (h *RatingsHandler) HandleInstall(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get updated stats
-	rating, ratingCount, installCount, err := h.db.GetServerStats(r.Context(), serverID)
+	stats, err := h.db.GetServerStats(r.Context(), serverID)
 	if err != nil {
 		log.Printf("Failed to get stats: %v", err)
 		// Don't fail the request, just return success without stats
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": true,
-			"message": "Installation tracked successfully",
+		if err := json.NewEncoder(w).Encode(InstallResponse{
+			Success: true,
+			Message: "Installation tracked successfully",
 		}); err != nil {
 			log.Printf("Error encoding install response: %v", err)
 		}
@@ -188,13 +394,17 @@ func (h *RatingsHandler) HandleInstall(w http.ResponseWriter, r *http.Request) {
 
 	// Return success with updated stats
 	w.Header().Set("Content-Type", "application/json")
-	response := map[string]interface{}{
-		"success": true,
-		"stats": map[string]interface{}{
-			"server_id":          serverID,
-			"rating":             rating,
-			"rating_count":       ratingCount,
-			"installation_count": installCount,
+	response := InstallResponse{
+		Success: true,
+		Stats: &InstallStats{
+			RatingStats: RatingStats{
+				ServerID:          serverID,
+				Rating:            stats.Rating,
+				RatingCount:       stats.RatingCount,
+				InstallationCount: stats.InstallationCount,
+			},
+			TotalInstalls:  stats.InstallationCount,
+			ActiveInstalls: stats.ActiveInstalls,
 		},
 	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
@@ -202,6 +412,106 @@ func (h *RatingsHandler) HandleInstall(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// HandleUninstall handles POST /v0/servers/:id/uninstall
+func (h *RatingsHandler) HandleUninstall(w http.ResponseWriter, r *http.Request) {
+	if !utils.RequireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	// Extract server ID from path: /v0/servers/{id}/uninstall
+	path := strings.TrimPrefix(r.URL.Path, "/v0/servers/")
+	serverID := strings.TrimSuffix(path, "/uninstall")
+	if serverID == "" || serverID == path {
+		utils.WriteJSONError(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	var req InstallRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSONError(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.UserID == "" {
+		utils.WriteJSONError(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.db.UninstallServer(r.Context(), serverID, req.UserID); err != nil {
+		if errors.Is(err, db.ErrInstallationNotFound) {
+			utils.WriteJSONError(w, "Installation not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to track uninstall: %v", err)
+		utils.WriteJSONError(w, "Failed to track uninstall", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeInstallStats(r.Context(), w, serverID, "Uninstall tracked successfully")
+}
+
+// HandleHeartbeat handles POST /v0/servers/:id/heartbeat, which installed clients call
+// periodically to stay counted in active_installs
+func (h *RatingsHandler) HandleHeartbeat(w http.ResponseWriter, r *http.Request) {
+	if !utils.RequireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	// Extract server ID from path: /v0/servers/{id}/heartbeat
+	path := strings.TrimPrefix(r.URL.Path, "/v0/servers/")
+	serverID := strings.TrimSuffix(path, "/heartbeat")
+	if serverID == "" || serverID == path {
+		utils.WriteJSONError(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	var req HeartbeatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSONError(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.UserID == "" {
+		utils.WriteJSONError(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.db.RecordHeartbeat(r.Context(), serverID, req.UserID, req.Version, req.Platform); err != nil {
+		if errors.Is(err, db.ErrInstallationNotFound) {
+			utils.WriteJSONError(w, "Installation not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to record heartbeat: %v", err)
+		utils.WriteJSONError(w, "Failed to record heartbeat", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeInstallStats responds with a server's install counts after a change, or with
+// success alone if the stats cannot be read
+func (h *RatingsHandler) writeInstallStats(ctx context.Context, w http.ResponseWriter, serverID, message string) {
+	response := UninstallResponse{
+		Success: true,
+		Message: message,
+	}
+
+	stats, err := h.db.GetServerStats(ctx, serverID)
+	if err != nil {
+		log.Printf("Failed to get stats: %v", err)
+	} else {
+		response.Stats = &InstallCounts{
+			ServerID:          serverID,
+			InstallationCount: stats.InstallationCount,
+			TotalInstalls:     stats.InstallationCount,
+			ActiveInstalls:    stats.ActiveInstalls,
+		}
+	}
+
+	if err := utils.WriteJSON(w, http.StatusOK, response); err != nil {
+		log.Printf("Error encoding install response: %v", err)
+	}
+}
+
 // HandleStats handles GET /v0/servers/:id/stats
 func (h *RatingsHandler) HandleStats(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -218,7 +528,7 @@ func (h *RatingsHandler) HandleStats(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get stats from database
-	rating, ratingCount, installCount, err := h.db.GetServerStats(r.Context(), serverID)
+	stats, err := h.db.GetServerStats(r.Context(), serverID)
 	if err != nil {
 		log.Printf("Failed to get stats for %s: %v", serverID, err)
 		http.Error(w, "Failed to get stats", http.StatusInternalServerError)
@@ -228,9 +538,14 @@ func (h *RatingsHandler) HandleStats(w http.ResponseWriter, r *http.Request) {
 	// Return stats
 	var response StatsResponse
 	response.Stats.ServerID = serverID
-	response.Stats.Rating = rating
-	response.Stats.RatingCount = ratingCount
-	response.Stats.InstallationCount = installCount
+	response.Stats.Rating = stats.Rating
+	response.Stats.RatingCount = stats.RatingCount
+	response.Stats.InstallationCount = stats.InstallationCount
+	response.Stats.TotalInstalls = stats.InstallationCount
+	response.Stats.ActiveInstalls = stats.ActiveInstalls
+	response.Stats.Retention = stats.Retention
+	response.Stats.WeightedRating = stats.WeightedRating
+	response.Stats.RatingDistribution = stats.Distribution
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
@@ -238,6 +553,100 @@ func (h *RatingsHandler) HandleStats(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// HandleStatsTimeseries handles GET /v0/servers/:id/stats/timeseries
+// Query parameters: metric (installs, uninstalls, ratings), interval (day, week, month) and days
+func (h *RatingsHandler) HandleStatsTimeseries(w http.ResponseWriter, r *http.Request) {
+	if !utils.RequireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	// Extract server ID from path: /v0/servers/{id}/stats/timeseries
+	path := strings.TrimPrefix(r.URL.Path, "/v0/servers/")
+	serverID := strings.TrimSuffix(path, "/stats/timeseries")
+	if serverID == "" || serverID == path {
+		utils.WriteJSONError(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	metric := query.Get("metric")
+	if metric == "" {
+		metric = "installs"
+	}
+	interval := query.Get("interval")
+	if interval == "" {
+		interval = "day"
+	}
+
+	days, ok := defaultTimeseriesDays[interval]
+	if !ok {
+		utils.WriteJSONError(w, "interval must be one of day, week, month", http.StatusBadRequest)
+		return
+	}
+	if d := query.Get("days"); d != "" {
+		parsed, err := strconv.Atoi(d)
+		if err != nil || parsed < 1 || parsed > maxTimeseriesDays {
+			utils.WriteJSONError(w, fmt.Sprintf("days must be between 1 and %d", maxTimeseriesDays), http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+
+	since := time.Now().AddDate(0, 0, -(days - 1))
+	points, err := h.db.GetTimeseries(r.Context(), serverID, metric, interval, since)
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidTimeseries) {
+			utils.WriteJSONError(w, "metric must be one of installs, uninstalls, ratings", http.StatusBadRequest)
+			return
+		}
+		log.Printf("Failed to get %s timeseries for %s: %v", metric, serverID, err)
+		utils.WriteJSONError(w, "Failed to get timeseries", http.StatusInternalServerError)
+		return
+	}
+
+	if err := utils.WriteJSON(w, http.StatusOK, TimeseriesResponse{
+		ServerID: serverID,
+		Metric:   metric,
+		Interval: interval,
+		Points:   points,
+	}); err != nil {
+		log.Printf("Error encoding timeseries response: %v", err)
+	}
+}
+
+// HandleStatsBreakdown handles GET /v0/servers/:id/stats/breakdown
+// Query parameters: active (count only active installations) and limit (values per dimension)
+func (h *RatingsHandler) HandleStatsBreakdown(w http.ResponseWriter, r *http.Request) {
+	if !utils.RequireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	// Extract server ID from path: /v0/servers/{id}/stats/breakdown
+	path := strings.TrimPrefix(r.URL.Path, "/v0/servers/")
+	serverID := strings.TrimSuffix(path, "/stats/breakdown")
+	if serverID == "" || serverID == path {
+		utils.WriteJSONError(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	activeOnly := query.Get("active") == "true"
+	limit := utils.ParseIntParam(query, "limit", defaultBreakdownLimit, maxBreakdownLimit)
+
+	breakdown, err := h.db.GetInstallBreakdown(r.Context(), serverID, activeOnly, limit)
+	if err != nil {
+		log.Printf("Failed to get install breakdown for %s: %v", serverID, err)
+		utils.WriteJSONError(w, "Failed to get install breakdown", http.StatusInternalServerError)
+		return
+	}
+
+	response := BreakdownResponse{serverID, activeOnly, breakdown}
+
+	if err := utils.WriteJSON(w, http.StatusOK, response); err != nil {
+		log.Printf("Error encoding breakdown response: %v", err)
+	}
+}
+
 // HandleGetReviews handles GET /v0/servers/:id/reviews
 func (h *RatingsHandler) HandleGetReviews(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -285,27 +694,68 @@ func (h *RatingsHandler) HandleGetReviews(w http.ResponseWriter, r *http.Request
 		reviews = []db.Review{}
 	}
 
+	h.applyProfiles(r.Context(), reviews)
+
 	// Return reviews
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(map[string]interface{}{
-		"reviews": reviews,
+	if err := json.NewEncoder(w).Encode(ReviewsResponse{
+		Reviews: reviews,
 	}); err != nil {
 		log.Printf("Error encoding reviews response: %v", err)
 	}
 }
 
+// applyProfiles fills reviewer names and avatars for a page of reviews with one provider
+// lookup. Profiles are optional: lookup failures are logged and the reviews left as is.
+func (h *RatingsHandler) applyProfiles(ctx context.Context, reviews []db.Review) {
+	if h.profiles == nil || len(reviews) == 0 {
+		return
+	}
+
+	userIDs := make([]string, len(reviews))
+	for i, review := range reviews {
+		userIDs[i] = review.UserID
+	}
+
+	found, err := h.profiles.GetProfiles(ctx, userIDs)
+	if err != nil {
+		log.Printf("Failed to look up reviewer profiles: %v", err)
+	}
+
+	for i := range reviews {
+		profile, ok := found[reviews[i].UserID]
+		if !ok {
+			continue
+		}
+		profile = profile.Public()
+		reviews[i].Username = profile.Username
+		reviews[i].UserAvatar = profile.AvatarURL
+	}
+}
+
 // FeedbackItem represents a feedback item in the format expected by the frontend
 type FeedbackItem struct {
-	ID           string `json:"id"`
-	ServerID     string `json:"server_id"`
-	Source       string `json:"source"`
-	UserID       string `json:"user_id"`
-	Username     string `json:"username,omitempty"`
-	UserAvatar   string `json:"user_avatar,omitempty"`
-	Rating       int    `json:"rating"`
-	Comment      string `json:"comment,omitempty"`
-	CreatedAt    string `json:"created_at"`
-	UpdatedAt    string `json:"updated_at"`
+	ID             string `json:"id"`
+	ServerID       string `json:"server_id"`
+	Source         string `json:"source"`
+	UserID         string `json:"user_id"`
+	Username       string `json:"username,omitempty"`
+	UserAvatar     string `json:"user_avatar,omitempty"`
+	Rating         int    `json:"rating"`
+	Comment        string `json:"comment,omitempty"`
+	HelpfulCount   int    `json:"helpful_count"`
+	UnhelpfulCount int    `json:"unhelpful_count"`
+	CreatedAt      string `json:"created_at"`
+	UpdatedAt      string `json:"updated_at"`
+
+	Response *db.ReviewResponse `json:"response,omitempty"`
+}
+
+// FeedbackResponse is a page of a server's reviews
+type FeedbackResponse struct {
+	Feedback   []FeedbackItem `json:"feedback"`
+	TotalCount int            `json:"total_count"`
+	HasMore    bool           `json:"has_more"`
 }
 
 // HandleGetFeedback handles GET /v0/servers/:id/feedback with pagination
@@ -377,20 +827,25 @@ func (h *RatingsHandler) HandleGetFeedback(w http.ResponseWriter, r *http.Reques
 		reviews = []db.Review{}
 	}
 
+	h.applyProfiles(r.Context(), reviews)
+
 	// Transform reviews to feedback items
 	feedbackItems := make([]FeedbackItem, len(reviews))
 	for i, review := range reviews {
 		feedbackItems[i] = FeedbackItem{
-			ID:         review.UUID,
-			ServerID:   review.ServerExternalID,
-			Source:     review.ServerSource,
-			UserID:     review.UserID,
-			Username:   "", // TODO: Fetch from user service if needed
-			UserAvatar: "", // TODO: Fetch from user service if needed
-			Rating:     review.Rating,
-			Comment:    review.Comment,
-			CreatedAt:  review.CreatedAt.Format("2006-01-02T15:04:05.999999Z07:00"),
-			UpdatedAt:  review.UpdatedAt.Format("2006-01-02T15:04:05.999999Z07:00"),
+			ID:             review.UUID,
+			ServerID:       review.ServerExternalID,
+			Source:         review.ServerSource,
+			UserID:         review.UserID,
+			Username:       review.Username,
+			UserAvatar:     review.UserAvatar,
+			Rating:         review.Rating,
+			Comment:        review.Comment,
+			HelpfulCount:   review.HelpfulCount,
+			UnhelpfulCount: review.UnhelpfulCount,
+			CreatedAt:      review.CreatedAt.Format("2006-01-02T15:04:05.999999Z07:00"),
+			UpdatedAt:      review.UpdatedAt.Format("2006-01-02T15:04:05.999999Z07:00"),
+			Response:       review.Response,
 		}
 	}
 
@@ -398,15 +853,30 @@ func (h *RatingsHandler) HandleGetFeedback(w http.ResponseWriter, r *http.Reques
 	hasMore := offset+len(reviews) < totalCount
 
 	// Return feedback response
-	if err := json.NewEncoder(w).Encode(map[string]interface{}{
-		"feedback":    feedbackItems,
-		"total_count": totalCount,
-		"has_more":    hasMore,
+	if err := json.NewEncoder(w).Encode(FeedbackResponse{
+		Feedback:   feedbackItems,
+		TotalCount: totalCount,
+		HasMore:    hasMore,
 	}); err != nil {
 		log.Printf("Error encoding feedback response: %v", err)
 	}
 }
 
+// UserRatingLookup tells whether a user has rated a server, with their rating if so
+type UserRatingLookup struct {
+	HasRated bool            `json:"has_rated"`
+	Feedback *UserRatingItem `json:"feedback,omitempty"`
+}
+
+// UserRatingItem is a user's rating of a server, whatever its moderation status
+type UserRatingItem struct {
+	ID        string `json:"id"`
+	Rating    int    `json:"rating"`
+	Comment   string `json:"comment"`
+	Status    string `json:"status"`
+	CreatedAt string `json:"created_at"`
+}
+
 // HandleGetUserRating handles GET /v0/servers/:id/rating/:userId
 func (h *RatingsHandler) HandleGetUserRating(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -432,24 +902,25 @@ func (h *RatingsHandler) HandleGetUserRating(w http.ResponseWriter, r *http.Requ
 	// Get user's rating from database
 	ctx := r.Context()
 	var rating int
-	var comment string
+	var comment, status string
 	var createdAt time.Time
 
+	// Reviews are stored under the hashed user ID
 	query := `
-		SELECT rating, comment, created_at
+		SELECT rating, comment, created_at, status
 		FROM proxy_user_ratings
-		WHERE server_id = $1 AND user_id = $2
+		WHERE server_id = $1 AND user_id = ANY($2)
 		LIMIT 1
 	`
 
-	err := h.db.QueryRowContext(ctx, query, serverID, userID).Scan(&rating, &comment, &createdAt)
+	err := h.db.QueryRowContext(ctx, query, serverID, pq.Array(h.db.UserIDCandidates(userID))).Scan(&rating, &comment, &createdAt, &status)
 	if err != nil {
 		if err.Error() == "sql: no rows in result set" {
 			// User hasn't rated yet
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusNotFound)
-			if err := json.NewEncoder(w).Encode(map[string]interface{}{
-				"has_rated": false,
+			if err := json.NewEncoder(w).Encode(UserRatingLookup{
+				HasRated: false,
 			}); err != nil {
 				log.Printf("Error encoding response: %v", err)
 			}
@@ -463,15 +934,269 @@ func (h *RatingsHandler) HandleGetUserRating(w http.ResponseWriter, r *http.Requ
 
 	// Return user's rating
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(map[string]interface{}{
-		"has_rated": true,
-		"feedback": map[string]interface{}{
-			"id":         fmt.Sprintf("%s:%s", serverID, userID),
-			"rating":     rating,
-			"comment":    comment,
-			"created_at": createdAt.Format(time.RFC3339),
+	if err := json.NewEncoder(w).Encode(UserRatingLookup{
+		HasRated: true,
+		Feedback: &UserRatingItem{
+			ID:        fmt.Sprintf("%s:%s", serverID, userID),
+			Rating:    rating,
+			Comment:   comment,
+			Status:    status,
+			CreatedAt: createdAt.Format(time.RFC3339),
 		},
 	}); err != nil {
 		log.Printf("Error encoding response: %v", err)
 	}
 }
+
+// HandleReportReview handles POST /v0/servers/:id/reviews/:userId/report
+// Anyone may report a visible review; each client IP counts once per review and is rate
+// limited. Services holding the API key may name the reporter instead.
+func (h *RatingsHandler) HandleReportReview(w http.ResponseWriter, r *http.Request) {
+	if !utils.RequireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	// Extract server ID and review author from path: /v0/servers/{id}/reviews/{userId}/report
+	path := strings.TrimPrefix(r.URL.Path, "/v0/servers/")
+	parts := strings.Split(path, "/")
+	if len(parts) < 4 || parts[len(parts)-1] != "report" || parts[len(parts)-3] != "reviews" {
+		utils.WriteJSONError(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	// Server ID could contain slashes (e.g., io.github.user/repo)
+	serverID := strings.Join(parts[:len(parts)-3], "/")
+	userID := parts[len(parts)-2]
+	if serverID == "" || userID == "" {
+		utils.WriteJSONError(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	// Anonymous reports count once per client IP and are rate limited; only services
+	// holding the API key may report on behalf of a user
+	authenticated := middleware.HasAPIKey(r)
+	clientIP := middleware.ClientIP(r)
+	if !authenticated && !h.reportLimiter.Allow(clientIP) {
+		utils.WriteJSONError(w, "Too many reports, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	var req ReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if len(req.Reason) > maxReportReasonLength {
+		utils.WriteJSONError(w, fmt.Sprintf("Reason must be at most %d characters", maxReportReasonLength), http.StatusBadRequest)
+		return
+	}
+
+	reporterID := "ip:" + clientIP
+	if id := strings.TrimSpace(req.ReporterID); authenticated && id != "" {
+		reporterID = id
+	}
+	if reporterID == userID {
+		utils.WriteJSONError(w, "Cannot report your own review", http.StatusBadRequest)
+		return
+	}
+
+	status, err := h.db.ReportReview(r.Context(), serverID, userID, reporterID, req.Reason, h.reportThreshold)
+	if err != nil {
+		if errors.Is(err, db.ErrReviewNotFound) {
+			utils.WriteJSONError(w, "Review not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, db.ErrSelfReport) {
+			utils.WriteJSONError(w, "Cannot report your own review", http.StatusBadRequest)
+			return
+		}
+		log.Printf("Failed to report review %s:%s: %v", serverID, userID, err)
+		utils.WriteJSONError(w, "Failed to report review", http.StatusInternalServerError)
+		return
+	}
+
+	// A review held for moderation no longer counts towards the rating
+	if status != db.ReviewStatusVisible && h.cache != nil {
+		h.cache.Clear()
+	}
+
+	if err := utils.WriteJSON(w, http.StatusAccepted, MessageResponse{
+		Success: true,
+		Message: "Report received",
+	}); err != nil {
+		log.Printf("Error encoding report response: %v", err)
+	}
+}
+
+// HandleVoteReview handles POST and DELETE /v0/servers/:id/reviews/:userId/vote
+// POST records or changes the voter's helpful/unhelpful vote; DELETE withdraws it
+func (h *RatingsHandler) HandleVoteReview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		utils.WriteJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract server ID and review author from path: /v0/servers/{id}/reviews/{userId}/vote
+	path := strings.TrimPrefix(r.URL.Path, "/v0/servers/")
+	parts := strings.Split(path, "/")
+	if len(parts) < 4 || parts[len(parts)-1] != "vote" || parts[len(parts)-3] != "reviews" {
+		utils.WriteJSONError(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	// Server ID could contain slashes (e.g., io.github.user/repo)
+	serverID := strings.Join(parts[:len(parts)-3], "/")
+	userID := parts[len(parts)-2]
+	if serverID == "" || userID == "" {
+		utils.WriteJSONError(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	var req VoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	req.VoterID = strings.TrimSpace(req.VoterID)
+	if req.VoterID == "" {
+		utils.WriteJSONError(w, "voter_id is required", http.StatusBadRequest)
+		return
+	}
+	var counts db.VoteCounts
+	var err error
+	if r.Method == http.MethodDelete {
+		counts, err = h.db.RemoveReviewVote(r.Context(), serverID, userID, req.VoterID)
+	} else {
+		if req.Helpful == nil {
+			utils.WriteJSONError(w, "helpful is required", http.StatusBadRequest)
+			return
+		}
+		counts, err = h.db.VoteReview(r.Context(), serverID, userID, req.VoterID, *req.Helpful)
+	}
+	if err != nil {
+		if errors.Is(err, db.ErrReviewNotFound) {
+			utils.WriteJSONError(w, "Review not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, db.ErrSelfVote) {
+			utils.WriteJSONError(w, "Cannot vote on your own review", http.StatusBadRequest)
+			return
+		}
+		log.Printf("Failed to vote on review %s:%s: %v", serverID, userID, err)
+		utils.WriteJSONError(w, "Failed to record vote", http.StatusInternalServerError)
+		return
+	}
+
+	if err := utils.WriteJSON(w, http.StatusOK, VoteResponse{
+		Success:        true,
+		HelpfulCount:   counts.Helpful,
+		UnhelpfulCount: counts.Unhelpful,
+	}); err != nil {
+		log.Printf("Error encoding vote response: %v", err)
+	}
+}
+
+// HandleReviewResponse handles PUT and DELETE /v0/servers/:id/reviews/:userId/response
+// Only the server's publisher may respond: the X-GitHub-Token header must belong to the
+// GitHub user or organization owning the server's io.github.<owner>/ namespace
+func (h *RatingsHandler) HandleReviewResponse(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		utils.WriteJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract server ID and review author from path: /v0/servers/{id}/reviews/{userId}/response
+	path := strings.TrimPrefix(r.URL.Path, "/v0/servers/")
+	parts := strings.Split(path, "/")
+	if len(parts) < 4 || parts[len(parts)-1] != "response" || parts[len(parts)-3] != "reviews" {
+		utils.WriteJSONError(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	// Server ID could contain slashes (e.g., io.github.user/repo)
+	serverID := strings.Join(parts[:len(parts)-3], "/")
+	userID := parts[len(parts)-2]
+	if serverID == "" || userID == "" {
+		utils.WriteJSONError(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	if publisher.NamespaceOwner(serverID) == "" {
+		utils.WriteJSONError(w, "Responses are only supported for io.github.<owner>/ servers", http.StatusForbidden)
+		return
+	}
+
+	token := strings.TrimSpace(r.Header.Get("X-GitHub-Token"))
+	if token == "" {
+		utils.WriteJSONError(w, "X-GitHub-Token header is required", http.StatusUnauthorized)
+		return
+	}
+
+	var body string
+	if r.Method == http.MethodPut {
+		var req ResponseRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		body = strings.TrimSpace(req.Body)
+		if body == "" {
+			utils.WriteJSONError(w, "body is required", http.StatusBadRequest)
+			return
+		}
+		if len([]rune(body)) > maxResponseLength {
+			utils.WriteJSONError(w, fmt.Sprintf("body must be at most %d characters", maxResponseLength), http.StatusBadRequest)
+			return
+		}
+	}
+
+	identity, err := h.publishers.Verify(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, publisher.ErrInvalidToken) {
+			utils.WriteJSONError(w, "Invalid GitHub token", http.StatusUnauthorized)
+			return
+		}
+		log.Printf("Failed to verify publisher for %s: %v", serverID, err)
+		utils.WriteJSONError(w, "Failed to verify publisher", http.StatusBadGateway)
+		return
+	}
+	if !identity.Owns(serverID) {
+		utils.WriteJSONError(w, "Only the server's publisher may respond to reviews", http.StatusForbidden)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		if err := h.db.DeleteReviewResponse(r.Context(), serverID, userID, identity.Login); err != nil {
+			if errors.Is(err, db.ErrResponseNotFound) {
+				utils.WriteJSONError(w, "Response not found", http.StatusNotFound)
+				return
+			}
+			log.Printf("Failed to delete response to review %s:%s: %v", serverID, userID, err)
+			utils.WriteJSONError(w, "Failed to delete response", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	resp, err := h.db.UpsertReviewResponse(r.Context(), serverID, userID, identity.Login, body)
+	if err != nil {
+		if errors.Is(err, db.ErrReviewNotFound) {
+			utils.WriteJSONError(w, "Review not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to save response to review %s:%s: %v", serverID, userID, err)
+		utils.WriteJSONError(w, "Failed to save response", http.StatusInternalServerError)
+		return
+	}
+
+	if err := utils.WriteJSON(w, http.StatusOK, ReviewResponseResult{
+		Success:  true,
+		Response: resp,
+	}); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/veriteknik/registry-proxy/internal/db"
+	"github.com/veriteknik/registry-proxy/internal/middleware"
+	"github.com/veriteknik/registry-proxy/internal/profiles"
+)
+
+// staticProfiles returns fixed profiles and counts lookups
+type staticProfiles struct {
+	profiles map[string]profiles.Profile
+	lookups  int
+}
+
+func (s *staticProfiles) GetProfiles(_ context.Context, _ []string) (map[string]profiles.Profile, error) {
+	s.lookups++
+	return s.profiles, nil
+}
+
+func TestApplyProfiles(t *testing.T) {
+	provider := &staticProfiles{profiles: map[string]profiles.Profile{
+		"alice": {UserID: "alice", Username: "Alice", AvatarURL: "https://example.test/alice.png"},
+		"bob":   {UserID: "bob", Username: "Bob", AvatarURL: "https://example.test/bob.png", OptedOut: true},
+	}}
+	handler := &RatingsHandler{profiles: provider}
+
+	reviews := []db.Review{{UserID: "alice"}, {UserID: "bob"}, {UserID: "carol"}}
+	handler.applyProfiles(context.Background(), reviews)
+
+	if provider.lookups != 1 {
+		t.Errorf("Expected one batched lookup, got %d", provider.lookups)
+	}
+	if reviews[0].Username != "Alice" || reviews[0].UserAvatar != "https://example.test/alice.png" {
+		t.Errorf("Unexpected profile for alice: %+v", reviews[0])
+	}
+	if reviews[1].Username != profiles.AnonymousName || reviews[1].UserAvatar != "" {
+		t.Errorf("Expected bob to be anonymized, got %+v", reviews[1])
+	}
+	if reviews[2].Username != "" {
+		t.Errorf("Expected no profile for carol, got %+v", reviews[2])
+	}
+}
+
+// reportHandler returns a handler whose database expects one report by reporter
+func reportHandler(t *testing.T, limiter *middleware.RateLimiter, reporter string) *RatingsHandler {
+	t.Helper()
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		conn.Close()
+	})
+
+	if reporter != "" {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT status FROM proxy_user_ratings`).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(db.ReviewStatusVisible))
+		mock.ExpectExec(`INSERT INTO proxy_review_reports`).WithArgs("server-a", "alice", reporter, "spam").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`SELECT COUNT`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectCommit()
+	}
+	return &RatingsHandler{db: &db.DB{DB: conn}, reportThreshold: 3, reportLimiter: limiter}
+}
+
+func postReport(h *RatingsHandler, apiKey string) int {
+	r := httptest.NewRequest(http.MethodPost, "/v0/servers/server-a/reviews/alice/report",
+		strings.NewReader(`{"reporter_id": "bob", "reason": "spam"}`))
+	r.RemoteAddr = "192.0.2.1:1234"
+	if apiKey != "" {
+		r.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	w := httptest.NewRecorder()
+	h.HandleReportReview(w, r)
+	return w.Code
+}
+
+func TestHandleReportReview_Reporter(t *testing.T) {
+	t.Setenv("API_KEY", "service-key")
+
+	// Anonymous callers cannot claim a reporter ID, so one client cannot file many reports
+	if code := postReport(reportHandler(t, nil, "ip:192.0.2.1"), ""); code != http.StatusAccepted {
+		t.Errorf("anonymous report: status = %d, want %d", code, http.StatusAccepted)
+	}
+	if code := postReport(reportHandler(t, nil, "ip:192.0.2.1"), "wrong-key"); code != http.StatusAccepted {
+		t.Errorf("report with a wrong API key: status = %d, want %d", code, http.StatusAccepted)
+	}
+	if code := postReport(reportHandler(t, nil, "bob"), "service-key"); code != http.StatusAccepted {
+		t.Errorf("report by a service: status = %d, want %d", code, http.StatusAccepted)
+	}
+}
+
+func TestHandleReportReview_RateLimit(t *testing.T) {
+	t.Setenv("API_KEY", "service-key")
+
+	h := reportHandler(t, middleware.NewRateLimiter(1, time.Hour), "ip:192.0.2.1")
+	if code := postReport(h, ""); code != http.StatusAccepted {
+		t.Fatalf("first report: status = %d, want %d", code, http.StatusAccepted)
+	}
+	if code := postReport(h, ""); code != http.StatusTooManyRequests {
+		t.Errorf("second report: status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+
+	// Services reporting for their users are not limited by their IP
+	h = reportHandler(t, middleware.NewRateLimiter(1, time.Hour), "bob")
+	h.reportLimiter.Allow("192.0.2.1")
+	if code := postReport(h, "service-key"); code != http.StatusAccepted {
+		t.Errorf("report by a service: status = %d, want %d", code, http.StatusAccepted)
+	}
+}
+
+func TestNewRatingsHandler_UnreadableWordList(t *testing.T) {
+	t.Setenv("REVIEW_BLOCKED_WORDS_FILE", t.TempDir()+"/missing.txt")
+
+	if _, err := NewRatingsHandler(nil, nil); err == nil {
+		t.Error("Expected an error for an unreadable blocked word list")
+	}
+}
+
+func TestNewRatingsHandler_UnknownProfileProvider(t *testing.T) {
+	t.Setenv("PROFILE_PROVIDER", "ldap")
+
+	if _, err := NewRatingsHandler(&db.DB{}, nil); err == nil {
+		t.Error("Expected an error for an unknown profile provider")
+	}
+}
+
+func TestHandleVoteReview_OwnReview(t *testing.T) {
+	h := &RatingsHandler{db: &db.DB{}}
+
+	r := httptest.NewRequest(http.MethodPost, "/v0/servers/server-a/reviews/alice/vote",
+		strings.NewReader(`{"voter_id": "alice", "helpful": true}`))
+	w := httptest.NewRecorder()
+	h.HandleVoteReview(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/veriteknik/registry-proxy/internal/db"
+	"github.com/veriteknik/registry-proxy/internal/models"
+	"github.com/veriteknik/registry-proxy/internal/utils"
+)
+
+// RelatedServerResponse is a server listed by GET /v0/servers/:id/related
+type RelatedServerResponse struct {
+	models.EnrichedServer
+	Score      float64  `json:"score"`
+	CoInstalls int      `json:"co_installs"` // Users who installed both servers
+	Reasons    []string `json:"reasons"`
+}
+
+// RelatedServersResponse lists the servers related to a server
+type RelatedServersResponse struct {
+	ServerID string                  `json:"server_id"`
+	Related  []RelatedServerResponse `json:"related"`
+	Count    int                     `json:"count"`
+}
+
+// HandleRelated handles GET /v0/servers/:id/related
+// Returns the servers most similar to a server, as precomputed by the related scorer
+func (h *ServersHandler) HandleRelated(w http.ResponseWriter, r *http.Request) {
+	if !utils.RequireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	// Extract server ID from path: /v0/servers/{id}/related
+	path := strings.TrimPrefix(r.URL.Path, "/v0/servers/")
+	serverID := strings.TrimSuffix(path, "/related")
+	if serverID == "" || serverID == path {
+		utils.WriteJSONError(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+	limit := utils.ParseIntParam(r.URL.Query(), "limit", 10, 50)
+
+	ctx := r.Context()
+	related, err := h.registryDB.GetRelatedServers(ctx, serverID, limit)
+	if err != nil {
+		log.Printf("Error getting related servers: %v", err)
+		utils.WriteJSONError(w, "Failed to get related servers", http.StatusInternalServerError)
+		return
+	}
+
+	ids := make([]string, len(related))
+	for i, rel := range related {
+		ids[i] = rel.RelatedID
+	}
+	servers, err := h.getEnrichedServersByID(ctx, ids)
+	if err != nil {
+		log.Printf("Error enriching related servers: %v", err)
+		utils.WriteJSONError(w, "Failed to get related servers", http.StatusInternalServerError)
+		return
+	}
+
+	response := relatedResponses(related, servers)
+	if err := utils.WriteJSON(w, http.StatusOK, RelatedServersResponse{
+		ServerID: serverID,
+		Related:  response,
+		Count:    len(response),
+	}); err != nil {
+		log.Printf("Error encoding related response: %v", err)
+	}
+}
+
+// relatedResponses pairs related servers with their enriched data, best first, leaving out
+// servers deleted since the scores were computed
+func relatedResponses(related []db.RelatedServer, servers map[string]models.EnrichedServer) []RelatedServerResponse {
+	response := make([]RelatedServerResponse, 0, len(related))
+	for _, rel := range related {
+		server, ok := servers[rel.RelatedID]
+		if !ok {
+			continue
+		}
+		response = append(response, RelatedServerResponse{
+			EnrichedServer: server,
+			Score:          rel.Score,
+			CoInstalls:     rel.CoInstalls,
+			Reasons:        rel.Reasons,
+		})
+	}
+	return response
+}
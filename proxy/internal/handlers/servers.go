@@ -15,6 +15,7 @@ import (
 	"github.com/veriteknik/registry-proxy/internal/client"
 	"github.com/veriteknik/registry-proxy/internal/db"
 	"github.com/veriteknik/registry-proxy/internal/models"
+	"github.com/veriteknik/registry-proxy/internal/utils"
 )
 
 // ServersHandler handles enriched server list requests
@@ -152,6 +153,27 @@ func (h *ServersHandler) getEnrichedServers(ctx context.Context) ([]models.Enric
 	return servers, nil
 }
 
+// getEnrichedServersByID fetches the latest version of the given servers with their stats,
+// keyed by server ID; servers that do not exist or were deleted are left out
+func (h *ServersHandler) getEnrichedServersByID(ctx context.Context, ids []string) (map[string]models.EnrichedServer, error) {
+	servers := make(map[string]models.EnrichedServer, len(ids))
+	if len(ids) == 0 {
+		return servers, nil
+	}
+
+	serverMaps, _, err := h.registryDB.QueryServersEnhanced(ctx, db.ServerFilter{ServerIDs: ids}, "", len(ids), 0)
+	if err != nil {
+		return nil, fmt.Errorf("fetching servers from database: %w", err)
+	}
+
+	for _, serverMap := range serverMaps {
+		enriched := h.convertMapToEnrichedServer(serverMap)
+		servers[enriched.ID] = enriched
+	}
+
+	return servers, nil
+}
+
 // convertMapToEnrichedServer converts a database map to EnrichedServer
 func (h *ServersHandler) convertMapToEnrichedServer(serverMap map[string]interface{}) models.EnrichedServer {
 	enriched := models.EnrichedServer{}
@@ -166,6 +188,21 @@ func (h *ServersHandler) convertMapToEnrichedServer(serverMap map[string]interfa
 		enriched.Description = desc
 	}
 
+	// Extract managed category and tags
+	if category, ok := serverMap["category"].(string); ok {
+		enriched.Category = category
+	}
+	switch tags := serverMap["tags"].(type) {
+	case []string:
+		enriched.Tags = tags
+	case []interface{}:
+		for _, tag := range tags {
+			if s, ok := tag.(string); ok {
+				enriched.Tags = append(enriched.Tags, s)
+			}
+		}
+	}
+
 	// Extract repository
 	if repo, ok := serverMap["repository"].(map[string]interface{}); ok {
 		enriched.Repository = models.Repository{
@@ -213,6 +250,15 @@ func (h *ServersHandler) convertMapToEnrichedServer(serverMap map[string]interfa
 		if installCount, ok := stats["install_count"].(int); ok {
 			enriched.InstallationCount = installCount
 		}
+		if weightedRating, ok := stats["weighted_rating"].(float64); ok {
+			enriched.WeightedRating = weightedRating
+		}
+		if activeInstalls, ok := stats["active_installs"].(int); ok {
+			enriched.ActiveInstalls = activeInstalls
+		}
+		if distribution, ok := stats["rating_distribution"].(models.RatingDistribution); ok {
+			enriched.RatingDistribution = &distribution
+		}
 	}
 
 	return enriched
@@ -437,26 +483,22 @@ func (h *ServersHandler) HandleDetail(w http.ResponseWriter, r *http.Request) {
 		SELECT
 			s.server_name,
 			s.value,
-			COALESCE(ss.rating, 0) as rating,
-			COALESCE(ss.rating_count, 0) as rating_count,
-			COALESCE(ss.installation_count, 0) as installation_count
+			` + db.ServerStatsColumns("ss") + `
 		FROM servers s
 		LEFT JOIN proxy_server_stats ss ON s.server_name = ss.server_id
-		WHERE s.server_name = $1 AND s.is_latest = true
+		WHERE s.server_name = $1 AND s.is_latest = true AND s.status IS DISTINCT FROM 'deleted'
 		LIMIT 1
 	`
 
 	ctx := r.Context()
 	var (
-		serverName   string
-		valueJSON    []byte
-		rating       float64
-		ratingCount  int
-		installCount int
+		serverName string
+		valueJSON  []byte
+		stats      db.ServerStats
 	)
 
 	err := h.registryDB.QueryRowContext(ctx, query, serverID).Scan(
-		&serverName, &valueJSON, &rating, &ratingCount, &installCount,
+		append([]interface{}{&serverName, &valueJSON}, stats.ScanDest()...)...,
 	)
 	if err != nil {
 		if err.Error() == "sql: no rows in result set" {
@@ -479,11 +521,23 @@ func (h *ServersHandler) HandleDetail(w http.ResponseWriter, r *http.Request) {
 	// Add ID field
 	serverMap["id"] = serverName
 
+	// Attach the managed category and tags
+	taxonomies, err := h.registryDB.GetServerTaxonomies(ctx, []string{serverName})
+	if err != nil {
+		log.Printf("Error loading server taxonomy: %v", err)
+		http.Error(w, "Failed to fetch server", http.StatusInternalServerError)
+		return
+	}
+	db.ApplyServerTaxonomy(serverMap, taxonomies[serverName])
+
 	// Convert to EnrichedServer with proper field names (snake_case)
 	enriched := h.convertMapToEnrichedServer(serverMap)
-	enriched.Rating = rating
-	enriched.RatingCount = ratingCount
-	enriched.InstallationCount = installCount
+	enriched.Rating = stats.Rating
+	enriched.RatingCount = stats.RatingCount
+	enriched.InstallationCount = stats.InstallationCount
+	enriched.WeightedRating = stats.WeightedRating
+	enriched.ActiveInstalls = stats.ActiveInstalls
+	enriched.RatingDistribution = &stats.Distribution
 
 	// Return with proper JSON serialization using struct tags
 	w.Header().Set("Content-Type", "application/json")
@@ -492,7 +546,101 @@ func (h *ServersHandler) HandleDetail(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// HandleRefresh forces a cache refresh
+const (
+	// maxBatchServers caps the servers looked up by one batch request
+	maxBatchServers = 500
+
+	// maxBatchBodyBytes caps batch requests at maxBatchServers IDs of up to 255 bytes
+	maxBatchBodyBytes = 160 << 10
+)
+
+// BatchServersRequest lists the servers to look up
+type BatchServersRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// BatchServersResponse returns the servers found, in the order requested, and the IDs of
+// those that do not exist or were deleted
+type BatchServersResponse struct {
+	Servers []models.EnrichedServer `json:"servers"`
+	Missing []string                `json:"missing"`
+	Count   int                     `json:"count"`
+}
+
+// HandleBatch handles POST /v0/servers/batch
+// Looks up several servers in one request, as HandleDetail does for one
+func (h *ServersHandler) HandleBatch(w http.ResponseWriter, r *http.Request) {
+	if !utils.RequireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var req BatchServersRequest
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSONError(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// Look each server up once, keeping the requested order
+	ids := make([]string, 0, len(req.IDs))
+	seen := make(map[string]bool, len(req.IDs))
+	for _, id := range req.IDs {
+		if id == "" {
+			utils.WriteJSONError(w, "ids must not be empty strings", http.StatusBadRequest)
+			return
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		utils.WriteJSONError(w, "ids is required", http.StatusBadRequest)
+		return
+	}
+	if len(ids) > maxBatchServers {
+		utils.WriteJSONError(w, fmt.Sprintf("At most %d servers can be looked up at once", maxBatchServers), http.StatusBadRequest)
+		return
+	}
+
+	found, err := h.getEnrichedServersByID(r.Context(), ids)
+	if err != nil {
+		log.Printf("Error looking up servers: %v", err)
+		utils.WriteJSONError(w, "Failed to fetch servers", http.StatusInternalServerError)
+		return
+	}
+
+	if err := utils.WriteJSON(w, http.StatusOK, batchServersResponse(ids, found)); err != nil {
+		log.Printf("Error encoding batch response: %v", err)
+	}
+}
+
+// batchServersResponse splits the requested IDs into the servers found, in the order
+// requested, and the IDs missing from found
+func batchServersResponse(ids []string, found map[string]models.EnrichedServer) BatchServersResponse {
+	response := BatchServersResponse{
+		Servers: make([]models.EnrichedServer, 0, len(found)),
+		Missing: []string{},
+	}
+	for _, id := range ids {
+		if server, ok := found[id]; ok {
+			response.Servers = append(response.Servers, server)
+		} else {
+			response.Missing = append(response.Missing, id)
+		}
+	}
+	response.Count = len(response.Servers)
+	return response
+}
+
+// RefreshResponse reports a cache refresh
+type RefreshResponse struct {
+	Message   string    `json:"message"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// HandleRefresh handles POST /v0/cache/refresh
+// Forces a cache refresh
 func (h *ServersHandler) HandleRefresh(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -512,9 +660,9 @@ func (h *ServersHandler) HandleRefresh(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(map[string]interface{}{
-		"message":    "Cache refreshed successfully",
-		"updated_at": h.cache.GetLastUpdate(),
+	if err := json.NewEncoder(w).Encode(RefreshResponse{
+		Message:   "Cache refreshed successfully",
+		UpdatedAt: h.cache.GetLastUpdate(),
 	}); err != nil {
 		log.Printf("Error encoding refresh response: %v", err)
 	}
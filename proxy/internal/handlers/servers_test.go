@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"reflect"
 	"testing"
 
+	"github.com/veriteknik/registry-proxy/internal/db"
 	"github.com/veriteknik/registry-proxy/internal/models"
 )
 
@@ -274,3 +276,47 @@ func TestRemoteHeaderBackwardCompatibility(t *testing.T) {
 		t.Errorf("Expected empty value, got '%s'", header.Value)
 	}
 }
+
+// TestBatchServersResponse tests that found servers keep the requested order and the rest are reported missing
+func TestBatchServersResponse(t *testing.T) {
+	found := map[string]models.EnrichedServer{
+		"a": {Server: models.Server{ID: "a"}},
+		"c": {Server: models.Server{ID: "c"}},
+	}
+
+	response := batchServersResponse([]string{"c", "b", "a", "d"}, found)
+
+	if response.Count != 2 || len(response.Servers) != 2 || response.Servers[0].ID != "c" || response.Servers[1].ID != "a" {
+		t.Errorf("Expected servers c and a in request order, got %+v", response.Servers)
+	}
+	if !reflect.DeepEqual(response.Missing, []string{"b", "d"}) {
+		t.Errorf("Expected b and d missing, got %v", response.Missing)
+	}
+
+	empty := batchServersResponse([]string{"x"}, map[string]models.EnrichedServer{})
+	if empty.Servers == nil || empty.Count != 0 || !reflect.DeepEqual(empty.Missing, []string{"x"}) {
+		t.Errorf("Expected an empty server list and x missing, got %+v", empty)
+	}
+}
+
+// TestRelatedResponses tests that related servers keep their order and deleted servers are left out
+func TestRelatedResponses(t *testing.T) {
+	related := []db.RelatedServer{
+		{RelatedID: "b", Score: 0.8, Reasons: []string{db.ReasonSharedTags}},
+		{RelatedID: "gone", Score: 0.6},
+		{RelatedID: "c", Score: 0.4, CoInstalls: 3, Reasons: []string{db.ReasonCoInstalled}},
+	}
+	servers := map[string]models.EnrichedServer{
+		"b": {Server: models.Server{ID: "b"}},
+		"c": {Server: models.Server{ID: "c"}, InstallationCount: 12},
+	}
+
+	response := relatedResponses(related, servers)
+
+	if len(response) != 2 || response[0].ID != "b" || response[1].ID != "c" {
+		t.Fatalf("Expected b then c, got %+v", response)
+	}
+	if response[1].CoInstalls != 3 || response[1].InstallationCount != 12 || response[1].Score != 0.4 {
+		t.Errorf("Expected c with its stats and co-installs, got %+v", response[1])
+	}
+}
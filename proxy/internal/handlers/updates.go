@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/veriteknik/registry-proxy/internal/db"
+	"github.com/veriteknik/registry-proxy/internal/models"
+	"github.com/veriteknik/registry-proxy/internal/semver"
+	"github.com/veriteknik/registry-proxy/internal/utils"
+)
+
+const (
+	// maxUpdateChecks caps the installed versions checked in one request
+	maxUpdateChecks = 500
+
+	// maxUpdateCheckBodyBytes caps check-updates requests at a little over maxUpdateChecks pairs
+	maxUpdateCheckBodyBytes = 256 << 10
+)
+
+// CheckUpdatesRequest lists the installed server versions to check
+type CheckUpdatesRequest struct {
+	Servers []db.InstalledVersion `json:"servers"`
+}
+
+// UpdateCheckResult tells whether a newer version of an installed server is published
+type UpdateCheckResult struct {
+	ServerID         string     `json:"server_id"`
+	InstalledVersion string     `json:"installed_version"`
+	Found            bool       `json:"found"` // False when the server is not in the registry
+	LatestVersion    string     `json:"latest_version,omitempty"`
+	UpdateAvailable  bool       `json:"update_available"`
+	ReleaseDate      *time.Time `json:"release_date,omitempty"`
+	BreakingChanges  bool       `json:"breaking_changes"`
+	BreakingReasons  []string   `json:"breaking_reasons,omitempty"`
+}
+
+// CheckUpdatesResponse holds the result of each installed version checked
+type CheckUpdatesResponse struct {
+	Results          []UpdateCheckResult `json:"results"`
+	Count            int                 `json:"count"`
+	UpdatesAvailable int                 `json:"updates_available"`
+}
+
+// HandleCheckUpdates handles POST /v0/servers/check-updates
+// Compares installed versions with the latest published versions, flagging updates that
+// need new configuration or drop a transport the client may be using
+func (h *ServersHandler) HandleCheckUpdates(w http.ResponseWriter, r *http.Request) {
+	if !utils.RequireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var req CheckUpdatesRequest
+	r.Body = http.MaxBytesReader(w, r.Body, maxUpdateCheckBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSONError(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Servers) == 0 {
+		utils.WriteJSONError(w, "servers is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Servers) > maxUpdateChecks {
+		utils.WriteJSONError(w, fmt.Sprintf("At most %d servers can be checked at once", maxUpdateChecks), http.StatusBadRequest)
+		return
+	}
+	for _, s := range req.Servers {
+		if s.ServerID == "" {
+			utils.WriteJSONError(w, "server_id is required", http.StatusBadRequest)
+			return
+		}
+	}
+
+	pairs, err := h.registryDB.GetVersionPairs(r.Context(), req.Servers)
+	if err != nil {
+		log.Printf("Error checking updates: %v", err)
+		utils.WriteJSONError(w, "Failed to check updates", http.StatusInternalServerError)
+		return
+	}
+
+	results := make([]UpdateCheckResult, len(pairs))
+	available := 0
+	for i, pair := range pairs {
+		results[i] = h.checkUpdate(pair)
+		if results[i].UpdateAvailable {
+			available++
+		}
+	}
+
+	if err := utils.WriteJSON(w, http.StatusOK, CheckUpdatesResponse{
+		Results:          results,
+		Count:            len(results),
+		UpdatesAvailable: available,
+	}); err != nil {
+		log.Printf("Error encoding check-updates response: %v", err)
+	}
+}
+
+// checkUpdate compares an installed version with the latest version of its server
+func (h *ServersHandler) checkUpdate(pair db.VersionPair) UpdateCheckResult {
+	result := UpdateCheckResult{
+		ServerID:         pair.ServerID,
+		InstalledVersion: pair.Version,
+	}
+	if pair.LatestValue == nil {
+		return result
+	}
+
+	result.Found = true
+	result.LatestVersion = pair.LatestVersion
+	result.ReleaseDate = pair.LatestPublished
+	result.UpdateAvailable = semver.Newer(pair.LatestVersion, pair.Version)
+
+	// Breaking changes can only be detected when the installed version is still published
+	if !result.UpdateAvailable || pair.InstalledValue == nil {
+		return result
+	}
+	latest, err := h.parseServerValue(pair.LatestValue)
+	if err != nil {
+		log.Printf("Error parsing server %s: %v", pair.ServerID, err)
+		return result
+	}
+	installed, err := h.parseServerValue(pair.InstalledValue)
+	if err != nil {
+		log.Printf("Error parsing server %s version %s: %v", pair.ServerID, pair.Version, err)
+		return result
+	}
+
+	result.BreakingReasons = breakingChanges(installed, latest)
+	result.BreakingChanges = len(result.BreakingReasons) > 0
+	return result
+}
+
+// parseServerValue converts a server as stored in the registry to EnrichedServer
+func (h *ServersHandler) parseServerValue(value []byte) (models.EnrichedServer, error) {
+	var serverMap map[string]interface{}
+	if err := json.Unmarshal(value, &serverMap); err != nil {
+		return models.EnrichedServer{}, err
+	}
+	return h.convertMapToEnrichedServer(serverMap), nil
+}
+
+// breakingChanges lists what an upgrade from installed to latest requires of the user: new
+// required environment variables or arguments, or transports that are no longer offered
+func breakingChanges(installed, latest models.EnrichedServer) []string {
+	var reasons []string
+
+	installedEnv, latestEnv := requiredEnvVars(installed), requiredEnvVars(latest)
+	for _, name := range sortedKeys(latestEnv) {
+		if !installedEnv[name] {
+			reasons = append(reasons, "new required environment variable "+name)
+		}
+	}
+
+	installedArgs, latestArgs := requiredArguments(installed), requiredArguments(latest)
+	for _, name := range sortedKeys(latestArgs) {
+		if !installedArgs[name] {
+			reasons = append(reasons, "new required argument "+name)
+		}
+	}
+
+	installedTransports, latestTransports := transports(installed), transports(latest)
+	for _, transport := range sortedKeys(installedTransports) {
+		if !latestTransports[transport] {
+			reasons = append(reasons, "removed transport "+transport)
+		}
+	}
+
+	return reasons
+}
+
+// requiredEnvVars returns the names of the environment variables a server requires
+func requiredEnvVars(server models.EnrichedServer) map[string]bool {
+	names := make(map[string]bool)
+	for _, pkg := range server.Packages {
+		for _, ev := range pkg.EnvironmentVariables {
+			if ev.IsRequired {
+				names[ev.Name] = true
+			}
+		}
+	}
+	return names
+}
+
+// requiredArguments returns the required runtime and package arguments of a server, named
+// ones by name and positional ones by position among the required positional arguments
+func requiredArguments(server models.EnrichedServer) map[string]bool {
+	names := make(map[string]bool)
+	for _, pkg := range server.Packages {
+		for _, args := range [][]models.Argument{pkg.RuntimeArguments, pkg.PackageArguments} {
+			positional := 0
+			for _, arg := range args {
+				if !arg.IsRequired {
+					continue
+				}
+				if arg.Name != "" {
+					names[arg.Name] = true
+					continue
+				}
+				positional++
+				names[fmt.Sprintf("positional #%d", positional)] = true
+			}
+		}
+	}
+	return names
+}
+
+// transports returns the transports a server can be used over, from its packages and remotes
+func transports(server models.EnrichedServer) map[string]bool {
+	types := make(map[string]bool)
+	for _, pkg := range server.Packages {
+		if pkg.Transport != nil && pkg.Transport.Type != "" {
+			types[pkg.Transport.Type] = true
+		}
+	}
+	for _, remote := range server.Remotes {
+		if remote.TransportType != "" {
+			types[remote.TransportType] = true
+		}
+	}
+	return types
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
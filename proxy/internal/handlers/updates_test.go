@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/veriteknik/registry-proxy/internal/db"
+)
+
+// TestCheckUpdate_BreakingChanges tests the comparison of an installed version with the latest one
+func TestCheckUpdate_BreakingChanges(t *testing.T) {
+	handler := &ServersHandler{}
+	released := time.Date(2025, 1, 20, 10, 0, 0, 0, time.UTC)
+
+	installed := `{
+		"packages": [{
+			"registryType": "npm", "identifier": "server",
+			"transport": {"type": "stdio"},
+			"environmentVariables": [{"name": "API_KEY", "isRequired": true}],
+			"packageArguments": [{"type": "named", "name": "--port", "isRequired": true}]
+		}],
+		"remotes": [{"type": "sse", "url": "https://example.com/sse"}]
+	}`
+	latest := `{
+		"packages": [{
+			"registryType": "npm", "identifier": "server",
+			"transport": {"type": "stdio"},
+			"environmentVariables": [
+				{"name": "API_KEY", "isRequired": true},
+				{"name": "REGION", "isRequired": true},
+				{"name": "DEBUG", "isRequired": false}
+			],
+			"packageArguments": [
+				{"type": "named", "name": "--port", "isRequired": true},
+				{"type": "positional", "value": "{workspace}", "isRequired": true}
+			]
+		}],
+		"remotes": [{"type": "streamable-http", "url": "https://example.com/mcp"}]
+	}`
+
+	result := handler.checkUpdate(db.VersionPair{
+		InstalledVersion: db.InstalledVersion{ServerID: "io.github.owner/server", Version: "1.2.0"},
+		LatestVersion:    "2.0.0",
+		LatestValue:      []byte(latest),
+		LatestPublished:  &released,
+		InstalledValue:   []byte(installed),
+	})
+
+	if !result.Found || !result.UpdateAvailable || result.LatestVersion != "2.0.0" {
+		t.Errorf("Expected an update to 2.0.0, got %+v", result)
+	}
+	if result.ReleaseDate == nil || !result.ReleaseDate.Equal(released) {
+		t.Errorf("Expected release date %v, got %v", released, result.ReleaseDate)
+	}
+
+	want := []string{
+		"new required environment variable REGION",
+		"new required argument positional #1",
+		"removed transport sse",
+	}
+	if !result.BreakingChanges || !reflect.DeepEqual(result.BreakingReasons, want) {
+		t.Errorf("Expected breaking changes %v, got %v", want, result.BreakingReasons)
+	}
+
+	// The same version has no update and no breaking changes
+	same := handler.checkUpdate(db.VersionPair{
+		InstalledVersion: db.InstalledVersion{ServerID: "io.github.owner/server", Version: "2.0.0"},
+		LatestVersion:    "2.0.0",
+		LatestValue:      []byte(latest),
+		InstalledValue:   []byte(latest),
+	})
+	if same.UpdateAvailable || same.BreakingChanges {
+		t.Errorf("Expected no update for the latest version, got %+v", same)
+	}
+
+	// Unknown servers are reported as not found
+	missing := handler.checkUpdate(db.VersionPair{
+		InstalledVersion: db.InstalledVersion{ServerID: "io.github.owner/gone", Version: "1.0.0"},
+	})
+	if missing.Found || missing.UpdateAvailable {
+		t.Errorf("Expected an unknown server to be not found, got %+v", missing)
+	}
+}
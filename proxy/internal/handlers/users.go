@@ -0,0 +1,422 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/veriteknik/registry-proxy/internal/db"
+	"github.com/veriteknik/registry-proxy/internal/middleware"
+	"github.com/veriteknik/registry-proxy/internal/models"
+	"github.com/veriteknik/registry-proxy/internal/profiles"
+	"github.com/veriteknik/registry-proxy/internal/recommend"
+	"github.com/veriteknik/registry-proxy/internal/semver"
+	"github.com/veriteknik/registry-proxy/internal/utils"
+)
+
+// UsersHandler handles endpoints about the data stored for a user
+type UsersHandler struct {
+	db      *db.DB
+	servers *ServersHandler
+	cache   Cache
+}
+
+// NewUsersHandler creates a new users handler; servers enriches the servers listed in a
+// user's installs and ratings
+func NewUsersHandler(database *db.DB, servers *ServersHandler, cache Cache) *UsersHandler {
+	return &UsersHandler{db: database, servers: servers, cache: cache}
+}
+
+// UserInstallResponse is a server listed by GET /v0/users/:userId/installs
+type UserInstallResponse struct {
+	db.UserInstallation
+	Server          *models.EnrichedServer `json:"server"` // nil once removed from the registry
+	UpdateAvailable bool                   `json:"update_available"`
+}
+
+// UserRatingResponse is a server listed by GET /v0/users/:userId/ratings
+type UserRatingResponse struct {
+	db.UserRating
+	Server          *models.EnrichedServer `json:"server"` // nil once removed from the registry
+	UpdateAvailable bool                   `json:"update_available"`
+}
+
+// UserRecommendationResponse is a server listed by GET /v0/users/:userId/recommendations
+type UserRecommendationResponse struct {
+	models.EnrichedServer
+	recommend.Recommendation
+}
+
+// EraseResponse counts what DELETE /v0/users/:userId erased
+type EraseResponse struct {
+	Success bool       `json:"success"`
+	Erased  db.Erasure `json:"erased"`
+}
+
+// ProfileRequest is the body of PUT /v0/users/:userId/profile
+type ProfileRequest struct {
+	Username  string `json:"username"`
+	AvatarURL string `json:"avatar_url"`
+	OptedOut  bool   `json:"opted_out"`
+}
+
+// maxUsernameLength matches proxy_user_profiles.username
+const maxUsernameLength = 255
+
+// UserExportResponse is the archive returned by GET /v0/users/me/export
+type UserExportResponse struct {
+	UserID     string    `json:"user_id"`
+	ExportedAt time.Time `json:"exported_at"`
+	*db.UserExport
+}
+
+// UserInstallsResponse lists the servers a user has installed
+type UserInstallsResponse struct {
+	UserID   string                `json:"user_id"`
+	Installs []UserInstallResponse `json:"installs"`
+	Count    int                   `json:"count"`
+}
+
+// UserRatingsResponse lists the servers a user has rated
+type UserRatingsResponse struct {
+	UserID  string               `json:"user_id"`
+	Ratings []UserRatingResponse `json:"ratings"`
+	Count   int                  `json:"count"`
+}
+
+// UserRecommendationsResponse lists the servers recommended to a user
+type UserRecommendationsResponse struct {
+	UserID          string                       `json:"user_id"`
+	Recommendations []UserRecommendationResponse `json:"recommendations"`
+	Count           int                          `json:"count"`
+}
+
+// HandleErase handles DELETE /v0/users/:userId
+// Erases the user's ratings, installations, votes, reports, events, profile and
+// collections (GDPR right to erasure) and recomputes the stats of the affected servers
+func (h *UsersHandler) HandleErase(w http.ResponseWriter, r *http.Request) {
+	if !utils.RequireMethod(w, r, http.MethodDelete) {
+		return
+	}
+
+	// Extract user ID from path: /v0/users/{userId}
+	userID := strings.TrimPrefix(r.URL.Path, "/v0/users/")
+	if userID == "" || strings.Contains(userID, "/") {
+		utils.WriteJSONError(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	erased, err := h.db.EraseUser(r.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to erase user data: %v", err)
+		utils.WriteJSONError(w, "Failed to erase user data", http.StatusInternalServerError)
+		return
+	}
+
+	// Erased ratings and installs change the stats of cached servers
+	if h.cache != nil {
+		h.cache.Clear()
+	}
+
+	if err := utils.WriteJSON(w, http.StatusOK, EraseResponse{
+		Success: true,
+		Erased:  erased,
+	}); err != nil {
+		log.Printf("Error encoding erase response: %v", err)
+	}
+}
+
+// HandleProfile handles PUT /v0/users/:userId/profile
+// Stores the public profile shown with the user's reviews. The user service pushes
+// profiles with the users' own IDs; they are hashed here like every other stored user ID.
+func (h *UsersHandler) HandleProfile(w http.ResponseWriter, r *http.Request) {
+	if !utils.RequireMethod(w, r, http.MethodPut) {
+		return
+	}
+
+	// Extract user ID from path: /v0/users/{userId}/profile
+	userID, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/v0/users/"), "/profile")
+	if !ok || userID == "" || strings.Contains(userID, "/") {
+		utils.WriteJSONError(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	var req ProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSONError(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(req.Username) > maxUsernameLength {
+		utils.WriteJSONError(w, fmt.Sprintf("username must be at most %d characters", maxUsernameLength), http.StatusBadRequest)
+		return
+	}
+
+	err := h.db.PutUserProfile(r.Context(), userID, profiles.Profile{
+		Username:  req.Username,
+		AvatarURL: req.AvatarURL,
+		OptedOut:  req.OptedOut,
+	})
+	if err != nil {
+		log.Printf("Failed to store user profile: %v", err)
+		utils.WriteJSONError(w, "Failed to store user profile", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleExport handles GET /v0/users/me/export
+// Returns everything stored about the authenticated user as a JSON archive (GDPR right
+// of access and data portability)
+func (h *UsersHandler) HandleExport(w http.ResponseWriter, r *http.Request) {
+	if !utils.RequireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	userID := middleware.UserIDFromContext(r.Context())
+	if userID == "" {
+		utils.WriteJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	export, err := h.db.ExportUser(r.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to export user data: %v", err)
+		utils.WriteJSONError(w, "Failed to export user data", http.StatusInternalServerError)
+		return
+	}
+
+	// Personal data must never be served from a shared cache
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Disposition", `attachment; filename="plugged-in-export.json"`)
+
+	if err := utils.WriteJSON(w, http.StatusOK, UserExportResponse{
+		UserID:     userID,
+		ExportedAt: time.Now().UTC(),
+		UserExport: export,
+	}); err != nil {
+		log.Printf("Error encoding export response: %v", err)
+	}
+}
+
+// HandleInstalls handles GET /v0/users/:userId/installs
+// Lists the servers the user has installed, each with the installed version and whether
+// a newer version has been published
+func (h *UsersHandler) HandleInstalls(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.ownUserID(w, r, "installs")
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	installs, err := h.db.GetUserInstallations(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to get user installations: %v", err)
+		utils.WriteJSONError(w, "Failed to get installations", http.StatusInternalServerError)
+		return
+	}
+
+	ids := make([]string, len(installs))
+	for i, install := range installs {
+		ids[i] = install.ServerID
+	}
+	servers, err := h.servers.getEnrichedServersByID(ctx, ids)
+	if err != nil {
+		log.Printf("Failed to enrich user installations: %v", err)
+		utils.WriteJSONError(w, "Failed to get installations", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]UserInstallResponse, len(installs))
+	for i, install := range installs {
+		response[i] = UserInstallResponse{UserInstallation: install}
+		if server, found := servers[install.ServerID]; found {
+			response[i].Server = &server
+			response[i].UpdateAvailable = semver.Newer(server.VersionDetail.Version, install.Version)
+		}
+	}
+
+	if err := utils.WriteJSON(w, http.StatusOK, UserInstallsResponse{
+		UserID:   userID,
+		Installs: response,
+		Count:    len(response),
+	}); err != nil {
+		log.Printf("Error encoding installs response: %v", err)
+	}
+}
+
+// HandleRatings handles GET /v0/users/:userId/ratings
+// Lists the user's ratings, including those held for moderation, each with the rated
+// server and whether a newer version than the user's installed one has been published
+func (h *UsersHandler) HandleRatings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.ownUserID(w, r, "ratings")
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	ratings, err := h.db.GetUserRatings(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to get user ratings: %v", err)
+		utils.WriteJSONError(w, "Failed to get ratings", http.StatusInternalServerError)
+		return
+	}
+
+	ids := make([]string, len(ratings))
+	for i, rating := range ratings {
+		ids[i] = rating.ServerID
+	}
+	servers, installed, err := h.serversWithInstalledVersions(ctx, userID, ids)
+	if err != nil {
+		log.Printf("Failed to enrich user ratings: %v", err)
+		utils.WriteJSONError(w, "Failed to get ratings", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]UserRatingResponse, len(ratings))
+	for i, rating := range ratings {
+		response[i] = UserRatingResponse{UserRating: rating}
+		if server, found := servers[rating.ServerID]; found {
+			response[i].Server = &server
+			response[i].UpdateAvailable = semver.Newer(server.VersionDetail.Version, installed[rating.ServerID])
+		}
+	}
+
+	if err := utils.WriteJSON(w, http.StatusOK, UserRatingsResponse{
+		UserID:  userID,
+		Ratings: response,
+		Count:   len(response),
+	}); err != nil {
+		log.Printf("Error encoding ratings response: %v", err)
+	}
+}
+
+// HandleRecommendations handles GET /v0/users/:userId/recommendations
+// Recommends servers the user has not installed, ranked by what the users who installed
+// the same servers also installed, or by popularity for users with little history
+func (h *UsersHandler) HandleRecommendations(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.ownUserID(w, r, "recommendations")
+	if !ok {
+		return
+	}
+	limit := utils.ParseIntParam(r.URL.Query(), "limit", 10, 50)
+
+	ctx := r.Context()
+	history, err := h.db.GetUserItems(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to get user history: %v", err)
+		utils.WriteJSONError(w, "Failed to get recommendations", http.StatusInternalServerError)
+		return
+	}
+
+	seen := make([]string, len(history))
+	var liked []string
+	for i, item := range history {
+		seen[i] = item.ServerID
+		if recommend.Weight(item) > 0 {
+			liked = append(liked, item.ServerID)
+		}
+	}
+	coInstalls, installers, err := h.db.GetItemCoInstalls(ctx, liked, userID, recommend.MinCoInstallers)
+	if err != nil {
+		log.Printf("Failed to get co-installations: %v", err)
+		utils.WriteJSONError(w, "Failed to get recommendations", http.StatusInternalServerError)
+		return
+	}
+	popular, err := h.servers.registryDB.GetPopularServers(ctx, seen, limit)
+	if err != nil {
+		log.Printf("Failed to get popular servers: %v", err)
+		utils.WriteJSONError(w, "Failed to get recommendations", http.StatusInternalServerError)
+		return
+	}
+
+	// Rank twice as many as needed, as servers deleted from the registry are left out below
+	recommendations := recommend.Recommend(history, coInstalls, installers, popular, 2*limit)
+	ids := make([]string, len(recommendations))
+	for i, rec := range recommendations {
+		ids[i] = rec.ServerID
+	}
+	servers, err := h.servers.getEnrichedServersByID(ctx, ids)
+	if err != nil {
+		log.Printf("Failed to enrich recommendations: %v", err)
+		utils.WriteJSONError(w, "Failed to get recommendations", http.StatusInternalServerError)
+		return
+	}
+
+	response := recommendationResponses(recommendations, servers, limit)
+	if err := utils.WriteJSON(w, http.StatusOK, UserRecommendationsResponse{
+		UserID:          userID,
+		Recommendations: response,
+		Count:           len(response),
+	}); err != nil {
+		log.Printf("Error encoding recommendations response: %v", err)
+	}
+}
+
+// recommendationResponses pairs at most limit recommendations with their enriched data,
+// best first, leaving out servers no longer in the registry
+func recommendationResponses(recommendations []recommend.Recommendation, servers map[string]models.EnrichedServer, limit int) []UserRecommendationResponse {
+	response := make([]UserRecommendationResponse, 0, limit)
+	for _, rec := range recommendations {
+		if len(response) >= limit {
+			break
+		}
+		server, ok := servers[rec.ServerID]
+		if !ok {
+			continue
+		}
+		response = append(response, UserRecommendationResponse{EnrichedServer: server, Recommendation: rec})
+	}
+	return response
+}
+
+// serversWithInstalledVersions enriches the given servers and returns the version of each
+// the user currently has installed
+func (h *UsersHandler) serversWithInstalledVersions(ctx context.Context, userID string, ids []string) (map[string]models.EnrichedServer, map[string]string, error) {
+	servers, err := h.servers.getEnrichedServersByID(ctx, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	installs, err := h.db.GetUserInstallations(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	installed := make(map[string]string, len(installs))
+	for _, install := range installs {
+		installed[install.ServerID] = install.Version
+	}
+
+	return servers, installed, nil
+}
+
+// ownUserID validates a GET /v0/users/{userId}/{resource} request and returns the user ID,
+// which must be the one authenticated by UserTokenAuth: users only read their own data
+func (h *UsersHandler) ownUserID(w http.ResponseWriter, r *http.Request, resource string) (string, bool) {
+	if !utils.RequireMethod(w, r, http.MethodGet) {
+		return "", false
+	}
+
+	// Extract user ID from path: /v0/users/{userId}/{resource}
+	userID, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/v0/users/"), "/"+resource)
+	if !ok || userID == "" || strings.Contains(userID, "/") {
+		utils.WriteJSONError(w, "Invalid path", http.StatusBadRequest)
+		return "", false
+	}
+
+	authenticated := middleware.UserIDFromContext(r.Context())
+	if authenticated == "" {
+		utils.WriteJSONError(w, "Authentication required", http.StatusUnauthorized)
+		return "", false
+	}
+	if authenticated != userID {
+		utils.WriteJSONError(w, "Forbidden", http.StatusForbidden)
+		return "", false
+	}
+
+	return userID, true
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/veriteknik/registry-proxy/internal/middleware"
+	"github.com/veriteknik/registry-proxy/internal/models"
+	"github.com/veriteknik/registry-proxy/internal/recommend"
+)
+
+// TestHandleErase_RejectsBeforeDatabase tests the validation done before a user's data is erased
+func TestHandleErase_RejectsBeforeDatabase(t *testing.T) {
+	handler := &UsersHandler{}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		want   int
+	}{
+		{"wrong method", http.MethodGet, "/v0/users/alice", http.StatusMethodNotAllowed},
+		{"missing user", http.MethodDelete, "/v0/users/", http.StatusBadRequest},
+		{"nested path", http.MethodDelete, "/v0/users/alice/installs", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			rec := httptest.NewRecorder()
+
+			handler.HandleErase(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+// TestOwnUserData_OnlyForTheTokenSubject tests that users can only read their own installs, ratings and recommendations
+func TestOwnUserData_OnlyForTheTokenSubject(t *testing.T) {
+	handler := &UsersHandler{}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		user   string
+		want   int
+	}{
+		{"no authenticated user", http.MethodGet, "/v0/users/alice/installs", "", http.StatusUnauthorized},
+		{"other user", http.MethodGet, "/v0/users/alice/installs", "bob", http.StatusForbidden},
+	}
+
+	handlers := map[string]http.HandlerFunc{
+		"installs":        handler.HandleInstalls,
+		"ratings":         handler.HandleRatings,
+		"recommendations": handler.HandleRecommendations,
+	}
+
+	for _, tt := range tests {
+		for resource, handle := range handlers {
+			t.Run(tt.name+" "+resource, func(t *testing.T) {
+				target := strings.TrimSuffix(tt.target, "/installs") + "/" + resource
+				req := httptest.NewRequest(tt.method, target, nil)
+				if tt.user != "" {
+					req = req.WithContext(middleware.ContextWithUserID(req.Context(), tt.user))
+				}
+				rec := httptest.NewRecorder()
+
+				handle(rec, req)
+
+				if rec.Code != tt.want {
+					t.Errorf("Expected status %d, got %d", tt.want, rec.Code)
+				}
+			})
+		}
+	}
+}
+
+// TestRecommendationResponses tests that recommendations keep their order up to the limit and skip servers no longer listed
+func TestRecommendationResponses(t *testing.T) {
+	recommendations := []recommend.Recommendation{
+		{ServerID: "a", Score: 0.9, Source: recommend.SourceCollaborative, Reason: "why a", BasedOn: "x"},
+		{ServerID: "deleted", Score: 0.5, Source: recommend.SourceCollaborative},
+		{ServerID: "b", Source: recommend.SourcePopular, Reason: "why b"},
+		{ServerID: "c", Source: recommend.SourcePopular},
+	}
+	servers := map[string]models.EnrichedServer{
+		"a": {Server: models.Server{ID: "a"}},
+		"b": {Server: models.Server{ID: "b"}, InstallationCount: 7},
+		"c": {Server: models.Server{ID: "c"}},
+	}
+
+	response := recommendationResponses(recommendations, servers, 2)
+
+	if len(response) != 2 || response[0].ID != "a" || response[1].ID != "b" {
+		t.Fatalf("Expected a then b, got %+v", response)
+	}
+	if response[0].BasedOn != "x" || response[1].Reason != "why b" || response[1].InstallationCount != 7 {
+		t.Errorf("Expected recommendations with their servers, got %+v", response)
+	}
+}
@@ -123,6 +123,22 @@ var (
 		},
 	)
 
+	// Comment Filter Metrics
+	CommentFilterDecisionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "registry_proxy_comment_filter_decisions_total",
+			Help: "Total number of review comment filter decisions by check and result (pass, flag, error)",
+		},
+		[]string{"check", "result"},
+	)
+
+	ReviewsHeldTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "registry_proxy_reviews_held_total",
+			Help: "Total number of reviews stored as pending moderation by the comment filter",
+		},
+	)
+
 	// Error Metrics
 	DatabaseQueryErrorsTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -200,6 +216,16 @@ func RecordFilterError(endpoint, filterType string) {
 	FilterErrorsTotal.WithLabelValues(endpoint, filterType).Inc()
 }
 
+// RecordCommentFilterDecision records the result of one comment filter check
+func RecordCommentFilterDecision(check, result string) {
+	CommentFilterDecisionsTotal.WithLabelValues(check, result).Inc()
+}
+
+// RecordReviewHeld records a review held for moderation by the comment filter
+func RecordReviewHeld() {
+	ReviewsHeldTotal.Inc()
+}
+
 // RecordErrorLog records an error-level log
 func RecordErrorLog(level string) {
 	ErrorLogsTotal.WithLabelValues(level).Inc()
@@ -61,6 +61,17 @@ func APIKeyAuth(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// HasAPIKey reports whether r carries the configured API key, for public endpoints that
+// trust authenticated services with more than anonymous callers
+func HasAPIKey(r *http.Request) bool {
+	validAPIKey := os.Getenv("API_KEY")
+	if validAPIKey == "" {
+		return false
+	}
+	apiKey, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(apiKey), []byte(validAPIKey)) == 1
+}
+
 // RateLimitByIP implements simple rate limiting by IP
 // This is a basic implementation - for production use a proper rate limiter
 func RateLimitByIP(next http.HandlerFunc) http.HandlerFunc {
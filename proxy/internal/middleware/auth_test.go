@@ -253,3 +253,28 @@ func BenchmarkAPIKeyAuth_InvalidKey(b *testing.B) {
 		authMiddleware(w, r)
 	}
 }
+
+func TestHasAPIKey(t *testing.T) {
+	os.Setenv("API_KEY", "correct-key")
+	defer os.Unsetenv("API_KEY")
+
+	tests := []struct {
+		authHeader string
+		want       bool
+	}{
+		{"Bearer correct-key", true},
+		{"Bearer wrong-key", false},
+		{"correct-key", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/test", nil)
+		if tt.authHeader != "" {
+			r.Header.Set("Authorization", tt.authHeader)
+		}
+		if got := HasAPIKey(r); got != tt.want {
+			t.Errorf("HasAPIKey(%q) = %v, want %v", tt.authHeader, got, tt.want)
+		}
+	}
+}
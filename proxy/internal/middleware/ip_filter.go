@@ -60,7 +60,7 @@ func InitMetricsIPFilter() error {
 // MetricsIPFilter is middleware that restricts access to metrics endpoint by IP
 func MetricsIPFilter(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		clientIP := getClientIP(r)
+		clientIP := ClientIP(r)
 
 		// Check if IP is allowed
 		if !isIPAllowed(clientIP) {
@@ -78,8 +78,8 @@ func MetricsIPFilter(next http.Handler) http.Handler {
 	})
 }
 
-// getClientIP extracts the real client IP from the request
-func getClientIP(r *http.Request) string {
+// ClientIP extracts the real client IP from the request
+func ClientIP(r *http.Request) string {
 	// Try X-Forwarded-For first (for requests through reverse proxy)
 	xff := r.Header.Get("X-Forwarded-For")
 	if xff != "" {
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter allows each key at most limit requests per window. Windows are fixed and
+// shared by all keys, so the counts are dropped at the end of every window and memory
+// stays bounded by the keys seen within one window.
+type RateLimiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu     sync.Mutex
+	start  time.Time
+	counts map[string]int
+}
+
+// NewRateLimiter creates a limiter of limit requests per window; it returns nil, which
+// allows every request, when limit is not positive
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	return &RateLimiter{limit: limit, window: window, now: time.Now, counts: make(map[string]int)}
+}
+
+// Allow counts a request of key and reports whether it is within the limit
+func (l *RateLimiter) Allow(key string) bool {
+	if l == nil {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now := l.now(); now.Sub(l.start) >= l.window {
+		l.start = now
+		l.counts = make(map[string]int)
+	}
+	if l.counts[key] >= l.limit {
+		return false
+	}
+	l.counts[key]++
+	return true
+}
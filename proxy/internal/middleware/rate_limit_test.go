@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	limiter := NewRateLimiter(2, time.Hour)
+	limiter.now = func() time.Time { return now }
+
+	for i, want := range []bool{true, true, false} {
+		if got := limiter.Allow("10.0.0.1"); got != want {
+			t.Errorf("request %d: Allow() = %v, want %v", i+1, got, want)
+		}
+	}
+	if !limiter.Allow("10.0.0.2") {
+		t.Error("Expected another key to have its own limit")
+	}
+
+	now = now.Add(time.Hour)
+	if !limiter.Allow("10.0.0.1") {
+		t.Error("Expected the limit to reset in the next window")
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	limiter := NewRateLimiter(0, time.Hour)
+	for i := 0; i < 100; i++ {
+		if !limiter.Allow("10.0.0.1") {
+			t.Fatal("Expected a disabled limiter to allow every request")
+		}
+	}
+}
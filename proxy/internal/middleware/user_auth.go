@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/veriteknik/registry-proxy/internal/utils"
+	"go.uber.org/zap"
+)
+
+type contextKey string
+
+const userIDContextKey contextKey = "user_id"
+
+// UserTokenAuth authenticates an end user by a signed token, an HS256 JWT issued by the
+// plugged.in app with USER_TOKEN_SECRET whose "sub" claim is the user ID and which must
+// carry an expiry. The user ID is available to next through UserIDFromContext.
+func UserTokenAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := utils.Logger
+
+		secret := os.Getenv("USER_TOKEN_SECRET")
+		if secret == "" {
+			// Without a secret no token can be verified, so reject all requests
+			logger.Warn("User authentication failed: token secret not configured")
+			utils.WriteJSONError(w, "Authentication failed", http.StatusUnauthorized)
+			return
+		}
+
+		// Expected format: "Bearer <token>"
+		parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+		if len(parts) != 2 || parts[0] != "Bearer" || parts[1] == "" {
+			utils.WriteJSONError(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+
+		claims := &jwt.RegisteredClaims{}
+		_, err := jwt.ParseWithClaims(parts[1], claims, func(token *jwt.Token) (interface{}, error) {
+			return []byte(secret), nil
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+		if err != nil || claims.Subject == "" {
+			// Security: Never log the token or any portion of it
+			logger.Info("User authentication failed", zap.Error(err))
+			utils.WriteJSONError(w, "Invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ContextWithUserID(r.Context(), claims.Subject)))
+	}
+}
+
+// UserIDFromContext returns the user authenticated by UserTokenAuth, or "" if there is none
+func UserIDFromContext(ctx context.Context) string {
+	userID, _ := ctx.Value(userIDContextKey).(string)
+	return userID
+}
+
+// ContextWithUserID returns a context carrying an authenticated user ID
+func ContextWithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDContextKey, userID)
+}
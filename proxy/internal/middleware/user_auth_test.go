@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestUserTokenAuth(t *testing.T) {
+	const secret = "test-user-token-secret"
+	t.Setenv("USER_TOKEN_SECRET", secret)
+
+	sign := func(method jwt.SigningMethod, key interface{}, claims jwt.RegisteredClaims) string {
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		return token
+	}
+	valid := jwt.RegisteredClaims{
+		Subject:   "alice",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+
+	tests := []struct {
+		name       string
+		authHeader string
+		wantStatus int
+		wantUser   string
+	}{
+		{
+			name:       "valid token",
+			authHeader: "Bearer " + sign(jwt.SigningMethodHS256, []byte(secret), valid),
+			wantStatus: http.StatusOK,
+			wantUser:   "alice",
+		},
+		{
+			name:       "wrong secret",
+			authHeader: "Bearer " + sign(jwt.SigningMethodHS256, []byte("other-secret"), valid),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "other signing method",
+			authHeader: "Bearer " + sign(jwt.SigningMethodHS512, []byte(secret), valid),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "expired token",
+			authHeader: "Bearer " + sign(jwt.SigningMethodHS256, []byte(secret), jwt.RegisteredClaims{
+				Subject:   "alice",
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour)),
+			}),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "no expiry",
+			authHeader: "Bearer " + sign(jwt.SigningMethodHS256, []byte(secret), jwt.RegisteredClaims{Subject: "alice"}),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "no subject",
+			authHeader: "Bearer " + sign(jwt.SigningMethodHS256, []byte(secret), jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			}),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "no authorization header",
+			authHeader: "",
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUser string
+			handler := UserTokenAuth(func(w http.ResponseWriter, r *http.Request) {
+				gotUser = UserIDFromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/v0/users/me/export", nil)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			rec := httptest.NewRecorder()
+
+			handler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if gotUser != tt.wantUser {
+				t.Errorf("Expected user %q, got %q", tt.wantUser, gotUser)
+			}
+		})
+	}
+}
+
+func TestUserTokenAuth_NoSecretConfigured(t *testing.T) {
+	t.Setenv("USER_TOKEN_SECRET", "")
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   "alice",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}).SignedString([]byte(""))
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	handler := UserTokenAuth(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called without a configured secret")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v0/users/me/export", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+
+	handler(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+}
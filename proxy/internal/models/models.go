@@ -92,9 +92,24 @@ type EnrichedServer struct {
 	Server
 	Packages          []Package `json:"packages,omitempty"`
 	Remotes           []Remote  `json:"remotes,omitempty"`
+	Category          string    `json:"category,omitempty"`
+	Tags              []string  `json:"tags,omitempty"`
 	Rating            float64   `json:"rating,omitempty"`
 	RatingCount       int       `json:"rating_count,omitempty"`
 	InstallationCount int       `json:"installation_count,omitempty"`
+	WeightedRating    float64   `json:"weighted_rating,omitempty"`
+	ActiveInstalls    int       `json:"active_installs,omitempty"`
+
+	RatingDistribution *RatingDistribution `json:"rating_distribution,omitempty"`
+}
+
+// RatingDistribution counts a server's visible reviews per star
+type RatingDistribution struct {
+	One   int `json:"1"`
+	Two   int `json:"2"`
+	Three int `json:"3"`
+	Four  int `json:"4"`
+	Five  int `json:"5"`
 }
 
 // ProxyResponse wraps the enriched servers with metadata
@@ -0,0 +1,247 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Spec is everything a document is built from
+type Spec struct {
+	Info            Info
+	SecuritySchemes map[string]SecurityScheme
+	Routes          []Route
+	Loose           []Loose
+}
+
+// Route declares one method of an endpoint
+type Route struct {
+	Method string
+	Path   string // With {name} for each path parameter
+
+	// Handler is the method expression of the handler serving the route, such as
+	// (*RatingsHandler).HandleStats; it is not part of the document, but lets contract
+	// tests find the handler's source
+	Handler interface{}
+
+	OperationID string
+	Summary     string
+	Description string
+	Tag         string
+	Security    string // Name of the security scheme required, if any
+
+	Params   []Param
+	Request  interface{} // A value of the type of the JSON body; nil when there is none
+	Status   int         // Status of a successful response; 200 by default
+	Response interface{} // A value of the JSON response type; nil when there is no content
+	Headers  []Param     // Response headers; only Name, Description and Type are used
+}
+
+// Param declares a request parameter
+type Param struct {
+	Name        string
+	In          string // query (the default), path or header
+	Description string
+
+	// Type is the zero value of the parameter's type: string by default, and []string
+	// for a comma-separated list
+	Type interface{}
+
+	Required bool
+	Enum     []string
+	Default  interface{}
+	Max      int // Largest value of an integer, or 0 for none
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}/]+)\}`)
+
+// Build builds the document of spec
+func Build(spec Spec) (*Document, error) {
+	g := newGenerator(spec.Loose)
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    spec.Info,
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas:         g.schemas,
+			SecuritySchemes: spec.SecuritySchemes,
+		},
+	}
+
+	operationIDs := make(map[string]bool)
+	for _, route := range spec.Routes {
+		op, err := g.operation(route, spec.SecuritySchemes)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", route.Method, route.Path, err)
+		}
+		if operationIDs[op.OperationID] {
+			return nil, fmt.Errorf("%s %s: duplicate operation ID %s", route.Method, route.Path, op.OperationID)
+		}
+		operationIDs[op.OperationID] = true
+
+		item, ok := doc.Paths[route.Path]
+		if !ok {
+			item = make(PathItem)
+			doc.Paths[route.Path] = item
+		}
+		method := strings.ToLower(route.Method)
+		if _, ok := item[method]; ok {
+			return nil, fmt.Errorf("%s %s: declared twice", route.Method, route.Path)
+		}
+		item[method] = op
+	}
+
+	if g.err != nil {
+		return nil, g.err
+	}
+	return doc, nil
+}
+
+// operation describes one route
+func (g *generator) operation(route Route, schemes map[string]SecurityScheme) (*Operation, error) {
+	if route.OperationID == "" {
+		return nil, fmt.Errorf("operation ID is required")
+	}
+	op := &Operation{
+		OperationID: route.OperationID,
+		Summary:     route.Summary,
+		Description: route.Description,
+		Responses:   make(map[string]Response),
+	}
+	if route.Tag != "" {
+		op.Tags = []string{route.Tag}
+	}
+	if route.Security != "" {
+		if _, ok := schemes[route.Security]; !ok {
+			return nil, fmt.Errorf("unknown security scheme %s", route.Security)
+		}
+		op.Security = []SecurityReq{{route.Security: {}}}
+	}
+
+	// Every path parameter is declared, and every declared one is in the path
+	inPath := make(map[string]bool)
+	for _, match := range pathParamPattern.FindAllStringSubmatch(route.Path, -1) {
+		inPath[match[1]] = true
+	}
+	for _, p := range route.Params {
+		param, err := parameter(p)
+		if err != nil {
+			return nil, err
+		}
+		if param.In == "path" {
+			if !inPath[p.Name] {
+				return nil, fmt.Errorf("path parameter %s is not in the path", p.Name)
+			}
+			delete(inPath, p.Name)
+		}
+		op.Parameters = append(op.Parameters, param)
+	}
+	for name := range inPath {
+		return nil, fmt.Errorf("path parameter %s is not declared", name)
+	}
+
+	if route.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  jsonContent(g.schema(reflect.TypeOf(route.Request), requestMode)),
+		}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	response := Response{Description: http.StatusText(status)}
+	if route.Response != nil {
+		response.Content = jsonContent(g.schema(reflect.TypeOf(route.Response), responseMode))
+	}
+	for _, h := range route.Headers {
+		schema, err := paramSchema(h)
+		if err != nil {
+			return nil, err
+		}
+		if response.Headers == nil {
+			response.Headers = make(map[string]Header)
+		}
+		response.Headers[h.Name] = Header{Description: h.Description, Schema: schema}
+	}
+	op.Responses[strconv.Itoa(status)] = response
+
+	return op, nil
+}
+
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
+
+// parameter describes a request parameter
+func parameter(p Param) (Parameter, error) {
+	param := Parameter{Name: p.Name, In: p.In, Description: p.Description, Required: p.Required}
+	switch p.In {
+	case "":
+		param.In = "query"
+	case "path":
+		param.Required = true
+	case "query", "header":
+	default:
+		return Parameter{}, fmt.Errorf("parameter %s is in unknown location %s", p.Name, p.In)
+	}
+
+	schema, err := paramSchema(p)
+	if err != nil {
+		return Parameter{}, err
+	}
+	param.Schema = schema
+	if _, ok := p.Type.([]string); ok {
+		explode := false
+		param.Style, param.Explode = "form", &explode
+	}
+	return param, nil
+}
+
+// paramSchema returns the schema of a parameter's values
+func paramSchema(p Param) (*Schema, error) {
+	var s *Schema
+	switch p.Type.(type) {
+	case nil, string:
+		s = &Schema{Type: Types{"string"}}
+	case int:
+		s = &Schema{Type: Types{"integer"}}
+	case float64:
+		s = &Schema{Type: Types{"number"}}
+	case bool:
+		s = &Schema{Type: Types{"boolean"}}
+	case []string:
+		s = &Schema{Type: Types{"array"}, Items: &Schema{Type: Types{"string"}}}
+	default:
+		return nil, fmt.Errorf("parameter %s has unsupported type %T", p.Name, p.Type)
+	}
+
+	for _, v := range p.Enum {
+		s.Enum = append(s.Enum, v)
+	}
+	s.Default = p.Default
+	if p.Max > 0 {
+		max := float64(p.Max)
+		s.Maximum = &max
+	}
+	return s, nil
+}
+
+// HandlerName returns the name of a handler method, such as "RatingsHandler.HandleStats"
+// for (*RatingsHandler).HandleStats or a method value of it
+func HandlerName(handler interface{}) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(handler).Pointer())
+	if fn == nil {
+		return ""
+	}
+	name := fn.Name()
+	name = name[strings.LastIndex(name, "/")+1:]
+	_, name, _ = strings.Cut(name, ".") // Package
+	name = strings.TrimSuffix(name, "-fm")
+	return strings.NewReplacer("(*", "", ")", "").Replace(name)
+}
@@ -0,0 +1,198 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testBase struct {
+	ID   string `json:"id"`
+	Name string `json:"name"` // Shadowed by testItem.Name
+}
+
+type testItem struct {
+	*testBase
+	Name     string            `json:"title"`
+	Shadow   string            `json:"name"`
+	Note     string            `json:"note,omitempty"`
+	Parent   *testItem         `json:"parent"`
+	Child    *testItem         `json:"child,omitempty"`
+	Count    *int              `json:"count"`
+	Created  time.Time         `json:"created"`
+	Seen     *time.Time        `json:"seen"`
+	Labels   map[string]string `json:"labels"`
+	Raw      []byte            `json:"raw"`
+	Any      interface{}       `json:"any"`
+	Skipped  string            `json:"-"`
+	Untagged bool
+	Inline   struct {
+		Rank float64 `json:"rank"`
+	} `json:"inline"`
+	hidden string
+}
+
+type testPage struct {
+	Items []testItem `json:"items"`
+}
+
+type testRequest struct {
+	Query string `json:"query"`
+}
+
+type testHandler struct{}
+
+func (h *testHandler) HandleList(w http.ResponseWriter, r *http.Request) {}
+
+func marshal(t *testing.T, v interface{}) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	return string(b)
+}
+
+func TestSchemaFollowsEncodingJSON(t *testing.T) {
+	g := newGenerator(nil)
+	ref := g.schema(reflect.TypeOf(testPage{}), responseMode)
+	if g.err != nil {
+		t.Fatalf("schema() error = %v", g.err)
+	}
+
+	if got, want := marshal(t, ref), `{"$ref":"#/components/schemas/testPage"}`; got != want {
+		t.Errorf("schema = %s, want %s", got, want)
+	}
+
+	want := `{"type":"object","properties":{` +
+		`"Untagged":{"type":"boolean"},` +
+		`"any":{},` +
+		`"child":{"$ref":"#/components/schemas/testItem"},` +
+		`"count":{"type":["integer","null"]},` +
+		`"created":{"type":"string","format":"date-time"},` +
+		`"id":{"type":"string"},` +
+		`"inline":{"type":"object","properties":{"rank":{"type":"number"}},"required":["rank"]},` +
+		`"labels":{"type":"object","additionalProperties":{"type":"string"}},` +
+		`"name":{"type":"string"},` +
+		`"note":{"type":"string"},` +
+		`"parent":{"anyOf":[{"$ref":"#/components/schemas/testItem"},{"type":"null"}]},` +
+		`"raw":{"type":"string","format":"byte"},` +
+		`"seen":{"type":["string","null"],"format":"date-time"},` +
+		`"title":{"type":"string"}},` +
+		`"required":["title","name","parent","count","created","seen","labels","raw","any","Untagged","inline","id"]}`
+	if got := marshal(t, g.schemas["testItem"]); got != want {
+		t.Errorf("testItem = %s\nwant %s", got, want)
+	}
+}
+
+func TestRequestFieldsAreOptional(t *testing.T) {
+	g := newGenerator(nil)
+	g.schema(reflect.TypeOf(testRequest{}), requestMode)
+	if got, want := marshal(t, g.schemas["testRequest"]), `{"type":"object","properties":{"query":{"type":"string"}}}`; got != want {
+		t.Errorf("testRequest = %s, want %s", got, want)
+	}
+
+	g.schema(reflect.TypeOf(testRequest{}), responseMode)
+	if g.err == nil || !strings.Contains(g.err.Error(), "both requests and responses") {
+		t.Errorf("error = %v, want a type used in both requests and responses", g.err)
+	}
+}
+
+func TestLooseMaps(t *testing.T) {
+	type itemJSON map[string]interface{}
+	g := newGenerator([]Loose{{Value: itemJSON{}, Doc: testRequest{}}})
+	ref := g.schema(reflect.TypeOf([]itemJSON{}), responseMode)
+
+	if got, want := marshal(t, ref), `{"type":"array","items":{"$ref":"#/components/schemas/testRequest"}}`; got != want {
+		t.Errorf("schema = %s, want %s", got, want)
+	}
+	want := `{"type":"object","properties":{"query":{"type":"string"}},"required":["query"],"additionalProperties":{}}`
+	if got := marshal(t, g.schemas["testRequest"]); got != want {
+		t.Errorf("testRequest = %s, want %s", got, want)
+	}
+
+	// The documenting struct names the schema, so it cannot also be used directly
+	g.schema(reflect.TypeOf(testRequest{}), responseMode)
+	if g.err == nil || !strings.Contains(g.err.Error(), "claimed by both") {
+		t.Errorf("error = %v, want a name collision", g.err)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	route := Route{
+		Method:      http.MethodGet,
+		Path:        "/v0/items/{id}",
+		Handler:     (*testHandler).HandleList,
+		OperationID: "getItem",
+		Security:    "apiKey",
+		Params: []Param{
+			{Name: "id", In: "path"},
+			{Name: "limit", Type: 0, Default: 10, Max: 50},
+			{Name: "tags", Type: []string{}},
+		},
+		Response: testPage{},
+		Headers:  []Param{{Name: "X-Total-Count", Type: 0}},
+	}
+	spec := Spec{SecuritySchemes: map[string]SecurityScheme{"apiKey": {Type: "http", Scheme: "bearer"}}}
+
+	spec.Routes = []Route{route}
+	doc, err := Build(spec)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	op := doc.Paths["/v0/items/{id}"]["get"]
+	if op == nil {
+		t.Fatalf("paths = %v, want GET /v0/items/{id}", doc.Paths)
+	}
+	want := `[{"name":"id","in":"path","required":true,"schema":{"type":"string"}},` +
+		`{"name":"limit","in":"query","schema":{"type":"integer","default":10,"maximum":50}},` +
+		`{"name":"tags","in":"query","style":"form","explode":false,"schema":{"type":"array","items":{"type":"string"}}}]`
+	if got := marshal(t, op.Parameters); got != want {
+		t.Errorf("parameters = %s\nwant %s", got, want)
+	}
+	if got := op.Responses["200"]; got.Description != "OK" || got.Headers["X-Total-Count"].Schema == nil {
+		t.Errorf("response = %+v", got)
+	}
+	if doc.Components.Schemas["testItem"] == nil {
+		t.Errorf("schemas = %v, want testItem collected", doc.Components.Schemas)
+	}
+
+	tests := []struct {
+		name      string
+		change    func(r *Route)
+		wantError string
+	}{
+		{"undeclared path parameter", func(r *Route) { r.Params = nil }, "path parameter id is not declared"},
+		{"parameter not in path", func(r *Route) { r.Path = "/v0/items" }, "path parameter id is not in the path"},
+		{"unknown security scheme", func(r *Route) { r.Security = "basic" }, "unknown security scheme"},
+		{"missing operation ID", func(r *Route) { r.OperationID = "" }, "operation ID is required"},
+		{"unsupported parameter type", func(r *Route) { r.Params = append(r.Params, Param{Name: "x", Type: uint(0)}) }, "unsupported type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := route
+			tt.change(&r)
+			spec.Routes = []Route{r}
+			if _, err := Build(spec); err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("Build() error = %v, want %q", err, tt.wantError)
+			}
+		})
+	}
+
+	spec.Routes = []Route{route, route}
+	if _, err := Build(spec); err == nil || !strings.Contains(err.Error(), "duplicate operation ID") {
+		t.Errorf("Build() error = %v, want a duplicate operation ID", err)
+	}
+}
+
+func TestHandlerName(t *testing.T) {
+	h := &testHandler{}
+	for _, handler := range []interface{}{(*testHandler).HandleList, h.HandleList} {
+		if got := HandlerName(handler); got != "testHandler.HandleList" {
+			t.Errorf("HandlerName() = %q, want testHandler.HandleList", got)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// mode tells whether a type is decoded from requests or encoded in responses: response
+// fields are required unless omitempty, request fields are all optional
+type mode int
+
+const (
+	responseMode mode = iota
+	requestMode
+)
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// Loose documents a map type by the fields of a struct: values of the map hold at least
+// the struct's fields, and may hold others. It describes raw registry JSON that the
+// handlers extend without decoding.
+type Loose struct {
+	Value interface{} // A value of the map type
+	Doc   interface{} // A value of the struct documenting it; names the schema
+}
+
+// generator derives schemas from Go types, collecting named structs as components
+type generator struct {
+	schemas map[string]*Schema
+	owners  map[string]reflect.Type // The Go type behind each component
+	modes   map[reflect.Type]mode
+	loose   map[reflect.Type]reflect.Type
+	err     error
+}
+
+func newGenerator(loose []Loose) *generator {
+	g := &generator{
+		schemas: make(map[string]*Schema),
+		owners:  make(map[string]reflect.Type),
+		modes:   make(map[reflect.Type]mode),
+		loose:   make(map[reflect.Type]reflect.Type),
+	}
+	for _, l := range loose {
+		value, doc := reflect.TypeOf(l.Value), reflect.TypeOf(l.Doc)
+		if value.Kind() != reflect.Map || doc.Kind() != reflect.Struct {
+			g.fail("loose type %s must be a map documented by a struct, not %s", value, doc)
+			continue
+		}
+		g.loose[value] = doc
+	}
+	return g
+}
+
+// fail records the first error; generation carries on so callers check once at the end
+func (g *generator) fail(format string, args ...interface{}) {
+	if g.err == nil {
+		g.err = fmt.Errorf(format, args...)
+	}
+}
+
+// schema returns the schema of values of type t as encoding/json writes them
+func (g *generator) schema(t reflect.Type, m mode) *Schema {
+	if t.Kind() == reflect.Pointer {
+		return g.schema(t.Elem(), m)
+	}
+	if doc, ok := g.loose[t]; ok {
+		return g.component(t, doc, m)
+	}
+	if t == timeType {
+		return &Schema{Type: Types{"string"}, Format: "date-time"}
+	}
+	if t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType) {
+		g.fail("%s has a custom JSON encoding and cannot be described", t)
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: Types{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: Types{"integer"}}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: Types{"number"}}
+	case reflect.String:
+		return &Schema{Type: Types{"string"}}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: Types{"string"}, Format: "byte"}
+		}
+		return &Schema{Type: Types{"array"}, Items: g.schema(t.Elem(), m)}
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			g.fail("%s has non-string keys", t)
+		}
+		return &Schema{Type: Types{"object"}, AdditionalProperties: g.schema(t.Elem(), m)}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t, m)
+		}
+		return g.component(t, t, m)
+	}
+
+	g.fail("%s cannot be encoded as JSON", t)
+	return &Schema{}
+}
+
+// component returns a reference to the component describing t, named and documented
+// by doc; doc differs from t for loose map types
+func (g *generator) component(t, doc reflect.Type, m mode) *Schema {
+	name := doc.Name()
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+
+	if owner, ok := g.owners[name]; ok {
+		if owner != t {
+			g.fail("schema %s is claimed by both %s and %s", name, owner, t)
+		} else if g.modes[t] != m {
+			g.fail("%s is used in both requests and responses", t)
+		}
+		return ref
+	}
+
+	// Register before describing the fields, so recursive types refer to themselves
+	g.owners[name] = t
+	g.modes[t] = m
+
+	s := g.object(doc, m)
+	if t != doc {
+		s.AdditionalProperties = &Schema{}
+	}
+	g.schemas[name] = s
+	return ref
+}
+
+// object describes a struct by its JSON fields
+func (g *generator) object(t reflect.Type, m mode) *Schema {
+	s := &Schema{Type: Types{"object"}, Properties: make(map[string]*Schema)}
+	g.fields(t, m, s, make(map[string]bool))
+	return s
+}
+
+// fields adds the JSON fields of struct t to s; fields of embedded structs are promoted
+// unless an outer field has the same name, as encoding/json does
+func (g *generator) fields(t reflect.Type, m mode, s *Schema, seen map[string]bool) {
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		omitempty := strings.Contains(","+opts+",", ",omitempty,")
+		fs := g.schema(ft, m)
+		if ft.Kind() == reflect.Pointer && !omitempty {
+			fs = nullable(fs)
+		}
+		s.Properties[name] = fs
+		if m == responseMode && !omitempty {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	for _, e := range embedded {
+		g.fields(e, m, s, seen)
+	}
+}
+
+// nullable returns a schema also allowing null
+func nullable(s *Schema) *Schema {
+	switch {
+	case s.Ref != "":
+		return &Schema{AnyOf: []*Schema{s, {Type: Types{"null"}}}}
+	case len(s.Type) == 0: // Anything, null included
+		return s
+	}
+	n := *s
+	n.Type = append(append(Types{}, s.Type...), "null")
+	return &n
+}
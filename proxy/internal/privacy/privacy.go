@@ -0,0 +1,132 @@
+// Package privacy pseudonymizes user identifiers before they are stored.
+//
+// User IDs are replaced by an HMAC-SHA256 keyed with a secret pepper, prefixed with the
+// pepper's ID so the pepper can be rotated: new writes use the current pepper, while
+// hashes made with previous peppers are still recognized and re-keyed as users return.
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// defaultPepperID identifies USER_ID_PEPPER when USER_ID_PEPPER_ID is not set
+const defaultPepperID = "1"
+
+// HashedPattern matches the stored IDs made by a Hasher; it is also valid in PostgreSQL
+const HashedPattern = `^u[A-Za-z0-9]+_[0-9a-f]{64}$`
+
+var (
+	pepperIDPattern = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+	hashedPattern   = regexp.MustCompile(HashedPattern)
+)
+
+// Pepper is an HMAC key with the ID recorded in the hashes it produces
+type Pepper struct {
+	ID     string
+	Secret []byte
+}
+
+// Hasher maps user IDs to stored IDs. A nil Hasher stores user IDs unchanged.
+type Hasher struct {
+	current  Pepper
+	previous []Pepper
+}
+
+// NewHasher creates a hasher that writes with current and still recognizes previous
+func NewHasher(current Pepper, previous ...Pepper) (*Hasher, error) {
+	seen := make(map[string]bool, len(previous)+1)
+	for _, p := range append([]Pepper{current}, previous...) {
+		if !pepperIDPattern.MatchString(p.ID) {
+			return nil, fmt.Errorf("invalid pepper ID %q: use letters and digits", p.ID)
+		}
+		if len(p.Secret) == 0 {
+			return nil, fmt.Errorf("pepper %q has no secret", p.ID)
+		}
+		if seen[p.ID] {
+			return nil, fmt.Errorf("duplicate pepper ID %q", p.ID)
+		}
+		seen[p.ID] = true
+	}
+	return &Hasher{current: current, previous: previous}, nil
+}
+
+// FromEnv builds a hasher from USER_ID_PEPPER (with USER_ID_PEPPER_ID, default "1") and
+// USER_ID_PREVIOUS_PEPPERS, a comma-separated list of id:secret pairs. It returns nil,
+// storing user IDs unchanged, when USER_ID_PEPPER is not set.
+func FromEnv() (*Hasher, error) {
+	secret := os.Getenv("USER_ID_PEPPER")
+	if secret == "" {
+		return nil, nil
+	}
+
+	id := os.Getenv("USER_ID_PEPPER_ID")
+	if id == "" {
+		id = defaultPepperID
+	}
+
+	var previous []Pepper
+	for _, entry := range strings.Split(os.Getenv("USER_ID_PREVIOUS_PEPPERS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		prevID, prevSecret, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid USER_ID_PREVIOUS_PEPPERS entry: want id:secret")
+		}
+		previous = append(previous, Pepper{ID: prevID, Secret: []byte(prevSecret)})
+	}
+
+	return NewHasher(Pepper{ID: id, Secret: []byte(secret)}, previous...)
+}
+
+// Hash returns the ID under which a user's data is stored
+func (h *Hasher) Hash(userID string) string {
+	if h == nil {
+		return userID
+	}
+	return hashWith(h.current, userID)
+}
+
+// Previous returns the IDs a user's data may still be stored under from previous peppers
+func (h *Hasher) Previous(userID string) []string {
+	if h == nil {
+		return nil
+	}
+	ids := make([]string, len(h.previous))
+	for i, p := range h.previous {
+		ids[i] = hashWith(p, userID)
+	}
+	return ids
+}
+
+// Candidates returns every ID a user's data may be stored under: the current hash, the
+// hashes of previous peppers and the raw ID of rows stored before hashing was enabled
+func (h *Hasher) Candidates(userID string) []string {
+	if h == nil {
+		return []string{userID}
+	}
+	return append(append([]string{h.Hash(userID)}, h.Previous(userID)...), userID)
+}
+
+// Enabled reports whether user IDs are hashed
+func (h *Hasher) Enabled() bool {
+	return h != nil
+}
+
+// IsHashed reports whether a stored ID was produced by a Hasher
+func IsHashed(storedID string) bool {
+	return hashedPattern.MatchString(storedID)
+}
+
+func hashWith(p Pepper, userID string) string {
+	mac := hmac.New(sha256.New, p.Secret)
+	mac.Write([]byte(userID))
+	return "u" + p.ID + "_" + hex.EncodeToString(mac.Sum(nil))
+}
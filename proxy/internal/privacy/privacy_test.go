@@ -0,0 +1,99 @@
+package privacy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHasher_Hash(t *testing.T) {
+	h, err := NewHasher(Pepper{ID: "2", Secret: []byte("new")}, Pepper{ID: "1", Secret: []byte("old")})
+	if err != nil {
+		t.Fatalf("NewHasher failed: %v", err)
+	}
+
+	stored := h.Hash("alice")
+	if !strings.HasPrefix(stored, "u2_") || !IsHashed(stored) {
+		t.Errorf("Expected a hash made with pepper 2, got %q", stored)
+	}
+	if h.Hash("alice") != stored {
+		t.Error("Expected hashing to be deterministic")
+	}
+	if h.Hash("bob") == stored {
+		t.Error("Expected different users to get different hashes")
+	}
+
+	old, _ := NewHasher(Pepper{ID: "1", Secret: []byte("old")})
+	previous := h.Previous("alice")
+	if len(previous) != 1 || previous[0] != old.Hash("alice") {
+		t.Errorf("Expected the previous pepper's hash, got %v", previous)
+	}
+
+	candidates := h.Candidates("alice")
+	want := []string{stored, old.Hash("alice"), "alice"}
+	if strings.Join(candidates, ",") != strings.Join(want, ",") {
+		t.Errorf("Candidates() = %v, want %v", candidates, want)
+	}
+}
+
+func TestHasher_Nil(t *testing.T) {
+	var h *Hasher
+
+	if h.Enabled() {
+		t.Error("Expected a nil hasher to be disabled")
+	}
+	if h.Hash("alice") != "alice" {
+		t.Error("Expected a nil hasher to store IDs unchanged")
+	}
+	if len(h.Previous("alice")) != 0 {
+		t.Error("Expected no previous IDs")
+	}
+	if c := h.Candidates("alice"); len(c) != 1 || c[0] != "alice" {
+		t.Errorf("Expected only the raw ID, got %v", c)
+	}
+	if IsHashed("alice") {
+		t.Error("Expected a raw ID not to look hashed")
+	}
+}
+
+func TestNewHasher_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  Pepper
+		previous []Pepper
+	}{
+		{"invalid ID", Pepper{ID: "a:b", Secret: []byte("x")}, nil},
+		{"empty secret", Pepper{ID: "1"}, nil},
+		{"duplicate ID", Pepper{ID: "1", Secret: []byte("x")}, []Pepper{{ID: "1", Secret: []byte("y")}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewHasher(tt.current, tt.previous...); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("USER_ID_PEPPER", "")
+	if h, err := FromEnv(); err != nil || h != nil {
+		t.Errorf("Expected no hasher without a pepper, got %v, %v", h, err)
+	}
+
+	t.Setenv("USER_ID_PEPPER", "secret")
+	t.Setenv("USER_ID_PEPPER_ID", "3")
+	t.Setenv("USER_ID_PREVIOUS_PEPPERS", "2:older, 1:oldest")
+	h, err := FromEnv()
+	if err != nil {
+		t.Fatalf("FromEnv failed: %v", err)
+	}
+	if !strings.HasPrefix(h.Hash("alice"), "u3_") || len(h.Previous("alice")) != 2 {
+		t.Errorf("Unexpected hasher: %+v", h)
+	}
+
+	t.Setenv("USER_ID_PREVIOUS_PEPPERS", "missing-separator")
+	if _, err := FromEnv(); err == nil {
+		t.Error("Expected an error for a malformed previous pepper")
+	}
+}
@@ -0,0 +1,158 @@
+// Package profiles resolves reviewer user IDs to display names and avatars.
+//
+// A Provider looks up a batch of users at once so a page of reviews costs one lookup.
+// Providers are wrapped in a Cache, and users who opted out of public profiles are
+// shown as anonymized.
+package profiles
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// AnonymousName is shown in place of the username of users who opted out
+const AnonymousName = "Anonymous"
+
+// defaultCacheTTL is how long looked-up profiles are reused
+const defaultCacheTTL = 10 * time.Minute
+
+// Profile is the public profile of a user
+type Profile struct {
+	UserID    string `json:"user_id"`
+	Username  string `json:"username"`
+	AvatarURL string `json:"avatar_url"`
+	OptedOut  bool   `json:"opted_out"`
+}
+
+// Public returns the profile as it may be shown to others: opted-out users are anonymized
+func (p Profile) Public() Profile {
+	if p.OptedOut {
+		return Profile{UserID: p.UserID, Username: AnonymousName, OptedOut: true}
+	}
+	return p
+}
+
+// Provider looks up the profiles of a batch of users. Unknown users are left out of the result.
+type Provider interface {
+	GetProfiles(ctx context.Context, userIDs []string) (map[string]Profile, error)
+}
+
+// Cache wraps a Provider and remembers its answers, including unknown users, for a TTL.
+// Only the users missing from the cache are passed on, in a single batch.
+type Cache struct {
+	provider Provider
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	profile Profile
+	found   bool
+	expires time.Time
+}
+
+// NewCache creates a cache in front of provider
+func NewCache(provider Provider, ttl time.Duration) *Cache {
+	return &Cache{
+		provider: provider,
+		ttl:      ttl,
+		entries:  make(map[string]cacheEntry),
+	}
+}
+
+// GetProfiles returns cached profiles and fetches the rest from the underlying provider
+func (c *Cache) GetProfiles(ctx context.Context, userIDs []string) (map[string]Profile, error) {
+	result := make(map[string]Profile, len(userIDs))
+	var missing []string
+	seen := make(map[string]bool, len(userIDs))
+
+	now := time.Now()
+	c.mu.Lock()
+	for _, id := range userIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		entry, ok := c.entries[id]
+		switch {
+		case !ok || now.After(entry.expires):
+			missing = append(missing, id)
+		case entry.found:
+			result[id] = entry.profile
+		}
+	}
+	c.mu.Unlock()
+
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	fetched, err := c.provider.GetProfiles(ctx, missing)
+	if err != nil {
+		return result, err
+	}
+
+	expires := time.Now().Add(c.ttl)
+	c.mu.Lock()
+	for id, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, id)
+		}
+	}
+	for _, id := range missing {
+		profile, found := fetched[id]
+		c.entries[id] = cacheEntry{profile: profile, found: found, expires: expires}
+		if found {
+			result[id] = profile
+		}
+	}
+	c.mu.Unlock()
+
+	return result, nil
+}
+
+// FromEnv builds the configured provider, wrapped in a cache. PROFILE_PROVIDER selects
+// "http" (the user service at USER_SERVICE_URL) or "table" (proxy_user_profiles via
+// store); when unset, "http" is used if USER_SERVICE_URL is set. Returns nil when
+// profiles are disabled. PROFILE_CACHE_TTL overrides the 10 minute cache lifetime.
+//
+// When hashedIDs is set, reviews carry hashed user IDs the user service cannot resolve
+// without the pepper, so only "table" is allowed and profiles are pushed to the proxy,
+// which hashes their IDs itself.
+func FromEnv(store Store, hashedIDs bool) (Provider, error) {
+	kind := os.Getenv("PROFILE_PROVIDER")
+	if kind == "" && os.Getenv("USER_SERVICE_URL") != "" {
+		kind = "http"
+	}
+	if kind == "http" && hashedIDs {
+		return nil, fmt.Errorf("PROFILE_PROVIDER=http cannot resolve hashed user IDs; push profiles to PUT /v0/users/{userId}/profile and use PROFILE_PROVIDER=table")
+	}
+
+	var provider Provider
+	switch kind {
+	case "", "none":
+		return nil, nil
+	case "http":
+		url := os.Getenv("USER_SERVICE_URL")
+		if url == "" {
+			return nil, fmt.Errorf("PROFILE_PROVIDER=http requires USER_SERVICE_URL")
+		}
+		provider = NewHTTPProvider(url, os.Getenv("USER_SERVICE_TOKEN"))
+	case "table":
+		provider = NewTableProvider(store)
+	default:
+		return nil, fmt.Errorf("unknown PROFILE_PROVIDER %q", kind)
+	}
+
+	ttl := defaultCacheTTL
+	if v, err := time.ParseDuration(os.Getenv("PROFILE_CACHE_TTL")); err == nil && v > 0 {
+		ttl = v
+	}
+
+	return NewCache(provider, ttl), nil
+}
@@ -0,0 +1,128 @@
+package profiles
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countingProvider records each batch it is asked for
+type countingProvider struct {
+	profiles map[string]Profile
+	batches  [][]string
+}
+
+func (p *countingProvider) GetProfiles(_ context.Context, userIDs []string) (map[string]Profile, error) {
+	p.batches = append(p.batches, append([]string(nil), userIDs...))
+	result := make(map[string]Profile)
+	for _, id := range userIDs {
+		if profile, ok := p.profiles[id]; ok {
+			result[id] = profile
+		}
+	}
+	return result, nil
+}
+
+func TestCacheBatchesMisses(t *testing.T) {
+	provider := &countingProvider{profiles: map[string]Profile{
+		"alice": {UserID: "alice", Username: "Alice"},
+		"bob":   {UserID: "bob", Username: "Bob"},
+	}}
+	cache := NewCache(provider, time.Minute)
+
+	got, err := cache.GetProfiles(context.Background(), []string{"alice", "ghost", "alice"})
+	if err != nil {
+		t.Fatalf("GetProfiles() error = %v", err)
+	}
+	if got["alice"].Username != "Alice" || len(got) != 1 {
+		t.Errorf("GetProfiles() = %v", got)
+	}
+
+	// alice and the unknown ghost are cached; only bob is fetched
+	if _, err := cache.GetProfiles(context.Background(), []string{"alice", "ghost", "bob"}); err != nil {
+		t.Fatalf("GetProfiles() error = %v", err)
+	}
+	if len(provider.batches) != 2 || strings.Join(provider.batches[1], ",") != "bob" {
+		t.Errorf("provider batches = %v, want second batch [bob]", provider.batches)
+	}
+}
+
+func TestProfilePublic(t *testing.T) {
+	p := Profile{UserID: "u1", Username: "Alice", AvatarURL: "https://example.test/a.png", OptedOut: true}.Public()
+	if p.Username != AnonymousName || p.AvatarURL != "" {
+		t.Errorf("Public() = %+v, want anonymized", p)
+	}
+
+	visible := Profile{UserID: "u2", Username: "Bob"}
+	if visible.Public() != visible {
+		t.Errorf("Public() changed a profile that did not opt out")
+	}
+}
+
+func TestHTTPProvider(t *testing.T) {
+	var requested [][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/profiles/batch" || r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			UserIDs []string `json:"user_ids"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		requested = append(requested, req.UserIDs)
+
+		var resp struct {
+			Profiles []Profile `json:"profiles"`
+		}
+		for _, id := range req.UserIDs {
+			resp.Profiles = append(resp.Profiles, Profile{UserID: id, Username: "name-" + id})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	ids := make([]string, maxBatchSize+1)
+	for i := range ids {
+		ids[i] = strings.Repeat("u", i+1)
+	}
+
+	got, err := NewHTTPProvider(srv.URL+"/", "secret").GetProfiles(context.Background(), ids)
+	if err != nil {
+		t.Fatalf("GetProfiles() error = %v", err)
+	}
+	if len(got) != len(ids) || got["u"].Username != "name-u" {
+		t.Errorf("GetProfiles() returned %d profiles", len(got))
+	}
+
+	if len(requested) != 2 {
+		t.Fatalf("requests = %d, want 2", len(requested))
+	}
+	sizes := []int{len(requested[0]), len(requested[1])}
+	sort.Ints(sizes)
+	if sizes[0] != 1 || sizes[1] != maxBatchSize {
+		t.Errorf("batch sizes = %v, want [1 %d]", sizes, maxBatchSize)
+	}
+}
+
+func TestFromEnvRejectsHTTPWithHashedIDs(t *testing.T) {
+	t.Setenv("PROFILE_PROVIDER", "")
+	t.Setenv("USER_SERVICE_URL", "http://users.example")
+
+	if provider, err := FromEnv(nil, false); err != nil || provider == nil {
+		t.Errorf("FromEnv(unhashed) = %v, %v, want the http provider", provider, err)
+	}
+	if _, err := FromEnv(nil, true); err == nil {
+		t.Error("FromEnv(hashed) error = nil, want http refused")
+	}
+
+	t.Setenv("PROFILE_PROVIDER", "table")
+	if provider, err := FromEnv(nil, true); err != nil || provider == nil {
+		t.Errorf("FromEnv(hashed, table) = %v, %v, want the table provider", provider, err)
+	}
+}
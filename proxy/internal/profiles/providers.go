@@ -0,0 +1,110 @@
+package profiles
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxBatchSize caps the user IDs sent to the user service in one request
+const maxBatchSize = 100
+
+// HTTPProvider looks up profiles from an external user service.
+//
+// It sends POST {baseURL}/profiles/batch with {"user_ids": [...]} and expects
+// {"profiles": [{"user_id", "username", "avatar_url", "opted_out"}]} in return.
+type HTTPProvider struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewHTTPProvider creates a provider for the user service at baseURL. A non-empty token
+// is sent as a bearer token.
+func NewHTTPProvider(baseURL, token string) *HTTPProvider {
+	return &HTTPProvider{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+	}
+}
+
+// GetProfiles fetches profiles in batches of at most maxBatchSize users
+func (p *HTTPProvider) GetProfiles(ctx context.Context, userIDs []string) (map[string]Profile, error) {
+	result := make(map[string]Profile, len(userIDs))
+	for start := 0; start < len(userIDs); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(userIDs) {
+			end = len(userIDs)
+		}
+		if err := p.fetch(ctx, userIDs[start:end], result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// fetch requests one batch of profiles and adds them to result
+func (p *HTTPProvider) fetch(ctx context.Context, userIDs []string, result map[string]Profile) error {
+	payload, err := json.Marshal(map[string][]string{"user_ids": userIDs})
+	if err != nil {
+		return fmt.Errorf("failed to encode profile request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/profiles/batch", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create profile request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach user service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("user service returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Profiles []Profile `json:"profiles"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("failed to decode user service response: %w", err)
+	}
+
+	for _, profile := range body.Profiles {
+		result[profile.UserID] = profile
+	}
+	return nil
+}
+
+// Store reads profiles from the proxy_user_profiles table
+type Store interface {
+	GetUserProfiles(ctx context.Context, userIDs []string) (map[string]Profile, error)
+}
+
+// TableProvider looks up profiles kept in the local proxy_user_profiles table
+type TableProvider struct {
+	store Store
+}
+
+// NewTableProvider creates a provider backed by the local profile table
+func NewTableProvider(store Store) *TableProvider {
+	return &TableProvider{store: store}
+}
+
+// GetProfiles reads the users' profiles in one query
+func (p *TableProvider) GetProfiles(ctx context.Context, userIDs []string) (map[string]Profile, error) {
+	return p.store.GetUserProfiles(ctx, userIDs)
+}
@@ -0,0 +1,171 @@
+// Package publisher verifies that a caller owns the namespace of a server.
+//
+// Ownership follows the registry's GitHub namespace rules: a GitHub account may act for
+// servers named io.github.<login>/... and io.github.<org>/... for every organization it
+// is a member of.
+package publisher
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// githubNamespacePrefix prefixes server names owned by a GitHub user or organization
+const githubNamespacePrefix = "io.github."
+
+// identityCacheTTL is how long a verified token is trusted before GitHub is asked again
+const identityCacheTTL = 5 * time.Minute
+
+// ErrInvalidToken is returned when GitHub rejects the token
+var ErrInvalidToken = errors.New("invalid GitHub token")
+
+// NamespaceOwner returns the GitHub user or organization owning a server name of the
+// form io.github.<owner>/..., or "" for names outside the GitHub namespace
+func NamespaceOwner(serverName string) string {
+	if !strings.HasPrefix(serverName, githubNamespacePrefix) {
+		return ""
+	}
+	owner, _, found := strings.Cut(strings.TrimPrefix(serverName, githubNamespacePrefix), "/")
+	if !found {
+		return ""
+	}
+	return owner
+}
+
+// Identity is a verified GitHub account and the organizations it belongs to
+type Identity struct {
+	Login string
+	Orgs  []string
+}
+
+// Owns reports whether the identity may act for the server. GitHub logins are case-insensitive.
+func (i *Identity) Owns(serverName string) bool {
+	owner := NamespaceOwner(serverName)
+	if owner == "" {
+		return false
+	}
+	if strings.EqualFold(owner, i.Login) {
+		return true
+	}
+	for _, org := range i.Orgs {
+		if strings.EqualFold(owner, org) {
+			return true
+		}
+	}
+	return false
+}
+
+// GitHubVerifier resolves GitHub access tokens to identities, caching results briefly
+type GitHubVerifier struct {
+	baseURL    string
+	httpClient *http.Client
+
+	mu    sync.Mutex
+	cache map[string]cachedIdentity
+}
+
+type cachedIdentity struct {
+	identity *Identity
+	expires  time.Time
+}
+
+// NewGitHubVerifier creates a verifier for the GitHub API at GITHUB_API_URL
+// (default https://api.github.com)
+func NewGitHubVerifier() *GitHubVerifier {
+	baseURL := os.Getenv("GITHUB_API_URL")
+	if baseURL == "" {
+		baseURL = "https://api.github.com"
+	}
+	return &GitHubVerifier{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		cache: make(map[string]cachedIdentity),
+	}
+}
+
+// Verify returns the identity behind a GitHub access token
+func (v *GitHubVerifier) Verify(ctx context.Context, token string) (*Identity, error) {
+	// Key the cache by a hash so tokens are never held in memory longer than a request
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+
+	v.mu.Lock()
+	cached, ok := v.cache[key]
+	v.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.identity, nil
+	}
+
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := v.get(ctx, token, "/user", &user); err != nil {
+		return nil, err
+	}
+	if user.Login == "" {
+		return nil, ErrInvalidToken
+	}
+
+	var orgs []struct {
+		Login string `json:"login"`
+	}
+	if err := v.get(ctx, token, "/user/orgs?per_page=100", &orgs); err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{Login: user.Login}
+	for _, org := range orgs {
+		identity.Orgs = append(identity.Orgs, org.Login)
+	}
+
+	v.mu.Lock()
+	now := time.Now()
+	for k, c := range v.cache {
+		if now.After(c.expires) {
+			delete(v.cache, k)
+		}
+	}
+	v.cache[key] = cachedIdentity{identity: identity, expires: now.Add(identityCacheTTL)}
+	v.mu.Unlock()
+
+	return identity, nil
+}
+
+// get fetches a GitHub API path with the caller's token and decodes the JSON response
+func (v *GitHubVerifier) get(ctx context.Context, token, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach GitHub: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return ErrInvalidToken
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GitHub returned status %d for %s", resp.StatusCode, path)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode GitHub response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNamespaceOwner(t *testing.T) {
+	tests := map[string]string{
+		"io.github.alice/weather":   "alice",
+		"io.github.acme-corp/tools": "acme-corp",
+		"io.github.alice":           "",
+		"com.example/server":        "",
+		"io.gitlab.alice/weather":   "",
+	}
+	for name, want := range tests {
+		if got := NamespaceOwner(name); got != want {
+			t.Errorf("NamespaceOwner(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestIdentityOwns(t *testing.T) {
+	id := &Identity{Login: "Alice", Orgs: []string{"acme-corp"}}
+
+	tests := []struct {
+		server string
+		want   bool
+	}{
+		{"io.github.alice/weather", true},
+		{"io.github.ACME-corp/tools", true},
+		{"io.github.bob/weather", false},
+		{"io.github.alice-fork/weather", false},
+		{"com.alice/weather", false},
+	}
+	for _, tt := range tests {
+		if got := id.Owns(tt.server); got != tt.want {
+			t.Errorf("Owns(%q) = %v, want %v", tt.server, got, tt.want)
+		}
+	}
+}
+
+func TestGitHubVerifier(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("Authorization") != "Bearer good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/user":
+			_, _ = w.Write([]byte(`{"login":"alice"}`))
+		case "/user/orgs":
+			_, _ = w.Write([]byte(`[{"login":"acme-corp"}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	t.Setenv("GITHUB_API_URL", srv.URL)
+	v := NewGitHubVerifier()
+
+	id, err := v.Verify(context.Background(), "good")
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if id.Login != "alice" || len(id.Orgs) != 1 || id.Orgs[0] != "acme-corp" {
+		t.Errorf("Verify() = %+v", id)
+	}
+
+	// A second lookup is served from the cache
+	if _, err := v.Verify(context.Background(), "good"); err != nil || calls != 2 {
+		t.Errorf("cached Verify() error = %v, GitHub calls = %d, want 2", err, calls)
+	}
+
+	if _, err := v.Verify(context.Background(), "bad"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify(bad) error = %v, want ErrInvalidToken", err)
+	}
+}
@@ -0,0 +1,148 @@
+// Package recommend picks servers a user has not installed yet.
+//
+// Servers are ranked by item-item collaborative filtering: every server in the user's
+// history votes for the servers its other installers also installed, weighted by how much
+// the user liked it and by the cosine similarity of the two servers' installers. Users
+// with too little history to go on get the most popular servers instead.
+package recommend
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/veriteknik/registry-proxy/internal/db"
+)
+
+// MinCoInstallers is the fewest other users who must have installed two servers for one
+// to be recommended because of the other. Besides ignoring coincidences, it keeps a
+// recommendation from revealing what a single other user installed.
+const MinCoInstallers = 2
+
+// Where a recommendation comes from
+const (
+	SourceCollaborative = "collaborative"
+	SourcePopular       = "popular"
+)
+
+// Recommendation is a server recommended to a user
+type Recommendation struct {
+	ServerID string  `json:"-"`
+	Score    float64 `json:"score"` // 0 for popular picks
+	Source   string  `json:"source"`
+	Reason   string  `json:"reason"`
+	BasedOn  string  `json:"based_on,omitempty"` // Server in the user's history that contributed most
+}
+
+// Weight is how much a server in a user's history says about their taste: an install or
+// a 3 star rating counts 1, and each star above or below adds or takes away half, so
+// servers rated 1 star count nothing
+func Weight(item db.UserItem) float64 {
+	if item.Rating == 0 {
+		return 1
+	}
+	return 1 + float64(item.Rating-3)/2
+}
+
+// contribution is the part of a candidate's score owed to one server in the history
+type contribution struct {
+	score float64
+	item  db.UserItem
+}
+
+// Recommend ranks the servers co-installed with the user's history (see GetItemCoInstalls),
+// best first, and fills the remaining places up to limit with popular servers. Servers in
+// the history are never recommended.
+func Recommend(history []db.UserItem, coInstalls []db.ItemCoInstall, installers map[string]int, popular []db.PopularServer, limit int) []Recommendation {
+	items := make(map[string]db.UserItem, len(history))
+	for _, item := range history {
+		items[item.ServerID] = item
+	}
+
+	scores := make(map[string]float64)
+	best := make(map[string]contribution)
+	for _, c := range coInstalls {
+		item, ok := items[c.ServerID]
+		if !ok || c.Users < MinCoInstallers {
+			continue
+		}
+		if _, seen := items[c.Neighbor]; seen {
+			continue
+		}
+		w := Weight(item)
+		n := installers[c.ServerID] * installers[c.Neighbor]
+		if w <= 0 || n == 0 {
+			continue
+		}
+
+		score := w * float64(c.Users) / math.Sqrt(float64(n))
+		scores[c.Neighbor] += score
+		if b, ok := best[c.Neighbor]; !ok || score > b.score || (score == b.score && item.ServerID < b.item.ServerID) {
+			best[c.Neighbor] = contribution{score: score, item: item}
+		}
+	}
+
+	recommendations := make([]Recommendation, 0, limit)
+	for id, score := range scores {
+		b := best[id]
+		recommendations = append(recommendations, Recommendation{
+			ServerID: id,
+			Score:    score,
+			Source:   SourceCollaborative,
+			Reason:   collaborativeReason(b.item),
+			BasedOn:  b.item.ServerID,
+		})
+	}
+	sort.Slice(recommendations, func(i, j int) bool {
+		if recommendations[i].Score != recommendations[j].Score {
+			return recommendations[i].Score > recommendations[j].Score
+		}
+		return recommendations[i].ServerID < recommendations[j].ServerID
+	})
+	if len(recommendations) > limit {
+		recommendations = recommendations[:limit]
+	}
+
+	picked := make(map[string]bool, len(recommendations))
+	for _, r := range recommendations {
+		picked[r.ServerID] = true
+	}
+
+	// Popularity fills in for users without history or whose servers few others installed
+	for _, p := range popular {
+		if len(recommendations) >= limit {
+			break
+		}
+		if _, seen := items[p.ServerID]; seen || picked[p.ServerID] {
+			continue
+		}
+		picked[p.ServerID] = true
+		recommendations = append(recommendations, Recommendation{
+			ServerID: p.ServerID,
+			Source:   SourcePopular,
+			Reason:   popularReason(p),
+		})
+	}
+
+	return recommendations
+}
+
+// collaborativeReason explains a recommendation by the server that contributed most
+func collaborativeReason(item db.UserItem) string {
+	if item.Rating > 0 {
+		return fmt.Sprintf("Installed by users who also installed %s, which you rated %d stars", item.ServerID, item.Rating)
+	}
+	return fmt.Sprintf("Installed by users who also installed %s", item.ServerID)
+}
+
+// popularReason explains a popular pick
+func popularReason(p db.PopularServer) string {
+	switch {
+	case p.ActiveInstalls > 0:
+		return fmt.Sprintf("Popular: %d active installs", p.ActiveInstalls)
+	case p.WeightedRating > 0:
+		return fmt.Sprintf("Popular: rated %.1f out of 5", p.WeightedRating)
+	default:
+		return "Popular in the registry"
+	}
+}
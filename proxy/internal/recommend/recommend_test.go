@@ -0,0 +1,88 @@
+package recommend
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/veriteknik/registry-proxy/internal/db"
+)
+
+func serverIDs(recommendations []Recommendation) []string {
+	ids := make([]string, len(recommendations))
+	for i, r := range recommendations {
+		ids[i] = r.ServerID
+	}
+	return ids
+}
+
+func TestRecommendCollaborative(t *testing.T) {
+	history := []db.UserItem{
+		{ServerID: "github", Installed: true, Rating: 5},
+		{ServerID: "slack", Installed: true},
+		{ServerID: "jira", Rating: 1},
+	}
+	coInstalls := []db.ItemCoInstall{
+		{ServerID: "github", Neighbor: "gitlab", Users: 4},
+		{ServerID: "slack", Neighbor: "gitlab", Users: 2},
+		{ServerID: "slack", Neighbor: "discord", Users: 3},
+		{ServerID: "slack", Neighbor: "github", Users: 5},    // Already installed
+		{ServerID: "jira", Neighbor: "confluence", Users: 6}, // Disliked
+		{ServerID: "github", Neighbor: "leaky", Users: 1},    // Too few users
+	}
+	installers := map[string]int{
+		"github": 9, "slack": 9, "jira": 6, "gitlab": 4, "discord": 9, "confluence": 6, "leaky": 1,
+	}
+	popular := []db.PopularServer{{ServerID: "gitlab", ActiveInstalls: 50}, {ServerID: "weather", ActiveInstalls: 40}}
+
+	got := Recommend(history, coInstalls, installers, popular, 5)
+
+	if want := []string{"gitlab", "discord", "weather"}; !reflect.DeepEqual(serverIDs(got), want) {
+		t.Fatalf("Recommend() = %v, want %v", serverIDs(got), want)
+	}
+	if got[0].Source != SourceCollaborative || got[0].BasedOn != "github" {
+		t.Errorf("gitlab = %+v, want a collaborative pick based on github", got[0])
+	}
+	if want := "Installed by users who also installed github, which you rated 5 stars"; got[0].Reason != want {
+		t.Errorf("reason = %q, want %q", got[0].Reason, want)
+	}
+	if want := "Installed by users who also installed slack"; got[1].Reason != want {
+		t.Errorf("reason = %q, want %q", got[1].Reason, want)
+	}
+	if got[2].Source != SourcePopular || got[2].Score != 0 || got[2].Reason != "Popular: 40 active installs" {
+		t.Errorf("weather = %+v, want a popular pick", got[2])
+	}
+}
+
+func TestRecommendColdStart(t *testing.T) {
+	popular := []db.PopularServer{
+		{ServerID: "a", ActiveInstalls: 10},
+		{ServerID: "b", WeightedRating: 4.25},
+		{ServerID: "c"},
+	}
+
+	got := Recommend(nil, nil, nil, popular, 2)
+
+	if want := []string{"a", "b"}; !reflect.DeepEqual(serverIDs(got), want) {
+		t.Fatalf("Recommend() = %v, want %v", serverIDs(got), want)
+	}
+	if got[1].Reason != "Popular: rated 4.2 out of 5" {
+		t.Errorf("reason = %q", got[1].Reason)
+	}
+}
+
+func TestWeight(t *testing.T) {
+	tests := []struct {
+		item db.UserItem
+		want float64
+	}{
+		{db.UserItem{Installed: true}, 1},
+		{db.UserItem{Installed: true, Rating: 5}, 2},
+		{db.UserItem{Rating: 4}, 1.5},
+		{db.UserItem{Rating: 1}, 0},
+	}
+	for _, tt := range tests {
+		if got := Weight(tt.item); got != tt.want {
+			t.Errorf("Weight(%+v) = %v, want %v", tt.item, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,407 @@
+// Package related periodically finds the servers most similar to each server.
+//
+// Two servers are similar when they share tags or a category, need the same API services
+// (judged from their environment variables), have similar descriptions or are installed by
+// the same users. The best matches of every server are written to proxy_server_related,
+// which backs /v0/servers/{id}/related.
+package related
+
+import (
+	"context"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/veriteknik/registry-proxy/internal/db"
+	"github.com/veriteknik/registry-proxy/internal/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	// minScore is the lowest score worth storing
+	minScore = 0.05
+
+	// minDescriptionSimilarity is the description similarity named as a reason
+	minDescriptionSimilarity = 0.1
+
+	// minTermServers is the fewest servers a feature may be shared by and still make them
+	// candidates, however few servers there are
+	minTermServers = 50
+)
+
+// Config configures the related scorer. The weights of the similarity of each feature,
+// all between 0 and 1, are summed into the score.
+type Config struct {
+	TagWeight         float64 // Jaccard similarity of the tags
+	CategoryWeight    float64 // Same category
+	EnvWeight         float64 // Jaccard similarity of the API services of the env vars
+	DescriptionWeight float64 // TF-IDF cosine similarity of the descriptions
+	CoInstallWeight   float64 // Cosine similarity of the users who installed each server
+	Limit             int     // Related servers kept per server
+	RefreshInterval   time.Duration
+}
+
+// DefaultConfig returns the scorer defaults
+func DefaultConfig() Config {
+	return Config{
+		TagWeight:         0.3,
+		CategoryWeight:    0.1,
+		EnvWeight:         0.15,
+		DescriptionWeight: 0.2,
+		CoInstallWeight:   0.25,
+		Limit:             20,
+		RefreshInterval:   6 * time.Hour,
+	}
+}
+
+// ConfigFromEnv builds a Config from RELATED_* environment variables, falling back to DefaultConfig
+func ConfigFromEnv() Config {
+	cfg := DefaultConfig()
+
+	if v, err := strconv.Atoi(os.Getenv("RELATED_LIMIT")); err == nil && v > 0 {
+		cfg.Limit = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("RELATED_REFRESH_INTERVAL")); err == nil && v > 0 {
+		cfg.RefreshInterval = v
+	}
+
+	return cfg
+}
+
+// Store reads server features and writes related servers
+type Store interface {
+	GetServerFeatures(ctx context.Context) ([]db.ServerFeatures, error)
+	GetCoInstalls(ctx context.Context) ([]db.CoInstall, map[string]int, error)
+	ReplaceRelatedServers(ctx context.Context, related []db.RelatedServer) error
+}
+
+// Scorer recomputes the related servers of every server
+type Scorer struct {
+	store Store
+	cfg   Config
+}
+
+// NewScorer creates a scorer
+func NewScorer(store Store, cfg Config) *Scorer {
+	return &Scorer{store: store, cfg: cfg}
+}
+
+// Refresh recomputes and replaces the related servers of every server
+func (s *Scorer) Refresh(ctx context.Context) error {
+	servers, err := s.store.GetServerFeatures(ctx)
+	if err != nil {
+		return err
+	}
+	coInstalls, installers, err := s.store.GetCoInstalls(ctx)
+	if err != nil {
+		return err
+	}
+
+	related := Compute(servers, coInstalls, installers, s.cfg)
+	if err := s.store.ReplaceRelatedServers(ctx, related); err != nil {
+		return err
+	}
+
+	utils.Logger.Debug("Refreshed related servers", zap.Int("servers", len(servers)), zap.Int("pairs", len(related)))
+	return nil
+}
+
+// Run refreshes the related servers immediately and then every RefreshInterval until ctx is done
+func (s *Scorer) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Refresh(ctx); err != nil {
+			utils.Logger.Warn("Failed to refresh related servers", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// server holds the features of a server in the form they are compared in
+type server struct {
+	id         string
+	category   string
+	tags       map[string]bool
+	services   map[string]bool
+	terms      map[string]float64 // L2-normalized TF-IDF vector of the description
+	installers int
+	coInstalls map[int]int // Users who also installed the server at that index
+}
+
+// Compute returns the related servers of every server, at most cfg.Limit each, best first.
+// Only servers sharing at least one selective feature, or installed together, are compared.
+func Compute(features []db.ServerFeatures, coInstalls []db.CoInstall, installers map[string]int, cfg Config) []db.RelatedServer {
+	servers := make([]server, len(features))
+	index := make(map[string]int, len(features))
+	for i, f := range features {
+		servers[i] = server{
+			id:         f.ServerID,
+			category:   f.Category,
+			tags:       toSet(f.Tags),
+			services:   envServices(f.EnvVars),
+			installers: installers[f.ServerID],
+			coInstalls: make(map[int]int),
+		}
+		index[f.ServerID] = i
+	}
+	descriptionVectors(servers, features)
+
+	for _, c := range coInstalls {
+		a, aok := index[c.A]
+		b, bok := index[c.B]
+		if aok && bok && a != b {
+			servers[a].coInstalls[b] = c.Users
+			servers[b].coInstalls[a] = c.Users
+		}
+	}
+
+	// Inverted index from each feature to the servers that have it
+	postings := make(map[string][]int)
+	for i := range servers {
+		for key := range servers[i].features() {
+			postings[key] = append(postings[key], i)
+		}
+	}
+
+	// Features shared by many servers, such as a large category, a common tag or a frequent
+	// description word, do not make servers candidates on their own; they still add to the
+	// score of servers compared for another feature. This keeps the comparisons well below
+	// every pair.
+	maxTermServers := len(servers) / 10
+	if maxTermServers < minTermServers {
+		maxTermServers = minTermServers
+	}
+
+	var related []db.RelatedServer
+	for i := range servers {
+		s := &servers[i]
+
+		candidates := make(map[int]bool)
+		for key := range s.features() {
+			if len(postings[key]) > maxTermServers {
+				continue
+			}
+			for _, j := range postings[key] {
+				candidates[j] = true
+			}
+		}
+		for j := range s.coInstalls {
+			candidates[j] = true
+		}
+		delete(candidates, i)
+
+		var matches []db.RelatedServer
+		for j := range candidates {
+			if match, ok := score(s, &servers[j], s.coInstalls[j], cfg); ok {
+				matches = append(matches, match)
+			}
+		}
+
+		sort.Slice(matches, func(a, b int) bool {
+			if matches[a].Score != matches[b].Score {
+				return matches[a].Score > matches[b].Score
+			}
+			return matches[a].RelatedID < matches[b].RelatedID
+		})
+		if len(matches) > cfg.Limit {
+			matches = matches[:cfg.Limit]
+		}
+		related = append(related, matches...)
+	}
+
+	return related
+}
+
+// features returns the inverted index keys of a server
+func (s *server) features() map[string]bool {
+	keys := make(map[string]bool, len(s.tags)+len(s.services)+len(s.terms)+1)
+	if s.category != "" {
+		keys["c:"+s.category] = true
+	}
+	for tag := range s.tags {
+		keys["t:"+tag] = true
+	}
+	for service := range s.services {
+		keys["e:"+service] = true
+	}
+	for term := range s.terms {
+		keys["d:"+term] = true
+	}
+	return keys
+}
+
+// score compares two servers installed together by coInstalls users, reporting false when
+// they are not related enough to store
+func score(a, b *server, coInstalls int, cfg Config) (db.RelatedServer, bool) {
+	type component struct {
+		reason       string
+		contribution float64
+		named        bool
+	}
+
+	description := cosine(a.terms, b.terms)
+	coInstall := 0.0
+	if coInstalls > 0 && a.installers > 0 && b.installers > 0 {
+		coInstall = math.Min(1, float64(coInstalls)/math.Sqrt(float64(a.installers)*float64(b.installers)))
+	}
+	sameCategory := 0.0
+	if a.category != "" && a.category == b.category {
+		sameCategory = 1
+	}
+
+	components := []component{
+		{db.ReasonSharedTags, cfg.TagWeight * jaccard(a.tags, b.tags), true},
+		{db.ReasonSameCategory, cfg.CategoryWeight * sameCategory, true},
+		{db.ReasonSharedEnv, cfg.EnvWeight * jaccard(a.services, b.services), true},
+		{db.ReasonSimilarDescription, cfg.DescriptionWeight * description, description >= minDescriptionSimilarity},
+		{db.ReasonCoInstalled, cfg.CoInstallWeight * coInstall, true},
+	}
+
+	match := db.RelatedServer{ServerID: a.id, RelatedID: b.id, CoInstalls: coInstalls}
+	for _, c := range components {
+		match.Score += c.contribution
+	}
+	if match.Score < minScore {
+		return db.RelatedServer{}, false
+	}
+
+	// Name the features behind the match, strongest first
+	sort.SliceStable(components, func(i, j int) bool { return components[i].contribution > components[j].contribution })
+	for _, c := range components {
+		if c.contribution > 0 && c.named {
+			match.Reasons = append(match.Reasons, c.reason)
+		}
+	}
+	if len(match.Reasons) == 0 {
+		return db.RelatedServer{}, false
+	}
+
+	match.Score = math.Round(match.Score*10000) / 10000
+	return match, true
+}
+
+// genericEnvWords are parts of env var names that do not identify an API service
+var genericEnvWords = toSet([]string{
+	"API", "APP", "AUTH", "BASE", "CLIENT", "CONFIG", "DATA", "DEBUG", "DIR", "ENV", "HOME",
+	"HOST", "ID", "KEY", "LOG", "LEVEL", "MCP", "MODE", "NODE", "PASSWORD", "PATH", "PORT",
+	"SECRET", "SERVER", "TIMEOUT", "TOKEN", "URL", "USER", "USERNAME",
+})
+
+// envServices returns the API services a server's env vars are for, taking the first part
+// of each name that is not generic: OPENAI_API_KEY and MCP_OPENAI_TOKEN are both OPENAI
+func envServices(envVars []string) map[string]bool {
+	services := make(map[string]bool)
+	for _, name := range envVars {
+		for _, part := range strings.Split(strings.ToUpper(name), "_") {
+			if len(part) >= 2 && !genericEnvWords[part] {
+				services[part] = true
+				break
+			}
+		}
+	}
+	return services
+}
+
+// stopWords are description words too common to tell servers apart
+var stopWords = toSet([]string{
+	"about", "access", "all", "allows", "also", "and", "any", "are", "based", "can", "context",
+	"for", "from", "has", "have", "into", "its", "mcp", "model", "more", "new", "not", "other",
+	"protocol", "provides", "server", "servers", "simple", "support", "supports", "that",
+	"the", "their", "this", "through", "tool", "tools", "use", "used", "using", "via",
+	"which", "with", "you", "your",
+})
+
+// descriptionVectors sets the L2-normalized TF-IDF vector of every server's description
+func descriptionVectors(servers []server, features []db.ServerFeatures) {
+	counts := make([]map[string]int, len(features))
+	docFreq := make(map[string]int)
+	for i, f := range features {
+		counts[i] = make(map[string]int)
+		for _, term := range tokenize(f.Description) {
+			counts[i][term]++
+		}
+		for term := range counts[i] {
+			docFreq[term]++
+		}
+	}
+
+	n := float64(len(features))
+	for i := range servers {
+		vector := make(map[string]float64, len(counts[i]))
+		norm := 0.0
+		for term, count := range counts[i] {
+			// Terms in every description carry no information
+			idf := math.Log(n / float64(docFreq[term]))
+			if idf <= 0 {
+				continue
+			}
+			weight := float64(count) * idf
+			vector[term] = weight
+			norm += weight * weight
+		}
+		norm = math.Sqrt(norm)
+		for term := range vector {
+			vector[term] /= norm
+		}
+		servers[i].terms = vector
+	}
+}
+
+// tokenize splits a description into lowercase words of three or more letters or digits,
+// leaving out stop words
+func tokenize(text string) []string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(word) >= 3 && !stopWords[word] {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// cosine returns the cosine similarity of two L2-normalized vectors
+func cosine(a, b map[string]float64) float64 {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	dot := 0.0
+	for term, weight := range a {
+		dot += weight * b[term]
+	}
+	return math.Min(1, dot)
+}
+
+// jaccard returns the Jaccard similarity of two sets, 0 when both are empty
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for key := range a {
+		if b[key] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
@@ -0,0 +1,128 @@
+package related
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/veriteknik/registry-proxy/internal/db"
+)
+
+// memoryStore serves fixed features and records the stored related servers
+type memoryStore struct {
+	servers    []db.ServerFeatures
+	coInstalls []db.CoInstall
+	installers map[string]int
+	stored     []db.RelatedServer
+}
+
+func (s *memoryStore) GetServerFeatures(context.Context) ([]db.ServerFeatures, error) {
+	return s.servers, nil
+}
+
+func (s *memoryStore) GetCoInstalls(context.Context) ([]db.CoInstall, map[string]int, error) {
+	return s.coInstalls, s.installers, nil
+}
+
+func (s *memoryStore) ReplaceRelatedServers(_ context.Context, related []db.RelatedServer) error {
+	s.stored = related
+	return nil
+}
+
+func TestScorerRefresh(t *testing.T) {
+	store := &memoryStore{
+		servers: []db.ServerFeatures{
+			{ServerID: "github", Description: "Manage GitHub issues and pull requests", Category: "developer-tools",
+				Tags: []string{"git", "github"}, EnvVars: []string{"GITHUB_PERSONAL_ACCESS_TOKEN"}},
+			{ServerID: "github-actions", Description: "Run GitHub Actions workflows", Category: "developer-tools",
+				Tags: []string{"github", "ci"}, EnvVars: []string{"GITHUB_TOKEN", "LOG_LEVEL"}},
+			{ServerID: "weather", Description: "Weather forecasts for any city", Category: "data",
+				Tags: []string{"weather"}, EnvVars: []string{"OPENWEATHER_API_KEY"}},
+			{ServerID: "slack", Description: "Post messages to Slack channels", Category: "communication",
+				Tags: []string{"chat"}, EnvVars: []string{"SLACK_BOT_TOKEN"}},
+		},
+		coInstalls: []db.CoInstall{{A: "slack", B: "weather", Users: 2}},
+		installers: map[string]int{"slack": 4, "weather": 2},
+	}
+
+	if err := NewScorer(store, DefaultConfig()).Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	related := make(map[string][]db.RelatedServer)
+	for _, r := range store.stored {
+		related[r.ServerID] = append(related[r.ServerID], r)
+	}
+
+	github := related["github"]
+	if len(github) != 1 || github[0].RelatedID != "github-actions" {
+		t.Fatalf("related of github = %+v, want github-actions only", github)
+	}
+	wantReasons := []string{db.ReasonSharedEnv, db.ReasonSameCategory, db.ReasonSharedTags}
+	if !reflect.DeepEqual(github[0].Reasons, wantReasons) {
+		t.Errorf("reasons = %v, want %v", github[0].Reasons, wantReasons)
+	}
+
+	// Co-installation alone relates servers without shared features, in both directions
+	slack := related["slack"]
+	if len(slack) != 1 || slack[0].RelatedID != "weather" || slack[0].CoInstalls != 2 {
+		t.Fatalf("related of slack = %+v, want weather co-installed by 2 users", slack)
+	}
+	if !reflect.DeepEqual(slack[0].Reasons, []string{db.ReasonCoInstalled}) {
+		t.Errorf("reasons = %v, want co_installed", slack[0].Reasons)
+	}
+	if len(related["weather"]) != 1 || related["weather"][0].Score != slack[0].Score {
+		t.Errorf("related of weather = %+v, want slack with the same score", related["weather"])
+	}
+}
+
+func TestComputeKeepsBestMatches(t *testing.T) {
+	servers := []db.ServerFeatures{{ServerID: "a", Tags: []string{"x", "y"}}}
+	for _, id := range []string{"b", "c", "d"} {
+		servers = append(servers, db.ServerFeatures{ServerID: id, Tags: []string{"x"}})
+	}
+	servers[1].Tags = []string{"x", "y"}
+
+	cfg := DefaultConfig()
+	cfg.Limit = 2
+
+	var fromA []string
+	for _, r := range Compute(servers, nil, nil, cfg) {
+		if r.ServerID == "a" {
+			fromA = append(fromA, r.RelatedID)
+		}
+	}
+	if want := []string{"b", "c"}; !reflect.DeepEqual(fromA, want) {
+		t.Errorf("related of a = %v, want %v", fromA, want)
+	}
+}
+
+// TestComputeSkipsCommonFeatures tests that a category shared by more servers than the cap
+// does not relate them on its own but still scores servers sharing a tag
+func TestComputeSkipsCommonFeatures(t *testing.T) {
+	var servers []db.ServerFeatures
+	for i := 0; i < minTermServers+10; i++ {
+		servers = append(servers, db.ServerFeatures{ServerID: fmt.Sprintf("server-%d", i), Category: "data"})
+	}
+	servers[0].Tags = []string{"weather"}
+	servers[1].Tags = []string{"weather"}
+
+	related := Compute(servers, nil, nil, DefaultConfig())
+
+	if len(related) != 2 || related[0].RelatedID != "server-1" || related[1].RelatedID != "server-0" {
+		t.Fatalf("related = %+v, want server-0 and server-1 related to each other only", related)
+	}
+	wantReasons := []string{db.ReasonSharedTags, db.ReasonSameCategory}
+	if !reflect.DeepEqual(related[0].Reasons, wantReasons) {
+		t.Errorf("reasons = %v, want %v", related[0].Reasons, wantReasons)
+	}
+}
+
+func TestEnvServices(t *testing.T) {
+	got := envServices([]string{"OPENAI_API_KEY", "MCP_OPENAI_TOKEN", "API_KEY", "LOG_LEVEL", "github_token"})
+	want := map[string]bool{"OPENAI": true, "GITHUB": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("envServices() = %v, want %v", got, want)
+	}
+}
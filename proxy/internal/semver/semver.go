@@ -0,0 +1,152 @@
+// Package semver compares the versions servers are published under.
+//
+// Versions follow Semantic Versioning 2.0.0, leniently: a leading "v" is allowed and a
+// missing minor or patch number counts as 0, so "v1.2" equals "1.2.0". Build metadata
+// is ignored.
+package semver
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed semantic version
+type Version struct {
+	Major, Minor, Patch uint64
+	Prerelease          []string
+}
+
+// Parse parses a version, reporting false if it is not a semantic version
+func Parse(s string) (Version, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+
+	var v Version
+	core, prerelease, hasPrerelease := strings.Cut(s, "-")
+	if hasPrerelease {
+		v.Prerelease = strings.Split(prerelease, ".")
+		for _, id := range v.Prerelease {
+			if !validIdentifier(id) {
+				return Version{}, false
+			}
+		}
+	}
+
+	parts := strings.Split(core, ".")
+	if len(parts) > 3 {
+		return Version{}, false
+	}
+	numbers := []*uint64{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		if !isNumeric(part) {
+			return Version{}, false
+		}
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return Version{}, false
+		}
+		*numbers[i] = n
+	}
+
+	return v, true
+}
+
+// Compare returns -1, 0 or +1 as a is lower than, equal to or higher than b
+func (a Version) Compare(b Version) int {
+	if c := compareUint(a.Major, b.Major); c != 0 {
+		return c
+	}
+	if c := compareUint(a.Minor, b.Minor); c != 0 {
+		return c
+	}
+	if c := compareUint(a.Patch, b.Patch); c != 0 {
+		return c
+	}
+
+	// A pre-release is lower than the release itself
+	switch {
+	case len(a.Prerelease) == 0 && len(b.Prerelease) == 0:
+		return 0
+	case len(a.Prerelease) == 0:
+		return 1
+	case len(b.Prerelease) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(a.Prerelease) && i < len(b.Prerelease); i++ {
+		if c := compareIdentifier(a.Prerelease[i], b.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	return compareUint(uint64(len(a.Prerelease)), uint64(len(b.Prerelease)))
+}
+
+// Newer reports whether latest is a newer version than current. Versions that are not
+// semantic versions are only compared for equality, and an unknown current version is
+// never outdated.
+func Newer(latest, current string) bool {
+	if latest == "" || current == "" {
+		return false
+	}
+	l, lok := Parse(latest)
+	c, cok := Parse(current)
+	if !lok || !cok {
+		return latest != current
+	}
+	return l.Compare(c) > 0
+}
+
+// compareIdentifier orders pre-release identifiers: numeric ones numerically and below
+// alphanumeric ones, which are ordered as strings
+func compareIdentifier(a, b string) int {
+	aNum, bNum := isNumeric(a), isNumeric(b)
+	switch {
+	case aNum && bNum:
+		if c := compareUint(uint64(len(a)), uint64(len(b))); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	case aNum:
+		return -1
+	case bNum:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// isNumeric reports whether s is a number without leading zeros
+func isNumeric(s string) bool {
+	if s == "" || (len(s) > 1 && s[0] == '0') {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func validIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
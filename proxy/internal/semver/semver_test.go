@@ -0,0 +1,67 @@
+package semver
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"v1.2", "1.2.0", 0},
+		{"1.0.0+build.5", "1.0.0", 0},
+		{"1.10.0", "1.9.0", 1},
+		{"2.0.0", "10.0.0", -1},
+		{"1.0.1", "1.0.0", 1},
+		// Pre-release precedence from the Semantic Versioning spec
+		{"1.0.0-alpha", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"1.0.0-rc.1", "1.0.0-beta.11", 1},
+	}
+
+	for _, tt := range tests {
+		a, ok := Parse(tt.a)
+		if !ok {
+			t.Fatalf("Parse(%q) failed", tt.a)
+		}
+		b, ok := Parse(tt.b)
+		if !ok {
+			t.Fatalf("Parse(%q) failed", tt.b)
+		}
+		if got := a.Compare(b); got != tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, s := range []string{"", "latest", "1.2.3.4", "01.2.3", "1.x", "1.0.0-", "1.0.0-rc..1"} {
+		if _, ok := Parse(s); ok {
+			t.Errorf("Parse(%q) succeeded, want failure", s)
+		}
+	}
+}
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		latest, current string
+		want            bool
+	}{
+		{"1.2.0", "1.1.9", true},
+		{"1.2.0", "1.2.0", false},
+		{"1.2.0", "2.0.0", false},
+		{"1.2.0", "1.2.0-rc.1", true},
+		{"2024-06-01", "2024-05-01", true},
+		{"latest", "latest", false},
+		{"1.2.0", "", false},
+		{"", "1.2.0", false},
+	}
+
+	for _, tt := range tests {
+		if got := Newer(tt.latest, tt.current); got != tt.want {
+			t.Errorf("Newer(%q, %q) = %v, want %v", tt.latest, tt.current, got, tt.want)
+		}
+	}
+}
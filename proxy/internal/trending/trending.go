@@ -0,0 +1,122 @@
+// Package trending periodically scores servers by recent install and rating velocity.
+//
+// The scores are written to proxy_server_trending, which backs both the trending sort of
+// /v0/enhanced/servers and /v0/enhanced/stats/trending, so the two always agree.
+package trending
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/veriteknik/registry-proxy/internal/db"
+	"github.com/veriteknik/registry-proxy/internal/utils"
+	"go.uber.org/zap"
+)
+
+// Config configures the trending scorer
+type Config struct {
+	HalfLife        time.Duration // Age at which an install or rating counts half
+	InstallWeight   float64
+	RatingWeight    float64
+	QualityWeight   float64 // Weight of the Bayesian-weighted rating
+	RefreshInterval time.Duration
+}
+
+// DefaultConfig returns the scorer defaults
+func DefaultConfig() Config {
+	return Config{
+		HalfLife:        72 * time.Hour,
+		InstallWeight:   1,
+		RatingWeight:    3,
+		QualityWeight:   0.5,
+		RefreshInterval: 15 * time.Minute,
+	}
+}
+
+// ConfigFromEnv builds a Config from TRENDING_* environment variables, falling back to DefaultConfig
+func ConfigFromEnv() Config {
+	cfg := DefaultConfig()
+
+	if v, err := time.ParseDuration(os.Getenv("TRENDING_HALF_LIFE")); err == nil && v > 0 {
+		cfg.HalfLife = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("TRENDING_INSTALL_WEIGHT"), 64); err == nil && v >= 0 {
+		cfg.InstallWeight = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("TRENDING_RATING_WEIGHT"), 64); err == nil && v >= 0 {
+		cfg.RatingWeight = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("TRENDING_QUALITY_WEIGHT"), 64); err == nil && v >= 0 {
+		cfg.QualityWeight = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("TRENDING_REFRESH_INTERVAL")); err == nil && v > 0 {
+		cfg.RefreshInterval = v
+	}
+
+	return cfg
+}
+
+// Weights converts the config into the parameters of the scoring query
+func (c Config) Weights() db.TrendingWeights {
+	return db.TrendingWeights{
+		HalfLifeDays: c.HalfLife.Hours() / 24,
+		Install:      c.InstallWeight,
+		Rating:       c.RatingWeight,
+		Quality:      c.QualityWeight,
+	}
+}
+
+// Store writes trending scores
+type Store interface {
+	RefreshTrendingScores(ctx context.Context, period string, days int, w db.TrendingWeights) (int, error)
+}
+
+// Scorer recomputes the trending scores of every period
+type Scorer struct {
+	store Store
+	cfg   Config
+}
+
+// NewScorer creates a scorer
+func NewScorer(store Store, cfg Config) *Scorer {
+	return &Scorer{store: store, cfg: cfg}
+}
+
+// Refresh recomputes the scores of every period in db.TrendingPeriods
+func (s *Scorer) Refresh(ctx context.Context) error {
+	periods := make([]string, 0, len(db.TrendingPeriods))
+	for period := range db.TrendingPeriods {
+		periods = append(periods, period)
+	}
+	sort.Strings(periods)
+
+	for _, period := range periods {
+		scored, err := s.store.RefreshTrendingScores(ctx, period, db.TrendingPeriods[period], s.cfg.Weights())
+		if err != nil {
+			return err
+		}
+		utils.Logger.Debug("Refreshed trending scores", zap.String("period", period), zap.Int("servers", scored))
+	}
+	return nil
+}
+
+// Run refreshes the scores immediately and then every RefreshInterval until ctx is done
+func (s *Scorer) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Refresh(ctx); err != nil {
+			utils.Logger.Warn("Failed to refresh trending scores", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package trending
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/veriteknik/registry-proxy/internal/db"
+)
+
+// recordingStore records each refresh
+type recordingStore struct {
+	calls []string
+	w     db.TrendingWeights
+}
+
+func (s *recordingStore) RefreshTrendingScores(_ context.Context, period string, days int, w db.TrendingWeights) (int, error) {
+	s.calls = append(s.calls, period)
+	s.w = w
+	return 0, nil
+}
+
+func TestScorerRefreshesEveryPeriod(t *testing.T) {
+	store := &recordingStore{}
+	cfg := DefaultConfig()
+	cfg.HalfLife = 36 * time.Hour
+
+	if err := NewScorer(store, cfg).Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	if got := strings.Join(store.calls, ","); got != "30d,7d,90d" {
+		t.Errorf("refreshed periods = %s, want 30d,7d,90d", got)
+	}
+	if store.w.HalfLifeDays != 1.5 || store.w.Rating != cfg.RatingWeight {
+		t.Errorf("weights = %+v", store.w)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("TRENDING_HALF_LIFE", "24h")
+	t.Setenv("TRENDING_INSTALL_WEIGHT", "2")
+	t.Setenv("TRENDING_RATING_WEIGHT", "-1") // Ignored: weights cannot be negative
+	t.Setenv("TRENDING_REFRESH_INTERVAL", "bogus")
+
+	cfg := ConfigFromEnv()
+	def := DefaultConfig()
+
+	if cfg.HalfLife != 24*time.Hour || cfg.InstallWeight != 2 {
+		t.Errorf("ConfigFromEnv() = %+v", cfg)
+	}
+	if cfg.RatingWeight != def.RatingWeight || cfg.RefreshInterval != def.RefreshInterval {
+		t.Errorf("invalid values should keep defaults, got %+v", cfg)
+	}
+}